@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// --- Bounces and Complaints ---
+// Bounce and complaint notifications arrive through the email events webhook (generic JSON
+// or a raw report) or as DSN/ARF messages delivered to BOUNCE_MAILDIR. Hard bounces and
+// complaints suppress the address, mark the contact's email as bad and flag the invite's
+// guest list item; soft bounces are only logged. Outgoing messages carry an X-Outbox-ID
+// header so a returned report can be tied back to the message and its item.
+
+const (
+	emailFeedbackBounce    = "bounce"
+	emailFeedbackComplaint = "complaint"
+
+	outboxIDHeader        = "X-Outbox-ID"
+	bounceMaildirInterval = 5 * time.Minute
+)
+
+var errNotBounceReport = errors.New("not a delivery status notification or feedback report")
+
+// emailFeedback is one bounce or complaint. It's also the generic webhook format.
+type emailFeedback struct {
+	Type       string `json:"type"`        // "bounce" or "complaint"
+	Email      string `json:"email"`       // the recipient that bounced or complained
+	BounceType string `json:"bounce_type"` // "hard" or "soft"; inferred from status if empty
+	Status     string `json:"status"`      // enhanced status code, e.g. "5.1.1"
+	Diagnostic string `json:"diagnostic"`  // remote server's response or feedback details
+	MessageID  string `json:"message_id"`  // outbox message ID from the X-Outbox-ID header, if known
+	OccurredAt string `json:"occurred_at"`
+}
+
+// permanent reports whether the address should no longer be sent to
+func (f emailFeedback) permanent() bool {
+	return f.Type == emailFeedbackComplaint || f.BounceType == "hard"
+}
+
+// emailFeedbackResult summarises what a bounce or complaint changed
+type emailFeedbackResult struct {
+	Type          string   `json:"type"`
+	BounceType    string   `json:"bounce_type,omitempty"`
+	Suppressed    bool     `json:"suppressed"`
+	OutboxMessage string   `json:"outbox_message,omitempty"`
+	GuestListItem string   `json:"guest_list_item,omitempty"`
+	Contacts      []string `json:"contacts"`
+}
+
+// normaliseEmailFeedback validates a notification and fills in the bounce type.
+// Bounces without a type or status are treated as hard.
+func normaliseEmailFeedback(f *emailFeedback) error {
+	f.Type = strings.ToLower(strings.TrimSpace(f.Type))
+	f.Email = normaliseEmail(strings.Trim(strings.TrimSpace(f.Email), "<>"))
+	f.BounceType = strings.ToLower(strings.TrimSpace(f.BounceType))
+	f.Status = strings.TrimSpace(f.Status)
+
+	if f.Type != emailFeedbackBounce && f.Type != emailFeedbackComplaint {
+		return fmt.Errorf("type must be %q or %q", emailFeedbackBounce, emailFeedbackComplaint)
+	}
+	if !strings.Contains(f.Email, "@") {
+		return errors.New("a valid email is required")
+	}
+	if f.Type == emailFeedbackComplaint {
+		f.BounceType = ""
+		return nil
+	}
+	switch f.BounceType {
+	case "hard", "soft":
+	case "":
+		f.BounceType = "hard"
+		if strings.HasPrefix(f.Status, "4") {
+			f.BounceType = "soft"
+		}
+	default:
+		return errors.New(`bounce_type must be "hard" or "soft"`)
+	}
+	return nil
+}
+
+// processEmailFeedback applies a validated bounce or complaint. source ("webhook",
+// "maildir") is recorded on the suppression.
+func processEmailFeedback(app *pocketbase.PocketBase, f emailFeedback, source string) (*emailFeedbackResult, error) {
+	result := &emailFeedbackResult{Type: f.Type, BounceType: f.BounceType, Contacts: []string{}}
+
+	status, reason, activityType := "bounced", suppressionHardBounce, "email_bounced"
+	switch {
+	case f.Type == emailFeedbackComplaint:
+		status, reason, activityType = "complained", suppressionComplaint, "email_complaint"
+	case !f.permanent():
+		activityType = "email_soft_bounced"
+	}
+	detail := strings.TrimSpace(f.Status + " " + f.Diagnostic)
+	now := types.NowDateTime()
+
+	if f.permanent() {
+		if _, err := addEmailSuppression(app, f.Email, reason, source, detail, ""); err != nil {
+			return nil, fmt.Errorf("failed to suppress address: %w", err)
+		}
+		result.Suppressed = true
+	}
+
+	// The message it was about, and through it the invite's guest list item
+	msg := findFeedbackOutboxMessage(app, f)
+	if msg != nil {
+		result.OutboxMessage = msg.Id
+
+		logStatus := status
+		if !f.permanent() {
+			logStatus = "soft_bounce"
+		}
+		var entries []outboxLogEntry
+		decodeJSONField(msg, "log", &entries)
+		entries = append(entries, outboxLogEntry{
+			Attempt:  msg.GetInt("attempts"),
+			At:       now.String(),
+			Status:   logStatus,
+			Provider: source,
+			Error:    detail,
+		})
+		if len(entries) > outboxLogMax {
+			entries = entries[len(entries)-outboxLogMax:]
+		}
+		msg.Set("log", entries)
+		if f.permanent() {
+			msg.Set("status", status)
+			msg.Set("last_error", detail)
+		}
+		if err := app.Save(msg); err != nil {
+			log.Printf("[Bounce] Failed to update outbox message %s: %v", msg.Id, err)
+		}
+
+		eventType := emailEventBounced
+		switch {
+		case f.Type == emailFeedbackComplaint:
+			eventType = emailEventComplained
+		case !f.permanent():
+			eventType = emailEventSoftBounced
+		}
+		recordEmailEvent(app, emailEvent{Type: eventType, Message: msg, Detail: detail})
+
+		if itemID := msg.GetString("guest_list_item"); itemID != "" && f.permanent() {
+			setItemEmailStatus(app, itemID, status, detail)
+			result.GuestListItem = itemID
+		}
+	}
+
+	idx := utils.BlindIndex(f.Email)
+	contacts, err := app.FindRecordsByFilter(utils.CollectionContacts,
+		"email_index = {:idx} || personal_email_index = {:idx}", "", 0, 0, dbx.Params{"idx": idx})
+	if err != nil || idx == "" {
+		contacts = nil
+	}
+	for _, contact := range contacts {
+		result.Contacts = append(result.Contacts, contact.Id)
+
+		// Only the primary email is marked; that's the address invites go to
+		if f.permanent() && contact.GetString("email_index") == idx {
+			contact.Set("email_bounce_status", status)
+			contact.Set("email_bounce_detail", detail)
+			contact.Set("email_bounce_at", now)
+
+			// Decrypt PII fields before save so PocketBase validation passes
+			for _, field := range []string{"email", "personal_email", "phone", "bio", "location"} {
+				if v := contact.GetString(field); v != "" {
+					contact.Set(field, utils.DecryptField(v))
+				}
+			}
+			if err := app.Save(contact); err != nil {
+				log.Printf("[Bounce] Failed to mark contact %s: %v", contact.Id, err)
+			}
+		}
+
+		logFeedbackActivity(app, contact.Id, activityType, f, msg)
+	}
+
+	action := "email_bounce"
+	if f.Type == emailFeedbackComplaint {
+		action = "email_complaint"
+	}
+	utils.LogAudit(app, utils.AuditEntry{
+		Action:       action,
+		ResourceType: utils.CollectionEmailOutbox,
+		ResourceID:   result.OutboxMessage,
+		Changes: map[string]any{
+			"source":          source,
+			"bounce_type":     f.BounceType,
+			"status":          f.Status,
+			"suppressed":      result.Suppressed,
+			"guest_list_item": result.GuestListItem,
+			"contacts":        result.Contacts,
+		},
+		Status: "success",
+	})
+
+	log.Printf("[Bounce] %s %s for %s (%s) — %d contacts", f.BounceType, f.Type, f.Email, detail, len(result.Contacts))
+	return result, nil
+}
+
+// findFeedbackOutboxMessage finds the message a notification refers to: by its outbox ID
+// when the report carried one, otherwise the latest message sent to the address
+func findFeedbackOutboxMessage(app core.App, f emailFeedback) *core.Record {
+	idx := utils.BlindIndex(f.Email)
+	if idx == "" {
+		return nil
+	}
+	if f.MessageID != "" {
+		if msg, err := app.FindRecordById(utils.CollectionEmailOutbox, f.MessageID); err == nil && msg.GetString("to_email_index") == idx {
+			return msg
+		}
+	}
+	msgs, err := app.FindRecordsByFilter(utils.CollectionEmailOutbox,
+		"to_email_index = {:idx} && status = 'sent'", "-sent_at", 1, 0, dbx.Params{"idx": idx})
+	if err != nil || len(msgs) == 0 {
+		return nil
+	}
+	return msgs[0]
+}
+
+// logFeedbackActivity adds a bounce or complaint to the contact's activity timeline
+func logFeedbackActivity(app core.App, contactID, activityType string, f emailFeedback, msg *core.Record) {
+	collection, err := app.FindCollectionByNameOrId(utils.CollectionActivities)
+	if err != nil {
+		return
+	}
+
+	title := "Email to " + f.Email
+	if msg != nil {
+		title = fmt.Sprintf("%q", msg.GetString("subject"))
+	}
+	switch activityType {
+	case "email_complaint":
+		title += " was reported as spam"
+	case "email_soft_bounced":
+		title += " was temporarily undeliverable"
+	default:
+		title += " bounced"
+	}
+
+	metadata := map[string]any{
+		"email":       f.Email,
+		"bounce_type": f.BounceType,
+		"status":      f.Status,
+		"diagnostic":  f.Diagnostic,
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("type", activityType)
+	record.Set("title", title)
+	record.Set("contact", contactID)
+	record.Set("source_app", "crm")
+	if msg != nil {
+		record.Set("source_id", msg.Id)
+		metadata["kind"] = msg.GetString("kind")
+		metadata["guest_list_item"] = msg.GetString("guest_list_item")
+	}
+	record.Set("metadata", metadata)
+	occurredAt := f.OccurredAt
+	if occurredAt == "" {
+		occurredAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	record.Set("occurred_at", occurredAt)
+
+	if err := app.Save(record); err != nil {
+		log.Printf("[Bounce] Failed to log activity for contact %s: %v", contactID, err)
+	}
+}
+
+// --- Report parsing ---
+
+// parseBounceReport extracts the failures from a raw RFC 3464 delivery status notification
+// (report-type=delivery-status) or RFC 5965 ARF complaint (report-type=feedback-report).
+// Delayed, delivered and relayed recipients are ignored.
+func parseBounceReport(raw []byte) ([]emailFeedback, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" {
+		return nil, errNotBounceReport
+	}
+
+	occurredAt := ""
+	if date, err := msg.Header.Date(); err == nil {
+		occurredAt = date.UTC().Format(time.RFC3339)
+	}
+
+	var reportBlocks []textproto.MIMEHeader
+	var original textproto.MIMEHeader
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var body io.Reader = part
+		if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+			body = base64.NewDecoder(base64.StdEncoding, part)
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case "message/delivery-status", "message/global-delivery-status", "message/feedback-report":
+			reportBlocks = readHeaderBlocks(data)
+		case "text/rfc822-headers", "message/rfc822", "message/rfc822-headers", "message/global", "message/global-headers":
+			if blocks := readHeaderBlocks(data); len(blocks) > 0 {
+				original = blocks[0]
+			}
+		}
+	}
+	if len(reportBlocks) == 0 {
+		return nil, errNotBounceReport
+	}
+
+	outboxID := ""
+	if original != nil {
+		outboxID = strings.TrimSpace(original.Get(outboxIDHeader))
+	}
+
+	reports := []emailFeedback{}
+	switch strings.ToLower(params["report-type"]) {
+	case "delivery-status":
+		// First block is per-message, the rest are one per recipient
+		for _, h := range reportBlocks {
+			recipient := typedFieldValue(h.Get("Final-Recipient"))
+			if recipient == "" {
+				recipient = typedFieldValue(h.Get("Original-Recipient"))
+			}
+			if recipient == "" || !strings.EqualFold(strings.TrimSpace(h.Get("Action")), "failed") {
+				continue
+			}
+			reports = append(reports, emailFeedback{
+				Type:       emailFeedbackBounce,
+				Email:      recipient,
+				Status:     strings.TrimSpace(h.Get("Status")),
+				Diagnostic: typedFieldValue(h.Get("Diagnostic-Code")),
+				MessageID:  outboxID,
+				OccurredAt: occurredAt,
+			})
+		}
+
+	case "feedback-report":
+		h := reportBlocks[0]
+		feedbackType := strings.ToLower(strings.TrimSpace(h.Get("Feedback-Type")))
+		if feedbackType == "not-spam" {
+			return reports, nil
+		}
+		recipient := strings.TrimSpace(h.Get("Original-Rcpt-To"))
+		if recipient == "" && original != nil {
+			if addr, err := mail.ParseAddress(original.Get("To")); err == nil {
+				recipient = addr.Address
+			}
+		}
+		if recipient == "" {
+			return nil, errors.New("feedback report has no recipient")
+		}
+		diagnostic := "Feedback-Type: " + feedbackType
+		if ua := h.Get("User-Agent"); ua != "" {
+			diagnostic += " (" + ua + ")"
+		}
+		reports = append(reports, emailFeedback{
+			Type:       emailFeedbackComplaint,
+			Email:      recipient,
+			Diagnostic: diagnostic,
+			MessageID:  outboxID,
+			OccurredAt: occurredAt,
+		})
+
+	default:
+		return nil, errNotBounceReport
+	}
+
+	return reports, nil
+}
+
+// readHeaderBlocks parses blank-line separated header blocks, as used by delivery status
+// and feedback report parts
+func readHeaderBlocks(data []byte) []textproto.MIMEHeader {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	blocks := []textproto.MIMEHeader{}
+	for {
+		h, err := r.ReadMIMEHeader()
+		if len(h) > 0 {
+			blocks = append(blocks, h)
+		}
+		if err != nil {
+			return blocks
+		}
+	}
+}
+
+// typedFieldValue strips the type prefix from DSN fields like "rfc822; user@example.com"
+// or "smtp; 550 5.1.1 User unknown"
+func typedFieldValue(v string) string {
+	if _, value, ok := strings.Cut(v, ";"); ok {
+		v = value
+	}
+	return strings.Trim(strings.TrimSpace(v), "<>")
+}
+
+// --- Maildir ---
+
+// scheduleBounceMaildir processes the reports delivered to BOUNCE_MAILDIR every few minutes.
+// Does nothing when it isn't set.
+func scheduleBounceMaildir(app *pocketbase.PocketBase) {
+	dir := os.Getenv("BOUNCE_MAILDIR")
+	if dir == "" {
+		return
+	}
+
+	// Wait for app to fully start
+	time.Sleep(60 * time.Second)
+	log.Printf("[Bounce] Watching %s for bounce reports", dir)
+
+	for {
+		if n := processBounceMaildir(app, dir); n > 0 {
+			log.Printf("[Bounce] Processed %d messages from Maildir", n)
+		}
+		time.Sleep(bounceMaildirInterval)
+	}
+}
+
+// processBounceMaildir handles every message in dir/new and moves it to dir/cur marked as
+// seen, so it's only processed once. Messages that aren't reports are moved too. A message
+// whose report couldn't be recorded (no ENCRYPTION_KEY, a database error) is left in new
+// and tried again on the next pass, so the bounce isn't lost.
+func processBounceMaildir(app *pocketbase.PocketBase, dir string) int {
+	entries, err := os.ReadDir(filepath.Join(dir, "new"))
+	if err != nil {
+		log.Printf("[Bounce] Failed to read Maildir: %v", err)
+		return 0
+	}
+
+	processed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, "new", entry.Name())
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("[Bounce] Failed to read %s: %v", entry.Name(), err)
+			continue
+		}
+
+		reports, err := parseBounceReport(raw)
+		if err != nil {
+			log.Printf("[Bounce] Skipping %s: %v", entry.Name(), err)
+		}
+		failed := false
+		for _, f := range reports {
+			if err := normaliseEmailFeedback(&f); err != nil {
+				log.Printf("[Bounce] Invalid report in %s: %v", entry.Name(), err)
+				continue
+			}
+			if _, err := processEmailFeedback(app, f, "maildir"); err != nil {
+				log.Printf("[Bounce] Failed to process report in %s, will retry: %v", entry.Name(), err)
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+
+		if err := os.Rename(path, filepath.Join(dir, "cur", entry.Name()+":2,S")); err != nil {
+			log.Printf("[Bounce] Failed to move %s to cur: %v", entry.Name(), err)
+			continue
+		}
+		processed++
+	}
+	return processed
+}
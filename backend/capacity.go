@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// --- Capacity & Waitlist ---
+// A guest list with a capacity seats that many people: each accepted guest takes a seat and
+// their plus-one takes another. Once it's full, guests who accept go on a waitlist in the
+// order they accepted, and are promoted (and emailed) as seats free up. Capacity is only
+// enforced on RSVPs; admins can still accept guests over it from the guest list.
+
+const rsvpWaitlisted = "waitlisted"
+
+// errNoPlusOneSeat is returned when an accepted guest adds a plus-one to a full event
+var errNoPlusOneSeat = errors.New("the event is full, so a plus-one can't be added")
+
+// guestListSeatsTaken counts accepted guests and their plus-ones. A plus-one who has also
+// accepted through their own item is counted once, on that item.
+func guestListSeatsTaken(app core.App, listID string) int {
+	var seats int
+	err := app.DB().NewQuery(`
+		SELECT
+			(SELECT COUNT(*) FROM guest_list_items
+				WHERE guest_list = {:id} AND invite_status = 'accepted')
+			+
+			(SELECT COUNT(*) FROM guest_list_items host
+				WHERE host.guest_list = {:id} AND host.invite_status = 'accepted' AND host.rsvp_plus_one = TRUE
+				AND NOT EXISTS (
+					SELECT 1 FROM guest_list_items p
+					WHERE p.plus_one_of = host.id AND p.invite_status = 'accepted'
+				))
+	`).Bind(dbx.Params{"id": listID}).Row(&seats)
+	if err != nil {
+		log.Printf("[Capacity] Failed to count seats for guest list %s: %v", listID, err)
+	}
+	return seats
+}
+
+// plusOneSeatHeld reports whether a plus-one's own item already has a seat through their
+// host: guestListSeatsTaken counts it on the host, who is accepted with a plus-one, until
+// this item accepts. Accepting then moves the seat rather than taking a new one.
+func plusOneSeatHeld(app core.App, item *core.Record) bool {
+	hostID := item.GetString("plus_one_of")
+	if hostID == "" {
+		return false
+	}
+	host, err := app.FindRecordById(utils.CollectionGuestListItems, hostID)
+	return err == nil && host.GetString("invite_status") == "accepted" && host.GetBool("rsvp_plus_one")
+}
+
+// itemSeats is the seats a guest needs: one, plus one for a plus-one
+func itemSeats(plusOne bool) int {
+	if plusOne {
+		return 2
+	}
+	return 1
+}
+
+// waitlistLength counts a guest list's waitlisted guests, leaving out excludeID
+func waitlistLength(app core.App, listID, excludeID string) int {
+	n, err := app.CountRecords(utils.CollectionGuestListItems,
+		dbx.HashExp{"guest_list": listID, "invite_status": rsvpWaitlisted},
+		dbx.Not(dbx.HashExp{"id": excludeID}))
+	if err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// waitlistPosition is a waitlisted guest's place in the queue, from 1; 0 if not waitlisted
+func waitlistPosition(app core.App, item *core.Record) int {
+	if item.GetString("invite_status") != rsvpWaitlisted {
+		return 0
+	}
+	ahead, err := app.CountRecords(utils.CollectionGuestListItems,
+		dbx.HashExp{"guest_list": item.GetString("guest_list"), "invite_status": rsvpWaitlisted},
+		dbx.NewExp("waitlisted_at < {:at}", dbx.Params{"at": item.GetString("waitlisted_at")}))
+	if err != nil {
+		return 0
+	}
+	return int(ahead) + 1
+}
+
+// setItemWaitlisted puts an item on the waitlist, keeping its place if it's already there.
+// Every other status clears waitlisted_at.
+func setItemWaitlisted(item *core.Record, waitlisted bool) {
+	if !waitlisted {
+		if item.GetString("invite_status") != rsvpWaitlisted {
+			item.Set("waitlisted_at", "")
+		}
+		return
+	}
+	if item.Original().GetString("invite_status") != rsvpWaitlisted || item.GetDateTime("waitlisted_at").IsZero() {
+		item.Set("waitlisted_at", types.NowDateTime())
+	}
+	item.Set("invite_status", rsvpWaitlisted)
+	item.Set("rsvp_status", rsvpWaitlisted)
+}
+
+// saveRSVPItem saves a guest's RSVP, first checking an acceptance against the guest list's
+// capacity. With no seat free the guest is waitlisted instead and input.Response becomes
+// "waitlisted", so no confirmation goes out. The check and the save share a transaction so
+// two guests can't both take the last seat. Returns the change added to the item's history.
+func saveRSVPItem(app *pocketbase.PocketBase, guestList *core.Record, item *core.Record, input *rsvpInput) (*rsvpChange, error) {
+	var change *rsvpChange
+	err := app.RunInTransaction(func(txApp core.App) error {
+		if input.Response == "accepted" {
+			gl, err := txApp.FindRecordById(utils.CollectionGuestLists, guestList.Id)
+			if err != nil {
+				return err
+			}
+
+			// Full sessions are refused, even for guests about to be waitlisted
+			if err := checkSessionCapacity(txApp, gl, item, input.Sessions, input.PlusOne); err != nil {
+				return err
+			}
+
+			original := item.Original()
+			wasAccepted := !item.IsNew() && original.GetString("invite_status") == "accepted"
+			wasWaitlisted := !item.IsNew() && original.GetString("invite_status") == rsvpWaitlisted
+
+			if capacity := gl.GetInt("capacity"); capacity > 0 {
+				taken := guestListSeatsTaken(txApp, gl.Id)
+				need := itemSeats(input.PlusOne)
+				if !wasAccepted && plusOneSeatHeld(txApp, item) {
+					need--
+				}
+
+				switch {
+				case wasAccepted:
+					// Already seated; only a newly added plus-one needs room
+					if extra := need - itemSeats(original.GetBool("rsvp_plus_one")); extra > 0 && taken+extra > capacity {
+						return errNoPlusOneSeat
+					}
+				case need == 0:
+					// A plus-one taking the seat their host already holds for them
+				case wasWaitlisted:
+					// Keeps their place; promotion seats them when there's room
+					setItemWaitlisted(item, true)
+					input.Response = rsvpWaitlisted
+				case waitlistLength(txApp, gl.Id, item.Id) > 0 || taken+need > capacity:
+					// Nobody jumps the queue, even if they'd fit where its head doesn't
+					setItemWaitlisted(item, true)
+					input.Response = rsvpWaitlisted
+				}
+			}
+		}
+		setItemWaitlisted(item, input.Response == rsvpWaitlisted)
+		change = appendRSVPChange(item, "", rsvpChangeByGuest, "")
+
+		return txApp.Save(item)
+	})
+	return change, err
+}
+
+// rsvpSubmittedResponse is the public RSVP reply, with the waitlist position if there is one
+func rsvpSubmittedResponse(re *core.RequestEvent, app *pocketbase.PocketBase, item *core.Record) error {
+	if item.GetString("invite_status") == rsvpWaitlisted {
+		return utils.DataResponse(re, map[string]any{
+			"message":           "The event is full, so you've been added to the waitlist",
+			"waitlisted":        true,
+			"waitlist_position": waitlistPosition(app, item),
+		})
+	}
+	return utils.DataResponse(re, map[string]any{"message": "RSVP submitted successfully"})
+}
+
+// promoteWaitlist fills free seats from the head of a guest list's waitlist and emails each
+// promoted guest. It stops at the first guest who doesn't fit, so the queue keeps its order.
+func promoteWaitlist(app *pocketbase.PocketBase, listID string) {
+	var promoted []*core.Record
+	var guestList *core.Record
+
+	err := app.RunInTransaction(func(txApp core.App) error {
+		gl, err := txApp.FindRecordById(utils.CollectionGuestLists, listID)
+		if err != nil {
+			return err
+		}
+		guestList = gl
+
+		waiting, err := txApp.FindRecordsByFilter(utils.CollectionGuestListItems,
+			"guest_list = {:id} && invite_status = 'waitlisted'", "waitlisted_at,created", 0, 0,
+			dbx.Params{"id": listID})
+		if err != nil || len(waiting) == 0 {
+			return err
+		}
+
+		capacity := gl.GetInt("capacity")
+		taken := guestListSeatsTaken(txApp, listID)
+		sessionsTaken := sessionSeatsTaken(txApp, listID)
+		for _, item := range waiting {
+			need := itemSeats(item.GetBool("rsvp_plus_one"))
+			if plusOneSeatHeld(txApp, item) {
+				need--
+			}
+			if capacity > 0 && taken+need > capacity {
+				break
+			}
+			item.Set("invite_status", "accepted")
+			item.Set("rsvp_status", "accepted")
+			item.Set("waitlisted_at", "")
+			item.Set("rsvp_sessions", seatInSessions(gl, item, sessionsTaken))
+			if err := txApp.Save(item); err != nil {
+				return err
+			}
+			taken += need
+			promoted = append(promoted, item)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Waitlist] Failed to promote guests on guest list %s: %v", listID, err)
+		return
+	}
+	if len(promoted) == 0 {
+		return
+	}
+
+	eventName := ""
+	if epID := guestList.GetString("event_projection"); epID != "" {
+		if ep, err := app.FindRecordById(utils.CollectionEventProjections, epID); err == nil {
+			eventName = ep.GetString("name")
+		}
+	}
+	emailTheme := buildEmailTheme(app, guestList)
+	sessions := guestListSessions(guestList)
+
+	for _, item := range promoted {
+		utils.LogAudit(app, utils.AuditEntry{
+			Action:       "rsvp_waitlist_promote",
+			ResourceType: utils.CollectionGuestListItems,
+			ResourceID:   item.Id,
+			Status:       "success",
+			Metadata:     map[string]any{"guest_list": listID},
+		})
+
+		// A waitlisted guest's plus-one is only added to the list once the guest has a seat
+		if item.GetBool("rsvp_plus_one") {
+			plusOne := &rsvpInput{
+				PlusOne:         true,
+				PlusOneName:     item.GetString("rsvp_plus_one_name"),
+				PlusOneLastName: item.GetString("rsvp_plus_one_last_name"),
+				PlusOneJobTitle: item.GetString("rsvp_plus_one_job_title"),
+				PlusOneCompany:  item.GetString("rsvp_plus_one_company"),
+				PlusOneEmail:    item.GetString("rsvp_plus_one_email"),
+				PlusOneDietary:  item.GetString("rsvp_plus_one_dietary"),
+			}
+			addPlusOneToGuestList(app, upsertPlusOneContact(app, plusOne), plusOne, listID, item.Id)
+		}
+
+		contact, err := app.FindRecordById(utils.CollectionContacts, item.GetString("contact"))
+		if err != nil {
+			continue
+		}
+		email := utils.DecryptField(contact.GetString("email"))
+		if email == "" || !strings.Contains(email, "@") || isEmailSuppressed(app, email, true) {
+			continue
+		}
+
+		rsvpURL := ""
+		if token := item.GetString("rsvp_token"); token != "" {
+			rsvpURL = fmt.Sprintf("%s/rsvp/%s", getPublicBaseURL(), token)
+		}
+		if err := sendRSVPPromotedEmail(app, email, contact.GetString("name"), rsvpURL, item.Id,
+			guestList.GetString("name"), guestList.GetString("description"), eventName,
+			guestList.GetString("event_date"), guestList.GetString("event_time"), guestList.GetString("event_location"),
+			emailTheme, pickedSessions(sessions, itemRSVPSessions(item)), guestListICS(app, guestList, itemRSVPSessions(item)),
+			checkInCode(item.Id)); err != nil {
+			log.Printf("[Waitlist] Failed to queue promotion email for item %s: %v", item.Id, err)
+		}
+	}
+
+	log.Printf("[Waitlist] Promoted %d guest(s) on guest list %s", len(promoted), listID)
+}
+
+// registerCapacityHooks promotes waitlisted guests whenever seats free up: an accepted guest
+// declines, drops their plus-one or is removed, or the capacity changes
+func registerCapacityHooks(app *pocketbase.PocketBase) {
+	app.OnRecordAfterUpdateSuccess(utils.CollectionGuestListItems).BindFunc(func(e *core.RecordEvent) error {
+		original := e.Record.Original()
+		if original.GetString("invite_status") == "accepted" &&
+			(e.Record.GetString("invite_status") != "accepted" ||
+				(original.GetBool("rsvp_plus_one") && !e.Record.GetBool("rsvp_plus_one"))) {
+			promoteWaitlist(app, e.Record.GetString("guest_list"))
+		}
+		return e.Next()
+	})
+
+	app.OnRecordAfterDeleteSuccess(utils.CollectionGuestListItems).BindFunc(func(e *core.RecordEvent) error {
+		if e.Record.GetString("invite_status") == "accepted" {
+			promoteWaitlist(app, e.Record.GetString("guest_list"))
+		}
+		return e.Next()
+	})
+
+	app.OnRecordAfterUpdateSuccess(utils.CollectionGuestLists).BindFunc(func(e *core.RecordEvent) error {
+		if e.Record.GetInt("capacity") != e.Record.Original().GetInt("capacity") {
+			promoteWaitlist(app, e.Record.Id)
+		}
+		return e.Next()
+	})
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+	"github.com/pocketbase/pocketbase/tools/types"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// --- Check-in ---
+// Accepted guests get a QR code in their confirmation email. It encodes a link to the CRM
+// check-in page carrying a signed code for the guest list item, so door staff can scan it
+// with the check-in screen or any camera app. Checking in records the arrival on the item
+// and adds an event_attended activity to the contact's history.
+
+const (
+	checkInTokenPurpose = "check_in"
+	checkInQRSize       = 512
+
+	checkInMethodQR     = "qr"
+	checkInMethodSearch = "search"
+
+	activityEventAttended = "event_attended"
+)
+
+// checkInCode returns the signed code for a guest list item, or "" if signing isn't
+// configured (no ENCRYPTION_KEY)
+func checkInCode(itemID string) string {
+	return utils.SignToken(checkInTokenPurpose, itemID)
+}
+
+// checkInURL is the link a guest's QR code encodes
+func checkInURL(code string) string {
+	return fmt.Sprintf("%s/check-in?code=%s", getBaseURL(), url.QueryEscape(code))
+}
+
+// checkInQRImageURL is the public PNG of a guest's QR code, for <img> tags in email
+func checkInQRImageURL(code string) string {
+	return fmt.Sprintf("%s/api/public/check-in/%s/qr.png", getPublicBaseURL(), url.PathEscape(code))
+}
+
+// checkInQRPNG renders a code's QR image
+func checkInQRPNG(code string) ([]byte, error) {
+	return qrcode.Encode(checkInURL(code), qrcode.Medium, checkInQRSize)
+}
+
+// parseCheckInCode verifies a scanned code, which may be the whole check-in link, and
+// returns the guest list item ID it was signed for
+func parseCheckInCode(scanned string) (string, bool) {
+	scanned = strings.TrimSpace(scanned)
+	if u, err := url.Parse(scanned); err == nil && u.Query().Get("code") != "" {
+		scanned = u.Query().Get("code")
+	}
+	return utils.VerifyToken(checkInTokenPurpose, scanned)
+}
+
+// buildCheckInQRHTML builds the QR block for confirmation emails
+func buildCheckInQRHTML(theme EmailTheme, imageURL string) string {
+	return fmt.Sprintf(`<div style="text-align: center; margin: 0 0 32px 0;">
+                <img src="%s" alt="Your check-in code" width="200" height="200" style="display: inline-block; width: 200px; height: 200px; background: #ffffff; padding: 12px;">
+                <p style="color: %s; font-size: 13px; margin: 12px 0 0 0;">Show this code at the door to check in.</p>
+            </div>`,
+		template.HTMLEscapeString(imageURL), textWithOpacity(theme.Text, "0.5"))
+}
+
+// addCheckInQRData adds a guest's QR code to RSVP email template data. Without a code the
+// variables are empty, so templates can test {{if .check_in_qr_url}}.
+func addCheckInQRData(data map[string]any, theme EmailTheme, code string) {
+	data["check_in_qr_url"] = ""
+	data["check_in_qr"] = template.HTML("")
+	if code == "" {
+		return
+	}
+	imageURL := checkInQRImageURL(code)
+	data["check_in_qr_url"] = imageURL
+	data["check_in_qr"] = template.HTML(buildCheckInQRHTML(theme, imageURL))
+}
+
+// sampleCheckInQRData is addCheckInQRData for template previews: the image is inlined, as
+// a preview code wouldn't verify at the public image URL
+func sampleCheckInQRData(data map[string]any, theme EmailTheme) {
+	png, err := qrcode.Encode(getBaseURL()+"/check-in?code=preview", qrcode.Medium, checkInQRSize)
+	if err != nil {
+		addCheckInQRData(data, theme, "")
+		return
+	}
+	imageURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	data["check_in_qr_url"] = imageURL
+	data["check_in_qr"] = template.HTML(buildCheckInQRHTML(theme, imageURL))
+}
+
+// attachCheckInQR attaches a guest's QR code as check-in.png, for mail clients that block
+// remote images
+func attachCheckInQR(msg *mailer.Message, code string) {
+	if code == "" {
+		return
+	}
+	png, err := checkInQRPNG(code)
+	if err != nil {
+		log.Printf("[CheckIn] Failed to render QR code: %v", err)
+		return
+	}
+	if msg.Attachments == nil {
+		msg.Attachments = map[string]io.Reader{}
+	}
+	msg.Attachments["check-in.png"] = bytes.NewReader(png)
+}
+
+// checkInItem records a guest's arrival, and their plus-one's if plusOne is set. Checking in
+// again is a no-op for whoever has already arrived. Returns whether anything changed.
+func checkInItem(app core.App, guestList, item *core.Record, method, by string, plusOne bool) (bool, error) {
+	changed := false
+	now := types.NowDateTime()
+
+	if item.GetDateTime("checked_in_at").IsZero() {
+		item.Set("checked_in_at", now)
+		item.Set("checked_in_by", by)
+		item.Set("check_in_method", method)
+		changed = true
+	}
+	if plusOne && item.GetDateTime("plus_one_checked_in_at").IsZero() {
+		item.Set("plus_one_checked_in_at", now)
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+	if err := app.Save(item); err != nil {
+		return false, err
+	}
+
+	logAttendedActivity(app, guestList, item, method, plusOne)
+
+	// A plus-one on the list in their own right gets their own arrival and activity
+	if plusOne {
+		plusOneItems, err := app.FindRecordsByFilter(utils.CollectionGuestListItems,
+			"plus_one_of = {:id} && checked_in_at = ''", "", 1, 0, dbx.Params{"id": item.Id})
+		if err == nil && len(plusOneItems) > 0 {
+			if _, err := checkInItem(app, guestList, plusOneItems[0], method, by, false); err != nil {
+				log.Printf("[CheckIn] Failed to check in plus-one item %s: %v", plusOneItems[0].Id, err)
+			}
+		}
+	}
+	return true, nil
+}
+
+// undoCheckIn clears a guest's arrival and removes the attendance activity it created
+func undoCheckIn(app core.App, item *core.Record) error {
+	item.Set("checked_in_at", "")
+	item.Set("checked_in_by", "")
+	item.Set("check_in_method", "")
+	item.Set("plus_one_checked_in_at", "")
+	if err := app.Save(item); err != nil {
+		return err
+	}
+
+	activities, err := app.FindRecordsByFilter(utils.CollectionActivities,
+		"type = {:type} && source_app = 'crm' && source_id = {:id}", "", 0, 0,
+		dbx.Params{"type": activityEventAttended, "id": item.Id})
+	if err != nil {
+		return nil
+	}
+	for _, a := range activities {
+		if err := app.Delete(a); err != nil {
+			log.Printf("[CheckIn] Failed to remove attendance activity %s: %v", a.Id, err)
+		}
+	}
+	return nil
+}
+
+// logAttendedActivity adds an event_attended activity to the guest's contact, once per item
+func logAttendedActivity(app core.App, guestList, item *core.Record, method string, plusOne bool) {
+	contactID := item.GetString("contact")
+	if contactID == "" {
+		return
+	}
+	collection, err := app.FindCollectionByNameOrId(utils.CollectionActivities)
+	if err != nil {
+		return
+	}
+
+	activities, err := app.FindRecordsByFilter(utils.CollectionActivities,
+		"type = {:type} && source_app = 'crm' && source_id = {:id}", "", 1, 0,
+		dbx.Params{"type": activityEventAttended, "id": item.Id})
+	if err == nil && len(activities) > 0 {
+		// Already logged; a later plus-one arrival just updates it
+		if plusOne {
+			activity := activities[0]
+			metadata := map[string]any{}
+			decodeJSONField(activity, "metadata", &metadata)
+			metadata["plus_one"] = true
+			activity.Set("metadata", metadata)
+			if err := app.Save(activity); err != nil {
+				log.Printf("[CheckIn] Failed to update attendance activity %s: %v", activity.Id, err)
+			}
+		}
+		return
+	}
+
+	eventName := guestList.GetString("name")
+	if epID := guestList.GetString("event_projection"); epID != "" {
+		if ep, err := app.FindRecordById(utils.CollectionEventProjections, epID); err == nil {
+			if n := ep.GetString("name"); n != "" {
+				eventName = n
+			}
+		}
+	}
+
+	activity := core.NewRecord(collection)
+	activity.Set("contact", contactID)
+	activity.Set("type", activityEventAttended)
+	activity.Set("title", "Attended "+eventName)
+	activity.Set("source_app", "crm")
+	activity.Set("source_id", item.Id)
+	activity.Set("metadata", map[string]any{
+		"guest_list":      guestList.Id,
+		"guest_list_name": guestList.GetString("name"),
+		"event_date":      guestList.GetString("event_date"),
+		"check_in_method": method,
+		"plus_one":        plusOne,
+	})
+	activity.Set("occurred_at", item.GetDateTime("checked_in_at"))
+	if err := app.Save(activity); err != nil {
+		log.Printf("[CheckIn] Failed to log attendance for item %s: %v", item.Id, err)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// --- Consent ---
+// contacts.consent holds the current answer per category; consent_events keeps every
+// change with its source and proof (IP, user agent, form). Only an explicit withdrawal
+// blocks a send — contacts who have never answered are treated as before.
+// Transactional emails (OTP codes, RSVP confirmations, share and plus-one notifications)
+// aren't subject to consent.
+
+const (
+	consentMarketing        = "marketing"
+	consentEventInvites     = "event_invites"
+	consentProgrammeUpdates = "programme_updates"
+
+	consentGranted   = "granted"
+	consentWithdrawn = "withdrawn"
+)
+
+var consentCategories = []string{consentMarketing, consentEventInvites, consentProgrammeUpdates}
+
+// errConsentWithdrawn is returned by send functions when the recipient has opted out
+var errConsentWithdrawn = errors.New("recipient has withdrawn consent")
+
+// consentState is a contact's current answer for one category
+type consentState struct {
+	Status    string `json:"status"`
+	Source    string `json:"source"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// consentProof describes where a consent change came from
+type consentProof struct {
+	Source     string // admin, attendee_portal, rsvp, mailchimp, unsubscribe_link, import
+	Form       string
+	IPAddress  string
+	UserAgent  string
+	RecordedBy string
+}
+
+// consentChange is one category whose status actually changed
+type consentChange struct {
+	Category string `json:"category"`
+	Status   string `json:"status"`
+}
+
+func consentProofFromRequest(re *core.RequestEvent, source, form string) consentProof {
+	proof := consentProof{
+		Source:    source,
+		Form:      form,
+		IPAddress: re.RealIP(),
+		UserAgent: re.Request.UserAgent(),
+	}
+	if re.Auth != nil {
+		proof.RecordedBy = re.Auth.GetString("email")
+	}
+	return proof
+}
+
+// validateConsentInput checks a {"category": true|false} map from a request
+func validateConsentInput(changes map[string]bool) error {
+	if len(changes) == 0 {
+		return errors.New("no consent categories given")
+	}
+	for category := range changes {
+		if !slices.Contains(consentCategories, category) {
+			return fmt.Errorf("unknown consent category %q", category)
+		}
+	}
+	return nil
+}
+
+// contactConsent returns a contact's consent per category
+func contactConsent(r *core.Record) map[string]consentState {
+	states := map[string]consentState{}
+	if err := decodeJSONField(r, "consent", &states); err != nil || states == nil {
+		return map[string]consentState{}
+	}
+	return states
+}
+
+// contactAllows reports whether a contact may be emailed for a category
+func contactAllows(r *core.Record, category string) bool {
+	return contactConsent(r)[category].Status != consentWithdrawn
+}
+
+// emailAllowed looks a recipient up by email and checks their consent. Addresses that
+// don't belong to a contact (e.g. a forwarded invite to someone new) are allowed.
+func emailAllowed(app core.App, email, category string) bool {
+	idx := utils.BlindIndex(strings.ToLower(strings.TrimSpace(email)))
+	if idx == "" {
+		return true
+	}
+	contacts, err := app.FindRecordsByFilter(utils.CollectionContacts,
+		"email_index = {:idx} || personal_email_index = {:idx}", "", 0, 0, dbx.Params{"idx": idx})
+	if err != nil {
+		return true
+	}
+	for _, c := range contacts {
+		if !contactAllows(c, category) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyConsentChanges sets the given categories on the contact (without saving) and
+// returns the ones whose status changed
+func applyConsentChanges(contact *core.Record, changes map[string]bool, source string) []consentChange {
+	states := contactConsent(contact)
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	applied := []consentChange{}
+	for _, category := range consentCategories {
+		granted, ok := changes[category]
+		if !ok {
+			continue
+		}
+		status := consentWithdrawn
+		if granted {
+			status = consentGranted
+		}
+		if states[category].Status == status {
+			continue
+		}
+		states[category] = consentState{Status: status, Source: source, UpdatedAt: now}
+		applied = append(applied, consentChange{Category: category, Status: status})
+	}
+
+	if len(applied) > 0 {
+		contact.Set("consent", states)
+	}
+	return applied
+}
+
+// mergeContactConsent combines the consent of contacts being merged, category by category.
+// A withdrawal on any of them wins over a grant, so merging never re-enables a contact who
+// opted out; otherwise the most recent answer is kept.
+func mergeContactConsent(records []*core.Record) map[string]consentState {
+	merged := map[string]consentState{}
+	for _, r := range records {
+		for category, state := range contactConsent(r) {
+			current, ok := merged[category]
+			switch {
+			case !ok:
+			case state.Status == consentWithdrawn && current.Status != consentWithdrawn:
+			case state.Status != consentWithdrawn && current.Status == consentWithdrawn:
+				continue
+			case state.UpdatedAt <= current.UpdatedAt:
+				continue
+			}
+			merged[category] = state
+		}
+	}
+	return merged
+}
+
+// logConsentEvents writes the proof records for applied changes
+func logConsentEvents(app core.App, contactID string, applied []consentChange, proof consentProof) {
+	if len(applied) == 0 {
+		return
+	}
+	collection, err := app.FindCollectionByNameOrId(utils.CollectionConsentEvents)
+	if err != nil {
+		log.Printf("[Consent] Collection not found: %v", err)
+		return
+	}
+
+	for _, change := range applied {
+		record := core.NewRecord(collection)
+		record.Set("contact", contactID)
+		record.Set("category", change.Category)
+		record.Set("status", change.Status)
+		record.Set("source", proof.Source)
+		record.Set("form", proof.Form)
+		record.Set("ip_address", proof.IPAddress)
+		record.Set("user_agent", proof.UserAgent)
+		record.Set("recorded_by", proof.RecordedBy)
+		if err := app.Save(record); err != nil {
+			log.Printf("[Consent] Failed to log %s change for %s: %v", change.Category, contactID, err)
+		}
+	}
+}
+
+// updateContactConsent applies, saves and logs consent changes for a contact.
+// Marketing changes made in the CRM are pushed to Mailchimp.
+func updateContactConsent(app *pocketbase.PocketBase, contact *core.Record, changes map[string]bool, proof consentProof) ([]consentChange, error) {
+	applied := applyConsentChanges(contact, changes, proof.Source)
+	if len(applied) == 0 {
+		return applied, nil
+	}
+
+	// Decrypt PII fields before save so PocketBase validation passes
+	for _, field := range []string{"email", "personal_email", "phone", "bio", "location"} {
+		if v := contact.GetString(field); v != "" {
+			contact.Set(field, utils.DecryptField(v))
+		}
+	}
+	if err := app.Save(contact); err != nil {
+		return nil, err
+	}
+
+	logConsentEvents(app, contact.Id, applied, proof)
+	syncMarketingConsentToMailchimp(app, contact, applied, proof.Source)
+
+	return applied, nil
+}
+
+// syncMarketingConsentToMailchimp updates the Mailchimp member's status when marketing
+// consent changes in the CRM. Changes that came from Mailchimp aren't sent back.
+func syncMarketingConsentToMailchimp(app *pocketbase.PocketBase, contact *core.Record, applied []consentChange, source string) {
+	if source == "mailchimp" || contact.GetString("mailchimp_id") == "" {
+		return
+	}
+
+	for _, change := range applied {
+		if change.Category != consentMarketing {
+			continue
+		}
+		email := utils.DecryptField(contact.GetString("email"))
+		status := "unsubscribed"
+		if change.Status == consentGranted {
+			status = "subscribed"
+		}
+
+		go func() {
+			listID, _ := getMailchimpSyncConfig(app)
+			if listID == "" {
+				return
+			}
+			path := fmt.Sprintf("/lists/%s/members/%s", listID, mailchimpSubscriberHash(email))
+			if _, code, err := mailchimpRequest("PATCH", path, map[string]any{"status": status}); err != nil || code >= 400 {
+				log.Printf("[Consent] Failed to update Mailchimp status (%d): %v", code, err)
+			}
+		}()
+	}
+}
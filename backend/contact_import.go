@@ -0,0 +1,817 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// --- Generic CSV Contact Import ---
+// Upload any CSV, map its columns to contact/organisation fields, review a dry run
+// (matching existing contacts on email_index), then run the import in the background.
+
+const (
+	contactImportMaxRows        = 20000
+	contactImportPreviewRows    = 500
+	contactImportMaxErrors      = 200
+	contactImportProgressEveryN = 25
+)
+
+// contactImportField is a mapping target. Organisation targets are resolved by name
+// rather than stored on the contact.
+type contactImportField struct {
+	Key     string   `json:"key"`
+	Label   string   `json:"label"`
+	Aliases []string `json:"-"`
+}
+
+var contactImportFields = []contactImportField{
+	{Key: "email", Label: "Email", Aliases: []string{"email address", "work email", "e-mail"}},
+	{Key: "first_name", Label: "First name", Aliases: []string{"first", "given name", "firstname"}},
+	{Key: "last_name", Label: "Last name", Aliases: []string{"last", "surname", "family name", "lastname"}},
+	{Key: "name", Label: "Full name", Aliases: []string{"full name", "contact name"}},
+	{Key: "preferred_name", Label: "Preferred name", Aliases: []string{"nickname"}},
+	{Key: "personal_email", Label: "Personal email"},
+	{Key: "phone", Label: "Phone", Aliases: []string{"mobile", "mobile phone", "phone number", "telephone"}},
+	{Key: "pronouns", Label: "Pronouns"},
+	{Key: "job_title", Label: "Job title", Aliases: []string{"title", "role", "position"}},
+	{Key: "linkedin", Label: "LinkedIn", Aliases: []string{"linkedin url"}},
+	{Key: "instagram", Label: "Instagram"},
+	{Key: "website", Label: "Website"},
+	{Key: "location", Label: "Location", Aliases: []string{"city"}},
+	{Key: "bio", Label: "Bio", Aliases: []string{"biography"}},
+	{Key: "notes", Label: "Notes"},
+	{Key: "tags", Label: "Tags"},
+	{Key: "roles", Label: "Roles"},
+	{Key: "status", Label: "Status"},
+	{Key: "degrees", Label: "Degrees"},
+	{Key: "relationship", Label: "Relationship"},
+	{Key: "dietary_requirements", Label: "Dietary requirements", Aliases: []string{"dietary"}},
+	{Key: "dietary_requirements_other", Label: "Dietary requirements (other)"},
+	{Key: "accessibility_requirements", Label: "Accessibility requirements", Aliases: []string{"accessibility"}},
+	{Key: "accessibility_requirements_other", Label: "Accessibility requirements (other)"},
+	{Key: "organisation", Label: "Organisation name", Aliases: []string{"organization", "company", "company name", "employer"}},
+	{Key: "organisation.website", Label: "Organisation website", Aliases: []string{"company website"}},
+	{Key: "organisation.linkedin", Label: "Organisation LinkedIn", Aliases: []string{"company linkedin"}},
+	{Key: "organisation.industry", Label: "Organisation industry", Aliases: []string{"industry"}},
+}
+
+// contactImportListFields are merged with existing values rather than treated as conflicts
+var contactImportListFields = map[string]bool{
+	"tags": true, "roles": true, "dietary_requirements": true, "accessibility_requirements": true,
+}
+
+// contactImportOptions controls how matched contacts and unknown organisations are handled
+type contactImportOptions struct {
+	UpdateExisting      bool `json:"update_existing"`      // update contacts matched on email (otherwise skip them)
+	Overwrite           bool `json:"overwrite"`            // replace differing non-empty values (otherwise only fill blanks)
+	CreateOrganisations bool `json:"create_organisations"` // create organisations not matched by name
+}
+
+func defaultContactImportOptions() contactImportOptions {
+	return contactImportOptions{UpdateExisting: true, CreateOrganisations: true}
+}
+
+type contactImportConflict struct {
+	Field    string `json:"field"`
+	Existing string `json:"existing"`
+	Incoming string `json:"incoming"`
+}
+
+// contactImportRowPlan is the dry-run outcome for one CSV row
+type contactImportRowPlan struct {
+	Line                int                     `json:"line"`   // line in the CSV file (header is line 1)
+	Action              string                  `json:"action"` // create, update, unchanged, skip, error
+	Email               string                  `json:"email"`
+	Name                string                  `json:"name"`
+	ContactID           string                  `json:"contact_id,omitempty"`
+	Changes             []string                `json:"changes,omitempty"`
+	Conflicts           []contactImportConflict `json:"conflicts,omitempty"`
+	Organisation        string                  `json:"organisation,omitempty"`
+	CreatesOrganisation bool                    `json:"creates_organisation,omitempty"`
+	Message             string                  `json:"message,omitempty"`
+}
+
+type contactImportSummary struct {
+	Total        int `json:"total"`
+	Create       int `json:"create"`
+	Update       int `json:"update"`
+	Unchanged    int `json:"unchanged"`
+	Conflicts    int `json:"conflicts"` // rows with at least one conflicting field
+	Skip         int `json:"skip"`
+	Error        int `json:"error"`
+	OrgsToCreate int `json:"orgs_to_create"`
+}
+
+func (s *contactImportSummary) add(p contactImportRowPlan) {
+	s.Total++
+	switch p.Action {
+	case "create":
+		s.Create++
+	case "update":
+		s.Update++
+	case "unchanged":
+		s.Unchanged++
+	case "skip":
+		s.Skip++
+	case "error":
+		s.Error++
+	}
+	if len(p.Conflicts) > 0 {
+		s.Conflicts++
+	}
+}
+
+// contactImporter plans (and optionally applies) rows against the current database
+type contactImporter struct {
+	app      core.App
+	importID string
+	headers  []string
+	mapping  map[string]string // CSV header -> target key
+	options  contactImportOptions
+	defs     map[string]customFieldDefinition
+
+	contacts *core.Collection
+	orgs     *core.Collection
+
+	orgCache    map[string]string // lowercased name -> id ("" = will be created by this import)
+	orgsCreated int
+	seenEmails  map[string]int // email -> first line it appeared on
+}
+
+func newContactImporter(app core.App, importID string, headers []string, mapping map[string]string, options contactImportOptions) (*contactImporter, error) {
+	contacts, err := app.FindCollectionByNameOrId(utils.CollectionContacts)
+	if err != nil {
+		return nil, fmt.Errorf("contacts collection not found")
+	}
+	orgs, err := app.FindCollectionByNameOrId(utils.CollectionOrganisations)
+	if err != nil {
+		return nil, fmt.Errorf("organisations collection not found")
+	}
+
+	imp := &contactImporter{
+		app:        app,
+		importID:   importID,
+		headers:    headers,
+		mapping:    mapping,
+		options:    options,
+		defs:       loadCustomFieldDefinitions(app, "contact"),
+		contacts:   contacts,
+		orgs:       orgs,
+		orgCache:   map[string]string{},
+		seenEmails: map[string]int{},
+	}
+
+	if allOrgs, err := app.FindAllRecords(utils.CollectionOrganisations); err == nil {
+		for _, org := range allOrgs {
+			imp.orgCache[strings.ToLower(org.GetString("name"))] = org.Id
+		}
+	}
+
+	return imp, nil
+}
+
+// validateContactImportMapping checks targets exist and that email is mapped exactly once
+func validateContactImportMapping(headers []string, mapping map[string]string, defs map[string]customFieldDefinition) error {
+	known := map[string]bool{}
+	for _, f := range contactImportFields {
+		known[f.Key] = true
+	}
+	headerSet := map[string]bool{}
+	for _, h := range headers {
+		headerSet[h] = true
+	}
+
+	used := map[string]string{}
+	for header, target := range mapping {
+		if target == "" {
+			continue
+		}
+		if !headerSet[header] {
+			return fmt.Errorf("column %q is not in the file", header)
+		}
+		if key, ok := strings.CutPrefix(target, "custom_fields."); ok {
+			if def, ok := defs[key]; !ok || def.Archived {
+				return fmt.Errorf("unknown custom field %q", key)
+			}
+		} else if !known[target] {
+			return fmt.Errorf("unknown field %q", target)
+		}
+		if other, ok := used[target]; ok {
+			return fmt.Errorf("columns %q and %q are both mapped to %s", other, header, target)
+		}
+		used[target] = header
+	}
+
+	if _, ok := used["email"]; !ok {
+		return fmt.Errorf("a column must be mapped to email")
+	}
+	return nil
+}
+
+var importHeaderNormaliser = regexp.MustCompile(`[^a-z0-9]+`)
+
+func normaliseImportHeader(s string) string {
+	return strings.Trim(importHeaderNormaliser.ReplaceAllString(strings.ToLower(s), " "), " ")
+}
+
+// suggestContactImportMapping guesses targets from header names, labels and common aliases
+func suggestContactImportMapping(headers []string, defs map[string]customFieldDefinition) map[string]string {
+	lookup := map[string]string{}
+	for _, f := range contactImportFields {
+		lookup[normaliseImportHeader(f.Key)] = f.Key
+		lookup[normaliseImportHeader(f.Label)] = f.Key
+		for _, alias := range f.Aliases {
+			lookup[normaliseImportHeader(alias)] = f.Key
+		}
+	}
+	for _, def := range defs {
+		if def.Archived {
+			continue
+		}
+		lookup[normaliseImportHeader(def.Key)] = "custom_fields." + def.Key
+		lookup[normaliseImportHeader(def.Label)] = "custom_fields." + def.Key
+	}
+
+	mapping := map[string]string{}
+	used := map[string]bool{}
+	for _, h := range headers {
+		target, ok := lookup[normaliseImportHeader(h)]
+		if !ok || used[target] {
+			continue
+		}
+		mapping[h] = target
+		used[target] = true
+	}
+	return mapping
+}
+
+// rowValues maps a CSV row to target -> trimmed value, dropping blanks
+func (imp *contactImporter) rowValues(row []string) map[string]string {
+	values := map[string]string{}
+	for i, header := range imp.headers {
+		target := imp.mapping[header]
+		if target == "" || i >= len(row) {
+			continue
+		}
+		if v := strings.TrimSpace(row[i]); v != "" {
+			values[target] = v
+		}
+	}
+	return values
+}
+
+// process plans one row. With apply set, organisations are created and the contact saved;
+// otherwise the record is only validated.
+func (imp *contactImporter) process(row []string, line int, apply bool) contactImportRowPlan {
+	values := imp.rowValues(row)
+	plan := contactImportRowPlan{Line: line}
+
+	email := strings.ToLower(values["email"])
+	plan.Email = email
+	if email == "" {
+		plan.Action, plan.Message = "skip", "No email"
+		return plan
+	}
+	if _, err := mail.ParseAddress(email); err != nil {
+		plan.Action, plan.Message = "error", "Invalid email address"
+		return plan
+	}
+	if first, ok := imp.seenEmails[email]; ok {
+		plan.Action, plan.Message = "skip", fmt.Sprintf("Duplicate of line %d", first)
+		return plan
+	}
+	imp.seenEmails[email] = line
+
+	record := imp.findContact(email)
+	isCreate := record == nil
+	if isCreate {
+		record = core.NewRecord(imp.contacts)
+		record.Set("email", email)
+		record.Set("status", "active")
+		record.Set("source", "csv_import")
+	} else {
+		plan.ContactID = record.Id
+		if !imp.options.UpdateExisting {
+			plan.Action, plan.Message = "skip", "Contact already exists"
+			plan.Name = record.GetString("name")
+			return plan
+		}
+		// Decrypt PII so comparisons work and the encryption hooks re-encrypt on save
+		for _, field := range []string{"email", "personal_email", "phone", "bio", "location"} {
+			if v := record.GetString(field); v != "" {
+				record.Set(field, utils.DecryptField(v))
+			}
+		}
+	}
+
+	if values["name"] == "" && (values["first_name"] != "" || values["last_name"] != "") {
+		first := values["first_name"]
+		if first == "" {
+			first = record.GetString("first_name")
+		}
+		last := values["last_name"]
+		if last == "" {
+			last = record.GetString("last_name")
+		}
+		values["name"] = strings.TrimSpace(first + " " + last)
+	}
+
+	customValues := recordCustomFields(record)
+	customChanged := false
+
+	for _, target := range sortedImportTargets(values) {
+		raw := values[target]
+		if target == "email" || strings.HasPrefix(target, "organisation") {
+			continue
+		}
+
+		if key, ok := strings.CutPrefix(target, "custom_fields."); ok {
+			incoming, err := coerceCustomFieldValue(imp.defs[key], raw)
+			if err != nil {
+				plan.Action, plan.Message = "error", err.Error()
+				return plan
+			}
+			if imp.applyValue(&plan, target, customValues[key], incoming) {
+				customValues[key] = incoming
+				customChanged = true
+			}
+			continue
+		}
+
+		field := imp.contacts.Fields.GetByName(target)
+		if field == nil {
+			continue
+		}
+		incoming, err := coerceImportValue(field, raw)
+		if err != nil {
+			plan.Action, plan.Message = "error", fmt.Sprintf("%s: %v", target, err)
+			return plan
+		}
+		if contactImportListFields[target] && !imp.options.Overwrite {
+			incoming = mergeImportList(record.Get(target), incoming)
+		}
+		if imp.applyValue(&plan, target, record.Get(target), incoming) {
+			record.Set(target, incoming)
+		}
+	}
+
+	if isCreate {
+		for key, def := range imp.defs {
+			if def.Required && !def.Archived && customFieldIsEmpty(customValues[key]) {
+				plan.Action, plan.Message = "error", fmt.Sprintf("%s is required", def.Label)
+				return plan
+			}
+		}
+	}
+	if customChanged {
+		record.Set("custom_fields", customValues)
+	}
+
+	// Organisation: link by name, optionally creating it
+	orgName := values["organisation"]
+	if orgName != "" && (record.GetString("organisation") == "" || imp.options.Overwrite) {
+		orgID, known := imp.orgCache[strings.ToLower(orgName)]
+		switch {
+		case known && orgID == "":
+			// Created earlier in this dry run
+			plan.Organisation = orgName
+			plan.Changes = append(plan.Changes, "organisation")
+		case known:
+			if record.GetString("organisation") != orgID {
+				plan.Organisation = orgName
+				plan.Changes = append(plan.Changes, "organisation")
+				record.Set("organisation", orgID)
+			}
+		case imp.options.CreateOrganisations:
+			plan.Organisation = orgName
+			plan.CreatesOrganisation = true
+			plan.Changes = append(plan.Changes, "organisation")
+			if apply {
+				id, err := imp.createOrganisation(orgName, values)
+				if err != nil {
+					plan.Action, plan.Message = "error", fmt.Sprintf("Failed to create organisation: %v", err)
+					return plan
+				}
+				record.Set("organisation", id)
+			} else {
+				imp.orgCache[strings.ToLower(orgName)] = ""
+			}
+		}
+	}
+
+	if isCreate {
+		sourceIDs := map[string]any{}
+		if imp.importID != "" {
+			sourceIDs["csv_import"] = imp.importID
+		}
+		record.Set("source_ids", sourceIDs)
+	}
+
+	plan.Name = record.GetString("name")
+	switch {
+	case isCreate:
+		plan.Action = "create"
+	case len(plan.Changes) > 0:
+		plan.Action = "update"
+	default:
+		plan.Action = "unchanged"
+		return plan
+	}
+
+	if apply {
+		if err := imp.app.Save(record); err != nil {
+			plan.Action, plan.Message = "error", err.Error()
+			return plan
+		}
+		plan.ContactID = record.Id
+	} else if err := imp.app.Validate(record); err != nil {
+		plan.Action, plan.Message = "error", err.Error()
+	}
+
+	return plan
+}
+
+// applyValue records a change or conflict for one field and reports whether to set it
+func (imp *contactImporter) applyValue(plan *contactImportRowPlan, target string, existing, incoming any) bool {
+	existingStr := customFieldString(normaliseSegmentValue(existing))
+	incomingStr := customFieldString(normaliseSegmentValue(incoming))
+	if existingStr == incomingStr {
+		return false
+	}
+	if existingStr != "" && existingStr != "0" {
+		plan.Conflicts = append(plan.Conflicts, contactImportConflict{Field: target, Existing: existingStr, Incoming: incomingStr})
+		if !imp.options.Overwrite {
+			return false
+		}
+	}
+	plan.Changes = append(plan.Changes, target)
+	return true
+}
+
+func (imp *contactImporter) findContact(email string) *core.Record {
+	filter, params := "email = {:email}", map[string]any{"email": email}
+	if idx := utils.BlindIndex(email); idx != "" {
+		filter, params = "email_index = {:idx}", map[string]any{"idx": idx}
+	}
+	records, err := imp.app.FindRecordsByFilter(utils.CollectionContacts, filter, "", 1, 0, params)
+	if err != nil || len(records) == 0 {
+		return nil
+	}
+	return records[0]
+}
+
+func (imp *contactImporter) createOrganisation(name string, values map[string]string) (string, error) {
+	org := core.NewRecord(imp.orgs)
+	org.Set("name", name)
+	org.Set("status", "active")
+	org.Set("source", "manual")
+	org.Set("source_ids", map[string]any{"csv_import": imp.importID})
+	for _, field := range []string{"website", "linkedin", "industry"} {
+		raw := values["organisation."+field]
+		if raw == "" {
+			continue
+		}
+		if f := imp.orgs.Fields.GetByName(field); f != nil {
+			if v, err := coerceImportValue(f, raw); err == nil {
+				org.Set(field, v)
+			}
+		}
+	}
+	if org.GetString("website") == "" {
+		if website := guessWebsiteFromEmail(values["email"]); website != "" {
+			org.Set("website", website)
+		}
+	}
+
+	if err := imp.app.Save(org); err != nil {
+		return "", err
+	}
+	imp.orgCache[strings.ToLower(name)] = org.Id
+	imp.orgsCreated++
+	return org.Id, nil
+}
+
+// sortedImportTargets returns value keys in contactImportFields order, then custom fields
+func sortedImportTargets(values map[string]string) []string {
+	order := map[string]int{}
+	for i, f := range contactImportFields {
+		order[f.Key] = i
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		oi, iKnown := order[keys[i]]
+		oj, jKnown := order[keys[j]]
+		if iKnown != jKnown {
+			return iKnown
+		}
+		if iKnown {
+			return oi < oj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// coerceImportValue converts a CSV string to the stored form for a collection field.
+// Select values match case-insensitively and by label ("Gluten free" -> gluten_free).
+func coerceImportValue(field core.Field, raw string) (any, error) {
+	switch f := field.(type) {
+	case *core.SelectField:
+		parts := []string{raw}
+		if f.MaxSelect > 1 {
+			parts = splitImportList(raw)
+		}
+		values := []string{}
+		for _, part := range parts {
+			match := ""
+			key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(part)), " ", "_")
+			for _, v := range f.Values {
+				if strings.EqualFold(v, part) || v == key {
+					match = v
+					break
+				}
+			}
+			if match == "" {
+				return nil, fmt.Errorf("%q is not one of: %s", part, strings.Join(f.Values, ", "))
+			}
+			values = append(values, match)
+		}
+		if f.MaxSelect > 1 {
+			return values, nil
+		}
+		return values[0], nil
+
+	case *core.NumberField:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		return n, nil
+
+	case *core.BoolField:
+		switch strings.ToLower(raw) {
+		case "true", "yes", "y", "1":
+			return true, nil
+		case "false", "no", "n", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not true or false", raw)
+
+	case *core.JSONField:
+		return splitImportList(raw), nil
+	}
+
+	return raw, nil
+}
+
+// splitImportList splits comma/semicolon separated cell values
+func splitImportList(raw string) []string {
+	values := []string{}
+	for _, part := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ';' }) {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// mergeImportList unions incoming list items into the existing list
+func mergeImportList(existing, incoming any) any {
+	list, ok := incoming.([]string)
+	if !ok {
+		return incoming
+	}
+	current, _ := normaliseSegmentValue(existing).([]string)
+	merged := append([]string{}, current...)
+	for _, item := range list {
+		found := false
+		for _, c := range current {
+			if strings.EqualFold(c, item) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+// --- Stored import state ---
+
+func encodeContactImportRows(rows [][]string) (string, error) {
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return "", err
+	}
+	return utils.Encrypt(string(data))
+}
+
+func decodeContactImportRows(r *core.Record) ([][]string, error) {
+	var stored string
+	decodeJSONField(r, "rows", &stored)
+	if stored == "" {
+		return nil, fmt.Errorf("import data is no longer available")
+	}
+	plain, err := utils.Decrypt(stored)
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	if err := json.Unmarshal([]byte(plain), &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func contactImportSettings(r *core.Record) ([]string, map[string]string, contactImportOptions) {
+	var headers []string
+	decodeJSONField(r, "headers", &headers)
+	mapping := map[string]string{}
+	decodeJSONField(r, "mapping", &mapping)
+	options := defaultContactImportOptions()
+	decodeJSONField(r, "options", &options)
+	return headers, mapping, options
+}
+
+// parseContactImportCSV reads the header row and data rows, dropping fully blank rows
+func parseContactImportCSV(reader *csv.Reader) ([]string, [][]string, error) {
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CSV: %v", err)
+	}
+	if len(records) < 2 {
+		return nil, nil, fmt.Errorf("CSV must have a header row and at least one data row")
+	}
+
+	headers := make([]string, len(records[0]))
+	seen := map[string]bool{}
+	for i, h := range records[0] {
+		h = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+		if h == "" {
+			h = fmt.Sprintf("Column %d", i+1)
+		}
+		if seen[h] {
+			return nil, nil, fmt.Errorf("duplicate column %q", h)
+		}
+		seen[h] = true
+		headers[i] = h
+	}
+
+	rows := make([][]string, 0, len(records)-1)
+	for _, row := range records[1:] {
+		if strings.TrimSpace(strings.Join(row, "")) != "" {
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("CSV has no data rows")
+	}
+	if len(rows) > contactImportMaxRows {
+		return nil, nil, fmt.Errorf("CSV has %d rows; the limit is %d", len(rows), contactImportMaxRows)
+	}
+	return headers, rows, nil
+}
+
+// dryRunContactImport plans every row without writing anything
+func dryRunContactImport(app core.App, importID string, headers []string, rows [][]string, mapping map[string]string, options contactImportOptions) (contactImportSummary, []contactImportRowPlan, error) {
+	summary := contactImportSummary{}
+	imp, err := newContactImporter(app, importID, headers, mapping, options)
+	if err != nil {
+		return summary, nil, err
+	}
+
+	plans := []contactImportRowPlan{}
+	orgsToCreate := map[string]bool{}
+	for i, row := range rows {
+		plan := imp.process(row, i+2, false)
+		summary.add(plan)
+		if plan.CreatesOrganisation {
+			orgsToCreate[strings.ToLower(plan.Organisation)] = true
+		}
+		if len(plans) < contactImportPreviewRows {
+			plans = append(plans, plan)
+		}
+	}
+	summary.OrgsToCreate = len(orgsToCreate)
+
+	return summary, plans, nil
+}
+
+// failInterruptedContactImports marks imports left running by a restart as failed and drops
+// their staged rows. They aren't resumed: some rows may already have been applied.
+func failInterruptedContactImports(app core.App) {
+	records, err := app.FindRecordsByFilter(utils.CollectionContactImports, "status = 'running'", "", 0, 0)
+	if err != nil {
+		log.Printf("[ContactImport] Failed to recover interrupted imports: %v", err)
+		return
+	}
+	for _, r := range records {
+		var importErrors []string
+		_ = decodeJSONField(r, "errors", &importErrors)
+		importErrors = append(importErrors, "Interrupted by a restart; some rows may have been imported")
+
+		r.Set("status", "failed")
+		r.Set("errors", importErrors)
+		r.Set("completed_at", time.Now().UTC().Format(time.RFC3339))
+		r.Set("rows", nil)
+		if err := app.Save(r); err != nil {
+			log.Printf("[ContactImport] Failed to mark import %s as failed: %v", r.Id, err)
+		}
+	}
+	if len(records) > 0 {
+		log.Printf("[ContactImport] Marked %d interrupted imports as failed", len(records))
+	}
+}
+
+// runContactImport applies a draft import in the background, updating its progress record
+func runContactImport(app *pocketbase.PocketBase, importID string) {
+	importRecord, err := app.FindRecordById(utils.CollectionContactImports, importID)
+	if err != nil {
+		log.Printf("[ContactImport] Failed to find import %s: %v", importID, err)
+		return
+	}
+
+	var importErrors []string
+	processed, created, updated, skipped := 0, 0, 0, 0
+	orgsCreated := 0
+
+	saveProgress := func(status string) {
+		importRecord.Set("records_processed", processed)
+		importRecord.Set("records_created", created)
+		importRecord.Set("records_updated", updated)
+		importRecord.Set("records_skipped", skipped)
+		importRecord.Set("orgs_created", orgsCreated)
+		importRecord.Set("errors", importErrors)
+		if status != "" {
+			importRecord.Set("status", status)
+			importRecord.Set("completed_at", time.Now().UTC().Format(time.RFC3339))
+			// Drop the uploaded PII now that it's been applied
+			importRecord.Set("rows", nil)
+		}
+		if err := app.Save(importRecord); err != nil {
+			log.Printf("[ContactImport] Failed to update import %s: %v", importID, err)
+		}
+	}
+
+	rows, err := decodeContactImportRows(importRecord)
+	if err != nil {
+		importErrors = append(importErrors, err.Error())
+		saveProgress("failed")
+		return
+	}
+
+	headers, mapping, options := contactImportSettings(importRecord)
+	imp, err := newContactImporter(app, importID, headers, mapping, options)
+	if err != nil {
+		importErrors = append(importErrors, err.Error())
+		saveProgress("failed")
+		return
+	}
+
+	for i, row := range rows {
+		plan := imp.process(row, i+2, true)
+		processed++
+		switch plan.Action {
+		case "create":
+			created++
+		case "update":
+			updated++
+		case "skip", "unchanged":
+			skipped++
+		case "error":
+			if len(importErrors) < contactImportMaxErrors {
+				importErrors = append(importErrors, fmt.Sprintf("Line %d (%s): %s", plan.Line, plan.Email, plan.Message))
+			}
+		}
+		orgsCreated = imp.orgsCreated
+
+		if processed%contactImportProgressEveryN == 0 {
+			saveProgress("")
+		}
+	}
+
+	saveProgress("completed")
+
+	log.Printf("[ContactImport] Import %s complete: %d processed, %d created, %d updated, %d skipped, %d orgs created, %d errors",
+		importID, processed, created, updated, skipped, orgsCreated, len(importErrors))
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// customFieldDefinition is an admin-defined field stored in a record's custom_fields JSON
+type customFieldDefinition struct {
+	ID       string   `json:"id"`
+	Entity   string   `json:"entity"` // contact, organisation
+	Key      string   `json:"key"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"` // text, number, date, select, multi_select, boolean
+	Options  []string `json:"options"`
+	Required bool     `json:"required"`
+	Archived bool     `json:"archived"`
+}
+
+func newCustomFieldDefinition(r *core.Record) customFieldDefinition {
+	def := customFieldDefinition{
+		ID:       r.Id,
+		Entity:   r.GetString("entity"),
+		Key:      r.GetString("key"),
+		Label:    r.GetString("label"),
+		Type:     r.GetString("type"),
+		Required: r.GetBool("required"),
+		Archived: r.GetBool("archived"),
+	}
+	decodeJSONField(r, "options", &def.Options)
+	return def
+}
+
+// loadCustomFieldDefinitions returns the definitions for an entity keyed by field key
+func loadCustomFieldDefinitions(app core.App, entity string) map[string]customFieldDefinition {
+	defs := map[string]customFieldDefinition{}
+	records, err := app.FindRecordsByFilter(
+		utils.CollectionCustomFieldDefinitions,
+		"entity = {:entity}",
+		"sort_order,label", 0, 0,
+		map[string]any{"entity": entity},
+	)
+	if err != nil {
+		return defs
+	}
+	for _, r := range records {
+		def := newCustomFieldDefinition(r)
+		defs[def.Key] = def
+	}
+	return defs
+}
+
+// coerceCustomFieldValue validates a raw value against its definition and returns the stored form.
+// Strings are accepted for every type so CSV/Humanitix imports can pass values through as-is.
+func coerceCustomFieldValue(def customFieldDefinition, v any) (any, error) {
+	switch def.Type {
+	case "text":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be text", def.Label)
+		}
+		return strings.TrimSpace(s), nil
+
+	case "number":
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a number", def.Label)
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("%s must be a number", def.Label)
+
+	case "date":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a date (YYYY-MM-DD)", def.Label)
+		}
+		s = strings.TrimSpace(s)
+		if len(s) >= 10 {
+			if _, err := time.Parse("2006-01-02", s[:10]); err == nil {
+				return s[:10], nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be a date (YYYY-MM-DD)", def.Label)
+
+	case "boolean":
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(b)) {
+			case "true", "yes", "y", "1":
+				return true, nil
+			case "false", "no", "n", "0":
+				return false, nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be true or false", def.Label)
+
+	case "select":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be one of: %s", def.Label, strings.Join(def.Options, ", "))
+		}
+		if match, ok := matchCustomFieldOption(def, s); ok {
+			return match, nil
+		}
+		return nil, fmt.Errorf("%s must be one of: %s", def.Label, strings.Join(def.Options, ", "))
+
+	case "multi_select":
+		var raw []string
+		switch list := v.(type) {
+		case []any:
+			for _, item := range list {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s must be a list of options", def.Label)
+				}
+				raw = append(raw, s)
+			}
+		case []string:
+			raw = list
+		case string:
+			// Comma/semicolon separated, as exported by ticketing and spreadsheet tools
+			for _, part := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ';' }) {
+				raw = append(raw, part)
+			}
+		default:
+			return nil, fmt.Errorf("%s must be a list of options", def.Label)
+		}
+		values := []string{}
+		for _, s := range raw {
+			if strings.TrimSpace(s) == "" {
+				continue
+			}
+			match, ok := matchCustomFieldOption(def, s)
+			if !ok {
+				return nil, fmt.Errorf("%s: %q is not an allowed option", def.Label, strings.TrimSpace(s))
+			}
+			values = append(values, match)
+		}
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("%s has unknown type %s", def.Label, def.Type)
+}
+
+func matchCustomFieldOption(def customFieldDefinition, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, opt := range def.Options {
+		if strings.EqualFold(opt, value) {
+			return opt, true
+		}
+	}
+	return "", false
+}
+
+// customFieldIsEmpty reports whether a stored value counts as unset for required checks
+func customFieldIsEmpty(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case []string:
+		return len(val) == 0
+	case []any:
+		return len(val) == 0
+	}
+	return false
+}
+
+// recordCustomFields decodes a record's custom_fields JSON into a map
+func recordCustomFields(r *core.Record) map[string]any {
+	values := map[string]any{}
+	decodeJSONField(r, "custom_fields", &values)
+	if values == nil {
+		values = map[string]any{}
+	}
+	return values
+}
+
+// applyCustomFields validates input (a {key: value} object) and merges it into the
+// record's custom_fields. A null value clears the field. Required fields are enforced
+// on create, and on update only when the request tries to clear them.
+func applyCustomFields(app core.App, record *core.Record, entity string, input any, isCreate bool) error {
+	defs := loadCustomFieldDefinitions(app, entity)
+	values := recordCustomFields(record)
+
+	if input != nil {
+		fields, ok := input.(map[string]any)
+		if !ok {
+			return fmt.Errorf("custom_fields must be an object")
+		}
+		for key, raw := range fields {
+			def, ok := defs[key]
+			if !ok || def.Archived {
+				return fmt.Errorf("unknown custom field %q", key)
+			}
+			if raw == nil || raw == "" {
+				if def.Required {
+					return fmt.Errorf("%s is required", def.Label)
+				}
+				delete(values, key)
+				continue
+			}
+			v, err := coerceCustomFieldValue(def, raw)
+			if err != nil {
+				return err
+			}
+			if def.Required && customFieldIsEmpty(v) {
+				return fmt.Errorf("%s is required", def.Label)
+			}
+			values[key] = v
+		}
+	}
+
+	if isCreate {
+		for key, def := range defs {
+			if def.Required && !def.Archived && customFieldIsEmpty(values[key]) {
+				return fmt.Errorf("%s is required", def.Label)
+			}
+		}
+	}
+
+	record.Set("custom_fields", values)
+	return nil
+}
+
+// setCustomFieldFromString coerces and stores a single imported string value,
+// silently skipping unknown keys and invalid values (imports shouldn't fail a whole row)
+func setCustomFieldFromString(record *core.Record, defs map[string]customFieldDefinition, key, raw string) bool {
+	def, ok := defs[key]
+	if !ok || def.Archived || strings.TrimSpace(raw) == "" {
+		return false
+	}
+	v, err := coerceCustomFieldValue(def, raw)
+	if err != nil {
+		return false
+	}
+	values := recordCustomFields(record)
+	values[key] = v
+	record.Set("custom_fields", values)
+	return true
+}
+
+// customFieldString renders a stored value as plain text (Mailchimp merge fields, exports)
+func customFieldString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		if val {
+			return "Yes"
+		}
+		return "No"
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case []any:
+		parts := make([]string, 0, len(val))
+		for _, item := range val {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, ", ")
+	case []string:
+		return strings.Join(val, ", ")
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// customFieldListFilter builds filter clauses from cf_<key>=value query params.
+// Text matches are partial, multi_select matches any selected option, the rest are exact.
+func customFieldListFilter(app core.App, entity string, query url.Values) ([]string, map[string]any) {
+	clauses := []string{}
+	params := map[string]any{}
+
+	defs := loadCustomFieldDefinitions(app, entity)
+	i := 0
+	for param, vals := range query {
+		key, ok := strings.CutPrefix(param, "cf_")
+		if !ok || len(vals) == 0 || vals[0] == "" {
+			continue
+		}
+		def, ok := defs[key]
+		if !ok {
+			continue
+		}
+
+		name := fmt.Sprintf("cf%d", i)
+		i++
+		switch def.Type {
+		case "text", "multi_select":
+			clauses = append(clauses, fmt.Sprintf("custom_fields.%s ~ {:%s}", key, name))
+			params[name] = vals[0]
+		case "number":
+			n, err := strconv.ParseFloat(vals[0], 64)
+			if err != nil {
+				continue
+			}
+			clauses = append(clauses, fmt.Sprintf("custom_fields.%s = {:%s}", key, name))
+			params[name] = n
+		case "boolean":
+			if vals[0] == "true" {
+				clauses = append(clauses, fmt.Sprintf("custom_fields.%s = true", key))
+			} else {
+				clauses = append(clauses, fmt.Sprintf("(custom_fields.%s = false || custom_fields.%s = null)", key, key))
+			}
+		default:
+			clauses = append(clauses, fmt.Sprintf("custom_fields.%s = {:%s}", key, name))
+			params[name] = vals[0]
+		}
+	}
+
+	return clauses, params
+}
@@ -0,0 +1,573 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	duplicateDetectionHour = 4   // 4 AM AEST, after the nightly backup
+	duplicateScoreMin      = 0.5 // Minimum score for a pair to enter the review queue
+	duplicateBucketMax     = 50  // Skip blocking buckets larger than this (e.g. common surnames)
+)
+
+// duplicateNicknames maps common nicknames to a canonical first name so
+// "Bob Smith" and "Robert Smith" compare as the same person.
+var duplicateNicknames = map[string]string{
+	"alex": "alexander", "andy": "andrew", "drew": "andrew", "ben": "benjamin",
+	"bill": "william", "will": "william", "liam": "william", "bob": "robert",
+	"rob": "robert", "bobby": "robert", "chris": "christopher", "dan": "daniel",
+	"danny": "daniel", "dave": "david", "ed": "edward", "eddie": "edward",
+	"jim": "james", "jimmy": "james", "jamie": "james", "joe": "joseph",
+	"jon": "jonathan", "kate": "katherine", "katie": "katherine", "kathy": "katherine",
+	"cathy": "catherine", "liz": "elizabeth", "beth": "elizabeth", "lizzie": "elizabeth",
+	"matt": "matthew", "mike": "michael", "mick": "michael", "nick": "nicholas",
+	"pat": "patrick", "pete": "peter", "rick": "richard", "rich": "richard",
+	"dick": "richard", "sam": "samuel", "steve": "stephen", "tom": "thomas",
+	"tony": "anthony", "jen": "jennifer", "jenny": "jennifer", "sue": "susan",
+	"mel": "melissa", "nat": "natalie", "tim": "timothy", "greg": "gregory",
+	"jess": "jessica", "vic": "victoria", "tash": "natasha", "pip": "philippa",
+}
+
+// duplicateContact is the subset of contact data used for scoring.
+// Built once per record so decrypting and normalising happens a single time.
+type duplicateContact struct {
+	ID        string
+	FirstName string // canonical (nicknames resolved)
+	LastName  string
+	FullName  string
+	EmailKeys []string
+	LinkedIn  string
+	OrgID     string
+}
+
+// duplicateMatch is a scored candidate pair.
+type duplicateMatch struct {
+	ContactA string
+	ContactB string
+	Score    float64
+	Reasons  []string
+}
+
+// normaliseNamePart lowercases a name and strips anything that isn't a letter
+func normaliseNamePart(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// canonicalFirstName resolves a first name through the nickname table
+func canonicalFirstName(s string) string {
+	n := normaliseNamePart(s)
+	if canonical, ok := duplicateNicknames[n]; ok {
+		return canonical
+	}
+	return n
+}
+
+// normaliseLinkedIn reduces a LinkedIn URL to its profile path (e.g. "in/jane-doe")
+func normaliseLinkedIn(raw string) string {
+	raw = strings.TrimSpace(strings.ToLower(raw))
+	if raw == "" {
+		return ""
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || !strings.Contains(u.Host, "linkedin.com") {
+		return ""
+	}
+	path := strings.Trim(u.Path, "/")
+	if !strings.HasPrefix(path, "in/") {
+		return ""
+	}
+	return path
+}
+
+// contactEmailKey returns a comparable key for an email field.
+// Uses the stored blind index when encryption is enabled, otherwise the normalised email.
+func contactEmailKey(r *core.Record, field, indexField string) string {
+	if idx := r.GetString(indexField); idx != "" {
+		return idx
+	}
+	email := utils.NormalizeEmail(utils.DecryptField(r.GetString(field)))
+	if email == "" {
+		return ""
+	}
+	if utils.IsEncryptionEnabled() {
+		return utils.BlindIndex(email)
+	}
+	return email
+}
+
+// newDuplicateContact extracts the scoring fields from a contact record
+func newDuplicateContact(r *core.Record) duplicateContact {
+	c := duplicateContact{
+		ID:        r.Id,
+		FirstName: canonicalFirstName(r.GetString("first_name")),
+		LastName:  normaliseNamePart(r.GetString("last_name")),
+		LinkedIn:  normaliseLinkedIn(r.GetString("linkedin")),
+		OrgID:     r.GetString("organisation"),
+	}
+	c.FullName = c.FirstName + " " + c.LastName
+
+	for _, key := range []string{
+		contactEmailKey(r, "email", "email_index"),
+		contactEmailKey(r, "personal_email", "personal_email_index"),
+	} {
+		if key != "" {
+			c.EmailKeys = append(c.EmailKeys, key)
+		}
+	}
+
+	return c
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// nameSimilarity returns a 0-1 similarity for two normalised full names
+func nameSimilarity(a, b string) float64 {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	longest := max(len([]rune(a)), len([]rune(b)))
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+// scoreDuplicatePair scores how likely two contacts are the same person.
+// Strong identifiers (email, LinkedIn) carry most of the weight; name and
+// organisation only push a pair over the threshold when they agree together.
+func scoreDuplicatePair(a, b duplicateContact) (float64, []string) {
+	score := 0.0
+	var reasons []string
+
+	emailMatch := false
+	for _, ka := range a.EmailKeys {
+		for _, kb := range b.EmailKeys {
+			if ka == kb {
+				emailMatch = true
+			}
+		}
+	}
+	if emailMatch {
+		score += 0.6
+		reasons = append(reasons, "email")
+	}
+
+	if a.LinkedIn != "" && a.LinkedIn == b.LinkedIn {
+		score += 0.5
+		reasons = append(reasons, "linkedin")
+	}
+
+	sim := nameSimilarity(a.FullName, b.FullName)
+	if sim == 1 {
+		score += 0.4
+		reasons = append(reasons, "name")
+	} else if sim >= 0.85 {
+		score += 0.35 * sim
+		reasons = append(reasons, "similar_name")
+	}
+
+	if sim >= 0.85 && a.OrgID != "" && a.OrgID == b.OrgID {
+		score += 0.2
+		reasons = append(reasons, "organisation")
+	}
+
+	if score > 1 {
+		score = 1
+	}
+	return score, reasons
+}
+
+// duplicateBlockingKeys returns the bucket keys a contact is compared within.
+// Only contacts sharing at least one key are scored, avoiding an O(n²) scan.
+func duplicateBlockingKeys(c duplicateContact) []string {
+	var keys []string
+	for _, k := range c.EmailKeys {
+		keys = append(keys, "email:"+k)
+	}
+	if c.LinkedIn != "" {
+		keys = append(keys, "linkedin:"+c.LinkedIn)
+	}
+	if c.LastName != "" && c.FirstName != "" {
+		keys = append(keys, "name:"+c.LastName+":"+string([]rune(c.FirstName)[0]))
+	}
+	if c.FirstName != "" && c.OrgID != "" {
+		keys = append(keys, "org:"+c.OrgID+":"+c.FirstName)
+	}
+	return keys
+}
+
+// orderedPair returns the two IDs in alphabetical order (same convention as contact_links)
+func orderedPair(a, b string) (string, string) {
+	if a > b {
+		return b, a
+	}
+	return a, b
+}
+
+// linkedContactPairs returns the set of contact pairs already linked via contact_links.
+// Linked contacts are known to be related and are never proposed as duplicates.
+func linkedContactPairs(app *pocketbase.PocketBase) map[string]bool {
+	pairs := map[string]bool{}
+	links, err := app.FindAllRecords(utils.CollectionContactLinks)
+	if err != nil {
+		return pairs
+	}
+	for _, l := range links {
+		a, b := orderedPair(l.GetString("contact_a"), l.GetString("contact_b"))
+		pairs[a+":"+b] = true
+	}
+	return pairs
+}
+
+// findDuplicateMatches scores all candidate pairs among the given contacts
+func findDuplicateMatches(contacts []duplicateContact, skip map[string]bool) []duplicateMatch {
+	buckets := map[string][]int{}
+	for i, c := range contacts {
+		for _, key := range duplicateBlockingKeys(c) {
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	seen := map[string]bool{}
+	var matches []duplicateMatch
+	for _, idxs := range buckets {
+		if len(idxs) < 2 || len(idxs) > duplicateBucketMax {
+			continue
+		}
+		for i := 0; i < len(idxs); i++ {
+			for j := i + 1; j < len(idxs); j++ {
+				ca, cb := contacts[idxs[i]], contacts[idxs[j]]
+				a, b := orderedPair(ca.ID, cb.ID)
+				pairKey := a + ":" + b
+				if seen[pairKey] || skip[pairKey] {
+					continue
+				}
+				seen[pairKey] = true
+
+				score, reasons := scoreDuplicatePair(ca, cb)
+				if score < duplicateScoreMin {
+					continue
+				}
+				matches = append(matches, duplicateMatch{ContactA: a, ContactB: b, Score: score, Reasons: reasons})
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
+
+// saveDuplicateMatches upserts matches into duplicate_candidates.
+// Dismissed pairs are left alone so they never reappear in the queue.
+func saveDuplicateMatches(app *pocketbase.PocketBase, matches []duplicateMatch) (created, updated int) {
+	collection, err := app.FindCollectionByNameOrId(utils.CollectionDuplicateCandidates)
+	if err != nil {
+		log.Printf("[Duplicates] Collection not found: %v", err)
+		return 0, 0
+	}
+
+	for _, match := range matches {
+		existing, _ := app.FindFirstRecordByFilter(
+			utils.CollectionDuplicateCandidates,
+			"contact_a = {:a} && contact_b = {:b}",
+			map[string]any{"a": match.ContactA, "b": match.ContactB},
+		)
+
+		record := existing
+		if record == nil {
+			record = core.NewRecord(collection)
+			record.Set("contact_a", match.ContactA)
+			record.Set("contact_b", match.ContactB)
+			record.Set("status", "pending")
+		} else if record.GetString("status") == "dismissed" {
+			continue
+		}
+
+		record.Set("score", match.Score)
+		record.Set("reasons", match.Reasons)
+
+		if err := app.Save(record); err != nil {
+			log.Printf("[Duplicates] Failed to save candidate %s/%s: %v", match.ContactA, match.ContactB, err)
+			continue
+		}
+		if existing == nil {
+			created++
+		} else {
+			updated++
+		}
+	}
+
+	return created, updated
+}
+
+// dismissDuplicatePairs records every pair among the given contacts as dismissed, so
+// detection doesn't propose them again (e.g. straight after a merge of them is undone)
+func dismissDuplicatePairs(app core.App, records []*core.Record, reviewedBy string) error {
+	collection, err := app.FindCollectionByNameOrId(utils.CollectionDuplicateCandidates)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i := 0; i < len(records); i++ {
+		for j := i + 1; j < len(records); j++ {
+			ca, cb := newDuplicateContact(records[i]), newDuplicateContact(records[j])
+			a, b := orderedPair(ca.ID, cb.ID)
+
+			record, _ := app.FindFirstRecordByFilter(
+				utils.CollectionDuplicateCandidates,
+				"contact_a = {:a} && contact_b = {:b}",
+				map[string]any{"a": a, "b": b},
+			)
+			if record == nil {
+				record = core.NewRecord(collection)
+				record.Set("contact_a", a)
+				record.Set("contact_b", b)
+			}
+			score, reasons := scoreDuplicatePair(ca, cb)
+			record.Set("score", score)
+			record.Set("reasons", reasons)
+			record.Set("status", "dismissed")
+			record.Set("reviewed_at", now)
+			record.Set("reviewed_by", reviewedBy)
+			if err := app.Save(record); err != nil {
+				return fmt.Errorf("failed to dismiss %s/%s: %w", a, b, err)
+			}
+		}
+	}
+	return nil
+}
+
+// duplicateDetectionResult summarises a detection run
+type duplicateDetectionResult struct {
+	Scanned int `json:"scanned"`
+	Matches int `json:"matches"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Pruned  int `json:"pruned"`
+}
+
+// duplicateScanRunning is set while a full scan is in progress, so the nightly job and
+// the admin "scan now" button can't rebucket and upsert the queue at the same time
+var duplicateScanRunning atomic.Bool
+
+var errDuplicateScanRunning = errors.New("a duplicate scan is already running")
+
+// runDuplicateDetection scans all non-archived contacts and refreshes the review queue.
+// Usable from both CLI and HTTP handler. Returns errDuplicateScanRunning if another scan
+// hasn't finished yet.
+func runDuplicateDetection(app *pocketbase.PocketBase) (*duplicateDetectionResult, error) {
+	if !duplicateScanRunning.CompareAndSwap(false, true) {
+		return nil, errDuplicateScanRunning
+	}
+	defer duplicateScanRunning.Store(false)
+
+	return scanDuplicates(app)
+}
+
+// scanDuplicates does the work of runDuplicateDetection. Callers must hold duplicateScanRunning.
+func scanDuplicates(app *pocketbase.PocketBase) (*duplicateDetectionResult, error) {
+	records, err := app.FindRecordsByFilter(utils.CollectionContacts, "status != 'archived'", "", 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch contacts: %w", err)
+	}
+
+	contacts := make([]duplicateContact, len(records))
+	for i, r := range records {
+		contacts[i] = newDuplicateContact(r)
+	}
+
+	skip := linkedContactPairs(app)
+	matches := findDuplicateMatches(contacts, skip)
+	created, updated := saveDuplicateMatches(app, matches)
+	pruned := pruneDuplicateCandidates(app, contacts, skip)
+
+	log.Printf("[Duplicates] Scanned %d contacts: %d matches (%d new, %d updated, %d pruned)", len(records), len(matches), created, updated, pruned)
+	return &duplicateDetectionResult{
+		Scanned: len(records),
+		Matches: len(matches),
+		Created: created,
+		Updated: updated,
+		Pruned:  pruned,
+	}, nil
+}
+
+// pruneDuplicateCandidates deletes pending candidates whose contacts no longer match: one
+// of them is archived, they've since been linked, or they've been edited and now score
+// below the threshold. Dismissed pairs are kept so they stay dismissed.
+func pruneDuplicateCandidates(app *pocketbase.PocketBase, contacts []duplicateContact, skip map[string]bool) int {
+	byID := make(map[string]duplicateContact, len(contacts))
+	for _, c := range contacts {
+		byID[c.ID] = c
+	}
+
+	pending, err := app.FindRecordsByFilter(utils.CollectionDuplicateCandidates, "status = 'pending'", "", 0, 0)
+	if err != nil {
+		log.Printf("[Duplicates] Failed to load pending candidates: %v", err)
+		return 0
+	}
+
+	pruned := 0
+	for _, r := range pending {
+		a, b := r.GetString("contact_a"), r.GetString("contact_b")
+		ca, okA := byID[a]
+		cb, okB := byID[b]
+		if okA && okB && !skip[a+":"+b] {
+			if score, _ := scoreDuplicatePair(ca, cb); score >= duplicateScoreMin {
+				continue
+			}
+		}
+		if err := app.Delete(r); err != nil {
+			log.Printf("[Duplicates] Failed to prune candidate %s/%s: %v", a, b, err)
+			continue
+		}
+		pruned++
+	}
+	return pruned
+}
+
+// detectDuplicatesForContact compares a single contact against likely matches.
+// Runs after contact creation so Humanitix syncs, RSVP upserts and imports
+// surface duplicates immediately instead of waiting for the nightly scan.
+func detectDuplicatesForContact(app *pocketbase.PocketBase, contactID string) {
+	record, err := app.FindRecordById(utils.CollectionContacts, contactID)
+	if err != nil || record.GetString("status") == "archived" {
+		return
+	}
+	target := newDuplicateContact(record)
+
+	filters := []string{}
+	params := map[string]any{"id": contactID}
+	for i, key := range target.EmailKeys {
+		p := fmt.Sprintf("ek%d", i)
+		params[p] = key
+		if utils.IsEncryptionEnabled() {
+			filters = append(filters, fmt.Sprintf("email_index = {:%s} || personal_email_index = {:%s}", p, p))
+		} else {
+			filters = append(filters, fmt.Sprintf("email = {:%s} || personal_email = {:%s}", p, p))
+		}
+	}
+	if ln := strings.ToLower(strings.TrimSpace(record.GetString("last_name"))); ln != "" {
+		filters = append(filters, "last_name:lower = {:ln}")
+		params["ln"] = ln
+	}
+	if target.LinkedIn != "" {
+		filters = append(filters, "linkedin ~ {:li}")
+		params["li"] = target.LinkedIn
+	}
+	if len(filters) == 0 {
+		return
+	}
+
+	filter := "id != {:id} && status != 'archived' && (" + strings.Join(filters, " || ") + ")"
+	others, err := app.FindRecordsByFilter(utils.CollectionContacts, filter, "", 200, 0, params)
+	if err != nil || len(others) == 0 {
+		return
+	}
+
+	contacts := []duplicateContact{target}
+	for _, o := range others {
+		contacts = append(contacts, newDuplicateContact(o))
+	}
+
+	skip := linkedContactPairs(app)
+	var matches []duplicateMatch
+	for _, other := range contacts[1:] {
+		a, b := orderedPair(target.ID, other.ID)
+		if skip[a+":"+b] {
+			continue
+		}
+		score, reasons := scoreDuplicatePair(target, other)
+		if score < duplicateScoreMin {
+			continue
+		}
+		matches = append(matches, duplicateMatch{ContactA: a, ContactB: b, Score: score, Reasons: reasons})
+	}
+
+	if len(matches) > 0 {
+		created, _ := saveDuplicateMatches(app, matches)
+		log.Printf("[Duplicates] Contact %s: %d possible duplicates (%d new)", contactID, len(matches), created)
+	}
+}
+
+// registerDuplicateDetectionHooks runs incremental detection when contacts are created
+func registerDuplicateDetectionHooks(app *pocketbase.PocketBase) {
+	app.OnRecordAfterCreateSuccess(utils.CollectionContacts).BindFunc(func(e *core.RecordEvent) error {
+		contactID := e.Record.Id
+		go detectDuplicatesForContact(app, contactID)
+		return e.Next()
+	})
+}
+
+// scheduleDuplicateDetection runs a full duplicate scan daily at the specified hour (AEST)
+func scheduleDuplicateDetection(app *pocketbase.PocketBase) {
+	// Wait for app to fully start
+	time.Sleep(60 * time.Second)
+
+	loc, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		log.Printf("[Duplicates] Warning: Could not load timezone, using UTC: %v", err)
+		loc = time.UTC
+	}
+
+	for {
+		now := time.Now().In(loc)
+		next := time.Date(now.Year(), now.Month(), now.Day(), duplicateDetectionHour, 0, 0, 0, loc)
+		if now.After(next) {
+			next = next.Add(24 * time.Hour)
+		}
+
+		duration := time.Until(next)
+		log.Printf("[Duplicates] Next scan scheduled for %s (in %v)", next.Format("2006-01-02 15:04 MST"), duration.Round(time.Minute))
+
+		time.Sleep(duration)
+
+		if _, err := runDuplicateDetection(app); errors.Is(err, errDuplicateScanRunning) {
+			log.Println("[Duplicates] Skipping nightly scan: a scan is already running")
+		} else if err != nil {
+			log.Printf("[Duplicates] ERROR: %v", err)
+		}
+	}
+}
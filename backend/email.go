@@ -29,6 +29,8 @@ type EmailTheme struct {
 	LogoURL      string // main logo
 	BrandLogoURL string // secondary brand wordmark
 	HeroImageURL string // hero image
+	GuestListID  string // scope for email template overrides
+	ThemeID      string
 }
 
 func defaultEmailTheme() EmailTheme {
@@ -53,7 +55,9 @@ func defaultEmailTheme() EmailTheme {
 func buildEmailTheme(app *pocketbase.PocketBase, guestList *core.Record) EmailTheme {
 	theme := fetchThemeForGuestList(app, guestList)
 	if theme == nil {
-		return defaultEmailTheme()
+		et := defaultEmailTheme()
+		et.GuestListID = guestList.Id
+		return et
 	}
 
 	base := getPublicBaseURL()
@@ -65,6 +69,8 @@ func buildEmailTheme(app *pocketbase.PocketBase, guestList *core.Record) EmailTh
 		Border:     getStr(theme, "color_border", "#333333"),
 		Primary:    getStr(theme, "color_primary", "#E95139"),
 		IsDark:     isDark,
+		GuestListID: guestList.Id,
+		ThemeID:     guestList.GetString("theme"),
 	}
 
 	// For light themes: grey outer bg, white card, dark text
@@ -252,7 +258,8 @@ func wrapEmailHTML(content string) string {
 }
 
 // wrapRSVPEmailHTML wraps content in the themed RSVP email template.
-func wrapRSVPEmailHTML(content string, theme EmailTheme) string {
+// If unsubscribeLink is set, the footer includes an unsubscribe link.
+func wrapRSVPEmailHTML(content string, theme EmailTheme, unsubscribeLink string) string {
 	// Build logo section
 	logoHTML := fmt.Sprintf(`<td style="vertical-align: middle;"><img src="%s" alt="The Outlook" style="height: 32px; display: block; font-weight: bold;"></td>`, theme.LogoURL)
 	if theme.BrandLogoURL != "" {
@@ -260,6 +267,14 @@ func wrapRSVPEmailHTML(content string, theme EmailTheme) string {
                 <td style="vertical-align: middle;"><img src="%s" alt="" style="height: 32px; display: block;"></td>`, theme.LogoURL, theme.BrandLogoURL)
 	}
 
+	unsubscribeHTML := ""
+	if unsubscribeLink != "" {
+		unsubscribeHTML = fmt.Sprintf(`
+            <p style="font-size: 11px; color: #555; margin: 8px 0 0 0;">
+                Don't want these emails? <a href="%s" style="color: #555; text-decoration: underline;">Unsubscribe</a>
+            </p>`, unsubscribeLink)
+	}
+
 	return fmt.Sprintf(`<!DOCTYPE html>
 <html lang="en">
 <head>
@@ -291,12 +306,12 @@ func wrapRSVPEmailHTML(content string, theme EmailTheme) string {
             </p>
             <p style="font-size: 11px; color: #555; margin: 0;">
                 &copy; 2021-2026 The Outlook Pty Ltd &mdash; ABN 72 655 333 403
-            </p>
+            </p>%s
         </div>
     </div>
 
 </body>
-</html>`, theme.Text, theme.OuterBackground, theme.Background, logoHTML, theme.HeroImageURL, content, theme.Border)
+</html>`, theme.Text, theme.OuterBackground, theme.Background, logoHTML, theme.HeroImageURL, content, theme.Border, unsubscribeHTML)
 }
 
 // buildEventDetailsHTML builds the event date/time/location block for RSVP emails.
@@ -319,82 +334,6 @@ func buildEventDetailsHTML(theme EmailTheme, eventDate, eventTime, eventLocation
 	return html
 }
 
-// buildRSVPInviteContent builds the inner content for an RSVP invite email.
-func buildRSVPInviteContent(theme EmailTheme, eventContext, firstName, listDescription, eventDate, eventTime, eventLocation, rsvpURL string) string {
-	detailsHTML := buildEventDetailsHTML(theme, eventDate, eventTime, eventLocation)
-
-	descriptionHTML := ""
-	if listDescription != "" {
-		descriptionHTML = fmt.Sprintf(`<p style="color: %s; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">%s</p>
-            <p style="color: %s; font-size: 16px; line-height: 1.6; margin: 0 0 8px 0;">We'd love to see you there.</p>`,
-			textWithOpacity(theme.Text, "0.8"), listDescription, textWithOpacity(theme.Text, "0.8"))
-	} else {
-		descriptionHTML = fmt.Sprintf(`<p style="color: %s; font-size: 16px; line-height: 1.6; margin: 0 0 8px 0;">We'd love for you to join us for an evening of conversation, connection and great food.</p>`,
-			textWithOpacity(theme.Text, "0.8"))
-	}
-
-	subtleText := textWithOpacity(theme.Text, "0.5")
-	bodyText := textWithOpacity(theme.Text, "0.8")
-	mutedText := textWithOpacity(theme.Text, "0.6")
-	faintText := textWithOpacity(theme.Text, "0.3")
-	faintBg := textWithOpacity(theme.Text, "0.05")
-
-	return fmt.Sprintf(`
-            <p style="color: %s; font-size: 12px; text-transform: uppercase; letter-spacing: 2px; margin: 0 0 16px 0;">You're invited</p>
-            <h1 style="color: %s; font-size: 32px; line-height: 1.1; margin: 0 0 20px 0;">%s</h1>
-            <p style="color: %s; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hi %s,</p>
-            %s
-            %s
-            <p style="color: %s; font-size: 15px; line-height: 1.6; margin: 0 0 32px 0;">
-                Spaces are limited, so please let us know if you can make it.
-            </p>
-            <!--[if mso]>
-            <table role="presentation" cellspacing="0" cellpadding="0" border="0" width="100%%"><tr>
-            <td width="50%%" style="padding-right: 6px;">
-            <![endif]-->
-            <div style="display: inline-block; width: 48%%; vertical-align: top;">
-                <a href="%s" style="display: block; background: %s; color: %s; padding: 16px 12px; text-decoration: none; font-size: 14px; text-align: center; border: 1px solid %s;">
-                    I can make it
-                </a>
-            </div>
-            <!--[if mso]>
-            </td><td width="50%%" style="padding-left: 6px;">
-            <![endif]-->
-            <div style="display: inline-block; width: 48%%; vertical-align: top;">
-                <a href="%s" style="display: block; background: transparent; color: %s; padding: 16px 12px; text-decoration: none; font-size: 14px; text-align: center; border: 1px solid %s;">
-                    I can't make it
-                </a>
-            </div>
-            <!--[if mso]>
-            </td></tr></table>
-            <![endif]-->
-            <p style="color: %s; font-size: 13px; margin: 16px 0 24px 0;">
-                This link is personal to you. Please don't share it.
-            </p>
-            <p style="color: %s; font-size: 13px; margin: 0 0 8px 0;">
-                Copy and paste if the link doesn't work:
-            </p>
-            <div style="background: %s; padding: 12px 16px; margin: 0;">
-                <p style="color: %s; font-size: 12px; font-family: 'Courier New', Courier, monospace; word-break: break-all; margin: 0;">
-                    %s
-                </p>
-            </div>
-`,
-		subtleText, theme.Text, eventContext,
-		bodyText, firstName,
-		descriptionHTML,
-		detailsHTML,
-		mutedText,
-		rsvpURL, theme.Button, theme.ButtonText, theme.Button,
-		rsvpURL, theme.Text, theme.Border,
-		bodyText,
-		faintText,
-		faintBg,
-		textWithOpacity(theme.Text, "0.4"),
-		rsvpURL,
-	)
-}
-
 // sendOTPEmail sends a 6-digit OTP code to the share recipient.
 func sendOTPEmail(app *pocketbase.PocketBase, email, recipientName, code string) error {
 	name := recipientName
@@ -402,31 +341,26 @@ func sendOTPEmail(app *pocketbase.PocketBase, email, recipientName, code string)
 		name = "there"
 	}
 
-	subject := "Your verification code"
-	content := fmt.Sprintf(`
-            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hi %s,</p>
-            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">
-                Your verification code to view the guest list is:
-            </p>
-            <div style="font-size: 32px; letter-spacing: 8px; text-align: center; padding: 24px; background: #f5f5f5; border-radius: 8px; margin: 24px 0; color: #202020;">
-                %s
-            </div>
-            <p style="color: #9a9a9a; font-size: 14px; margin: 24px 0 0 0;">This code expires in 10 minutes.</p>
-`, name, code)
+	rendered, err := renderEmailTemplate(app, emailKindOTP, "", "", map[string]any{"name": name, "code": code})
+	if err != nil {
+		log.Printf("[Email] Failed to render OTP for %s: %v", email, err)
+		return err
+	}
 
 	msg := &mailer.Message{
 		From:    mail.Address{Address: app.Settings().Meta.SenderAddress, Name: app.Settings().Meta.SenderName},
 		To:      []mail.Address{{Address: email, Name: recipientName}},
-		Subject: subject,
-		HTML:    wrapEmailHTML(content),
+		Subject: rendered.Subject,
+		HTML:    wrapEmailHTML(rendered.HTML),
+		Text:    wrapEmailText(rendered.Text, ""),
 	}
 
-	if err := app.NewMailClient().Send(msg); err != nil {
-		log.Printf("[Email] Failed to send OTP to %s: %v", email, err)
+	if _, err := enqueueEmail(app, msg, outboxOptions{Kind: emailKindOTP, Transactional: true, TemplateVersion: rendered.TemplateVersion}); err != nil {
+		log.Printf("[Email] Failed to queue OTP to %s: %v", email, err)
 		return err
 	}
 
-	log.Printf("[Email] OTP sent to %s", email)
+	log.Printf("[Email] OTP queued for %s", email)
 	return nil
 }
 
@@ -437,51 +371,33 @@ func sendShareNotificationEmail(app *pocketbase.PocketBase, recipientEmail, reci
 		name = "there"
 	}
 
-	eventLine := ""
-	if eventName != "" {
-		eventLine = fmt.Sprintf(`
-            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Event: %s</p>`, eventName)
-	}
-
 	firstName := strings.Fields(name)[0]
-	subject := fmt.Sprintf("%s, you've been invited to review %s", firstName, listName)
-	content := fmt.Sprintf(`
-            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hi %s,</p>
-            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">
-                You've been invited to review the guest list <strong>%s</strong>.
-            </p>
-            %s
-            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 24px 0;">
-                You'll need to verify your email to access the list. The link expires in 30 days.
-            </p>
-            <div style="text-align: center; margin: 32px 0;">
-                <a href="%s" style="display: inline-block; background: #0d0d0d; color: #ffffff; padding: 14px 32px; text-decoration: none; border-radius: 6px; font-size: 16px;">
-                    View guest list
-                </a>
-            </div>
-            <p style="color: #9a9a9a; font-size: 14px; margin: 24px 0 8px 0;">
-                Copy and paste if the link doesn't work:
-            </p>
-            <div style="background: #f5f5f5; padding: 12px 16px; border-radius: 6px; margin: 0;">
-                <p style="color: #666666; font-size: 13px; font-family: 'Courier New', Courier, monospace; word-break: break-all; margin: 0;">
-                    %s
-                </p>
-            </div>
-`, name, listName, eventLine, shareURL, shareURL)
+	rendered, err := renderEmailTemplate(app, emailKindShareNotification, "", "", map[string]any{
+		"name":       name,
+		"first_name": firstName,
+		"list_name":  listName,
+		"event_name": eventName,
+		"share_url":  shareURL,
+	})
+	if err != nil {
+		log.Printf("[Email] Failed to render share notification for %s: %v", recipientEmail, err)
+		return err
+	}
 
 	msg := &mailer.Message{
 		From:    mail.Address{Address: app.Settings().Meta.SenderAddress, Name: app.Settings().Meta.SenderName},
 		To:      []mail.Address{{Address: recipientEmail, Name: recipientName}},
-		Subject: subject,
-		HTML:    wrapEmailHTML(content),
+		Subject: rendered.Subject,
+		HTML:    wrapEmailHTML(rendered.HTML),
+		Text:    wrapEmailText(rendered.Text, ""),
 	}
 
-	if err := app.NewMailClient().Send(msg); err != nil {
-		log.Printf("[Email] Failed to send share notification to %s: %v", recipientEmail, err)
+	if _, err := enqueueEmail(app, msg, outboxOptions{Kind: emailKindShareNotification, Transactional: true, TemplateVersion: rendered.TemplateVersion}); err != nil {
+		log.Printf("[Email] Failed to queue share notification to %s: %v", recipientEmail, err)
 		return err
 	}
 
-	log.Printf("[Email] Share notification sent to %s for list %s", recipientEmail, listName)
+	log.Printf("[Email] Share notification to %s queued for list %s", recipientEmail, listName)
 	return nil
 }
 
@@ -492,86 +408,115 @@ func sendAttendeeOTPEmail(app *pocketbase.PocketBase, email, recipientName, code
 		name = "there"
 	}
 
-	subject := "Your login code"
-	content := fmt.Sprintf(`
-            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hi %s,</p>
-            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">
-                Your login code is:
-            </p>
-            <div style="font-size: 32px; letter-spacing: 8px; text-align: center; padding: 24px; background: #f5f5f5; border-radius: 8px; margin: 24px 0; color: #202020;">
-                %s
-            </div>
-            <p style="color: #9a9a9a; font-size: 14px; margin: 24px 0 0 0;">This code expires in 10 minutes.</p>
-`, name, code)
+	rendered, err := renderEmailTemplate(app, emailKindAttendeeOTP, "", "", map[string]any{"name": name, "code": code})
+	if err != nil {
+		log.Printf("[Email] Failed to render attendee OTP for %s: %v", email, err)
+		return err
+	}
 
 	msg := &mailer.Message{
 		From:    mail.Address{Address: app.Settings().Meta.SenderAddress, Name: app.Settings().Meta.SenderName},
 		To:      []mail.Address{{Address: email, Name: recipientName}},
-		Subject: subject,
-		HTML:    wrapEmailHTML(content),
+		Subject: rendered.Subject,
+		HTML:    wrapEmailHTML(rendered.HTML),
+		Text:    wrapEmailText(rendered.Text, ""),
 	}
 
-	if err := app.NewMailClient().Send(msg); err != nil {
-		log.Printf("[Email] Failed to send attendee OTP to %s: %v", email, err)
+	if _, err := enqueueEmail(app, msg, outboxOptions{Kind: emailKindAttendeeOTP, Transactional: true, TemplateVersion: rendered.TemplateVersion}); err != nil {
+		log.Printf("[Email] Failed to queue attendee OTP to %s: %v", email, err)
 		return err
 	}
 
-	log.Printf("[Email] Attendee OTP sent to %s", email)
+	log.Printf("[Email] Attendee OTP queued for %s", email)
 	return nil
 }
 
+// addInviteTracking click-wraps links to rsvpURL and appends an open pixel. messageID is
+// the outbox ID the email will be queued under, so opens and clicks are credited to it.
+func addInviteTracking(content, rsvpURL, rsvpToken, messageID string) string {
+	baseURL := getPublicBaseURL()
+	trackBase := fmt.Sprintf("%s/api/public/t/%s", baseURL, rsvpToken)
+	clickURL := fmt.Sprintf("%s/click?url=%s&m=%s", trackBase, url.QueryEscape(rsvpURL), url.QueryEscape(messageID))
+	pixelURL := fmt.Sprintf("%s/open.gif?m=%s", trackBase, url.QueryEscape(messageID))
+
+	// Replace rsvpURL hrefs with click-tracked URL
+	content = strings.ReplaceAll(content, fmt.Sprintf(`href="%s"`, rsvpURL), fmt.Sprintf(`href="%s"`, clickURL))
+
+	// Append tracking pixel
+	content += fmt.Sprintf(`<img src="%s" width="1" height="1" alt="" style="display:block;width:1px;height:1px;border:0;" />`, pixelURL)
+	return content
+}
+
 // sendRSVPInviteEmail sends an RSVP invitation to a guest with their personal RSVP link.
-// rsvpToken is used to embed open/click tracking in the email.
-func sendRSVPInviteEmail(app *pocketbase.PocketBase, recipientEmail, recipientName, rsvpURL, rsvpToken, listName, listDescription, eventName, eventDate, eventTime, eventLocation string, theme EmailTheme) error {
+// rsvpToken is used to embed open/click tracking in the email; the item's email_status
+// follows the queued message. If icsData is non-nil, attaches it as an invite.ics calendar file.
+func sendRSVPInviteEmail(app *pocketbase.PocketBase, recipientEmail, recipientName, rsvpURL, rsvpToken, itemID, listName, listDescription, eventName, eventDate, eventTime, eventLocation string, theme EmailTheme, icsData []byte) error {
+	if !emailAllowed(app, recipientEmail, consentEventInvites) {
+		log.Printf("[Email] Skipped RSVP invite to %s: event invite consent withdrawn", recipientEmail)
+		return errConsentWithdrawn
+	}
+
 	name := recipientName
 	if name == "" {
 		name = "there"
 	}
 
-	firstName := strings.Fields(name)[0]
+	firstName := "there"
+	if f := strings.Fields(name); len(f) > 0 {
+		firstName = f[0]
+	}
 
 	eventContext := listName
 	if eventName != "" {
 		eventContext = eventName
 	}
 
-	subject := fmt.Sprintf("%s, you're invited to %s", firstName, eventContext)
-	content := buildRSVPInviteContent(theme, eventContext, firstName, listDescription, eventDate, eventTime, eventLocation, rsvpURL)
+	rendered, err := renderEmailTemplate(app, emailKindRSVPInvite, theme.GuestListID, theme.ThemeID,
+		rsvpEmailData(theme, eventContext, firstName, listName, listDescription, eventDate, eventTime, eventLocation, rsvpURL))
+	if err != nil {
+		log.Printf("[Email] Failed to render RSVP invite for %s: %v", recipientEmail, err)
+		return err
+	}
 
 	// Add invite tracking (open pixel + click-wrapped links)
+	messageID := core.GenerateDefaultRandomId()
+	content := rendered.HTML
 	if rsvpToken != "" {
-		baseURL := getPublicBaseURL()
-		trackBase := fmt.Sprintf("%s/api/public/t/%s", baseURL, rsvpToken)
-		clickURL := fmt.Sprintf("%s/click?url=%s", trackBase, url.QueryEscape(rsvpURL))
-		pixelURL := fmt.Sprintf("%s/open.gif", trackBase)
-
-		// Replace rsvpURL hrefs with click-tracked URL
-		content = strings.ReplaceAll(content, fmt.Sprintf(`href="%s"`, rsvpURL), fmt.Sprintf(`href="%s"`, clickURL))
-
-		// Append tracking pixel
-		content += fmt.Sprintf(`<img src="%s" width="1" height="1" alt="" style="display:block;width:1px;height:1px;border:0;" />`, pixelURL)
+		content = addInviteTracking(content, rsvpURL, rsvpToken, messageID)
 	}
 
 	msg := &mailer.Message{
 		From:    mail.Address{Address: app.Settings().Meta.SenderAddress, Name: app.Settings().Meta.SenderName},
 		To:      []mail.Address{{Address: recipientEmail, Name: recipientName}},
 		Bcc:     []mail.Address{{Address: "hello@wearetheoutlook.com.au"}},
-		Subject: subject,
-		HTML:    wrapRSVPEmailHTML(content, theme),
+		Subject: rendered.Subject,
+		HTML:    wrapRSVPEmailHTML(content, theme, unsubscribeURL(recipientEmail)),
+		Text:    wrapEmailText(rendered.Text, unsubscribeURL(recipientEmail)),
+	}
+
+	if len(icsData) > 0 {
+		msg.Attachments = map[string]io.Reader{
+			"invite.ics": bytes.NewReader(icsData),
+		}
 	}
 
-	if err := app.NewMailClient().Send(msg); err != nil {
-		log.Printf("[Email] Failed to send RSVP invite to %s: %v", recipientEmail, err)
+	if _, err := enqueueEmail(app, msg, outboxOptions{ID: messageID, Kind: emailKindRSVPInvite, GuestListItem: itemID, TemplateVersion: rendered.TemplateVersion}); err != nil {
+		log.Printf("[Email] Failed to queue RSVP invite to %s: %v", recipientEmail, err)
 		return err
 	}
 
-	log.Printf("[Email] RSVP invite sent to %s for %s", recipientEmail, eventContext)
+	log.Printf("[Email] RSVP invite to %s queued for %s", recipientEmail, eventContext)
 	return nil
 }
 
 // sendRSVPForwardEmail sends an invitation email when someone forwards their RSVP to another person.
 // To: recipient, CC: forwarder, BCC: hello@wearetheoutlook.com.au
-func sendRSVPForwardEmail(app *pocketbase.PocketBase, recipientEmail, recipientName, forwarderName, forwarderEmail, rsvpURL, listDescription, eventName, eventDate, eventTime, eventLocation string, theme EmailTheme) error {
+func sendRSVPForwardEmail(app *pocketbase.PocketBase, recipientEmail, recipientName, forwarderName, forwarderEmail, rsvpURL, itemID, listDescription, eventName, eventDate, eventTime, eventLocation string, theme EmailTheme) error {
+	if !emailAllowed(app, recipientEmail, consentEventInvites) {
+		log.Printf("[Email] Skipped forwarded RSVP invite to %s: event invite consent withdrawn", recipientEmail)
+		return errConsentWithdrawn
+	}
+
 	name := recipientName
 	if name == "" {
 		name = "there"
@@ -579,98 +524,40 @@ func sendRSVPForwardEmail(app *pocketbase.PocketBase, recipientEmail, recipientN
 	firstName := strings.Fields(name)[0]
 	forwarderFirst := strings.Fields(forwarderName)[0]
 
-	detailsHTML := buildEventDetailsHTML(theme, eventDate, eventTime, eventLocation)
-
-	descriptionHTML := ""
-	if listDescription != "" {
-		descriptionHTML = fmt.Sprintf(`<p style="color: %s; font-size: 16px; line-height: 1.6; margin: 16px 0 8px 0;">%s We'd love to see you there.</p>`,
-			textWithOpacity(theme.Text, "0.8"), listDescription)
+	data := rsvpEmailData(theme, eventName, firstName, eventName, listDescription, eventDate, eventTime, eventLocation, rsvpURL)
+	data["forwarder_name"] = forwarderFirst
+	data["forwarder_email"] = forwarderEmail
+	rendered, err := renderEmailTemplate(app, emailKindRSVPForward, theme.GuestListID, theme.ThemeID, data)
+	if err != nil {
+		log.Printf("[Email] Failed to render forward invite for %s: %v", recipientEmail, err)
+		return err
 	}
 
-	subtleText := textWithOpacity(theme.Text, "0.5")
-	bodyText := textWithOpacity(theme.Text, "0.8")
-	mutedText := textWithOpacity(theme.Text, "0.6")
-	faintText := textWithOpacity(theme.Text, "0.3")
-	faintBg := textWithOpacity(theme.Text, "0.05")
-
-	subject := fmt.Sprintf("%s thinks you'd love %s", forwarderFirst, eventName)
-	content := fmt.Sprintf(`
-            <p style="color: %s; font-size: 12px; text-transform: uppercase; letter-spacing: 2px; margin: 0 0 16px 0;">You're invited</p>
-            <h1 style="color: %s; font-size: 32px; line-height: 1.1; margin: 0 0 20px 0;">%s</h1>
-            <p style="color: %s; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hi %s,</p>
-            <p style="color: %s; font-size: 16px; line-height: 1.6; margin: 0 0 8px 0;">
-                %s thought you'd enjoy %s, and has passed along an invitation for you.
-            </p>
-            %s
-            %s
-            <p style="color: %s; font-size: 15px; line-height: 1.6; margin: 0 0 32px 0;">
-                Spaces are limited, so let us know if you can make it.
-            </p>
-            <!--[if mso]>
-            <table role="presentation" cellspacing="0" cellpadding="0" border="0" width="100%%"><tr>
-            <td width="50%%" style="padding-right: 6px;">
-            <![endif]-->
-            <div style="display: inline-block; width: 48%%; vertical-align: top;">
-                <a href="%s" style="display: block; background: %s; color: %s; padding: 16px 12px; text-decoration: none; font-size: 14px; text-align: center; border: 1px solid %s;">
-                    I can make it
-                </a>
-            </div>
-            <!--[if mso]>
-            </td><td width="50%%" style="padding-left: 6px;">
-            <![endif]-->
-            <div style="display: inline-block; width: 48%%; vertical-align: top;">
-                <a href="%s" style="display: block; background: transparent; color: %s; padding: 16px 12px; text-decoration: none; font-size: 14px; text-align: center; border: 1px solid %s;">
-                    I can't make it
-                </a>
-            </div>
-            <!--[if mso]>
-            </td></tr></table>
-            <![endif]-->
-            <p style="color: %s; font-size: 13px; margin: 24px 0 8px 0;">
-                Copy and paste if the link doesn't work:
-            </p>
-            <div style="background: %s; padding: 12px 16px; margin: 0;">
-                <p style="color: %s; font-size: 12px; font-family: 'Courier New', Courier, monospace; word-break: break-all; margin: 0;">
-                    %s
-                </p>
-            </div>
-`,
-		subtleText, theme.Text, eventName,
-		bodyText, firstName,
-		bodyText, forwarderFirst, eventName,
-		descriptionHTML,
-		detailsHTML,
-		mutedText,
-		rsvpURL, theme.Button, theme.ButtonText, theme.Button,
-		rsvpURL, theme.Text, theme.Border,
-		faintText,
-		faintBg,
-		textWithOpacity(theme.Text, "0.4"),
-		rsvpURL,
-	)
-
 	msg := &mailer.Message{
 		From:    mail.Address{Address: app.Settings().Meta.SenderAddress, Name: app.Settings().Meta.SenderName},
 		To:      []mail.Address{{Address: recipientEmail, Name: recipientName}},
 		Cc:      []mail.Address{{Address: forwarderEmail, Name: forwarderName}},
 		Bcc:     []mail.Address{{Address: "hello@wearetheoutlook.com.au"}},
-		Subject: subject,
-		HTML:    wrapRSVPEmailHTML(content, theme),
+		Subject: rendered.Subject,
+		HTML:    wrapRSVPEmailHTML(rendered.HTML, theme, unsubscribeURL(recipientEmail)),
+		Text:    wrapEmailText(rendered.Text, unsubscribeURL(recipientEmail)),
 	}
 
-	if err := app.NewMailClient().Send(msg); err != nil {
-		log.Printf("[Email] Failed to send forward invite to %s: %v", recipientEmail, err)
+	if _, err := enqueueEmail(app, msg, outboxOptions{Kind: emailKindRSVPForward, GuestListItem: itemID, TemplateVersion: rendered.TemplateVersion}); err != nil {
+		log.Printf("[Email] Failed to queue forward invite to %s: %v", recipientEmail, err)
 		return err
 	}
 
-	log.Printf("[Email] Forward invite sent to %s (forwarded by %s) for %s", recipientEmail, forwarderEmail, eventName)
+	log.Printf("[Email] Forward invite to %s queued (forwarded by %s) for %s", recipientEmail, forwarderEmail, eventName)
 	return nil
 }
 
 // sendRSVPConfirmationEmail sends a confirmation email when someone accepts an RSVP.
 // Always BCCs hello@wearetheoutlook.com.au plus any additional BCC emails from the guest list config.
-// If icsData is non-nil, attaches it as an invite.ics calendar file.
-func sendRSVPConfirmationEmail(app *pocketbase.PocketBase, recipientEmail, recipientName, eventName, eventDate, eventTime, eventLocation string, bccEmails []string, theme EmailTheme, icsData []byte) error {
+// If icsData is non-nil, attaches it as an invite.ics calendar file. With a checkInCode the
+// guest's check-in QR code is shown in the email and attached. sessions are the ones the
+// guest picked, listed in the email.
+func sendRSVPConfirmationEmail(app *pocketbase.PocketBase, recipientEmail, recipientName, eventName, eventDate, eventTime, eventLocation string, bccEmails []string, theme EmailTheme, sessions []eventSession, icsData []byte, itemID, checkInCode string) error {
 	name := recipientName
 	if name == "" {
 		name = "there"
@@ -678,24 +565,14 @@ func sendRSVPConfirmationEmail(app *pocketbase.PocketBase, recipientEmail, recip
 
 	firstName := strings.Fields(name)[0]
 
-	subject := fmt.Sprintf("You're confirmed for %s", eventName)
-
-	detailsHTML := buildEventDetailsHTML(theme, eventDate, eventTime, eventLocation)
-
-	subtleText := textWithOpacity(theme.Text, "0.5")
-	bodyText := textWithOpacity(theme.Text, "0.8")
-
-	content := fmt.Sprintf(`
-            <p style="color: %s; font-size: 12px; text-transform: uppercase; letter-spacing: 2px; margin: 0 0 16px 0;">You're confirmed</p>
-            <h1 style="color: %s; font-size: 32px; line-height: 1.1; margin: 0 0 20px 0;">%s</h1>
-            <p style="color: %s; font-size: 16px; line-height: 1.6; margin: 0 0 8px 0;">
-                %s, confirming your RSVP and looking forward to seeing you on the night.
-            </p>
-            %s
-            <p style="color: %s; font-size: 14px; margin: 0;">
-                If your plans change, please let us know by replying to this email.
-            </p>
-`, subtleText, theme.Text, eventName, bodyText, firstName, detailsHTML, textWithOpacity(theme.Text, "0.4"))
+	data := rsvpEmailData(theme, eventName, firstName, eventName, "", eventDate, eventTime, eventLocation, "")
+	addSessionsData(data, theme, sessions)
+	addCheckInQRData(data, theme, checkInCode)
+	rendered, err := renderEmailTemplate(app, emailKindRSVPConfirmation, theme.GuestListID, theme.ThemeID, data)
+	if err != nil {
+		log.Printf("[Email] Failed to render RSVP confirmation for %s: %v", recipientEmail, err)
+		return err
+	}
 
 	// Build BCC list: always include hello@ + any configured contacts
 	bccList := []mail.Address{{Address: "hello@wearetheoutlook.com.au"}}
@@ -709,8 +586,9 @@ func sendRSVPConfirmationEmail(app *pocketbase.PocketBase, recipientEmail, recip
 		From:    mail.Address{Address: app.Settings().Meta.SenderAddress, Name: app.Settings().Meta.SenderName},
 		To:      []mail.Address{{Address: recipientEmail, Name: recipientName}},
 		Bcc:     bccList,
-		Subject: subject,
-		HTML:    wrapRSVPEmailHTML(content, theme),
+		Subject: rendered.Subject,
+		HTML:    wrapRSVPEmailHTML(rendered.HTML, theme, ""),
+		Text:    wrapEmailText(rendered.Text, ""),
 	}
 
 	if len(icsData) > 0 {
@@ -718,169 +596,184 @@ func sendRSVPConfirmationEmail(app *pocketbase.PocketBase, recipientEmail, recip
 			"invite.ics": bytes.NewReader(icsData),
 		}
 	}
+	attachCheckInQR(msg, checkInCode)
 
-	if err := app.NewMailClient().Send(msg); err != nil {
-		log.Printf("[Email] Failed to send RSVP confirmation to %s: %v", recipientEmail, err)
+	if _, err := enqueueEmail(app, msg, outboxOptions{Kind: emailKindRSVPConfirmation, Transactional: true, GuestListItem: itemID, TemplateVersion: rendered.TemplateVersion}); err != nil {
+		log.Printf("[Email] Failed to queue RSVP confirmation to %s: %v", recipientEmail, err)
 		return err
 	}
 
-	log.Printf("[Email] RSVP confirmation sent to %s for %s", recipientEmail, eventName)
+	log.Printf("[Email] RSVP confirmation to %s queued for %s", recipientEmail, eventName)
 	return nil
 }
 
-// buildRSVPFollowUpContent builds the inner content for an RSVP follow-up email.
-func buildRSVPFollowUpContent(theme EmailTheme, eventContext, firstName, listDescription, eventDate, eventTime, eventLocation, rsvpURL string) string {
-	detailsHTML := buildEventDetailsHTML(theme, eventDate, eventTime, eventLocation)
-
-	descriptionHTML := ""
-	if listDescription != "" {
-		descriptionHTML = fmt.Sprintf(`<p style="color: %s; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">%s</p>`,
-			textWithOpacity(theme.Text, "0.8"), listDescription)
-	}
-
-	subtleText := textWithOpacity(theme.Text, "0.5")
-	bodyText := textWithOpacity(theme.Text, "0.8")
-	mutedText := textWithOpacity(theme.Text, "0.6")
-	faintText := textWithOpacity(theme.Text, "0.3")
-	faintBg := textWithOpacity(theme.Text, "0.05")
-
-	return fmt.Sprintf(`
-            <p style="color: %s; font-size: 12px; text-transform: uppercase; letter-spacing: 2px; margin: 0 0 16px 0;">Just a reminder</p>
-            <h1 style="color: %s; font-size: 32px; line-height: 1.1; margin: 0 0 20px 0;">%s</h1>
-            <p style="color: %s; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hey %s,</p>
-            <p style="color: %s; font-size: 16px; line-height: 1.6; margin: 0 0 8px 0;">We'd still love to see you at %s. If you haven't had a chance to RSVP yet, we'd love to hear from you.</p>
-            %s
-            %s
-            <p style="color: %s; font-size: 15px; line-height: 1.6; margin: 0 0 32px 0;">
-                Spaces are limited, so please let us know if you can make it.
-            </p>
-            <!--[if mso]>
-            <table role="presentation" cellspacing="0" cellpadding="0" border="0" width="100%%"><tr>
-            <td width="50%%" style="padding-right: 6px;">
-            <![endif]-->
-            <div style="display: inline-block; width: 48%%; vertical-align: top;">
-                <a href="%s" style="display: block; background: %s; color: %s; padding: 16px 12px; text-decoration: none; font-size: 14px; text-align: center; border: 1px solid %s;">
-                    I can make it
-                </a>
-            </div>
-            <!--[if mso]>
-            </td><td width="50%%" style="padding-left: 6px;">
-            <![endif]-->
-            <div style="display: inline-block; width: 48%%; vertical-align: top;">
-                <a href="%s" style="display: block; background: transparent; color: %s; padding: 16px 12px; text-decoration: none; font-size: 14px; text-align: center; border: 1px solid %s;">
-                    I can't make it
-                </a>
-            </div>
-            <!--[if mso]>
-            </td></tr></table>
-            <![endif]-->
-            <p style="color: %s; font-size: 13px; margin: 16px 0 24px 0;">
-                This link is personal to you. Please don't share it.
-            </p>
-            <p style="color: %s; font-size: 13px; margin: 0 0 8px 0;">
-                Copy and paste if the link doesn't work:
-            </p>
-            <div style="background: %s; padding: 12px 16px; margin: 0;">
-                <p style="color: %s; font-size: 12px; font-family: 'Courier New', Courier, monospace; word-break: break-all; margin: 0;">
-                    %s
-                </p>
-            </div>
-`,
-		subtleText, theme.Text, eventContext,
-		bodyText, firstName,
-		bodyText, eventContext,
-		descriptionHTML,
-		detailsHTML,
-		mutedText,
-		rsvpURL, theme.Button, theme.ButtonText, theme.Button,
-		rsvpURL, theme.Text, theme.Border,
-		bodyText,
-		faintText,
-		faintBg,
-		textWithOpacity(theme.Text, "0.4"),
-		rsvpURL,
-	)
-}
-
 // sendRSVPFollowUpEmail sends a follow-up reminder to a guest who hasn't RSVP'd yet.
-func sendRSVPFollowUpEmail(app *pocketbase.PocketBase, recipientEmail, recipientName, rsvpURL, rsvpToken, listName, listDescription, eventName, eventDate, eventTime, eventLocation string, theme EmailTheme) error {
+func sendRSVPFollowUpEmail(app *pocketbase.PocketBase, recipientEmail, recipientName, rsvpURL, rsvpToken, itemID, listName, listDescription, eventName, eventDate, eventTime, eventLocation string, theme EmailTheme) error {
+	if !emailAllowed(app, recipientEmail, consentEventInvites) {
+		log.Printf("[Email] Skipped RSVP follow-up to %s: event invite consent withdrawn", recipientEmail)
+		return errConsentWithdrawn
+	}
+
 	name := recipientName
 	if name == "" {
 		name = "there"
 	}
 
-	firstName := strings.Fields(name)[0]
+	firstName := "there"
+	if f := strings.Fields(name); len(f) > 0 {
+		firstName = f[0]
+	}
 
 	eventContext := listName
 	if eventName != "" {
 		eventContext = eventName
 	}
 
-	subject := fmt.Sprintf("%s, we'd still love to see you at %s", firstName, eventContext)
-	content := buildRSVPFollowUpContent(theme, eventContext, firstName, listDescription, eventDate, eventTime, eventLocation, rsvpURL)
+	rendered, err := renderEmailTemplate(app, emailKindRSVPFollowUp, theme.GuestListID, theme.ThemeID,
+		rsvpEmailData(theme, eventContext, firstName, listName, listDescription, eventDate, eventTime, eventLocation, rsvpURL))
+	if err != nil {
+		log.Printf("[Email] Failed to render RSVP follow-up for %s: %v", recipientEmail, err)
+		return err
+	}
 
 	// Add invite tracking (open pixel + click-wrapped links)
+	messageID := core.GenerateDefaultRandomId()
+	content := rendered.HTML
 	if rsvpToken != "" {
-		baseURL := getPublicBaseURL()
-		trackBase := fmt.Sprintf("%s/api/public/t/%s", baseURL, rsvpToken)
-		clickURL := fmt.Sprintf("%s/click?url=%s", trackBase, url.QueryEscape(rsvpURL))
-		pixelURL := fmt.Sprintf("%s/open.gif", trackBase)
+		content = addInviteTracking(content, rsvpURL, rsvpToken, messageID)
+	}
+
+	msg := &mailer.Message{
+		From:    mail.Address{Address: app.Settings().Meta.SenderAddress, Name: app.Settings().Meta.SenderName},
+		To:      []mail.Address{{Address: recipientEmail, Name: recipientName}},
+		Bcc:     []mail.Address{{Address: "hello@wearetheoutlook.com.au"}},
+		Subject: rendered.Subject,
+		HTML:    wrapRSVPEmailHTML(content, theme, unsubscribeURL(recipientEmail)),
+		Text:    wrapEmailText(rendered.Text, unsubscribeURL(recipientEmail)),
+	}
 
-		content = strings.ReplaceAll(content, fmt.Sprintf(`href="%s"`, rsvpURL), fmt.Sprintf(`href="%s"`, clickURL))
-		content += fmt.Sprintf(`<img src="%s" width="1" height="1" alt="" style="display:block;width:1px;height:1px;border:0;" />`, pixelURL)
+	if _, err := enqueueEmail(app, msg, outboxOptions{ID: messageID, Kind: emailKindRSVPFollowUp, GuestListItem: itemID, TemplateVersion: rendered.TemplateVersion}); err != nil {
+		log.Printf("[Email] Failed to queue RSVP follow-up to %s: %v", recipientEmail, err)
+		return err
+	}
+
+	log.Printf("[Email] RSVP follow-up to %s queued for %s", recipientEmail, eventContext)
+	return nil
+}
+
+// sendRSVPLogisticsEmail sends event-day details to a guest who has accepted. Like the
+// confirmation it's transactional, so it isn't blocked by an unsubscribe. rsvpURL may be
+// empty if the guest has no personal link.
+func sendRSVPLogisticsEmail(app *pocketbase.PocketBase, recipientEmail, recipientName, rsvpURL, itemID, listName, listDescription, eventName, eventDate, eventTime, eventLocation string, theme EmailTheme) error {
+	name := recipientName
+	if name == "" {
+		name = "there"
+	}
+
+	firstName := "there"
+	if f := strings.Fields(name); len(f) > 0 {
+		firstName = f[0]
+	}
+
+	eventContext := listName
+	if eventName != "" {
+		eventContext = eventName
+	}
+
+	rendered, err := renderEmailTemplate(app, emailKindRSVPLogistics, theme.GuestListID, theme.ThemeID,
+		rsvpEmailData(theme, eventContext, firstName, listName, listDescription, eventDate, eventTime, eventLocation, rsvpURL))
+	if err != nil {
+		log.Printf("[Email] Failed to render RSVP logistics for %s: %v", recipientEmail, err)
+		return err
 	}
 
 	msg := &mailer.Message{
 		From:    mail.Address{Address: app.Settings().Meta.SenderAddress, Name: app.Settings().Meta.SenderName},
 		To:      []mail.Address{{Address: recipientEmail, Name: recipientName}},
 		Bcc:     []mail.Address{{Address: "hello@wearetheoutlook.com.au"}},
-		Subject: subject,
-		HTML:    wrapRSVPEmailHTML(content, theme),
+		Subject: rendered.Subject,
+		HTML:    wrapRSVPEmailHTML(rendered.HTML, theme, ""),
+		Text:    wrapEmailText(rendered.Text, ""),
 	}
 
-	if err := app.NewMailClient().Send(msg); err != nil {
-		log.Printf("[Email] Failed to send RSVP follow-up to %s: %v", recipientEmail, err)
+	if _, err := enqueueEmail(app, msg, outboxOptions{Kind: emailKindRSVPLogistics, Transactional: true, GuestListItem: itemID, TemplateVersion: rendered.TemplateVersion}); err != nil {
+		log.Printf("[Email] Failed to queue RSVP logistics to %s: %v", recipientEmail, err)
 		return err
 	}
 
-	log.Printf("[Email] RSVP follow-up sent to %s for %s", recipientEmail, eventContext)
+	log.Printf("[Email] RSVP logistics to %s queued for %s", recipientEmail, eventContext)
 	return nil
 }
 
-// sendPlusOneNotificationEmail sends an internal notification when someone requests a plus-one.
-// Recipients are hello@ + configured BCC contacts, all as direct To recipients.
-func sendPlusOneNotificationEmail(app *pocketbase.PocketBase, requesterName, plusOneName, plusOneJobTitle, plusOneCompany, plusOneEmail, eventName, guestListID string, toEmails []string) error {
-	subject := fmt.Sprintf("Plus-one request: %s for %s", requesterName, eventName)
+// sendRSVPPromotedEmail tells a waitlisted guest a seat has opened and their place is
+// confirmed. Transactional, like the confirmation it stands in for, and likewise lists the
+// guest's sessions and carries the check-in QR code when there's a checkInCode.
+func sendRSVPPromotedEmail(app *pocketbase.PocketBase, recipientEmail, recipientName, rsvpURL, itemID, listName, listDescription, eventName, eventDate, eventTime, eventLocation string, theme EmailTheme, sessions []eventSession, icsData []byte, checkInCode string) error {
+	name := recipientName
+	if name == "" {
+		name = "there"
+	}
 
-	// Build plus-one details
-	detailsHTML := ""
-	if plusOneName != "" {
-		detailsHTML += fmt.Sprintf(`<p style="margin: 0 0 4px 0;"><strong>Name:</strong> %s</p>`, plusOneName)
+	firstName := "there"
+	if f := strings.Fields(name); len(f) > 0 {
+		firstName = f[0]
 	}
-	if plusOneJobTitle != "" {
-		detailsHTML += fmt.Sprintf(`<p style="margin: 0 0 4px 0;"><strong>Job title:</strong> %s</p>`, plusOneJobTitle)
+
+	eventContext := listName
+	if eventName != "" {
+		eventContext = eventName
 	}
-	if plusOneCompany != "" {
-		detailsHTML += fmt.Sprintf(`<p style="margin: 0 0 4px 0;"><strong>Company:</strong> %s</p>`, plusOneCompany)
+
+	data := rsvpEmailData(theme, eventContext, firstName, listName, listDescription, eventDate, eventTime, eventLocation, rsvpURL)
+	addSessionsData(data, theme, sessions)
+	addCheckInQRData(data, theme, checkInCode)
+	rendered, err := renderEmailTemplate(app, emailKindRSVPPromoted, theme.GuestListID, theme.ThemeID, data)
+	if err != nil {
+		log.Printf("[Email] Failed to render waitlist promotion for %s: %v", recipientEmail, err)
+		return err
 	}
-	if plusOneEmail != "" {
-		detailsHTML += fmt.Sprintf(`<p style="margin: 0 0 4px 0;"><strong>Email:</strong> %s</p>`, plusOneEmail)
+
+	msg := &mailer.Message{
+		From:    mail.Address{Address: app.Settings().Meta.SenderAddress, Name: app.Settings().Meta.SenderName},
+		To:      []mail.Address{{Address: recipientEmail, Name: recipientName}},
+		Bcc:     []mail.Address{{Address: "hello@wearetheoutlook.com.au"}},
+		Subject: rendered.Subject,
+		HTML:    wrapRSVPEmailHTML(rendered.HTML, theme, ""),
+		Text:    wrapEmailText(rendered.Text, ""),
 	}
 
-	content := fmt.Sprintf(`
-            <p style="font-size: 16px; margin: 0 0 16px 0;">
-                <strong>%s</strong> would like to bring a plus-one to <strong>%s</strong>.
-            </p>
-            <div style="background: #f8f8f8; padding: 16px; border-radius: 8px; margin: 0 0 16px 0;">
-                %s
-            </div>
-            <p style="font-size: 14px; color: #666; margin: 0 0 16px 0;">
-                This plus-one has been added to the guest list as "Maybe" for your review.
-            </p>
-            <p style="margin: 0;">
-                <a href="https://crm.theoutlook.io/guest-lists/%s" style="color: #E95139; text-decoration: underline;">View guest list</a>
-            </p>
-`, requesterName, eventName, detailsHTML, guestListID)
+	if len(icsData) > 0 {
+		msg.Attachments = map[string]io.Reader{
+			"invite.ics": bytes.NewReader(icsData),
+		}
+	}
+	attachCheckInQR(msg, checkInCode)
+
+	if _, err := enqueueEmail(app, msg, outboxOptions{Kind: emailKindRSVPPromoted, Transactional: true, GuestListItem: itemID, TemplateVersion: rendered.TemplateVersion}); err != nil {
+		log.Printf("[Email] Failed to queue waitlist promotion to %s: %v", recipientEmail, err)
+		return err
+	}
+
+	log.Printf("[Email] Waitlist promotion to %s queued for %s", recipientEmail, eventContext)
+	return nil
+}
+
+// sendPlusOneNotificationEmail sends an internal notification when someone requests a plus-one.
+// Recipients are hello@ + configured BCC contacts, all as direct To recipients.
+func sendPlusOneNotificationEmail(app *pocketbase.PocketBase, requesterName, plusOneName, plusOneJobTitle, plusOneCompany, plusOneEmail, eventName, guestListID string, toEmails []string) error {
+	rendered, err := renderEmailTemplate(app, emailKindPlusOne, guestListID, "", map[string]any{
+		"requester_name":     requesterName,
+		"event_name":         eventName,
+		"plus_one_name":      plusOneName,
+		"plus_one_job_title": plusOneJobTitle,
+		"plus_one_company":   plusOneCompany,
+		"plus_one_email":     plusOneEmail,
+		"guest_list_url":     "https://crm.theoutlook.io/guest-lists/" + guestListID,
+	})
+	if err != nil {
+		log.Printf("[Email] Failed to render plus-one notification for %s: %v", requesterName, err)
+		return err
+	}
 
 	// Build To list: hello@ + configured contacts
 	toList := []mail.Address{{Address: "hello@wearetheoutlook.com.au"}}
@@ -893,15 +786,49 @@ func sendPlusOneNotificationEmail(app *pocketbase.PocketBase, requesterName, plu
 	msg := &mailer.Message{
 		From:    mail.Address{Address: app.Settings().Meta.SenderAddress, Name: app.Settings().Meta.SenderName},
 		To:      toList,
-		Subject: subject,
-		HTML:    wrapEmailHTML(content),
+		Subject: rendered.Subject,
+		HTML:    wrapEmailHTML(rendered.HTML),
+		Text:    wrapEmailText(rendered.Text, ""),
+	}
+
+	if _, err := enqueueEmail(app, msg, outboxOptions{Kind: emailKindPlusOne, Transactional: true, TemplateVersion: rendered.TemplateVersion}); err != nil {
+		log.Printf("[Email] Failed to queue plus-one notification for %s: %v", requesterName, err)
+		return err
+	}
+
+	log.Printf("[Email] Plus-one notification queued for %s (event: %s)", requesterName, eventName)
+	return nil
+}
+
+// sendRSVPChangedEmail tells the guest list's BCC contacts that a guest changed or cancelled
+// their RSVP. data holds the rsvp_changed template variables.
+func sendRSVPChangedEmail(app *pocketbase.PocketBase, guestListID string, data map[string]any, toEmails []string) error {
+	rendered, err := renderEmailTemplate(app, emailKindRSVPChanged, guestListID, "", data)
+	if err != nil {
+		log.Printf("[Email] Failed to render RSVP change notification: %v", err)
+		return err
+	}
+
+	var toList []mail.Address
+	for _, addr := range toEmails {
+		if addr != "" {
+			toList = append(toList, mail.Address{Address: addr})
+		}
+	}
+
+	msg := &mailer.Message{
+		From:    mail.Address{Address: app.Settings().Meta.SenderAddress, Name: app.Settings().Meta.SenderName},
+		To:      toList,
+		Subject: rendered.Subject,
+		HTML:    wrapEmailHTML(rendered.HTML),
+		Text:    wrapEmailText(rendered.Text, ""),
 	}
 
-	if err := app.NewMailClient().Send(msg); err != nil {
-		log.Printf("[Email] Failed to send plus-one notification for %s: %v", requesterName, err)
+	if _, err := enqueueEmail(app, msg, outboxOptions{Kind: emailKindRSVPChanged, Transactional: true, TemplateVersion: rendered.TemplateVersion}); err != nil {
+		log.Printf("[Email] Failed to queue RSVP change notification: %v", err)
 		return err
 	}
 
-	log.Printf("[Email] Plus-one notification sent for %s (event: %s)", requesterName, eventName)
+	log.Printf("[Email] RSVP change notification queued for guest list %s", guestListID)
 	return nil
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"log"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// --- Email Events ---
+// email_events is the engagement timeline for outbox messages: sent, opened, clicked (with
+// the URL), bounced, complained, and the RSVP a message led to. Guest list, item and contact
+// are copied onto each event so timelines can be read per contact and totalled per guest
+// list, and survive the message itself being purged.
+
+const (
+	emailEventSent        = "sent"
+	emailEventOpened      = "opened"
+	emailEventClicked     = "clicked"
+	emailEventBounced     = "bounced"
+	emailEventSoftBounced = "soft_bounced"
+	emailEventComplained  = "complained"
+	emailEventRSVP        = "rsvp"
+)
+
+// emailEventTypes lists the event types in funnel order
+var emailEventTypes = []string{
+	emailEventSent, emailEventOpened, emailEventClicked, emailEventRSVP,
+	emailEventBounced, emailEventSoftBounced, emailEventComplained,
+}
+
+// rsvpMessageKinds are the emails an RSVP can be credited to
+var rsvpMessageKinds = []any{emailKindRSVPInvite, emailKindRSVPFollowUp, emailKindRSVPForward}
+
+// emailEvent is one row for email_events. Message or GuestListItem may be nil: an RSVP
+// through the public link has no message, and most non-RSVP emails have no item.
+type emailEvent struct {
+	Type          string
+	Message       *core.Record
+	GuestListItem *core.Record
+	URL           string
+	Detail        string
+}
+
+// recordEmailEvent saves an event. Failures are logged, never returned: tracking mustn't
+// break a send, a redirect or an RSVP.
+func recordEmailEvent(app core.App, ev emailEvent) {
+	collection, err := app.FindCollectionByNameOrId(utils.CollectionEmailEvents)
+	if err != nil {
+		return
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("type", ev.Type)
+	record.Set("url", ev.URL)
+	record.Set("detail", ev.Detail)
+
+	item := ev.GuestListItem
+	if msg := ev.Message; msg != nil {
+		record.Set("message", msg.Id)
+		record.Set("kind", msg.GetString("kind"))
+		record.Set("subject", msg.GetString("subject"))
+		if itemID := msg.GetString("guest_list_item"); item == nil && itemID != "" {
+			item, _ = app.FindRecordById(utils.CollectionGuestListItems, itemID)
+		}
+	}
+
+	contactID := ""
+	if item != nil {
+		record.Set("guest_list_item", item.Id)
+		record.Set("guest_list", item.GetString("guest_list"))
+		contactID = item.GetString("contact")
+	}
+	if contactID == "" && ev.Message != nil {
+		// Not an invite: match the recipient to a contact by address
+		if idx := ev.Message.GetString("to_email_index"); idx != "" {
+			contacts, err := app.FindRecordsByFilter(utils.CollectionContacts,
+				"email_index = {:idx}", "", 1, 0, dbx.Params{"idx": idx})
+			if err == nil && len(contacts) > 0 {
+				contactID = contacts[0].Id
+			}
+		}
+	}
+	record.Set("contact", contactID)
+
+	if err := app.Save(record); err != nil {
+		log.Printf("[EmailEvents] Failed to record %s event: %v", ev.Type, err)
+	}
+}
+
+// latestItemMessage returns the last invite, follow-up or forward sent to a guest list item
+func latestItemMessage(app core.App, itemID string) *core.Record {
+	record := &core.Record{}
+	err := app.RecordQuery(utils.CollectionEmailOutbox).
+		AndWhere(dbx.HashExp{"guest_list_item": itemID, "status": outboxSent}).
+		AndWhere(dbx.In("kind", rsvpMessageKinds...)).
+		OrderBy("sent_at DESC").
+		Limit(1).
+		One(record)
+	if err != nil {
+		return nil
+	}
+	return record
+}
+
+// trackedMessage resolves the message a tracking hit is for: the one named in the link if it
+// belongs to the item, otherwise (links sent before messages were named) the item's latest
+func trackedMessage(app core.App, item *core.Record, messageID string) *core.Record {
+	if messageID != "" {
+		if msg, err := app.FindRecordById(utils.CollectionEmailOutbox, messageID); err == nil && msg.GetString("guest_list_item") == item.Id {
+			return msg
+		}
+	}
+	return latestItemMessage(app, item.Id)
+}
+
+// recordRSVPEvent credits an RSVP to the last RSVP email the guest was sent, if any
+func recordRSVPEvent(app core.App, item *core.Record, response string) {
+	recordEmailEvent(app, emailEvent{
+		Type:          emailEventRSVP,
+		Message:       latestItemMessage(app, item.Id),
+		GuestListItem: item,
+		Detail:        response,
+	})
+}
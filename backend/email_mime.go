@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/tools/mailer"
+	"golang.org/x/net/html"
+)
+
+// --- Plain text and MIME ---
+// Every email goes out as multipart/alternative with a plain-text part. The text comes
+// from the template's text source when it has one, otherwise it's generated from the HTML.
+// PocketBase's SMTP client builds the multipart message itself from msg.Text; its sendmail
+// client only sends the HTML (and drops cc, bcc, headers and attachments), so for sendmail
+// the message is built here instead.
+
+var textWhitespace = regexp.MustCompile(`\s+`)
+
+// textBlockTags start and end on their own line in the plain-text version
+var textBlockTags = map[string]bool{
+	"p": true, "div": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"table": true, "tr": true, "ul": true, "ol": true, "blockquote": true, "hr": true,
+}
+
+// htmlToText converts email HTML to readable plain text. Links become "text (url)";
+// images, styles and comments (including Outlook conditionals) are dropped.
+func htmlToText(s string) string {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	var walk func(n *html.Node)
+	children := func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.WriteString(textWhitespace.ReplaceAllString(n.Data, " "))
+			return
+		case html.CommentNode:
+			return
+		case html.ElementNode:
+		default:
+			children(n)
+			return
+		}
+
+		switch n.Data {
+		case "head", "style", "script", "title", "img":
+			return
+		case "br":
+			b.WriteString("\n")
+			return
+		case "li":
+			b.WriteString("\n- ")
+			children(n)
+			return
+		case "td", "th":
+			children(n)
+			b.WriteString(" ")
+			return
+		case "a":
+			start := b.Len()
+			children(n)
+			text := strings.TrimSpace(b.String()[start:])
+			href := strings.TrimSpace(htmlAttr(n, "href"))
+			if href == "" || strings.HasPrefix(href, "#") || text == strings.TrimPrefix(href, "mailto:") {
+				return
+			}
+			if text == "" {
+				b.WriteString(href)
+			} else {
+				b.WriteString(" (" + href + ")")
+			}
+			return
+		}
+
+		block := textBlockTags[n.Data]
+		if block {
+			b.WriteString("\n")
+		}
+		children(n)
+		if block {
+			b.WriteString("\n")
+		}
+		if n.Data == "p" || (len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '6') {
+			b.WriteString("\n")
+		}
+	}
+	walk(doc)
+
+	// Trim every line and keep at most one blank line between paragraphs
+	lines := []string{}
+	blank := true
+	for _, line := range strings.Split(b.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if !blank {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		lines = append(lines, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// wrapEmailText adds the footer to a plain-text body, the counterpart of the HTML wrappers
+func wrapEmailText(content, unsubscribeLink string) string {
+	text := strings.TrimSpace(content) + "\n\n--\nThe Outlook\nhttps://theoutlook.io\n"
+	if unsubscribeLink != "" {
+		text += "\nDon't want these emails? Unsubscribe: " + unsubscribeLink + "\n"
+	}
+	return text
+}
+
+// buildMIMEMessage renders msg as an RFC 5322 message: multipart/alternative (text + HTML),
+// inside multipart/mixed when there are attachments. Bcc recipients are left out of the
+// headers; pass them to the transport separately.
+func buildMIMEMessage(msg *mailer.Message) ([]byte, error) {
+	headers := []string{
+		"From: " + msg.From.String(),
+		"To: " + joinAddresses(msg.To),
+	}
+	if len(msg.Cc) > 0 {
+		headers = append(headers, "Cc: "+joinAddresses(msg.Cc))
+	}
+	headers = append(headers,
+		"Subject: "+mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: "+time.Now().Format(time.RFC1123Z),
+		"Message-ID: "+newMessageID(msg.From.Address),
+		"MIME-Version: 1.0",
+	)
+	custom := make([]string, 0, len(msg.Headers))
+	for k := range msg.Headers {
+		custom = append(custom, k)
+	}
+	sort.Strings(custom)
+	for _, k := range custom {
+		headers = append(headers, textproto.CanonicalMIMEHeaderKey(k)+": "+msg.Headers[k])
+	}
+
+	text := msg.Text
+	if text == "" {
+		text = htmlToText(msg.HTML)
+	}
+
+	// Body first, so the top-level boundary is known when writing the headers
+	var altBody bytes.Buffer
+	alternative := multipart.NewWriter(&altBody)
+	if err := writeAlternativeParts(alternative, text, msg.HTML); err != nil {
+		return nil, err
+	}
+	altType := `multipart/alternative; boundary="` + alternative.Boundary() + `"`
+
+	body, contentType := altBody.Bytes(), altType
+	if len(msg.Attachments) > 0 {
+		var mixedBody bytes.Buffer
+		mixed := multipart.NewWriter(&mixedBody)
+		part, err := mixed.CreatePart(textproto.MIMEHeader{"Content-Type": {altType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(altBody.Bytes()); err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(msg.Attachments))
+		for name := range msg.Attachments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			data, err := io.ReadAll(msg.Attachments[name])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read attachment %s: %w", name, err)
+			}
+			if err := writeAttachmentPart(mixed, name, data); err != nil {
+				return nil, err
+			}
+		}
+		if err := mixed.Close(); err != nil {
+			return nil, err
+		}
+		body = mixedBody.Bytes()
+		contentType = `multipart/mixed; boundary="` + mixed.Boundary() + `"`
+	}
+	headers = append(headers, "Content-Type: "+contentType)
+
+	var buf bytes.Buffer
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+	buf.Write(body)
+	return buf.Bytes(), nil
+}
+
+// writeAlternativeParts writes the text and HTML parts (least preferred first) and closes w
+func writeAlternativeParts(w *multipart.Writer, text, htmlBody string) error {
+	for _, p := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", htmlBody},
+	} {
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return err
+		}
+		qp := quotedprintable.NewWriter(part)
+		if _, err := qp.Write([]byte(p.body)); err != nil {
+			return err
+		}
+		if err := qp.Close(); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// writeAttachmentPart adds a base64 attachment. .ics files are sent as text/calendar so
+// mail clients offer to add the event.
+func writeAttachmentPart(w *multipart.Writer, name string, data []byte) error {
+	ext := strings.ToLower(filepath.Ext(name))
+	mediaType, params := "application/octet-stream", map[string]string{}
+	if mt, p, err := mime.ParseMediaType(mime.TypeByExtension(ext)); err == nil {
+		mediaType, params = mt, p
+	}
+	if ext == ".ics" {
+		mediaType, params = "text/calendar", map[string]string{"charset": "UTF-8", "method": "PUBLISH"}
+	}
+	params["name"] = name
+
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(mediaType, params)},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+	})
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(part, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(part, encoded+"\r\n")
+	return err
+}
+
+func joinAddresses(addrs []mail.Address) string {
+	parts := make([]string, len(addrs))
+	for i, a := range addrs {
+		parts[i] = a.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+func newMessageID(from string) string {
+	domain := "localhost"
+	if _, d, ok := strings.Cut(from, "@"); ok && d != "" {
+		domain = d
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// sendViaSendmail pipes a multipart message to the local sendmail binary, with every
+// recipient (including bcc) passed on the command line
+func sendViaSendmail(msg *mailer.Message) error {
+	var path string
+	for _, candidate := range []string{"/usr/sbin/sendmail", "/usr/bin/sendmail", "sendmail"} {
+		if p, err := exec.LookPath(candidate); err == nil {
+			path = p
+			break
+		}
+	}
+	if path == "" {
+		return fmt.Errorf("sendmail not found")
+	}
+
+	raw, err := buildMIMEMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-i", "-f", msg.From.Address, "--"}
+	for _, list := range [][]mail.Address{msg.To, msg.Cc, msg.Bcc} {
+		for _, a := range list {
+			args = append(args, a.Address)
+		}
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = bytes.NewReader(raw)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("sendmail: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,623 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// --- Email Templates ---
+// Email copy can be overridden from the admin UI via email_templates. A template is scoped
+// to a guest list, a theme, or neither (global); the most specific active one wins and the
+// built-in copy below is used when there's none, or when an override fails to render.
+// Subjects and plain-text bodies are text/template and HTML bodies html/template, all rendered
+// with the variables in emailTemplateVariables; the body is the inner content, and the
+// branded wrapper (logos, hero image, footer) is added around it. Every content change is kept as a numbered
+// version and the outbox records which version rendered each message.
+
+// emailTemplateSource is the subject and body source of a template
+type emailTemplateSource struct {
+	Label   string
+	Subject string
+	HTML    string
+	Text    string // plain-text part; generated from the HTML when empty
+	RSVP    bool   // rendered inside the themed RSVP wrapper rather than the plain one
+}
+
+// emailTemplateVariable documents one variable available to a template
+type emailTemplateVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// renderedEmail is a template rendered for one message
+type renderedEmail struct {
+	Subject         string
+	HTML            string // inner content, before the wrapper
+	Text            string // plain-text content, before wrapEmailText
+	TemplateVersion string // email_template_versions ID, empty for the built-in copy
+}
+
+// emailTemplateKeys lists the templatable emails in display order
+var emailTemplateKeys = []string{
+	emailKindRSVPInvite, emailKindRSVPFollowUp, emailKindRSVPForward, emailKindRSVPConfirmation,
+	emailKindRSVPLogistics, emailKindRSVPPromoted, emailKindOTP, emailKindAttendeeOTP, emailKindShareNotification, emailKindPlusOne,
+	emailKindRSVPChanged,
+}
+
+var rsvpTemplateVariables = []emailTemplateVariable{
+	{"first_name", `Recipient's first name, or "there"`},
+	{"event_name", "Event name, or the guest list name if there's no event"},
+	{"list_name", "Guest list name"},
+	{"list_description", "Guest list description (HTML)"},
+	{"event_date", "Event date as entered on the guest list"},
+	{"event_time", "Event time as entered on the guest list"},
+	{"event_location", "Event location as entered on the guest list"},
+	{"event_details", "Date, time and location block (HTML), empty if none are set"},
+	{"theme", "Theme colours: .theme.text, .theme.text_muted, .theme.background, .theme.border, .theme.button, .theme.button_text, .theme.primary, .theme.is_dark"},
+}
+
+var checkInVariables = []emailTemplateVariable{
+	{"check_in_qr", "Guest's check-in QR code with a short caption (HTML), empty if there isn't one"},
+	{"check_in_qr_url", "Image URL of the guest's check-in QR code, empty if there isn't one"},
+}
+
+var sessionVariables = []emailTemplateVariable{
+	{"sessions", "The sessions the guest picked, with their times and locations (HTML), empty if none"},
+}
+
+var rsvpLinkVariables = []emailTemplateVariable{
+	{"rsvp_url", "Recipient's personal RSVP link"},
+	{"rsvp_buttons", `"I can make it" / "I can't make it" buttons linking to rsvp_url (HTML)`},
+}
+
+// emailTemplateVariables documents the variables each template can use. RSVP templates can
+// also call opacity to fade a theme colour, e.g. {{opacity .theme.text "0.8"}}.
+var emailTemplateVariables = map[string][]emailTemplateVariable{
+	emailKindRSVPInvite:   slices.Concat(rsvpTemplateVariables, rsvpLinkVariables),
+	emailKindRSVPFollowUp: slices.Concat(rsvpTemplateVariables, rsvpLinkVariables),
+	emailKindRSVPForward: slices.Concat(rsvpTemplateVariables, rsvpLinkVariables, []emailTemplateVariable{
+		{"forwarder_name", "First name of the guest who forwarded the invite"},
+		{"forwarder_email", "Email of the guest who forwarded the invite"},
+	}),
+	emailKindRSVPConfirmation: slices.Concat(rsvpTemplateVariables, sessionVariables, checkInVariables),
+	emailKindRSVPLogistics: slices.Concat(rsvpTemplateVariables, []emailTemplateVariable{
+		{"rsvp_url", "Recipient's personal RSVP link, for changing their response; empty if they don't have one"},
+	}),
+	emailKindRSVPPromoted: slices.Concat(rsvpTemplateVariables, sessionVariables, checkInVariables, []emailTemplateVariable{
+		{"rsvp_url", "Recipient's personal RSVP link, for changing their response; empty if they don't have one"},
+	}),
+	emailKindOTP: {
+		{"name", `Recipient's name, or "there"`},
+		{"code", "6-digit verification code"},
+	},
+	emailKindAttendeeOTP: {
+		{"name", `Recipient's name, or "there"`},
+		{"code", "6-digit login code"},
+	},
+	emailKindShareNotification: {
+		{"name", `Recipient's name, or "there"`},
+		{"first_name", `Recipient's first name, or "there"`},
+		{"list_name", "Shared guest list name"},
+		{"event_name", "Event name, empty if the list has no event"},
+		{"share_url", "Link to the shared guest list"},
+	},
+	emailKindPlusOne: {
+		{"requester_name", "Guest asking to bring a plus-one"},
+		{"event_name", "Event name"},
+		{"plus_one_name", "Plus-one's name"},
+		{"plus_one_job_title", "Plus-one's job title"},
+		{"plus_one_company", "Plus-one's company"},
+		{"plus_one_email", "Plus-one's email"},
+		{"guest_list_url", "Link to the guest list in the CRM"},
+	},
+	emailKindRSVPChanged: {
+		{"guest_name", "Guest who changed their RSVP"},
+		{"event_name", "Event name"},
+		{"action", `"changed" or "cancelled"`},
+		{"from_status", `Previous response, e.g. "Attending"`},
+		{"to_status", `New response, e.g. "Not attending"`},
+		{"changed", `What changed, e.g. "Response, Plus-one"`},
+		{"note", "The guest's reason for cancelling, if they gave one"},
+		{"guest_list_url", "Link to the guest list in the CRM"},
+	},
+}
+
+// emailTemplateFuncs are available to every body template
+var emailTemplateFuncs = template.FuncMap{
+	"opacity": func(color template.CSS, alpha string) template.CSS {
+		return template.CSS(textWithOpacity(string(color), alpha))
+	},
+}
+
+// defaultEmailTemplates is the built-in copy, used when no override applies
+var defaultEmailTemplates = map[string]emailTemplateSource{
+	emailKindRSVPInvite: {
+		Label:   "RSVP invite",
+		RSVP:    true,
+		Subject: `{{.first_name}}, you're invited to {{.event_name}}`,
+		HTML: `
+            <p style="color: {{opacity .theme.text "0.5"}}; font-size: 12px; text-transform: uppercase; letter-spacing: 2px; margin: 0 0 16px 0;">You're invited</p>
+            <h1 style="color: {{.theme.text}}; font-size: 32px; line-height: 1.1; margin: 0 0 20px 0;">{{.event_name}}</h1>
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hi {{.first_name}},</p>
+            {{if .list_description}}<p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">{{.list_description}}</p>
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 8px 0;">We'd love to see you there.</p>{{else}}<p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 8px 0;">We'd love for you to join us for an evening of conversation, connection and great food.</p>{{end}}
+            {{.event_details}}
+            <p style="color: {{opacity .theme.text "0.6"}}; font-size: 15px; line-height: 1.6; margin: 0 0 32px 0;">
+                Spaces are limited, so please let us know if you can make it.
+            </p>
+            {{.rsvp_buttons}}
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 13px; margin: 16px 0 24px 0;">
+                This link is personal to you. Please don't share it.
+            </p>
+            <p style="color: {{opacity .theme.text "0.3"}}; font-size: 13px; margin: 0 0 8px 0;">
+                Copy and paste if the link doesn't work:
+            </p>
+            <div style="background: {{opacity .theme.text "0.05"}}; padding: 12px 16px; margin: 0;">
+                <p style="color: {{opacity .theme.text "0.4"}}; font-size: 12px; font-family: 'Courier New', Courier, monospace; word-break: break-all; margin: 0;">
+                    {{.rsvp_url}}
+                </p>
+            </div>
+`,
+	},
+	emailKindRSVPFollowUp: {
+		Label:   "RSVP follow-up",
+		RSVP:    true,
+		Subject: `{{.first_name}}, we'd still love to see you at {{.event_name}}`,
+		HTML: `
+            <p style="color: {{opacity .theme.text "0.5"}}; font-size: 12px; text-transform: uppercase; letter-spacing: 2px; margin: 0 0 16px 0;">Just a reminder</p>
+            <h1 style="color: {{.theme.text}}; font-size: 32px; line-height: 1.1; margin: 0 0 20px 0;">{{.event_name}}</h1>
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hey {{.first_name}},</p>
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 8px 0;">We'd still love to see you at {{.event_name}}. If you haven't had a chance to RSVP yet, we'd love to hear from you.</p>
+            {{if .list_description}}<p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">{{.list_description}}</p>{{end}}
+            {{.event_details}}
+            <p style="color: {{opacity .theme.text "0.6"}}; font-size: 15px; line-height: 1.6; margin: 0 0 32px 0;">
+                Spaces are limited, so please let us know if you can make it.
+            </p>
+            {{.rsvp_buttons}}
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 13px; margin: 16px 0 24px 0;">
+                This link is personal to you. Please don't share it.
+            </p>
+            <p style="color: {{opacity .theme.text "0.3"}}; font-size: 13px; margin: 0 0 8px 0;">
+                Copy and paste if the link doesn't work:
+            </p>
+            <div style="background: {{opacity .theme.text "0.05"}}; padding: 12px 16px; margin: 0;">
+                <p style="color: {{opacity .theme.text "0.4"}}; font-size: 12px; font-family: 'Courier New', Courier, monospace; word-break: break-all; margin: 0;">
+                    {{.rsvp_url}}
+                </p>
+            </div>
+`,
+	},
+	emailKindRSVPForward: {
+		Label:   "Forwarded RSVP invite",
+		RSVP:    true,
+		Subject: `{{.forwarder_name}} thinks you'd love {{.event_name}}`,
+		HTML: `
+            <p style="color: {{opacity .theme.text "0.5"}}; font-size: 12px; text-transform: uppercase; letter-spacing: 2px; margin: 0 0 16px 0;">You're invited</p>
+            <h1 style="color: {{.theme.text}}; font-size: 32px; line-height: 1.1; margin: 0 0 20px 0;">{{.event_name}}</h1>
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hi {{.first_name}},</p>
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 8px 0;">
+                {{.forwarder_name}} thought you'd enjoy {{.event_name}}, and has passed along an invitation for you.
+            </p>
+            {{if .list_description}}<p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 16px 0 8px 0;">{{.list_description}} We'd love to see you there.</p>{{end}}
+            {{.event_details}}
+            <p style="color: {{opacity .theme.text "0.6"}}; font-size: 15px; line-height: 1.6; margin: 0 0 32px 0;">
+                Spaces are limited, so let us know if you can make it.
+            </p>
+            {{.rsvp_buttons}}
+            <p style="color: {{opacity .theme.text "0.3"}}; font-size: 13px; margin: 24px 0 8px 0;">
+                Copy and paste if the link doesn't work:
+            </p>
+            <div style="background: {{opacity .theme.text "0.05"}}; padding: 12px 16px; margin: 0;">
+                <p style="color: {{opacity .theme.text "0.4"}}; font-size: 12px; font-family: 'Courier New', Courier, monospace; word-break: break-all; margin: 0;">
+                    {{.rsvp_url}}
+                </p>
+            </div>
+`,
+	},
+	emailKindRSVPConfirmation: {
+		Label:   "RSVP confirmation",
+		RSVP:    true,
+		Subject: `You're confirmed for {{.event_name}}`,
+		HTML: `
+            <p style="color: {{opacity .theme.text "0.5"}}; font-size: 12px; text-transform: uppercase; letter-spacing: 2px; margin: 0 0 16px 0;">You're confirmed</p>
+            <h1 style="color: {{.theme.text}}; font-size: 32px; line-height: 1.1; margin: 0 0 20px 0;">{{.event_name}}</h1>
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 8px 0;">
+                {{.first_name}}, confirming your RSVP and looking forward to seeing you on the night.
+            </p>
+            {{.event_details}}
+            {{.sessions}}
+            {{.check_in_qr}}
+            <p style="color: {{opacity .theme.text "0.4"}}; font-size: 14px; margin: 0;">
+                If your plans change, please let us know by replying to this email.
+            </p>
+`,
+	},
+	emailKindRSVPLogistics: {
+		Label:   "Event logistics (accepted guests)",
+		RSVP:    true,
+		Subject: `{{.first_name}}, see you soon at {{.event_name}}`,
+		HTML: `
+            <p style="color: {{opacity .theme.text "0.5"}}; font-size: 12px; text-transform: uppercase; letter-spacing: 2px; margin: 0 0 16px 0;">See you soon</p>
+            <h1 style="color: {{.theme.text}}; font-size: 32px; line-height: 1.1; margin: 0 0 20px 0;">{{.event_name}}</h1>
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hi {{.first_name}},</p>
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 8px 0;">
+                We're looking forward to seeing you. Here are the details for the day.
+            </p>
+            {{.event_details}}
+            {{if .rsvp_url}}<p style="color: {{opacity .theme.text "0.4"}}; font-size: 14px; margin: 0;">
+                If your plans have changed, please <a href="{{.rsvp_url}}" style="color: {{opacity .theme.text "0.6"}}; text-decoration: underline;">update your RSVP</a>.
+            </p>{{else}}<p style="color: {{opacity .theme.text "0.4"}}; font-size: 14px; margin: 0;">
+                If your plans have changed, please let us know by replying to this email.
+            </p>{{end}}
+`,
+	},
+	emailKindRSVPPromoted: {
+		Label:   "Waitlist place confirmed",
+		RSVP:    true,
+		Subject: `A spot has opened up at {{.event_name}}`,
+		HTML: `
+            <p style="color: {{opacity .theme.text "0.5"}}; font-size: 12px; text-transform: uppercase; letter-spacing: 2px; margin: 0 0 16px 0;">You're off the waitlist</p>
+            <h1 style="color: {{.theme.text}}; font-size: 32px; line-height: 1.1; margin: 0 0 20px 0;">{{.event_name}}</h1>
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hi {{.first_name}},</p>
+            <p style="color: {{opacity .theme.text "0.8"}}; font-size: 16px; line-height: 1.6; margin: 0 0 8px 0;">
+                Good news: a spot has opened up and your place is confirmed. We're looking forward to seeing you.
+            </p>
+            {{.event_details}}
+            {{.sessions}}
+            {{.check_in_qr}}
+            {{if .rsvp_url}}<p style="color: {{opacity .theme.text "0.4"}}; font-size: 14px; margin: 0;">
+                If you can no longer make it, please <a href="{{.rsvp_url}}" style="color: {{opacity .theme.text "0.6"}}; text-decoration: underline;">update your RSVP</a> so we can offer your spot to someone else.
+            </p>{{else}}<p style="color: {{opacity .theme.text "0.4"}}; font-size: 14px; margin: 0;">
+                If you can no longer make it, please reply to this email so we can offer your spot to someone else.
+            </p>{{end}}
+`,
+	},
+	emailKindOTP: {
+		Label:   "Guest list share verification code",
+		Subject: `Your verification code`,
+		HTML: `
+            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hi {{.name}},</p>
+            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">
+                Your verification code to view the guest list is:
+            </p>
+            <div style="font-size: 32px; letter-spacing: 8px; text-align: center; padding: 24px; background: #f5f5f5; border-radius: 8px; margin: 24px 0; color: #202020;">
+                {{.code}}
+            </div>
+            <p style="color: #9a9a9a; font-size: 14px; margin: 24px 0 0 0;">This code expires in 10 minutes.</p>
+`,
+	},
+	emailKindAttendeeOTP: {
+		Label:   "Attendee login code",
+		Subject: `Your login code`,
+		HTML: `
+            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hi {{.name}},</p>
+            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">
+                Your login code is:
+            </p>
+            <div style="font-size: 32px; letter-spacing: 8px; text-align: center; padding: 24px; background: #f5f5f5; border-radius: 8px; margin: 24px 0; color: #202020;">
+                {{.code}}
+            </div>
+            <p style="color: #9a9a9a; font-size: 14px; margin: 24px 0 0 0;">This code expires in 10 minutes.</p>
+`,
+	},
+	emailKindShareNotification: {
+		Label:   "Guest list shared",
+		Subject: `{{.first_name}}, you've been invited to review {{.list_name}}`,
+		HTML: `
+            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Hi {{.name}},</p>
+            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">
+                You've been invited to review the guest list <strong>{{.list_name}}</strong>.
+            </p>
+            {{if .event_name}}
+            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 16px 0;">Event: {{.event_name}}</p>{{end}}
+            <p style="color: #4a4a4a; font-size: 16px; line-height: 1.6; margin: 0 0 24px 0;">
+                You'll need to verify your email to access the list. The link expires in 30 days.
+            </p>
+            <div style="text-align: center; margin: 32px 0;">
+                <a href="{{.share_url}}" style="display: inline-block; background: #0d0d0d; color: #ffffff; padding: 14px 32px; text-decoration: none; border-radius: 6px; font-size: 16px;">
+                    View guest list
+                </a>
+            </div>
+            <p style="color: #9a9a9a; font-size: 14px; margin: 24px 0 8px 0;">
+                Copy and paste if the link doesn't work:
+            </p>
+            <div style="background: #f5f5f5; padding: 12px 16px; border-radius: 6px; margin: 0;">
+                <p style="color: #666666; font-size: 13px; font-family: 'Courier New', Courier, monospace; word-break: break-all; margin: 0;">
+                    {{.share_url}}
+                </p>
+            </div>
+`,
+	},
+	emailKindPlusOne: {
+		Label:   "Plus-one request (internal)",
+		Subject: `Plus-one request: {{.requester_name}} for {{.event_name}}`,
+		HTML: `
+            <p style="font-size: 16px; margin: 0 0 16px 0;">
+                <strong>{{.requester_name}}</strong> would like to bring a plus-one to <strong>{{.event_name}}</strong>.
+            </p>
+            <div style="background: #f8f8f8; padding: 16px; border-radius: 8px; margin: 0 0 16px 0;">
+                {{if .plus_one_name}}<p style="margin: 0 0 4px 0;"><strong>Name:</strong> {{.plus_one_name}}</p>{{end}}
+                {{if .plus_one_job_title}}<p style="margin: 0 0 4px 0;"><strong>Job title:</strong> {{.plus_one_job_title}}</p>{{end}}
+                {{if .plus_one_company}}<p style="margin: 0 0 4px 0;"><strong>Company:</strong> {{.plus_one_company}}</p>{{end}}
+                {{if .plus_one_email}}<p style="margin: 0 0 4px 0;"><strong>Email:</strong> {{.plus_one_email}}</p>{{end}}
+            </div>
+            <p style="font-size: 14px; color: #666; margin: 0 0 16px 0;">
+                This plus-one has been added to the guest list as "Maybe" for your review.
+            </p>
+            <p style="margin: 0;">
+                <a href="{{.guest_list_url}}" style="color: #E95139; text-decoration: underline;">View guest list</a>
+            </p>
+`,
+	},
+	emailKindRSVPChanged: {
+		Label:   "RSVP change (internal)",
+		Subject: `RSVP {{.action}}: {{.guest_name}} for {{.event_name}}`,
+		HTML: `
+            <p style="font-size: 16px; margin: 0 0 16px 0;">
+                <strong>{{.guest_name}}</strong> has {{if eq .action "cancelled"}}cancelled{{else}}changed{{end}} their RSVP to <strong>{{.event_name}}</strong>.
+            </p>
+            <div style="background: #f8f8f8; padding: 16px; border-radius: 8px; margin: 0 0 16px 0;">
+                <p style="margin: 0 0 4px 0;"><strong>Was:</strong> {{.from_status}}</p>
+                <p style="margin: 0 0 4px 0;"><strong>Now:</strong> {{.to_status}}</p>
+                {{if .changed}}<p style="margin: 0 0 4px 0;"><strong>Changed:</strong> {{.changed}}</p>{{end}}
+                {{if .note}}<p style="margin: 0 0 4px 0;"><strong>Reason:</strong> {{.note}}</p>{{end}}
+            </div>
+            <p style="margin: 0;">
+                <a href="{{.guest_list_url}}" style="color: #E95139; text-decoration: underline;">View guest list</a>
+            </p>
+`,
+	},
+}
+
+// renderEmailTemplate renders the template for key that applies to the guest list/theme
+// (either may be empty), falling back to the built-in copy
+func renderEmailTemplate(app core.App, key, guestListID, themeID string, data map[string]any) (renderedEmail, error) {
+	if record := findEmailTemplate(app, key, guestListID, themeID); record != nil {
+		src := emailTemplateSource{
+			Subject: record.GetString("subject"),
+			HTML:    record.GetString("html"),
+			Text:    record.GetString("text"),
+		}
+		rendered, err := executeEmailTemplate(src, data)
+		if err == nil {
+			rendered.TemplateVersion = currentTemplateVersionID(app, record)
+			return rendered, nil
+		}
+		log.Printf("[EmailTemplate] %s template %s failed to render, using built-in copy: %v", key, record.Id, err)
+	}
+
+	src, ok := defaultEmailTemplates[key]
+	if !ok {
+		return renderedEmail{}, fmt.Errorf("unknown email template %q", key)
+	}
+	return executeEmailTemplate(src, data)
+}
+
+// executeEmailTemplate renders a subject, HTML body and plain-text body. Unknown variables
+// are errors rather than blanks so a typo in an override falls back to the built-in copy
+// instead of going out.
+func executeEmailTemplate(src emailTemplateSource, data map[string]any) (renderedEmail, error) {
+	subjectTmpl, err := texttemplate.New("subject").Option("missingkey=error").Parse(src.Subject)
+	if err != nil {
+		return renderedEmail{}, fmt.Errorf("subject: %w", err)
+	}
+	htmlTmpl, err := template.New("html").Option("missingkey=error").Funcs(emailTemplateFuncs).Parse(src.HTML)
+	if err != nil {
+		return renderedEmail{}, fmt.Errorf("html: %w", err)
+	}
+
+	var subject, html bytes.Buffer
+	if err := subjectTmpl.Execute(&subject, data); err != nil {
+		return renderedEmail{}, fmt.Errorf("subject: %w", err)
+	}
+	if err := htmlTmpl.Execute(&html, data); err != nil {
+		return renderedEmail{}, fmt.Errorf("html: %w", err)
+	}
+
+	rendered := renderedEmail{
+		// Subjects are a single line
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		HTML:    html.String(),
+	}
+
+	if strings.TrimSpace(src.Text) == "" {
+		rendered.Text = htmlToText(rendered.HTML)
+		return rendered, nil
+	}
+	textTmpl, err := texttemplate.New("text").Option("missingkey=error").Parse(src.Text)
+	if err != nil {
+		return renderedEmail{}, fmt.Errorf("text: %w", err)
+	}
+	var text bytes.Buffer
+	if err := textTmpl.Execute(&text, data); err != nil {
+		return renderedEmail{}, fmt.Errorf("text: %w", err)
+	}
+	rendered.Text = text.String()
+	return rendered, nil
+}
+
+// findEmailTemplate returns the most specific active override: guest list, then theme,
+// then global
+func findEmailTemplate(app core.App, key, guestListID, themeID string) *core.Record {
+	type scope struct {
+		filter string
+		id     string
+	}
+	scopes := []scope{}
+	if guestListID != "" {
+		scopes = append(scopes, scope{"guest_list = {:scope}", guestListID})
+	}
+	if themeID != "" {
+		scopes = append(scopes, scope{"guest_list = '' && theme = {:scope}", themeID})
+	}
+	scopes = append(scopes, scope{"guest_list = '' && theme = ''", ""})
+
+	for _, s := range scopes {
+		records, err := app.FindRecordsByFilter(utils.CollectionEmailTemplates,
+			"key = {:key} && active = true && "+s.filter, "-updated", 1, 0,
+			dbx.Params{"key": key, "scope": s.id})
+		if err == nil && len(records) > 0 {
+			return records[0]
+		}
+	}
+	return nil
+}
+
+// currentTemplateVersionID returns the version row for a template's current content
+func currentTemplateVersionID(app core.App, record *core.Record) string {
+	records, err := app.FindRecordsByFilter(utils.CollectionEmailTemplateVersions,
+		"template = {:template} && version = {:version}", "", 1, 0,
+		dbx.Params{"template": record.Id, "version": record.GetInt("version")})
+	if err != nil || len(records) == 0 {
+		return ""
+	}
+	return records[0].Id
+}
+
+// wrapTemplateHTML adds the branded wrapper a template's content is sent in
+func wrapTemplateHTML(key, content string, theme EmailTheme, unsubscribeLink string) string {
+	if defaultEmailTemplates[key].RSVP {
+		return wrapRSVPEmailHTML(content, theme, unsubscribeLink)
+	}
+	return wrapEmailHTML(content)
+}
+
+// emailThemeData exposes an EmailTheme to templates. Colours are template.CSS so they can be
+// used in style attributes.
+func emailThemeData(theme EmailTheme) map[string]any {
+	return map[string]any{
+		"text":        template.CSS(theme.Text),
+		"text_muted":  template.CSS(theme.TextMuted),
+		"background":  template.CSS(theme.Background),
+		"border":      template.CSS(theme.Border),
+		"button":      template.CSS(theme.Button),
+		"button_text": template.CSS(theme.ButtonText),
+		"primary":     template.CSS(theme.Primary),
+		"is_dark":     theme.IsDark,
+	}
+}
+
+// rsvpEmailData builds the variables shared by the RSVP templates. rsvpURL may be empty
+// for the confirmation.
+func rsvpEmailData(theme EmailTheme, eventContext, firstName, listName, listDescription, eventDate, eventTime, eventLocation, rsvpURL string) map[string]any {
+	data := map[string]any{
+		"first_name":       firstName,
+		"event_name":       eventContext,
+		"list_name":        listName,
+		"list_description": template.HTML(listDescription),
+		"event_date":       eventDate,
+		"event_time":       eventTime,
+		"event_location":   eventLocation,
+		"event_details":    template.HTML(buildEventDetailsHTML(theme, eventDate, eventTime, eventLocation)),
+		"theme":            emailThemeData(theme),
+		"rsvp_url":         rsvpURL,
+		"rsvp_buttons":     template.HTML(""),
+	}
+	if rsvpURL != "" {
+		data["rsvp_buttons"] = template.HTML(buildRSVPButtonsHTML(theme, rsvpURL))
+	}
+	return data
+}
+
+// sampleEmailTemplateData is used to validate and preview templates. Event details come
+// from the guest list when one is given.
+func sampleEmailTemplateData(key string, theme EmailTheme, guestList *core.Record) map[string]any {
+	base := getPublicBaseURL()
+	listName, listDescription := "Sample guest list", ""
+	eventContext, eventDate, eventTime, eventLocation := "Sample event", "Thursday 12 March", "6:00pm – 9:00pm", "The Outlook, Sydney"
+	guestListID := "preview"
+	if guestList != nil {
+		guestListID = guestList.Id
+		listName = guestList.GetString("name")
+		listDescription = guestList.GetString("description")
+		eventDate = guestList.GetString("event_date")
+		eventTime = guestList.GetString("event_time")
+		eventLocation = guestList.GetString("event_location")
+		eventContext = listName
+	}
+
+	switch key {
+	case emailKindOTP, emailKindAttendeeOTP:
+		return map[string]any{"name": "Alex Citizen", "code": "123456"}
+	case emailKindShareNotification:
+		return map[string]any{
+			"name":       "Alex Citizen",
+			"first_name": "Alex",
+			"list_name":  listName,
+			"event_name": eventContext,
+			"share_url":  base + "/shared/preview",
+		}
+	case emailKindPlusOne:
+		return map[string]any{
+			"requester_name":     "Alex Citizen",
+			"event_name":         eventContext,
+			"plus_one_name":      "Sam Example",
+			"plus_one_job_title": "Head of Marketing",
+			"plus_one_company":   "Example Co",
+			"plus_one_email":     "sam@example.com",
+			"guest_list_url":     "https://crm.theoutlook.io/guest-lists/" + guestListID,
+		}
+	case emailKindRSVPChanged:
+		return map[string]any{
+			"guest_name":     "Alex Citizen",
+			"event_name":     eventContext,
+			"action":         rsvpChangeCancelled,
+			"from_status":    rsvpStatusLabels["accepted"],
+			"to_status":      rsvpStatusLabels["declined"],
+			"changed":        "Response, Plus-one",
+			"note":           "Something came up at work",
+			"guest_list_url": "https://crm.theoutlook.io/guest-lists/" + guestListID,
+		}
+	}
+
+	rsvpURL := base + "/rsvp/preview"
+	if key == emailKindRSVPConfirmation {
+		rsvpURL = ""
+	}
+	data := rsvpEmailData(theme, eventContext, "Alex", listName, listDescription, eventDate, eventTime, eventLocation, rsvpURL)
+	switch key {
+	case emailKindRSVPForward:
+		data["forwarder_name"] = "Sam"
+		data["forwarder_email"] = "sam@example.com"
+	case emailKindRSVPConfirmation, emailKindRSVPPromoted:
+		sampleSessionsData(data, theme, guestList)
+		sampleCheckInQRData(data, theme)
+	}
+	return data
+}
+
+// buildRSVPButtonsHTML builds the accept/decline buttons, with a table fallback for Outlook
+func buildRSVPButtonsHTML(theme EmailTheme, rsvpURL string) string {
+	return fmt.Sprintf(`<!--[if mso]>
+            <table role="presentation" cellspacing="0" cellpadding="0" border="0" width="100%%"><tr>
+            <td width="50%%" style="padding-right: 6px;">
+            <![endif]-->
+            <div style="display: inline-block; width: 48%%; vertical-align: top;">
+                <a href="%s" style="display: block; background: %s; color: %s; padding: 16px 12px; text-decoration: none; font-size: 14px; text-align: center; border: 1px solid %s;">
+                    I can make it
+                </a>
+            </div>
+            <!--[if mso]>
+            </td><td width="50%%" style="padding-left: 6px;">
+            <![endif]-->
+            <div style="display: inline-block; width: 48%%; vertical-align: top;">
+                <a href="%s" style="display: block; background: transparent; color: %s; padding: 16px 12px; text-decoration: none; font-size: 14px; text-align: center; border: 1px solid %s;">
+                    I can't make it
+                </a>
+            </div>
+            <!--[if mso]>
+            </td></tr></table>
+            <![endif]-->`,
+		template.HTMLEscapeString(rsvpURL), theme.Button, theme.ButtonText, theme.Button,
+		template.HTMLEscapeString(rsvpURL), theme.Text, theme.Border,
+	)
+}
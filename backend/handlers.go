@@ -11,6 +11,7 @@ import (
 	"log"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -449,14 +450,108 @@ func handleContactsList(re *core.RequestEvent, app *pocketbase.PocketBase) error
 	if perPage < 1 || perPage > 500 {
 		perPage = 50
 	}
-	search := re.Request.URL.Query().Get("search")
-	status := re.Request.URL.Query().Get("status")
-	humanitixEvent := re.Request.URL.Query().Get("humanitix_event")
 	sort := re.Request.URL.Query().Get("sort")
 	if sort == "" {
 		sort = "name"
 	}
 
+	filter, params, ok := contactsListFilter(app, re.Request.URL.Query())
+	if !ok {
+		return utils.DataResponse(re, map[string]any{
+			"items":      []any{},
+			"page":       page,
+			"perPage":    perPage,
+			"totalItems": 0,
+			"totalPages": 0,
+		})
+	}
+
+	// Get total count
+	allRecords, _ := app.FindRecordsByFilter(utils.CollectionContacts, filter, "", 0, 0, params)
+	totalItems := len(allRecords)
+
+	// Get paginated records
+	offset := (page - 1) * perPage
+	records, err := app.FindRecordsByFilter(utils.CollectionContacts, filter, sort, perPage, offset, params)
+	if err != nil {
+		return utils.DataResponse(re, map[string]any{
+			"items":      []any{},
+			"page":       page,
+			"perPage":    perPage,
+			"totalItems": 0,
+			"totalPages": 0,
+		})
+	}
+
+	baseURL := getBaseURL()
+	items := make([]map[string]any, len(records))
+	for i, r := range records {
+		items[i] = buildContactResponse(r, app, baseURL)
+	}
+
+	totalPages := (totalItems + perPage - 1) / perPage
+
+	return utils.DataResponse(re, map[string]any{
+		"items":      items,
+		"page":       page,
+		"perPage":    perPage,
+		"totalItems": totalItems,
+		"totalPages": totalPages,
+	})
+}
+
+// contactBlindSearchClauses matches encrypted fields from partial search input using the
+// partial-match blind indexes: "@acme" or "acme.com" (email domain), a phone-like number
+// (last 4 digits) and location words (prefixes). Adds its params to params.
+func contactBlindSearchClauses(search string, params map[string]any) []string {
+	s := strings.ToLower(strings.TrimSpace(search))
+	clauses := []string{}
+
+	// Truncate to the longest indexed prefix
+	prefix := func(v string) string {
+		if r := []rune(v); len(r) > 32 {
+			return string(r[:32])
+		}
+		return v
+	}
+
+	domain := ""
+	if strings.HasPrefix(s, "@") {
+		domain = s[1:]
+	} else if strings.Contains(s, ".") && !strings.ContainsAny(s, " @") {
+		domain = s
+	}
+	if len([]rune(domain)) >= 3 {
+		params["domainIdx"] = utils.SearchToken(utils.TokenKindEmailDomain, prefix(domain))
+		clauses = append(clauses, "email_domain_index ~ {:domainIdx}")
+	}
+
+	if digits := utils.PhoneDigits(s); len(digits) >= 4 && strings.Trim(s, "0123456789 +-().") == "" {
+		params["phoneIdx"] = utils.SearchToken(utils.TokenKindPhoneLast4, digits[len(digits)-4:])
+		clauses = append(clauses, "phone_last4_index = {:phoneIdx}")
+	}
+
+	if words := utils.LocationWords(s); len(words) > 0 && !strings.Contains(s, "@") {
+		wordClauses := make([]string, 0, len(words))
+		for i, word := range words {
+			name := fmt.Sprintf("locIdx%d", i)
+			params[name] = utils.SearchToken(utils.TokenKindLocation, prefix(word))
+			wordClauses = append(wordClauses, fmt.Sprintf("location_tokens_index ~ {:%s}", name))
+		}
+		clauses = append(clauses, "("+strings.Join(wordClauses, " && ")+")")
+	}
+
+	return clauses
+}
+
+// contactsListFilter builds the contacts list filter from query params (search, status,
+// humanitix_event, alpha range, cf_<key>). Shared by the list and export endpoints.
+// Returns ok=false when the filter can't match anything.
+func contactsListFilter(app *pocketbase.PocketBase, query url.Values) (string, map[string]any, bool) {
+	search := query.Get("search")
+	status := query.Get("status")
+	humanitixEvent := query.Get("humanitix_event")
+
 	// If filtering by Humanitix event, find contact IDs from activities first
 	var humanitixContactIDs []string
 	if humanitixEvent != "" {
@@ -479,18 +574,13 @@ func handleContactsList(re *core.RequestEvent, app *pocketbase.PocketBase) error
 			}
 		}
 		if len(humanitixContactIDs) == 0 {
-			return utils.DataResponse(re, map[string]any{
-				"items":      []any{},
-				"page":       page,
-				"perPage":    perPage,
-				"totalItems": 0,
-				"totalPages": 0,
-			})
+			return "", nil, false
 		}
 	}
 
 	// Build filter
 	filter := ""
+	blindParams := map[string]any{}
 	if status != "" {
 		filter = "status = {:status}"
 	}
@@ -499,6 +589,9 @@ func handleContactsList(re *core.RequestEvent, app *pocketbase.PocketBase) error
 		searchFilter := "(name ~ {:search} || first_name ~ {:search} || last_name ~ {:search}"
 		if utils.IsEncryptionEnabled() {
 			searchFilter += " || email_index = {:emailIdx}"
+			for _, clause := range contactBlindSearchClauses(search, blindParams) {
+				searchFilter += " || " + clause
+			}
 		} else {
 			searchFilter += " || email ~ {:search}"
 		}
@@ -509,8 +602,8 @@ func handleContactsList(re *core.RequestEvent, app *pocketbase.PocketBase) error
 			filter = searchFilter
 		}
 	}
-	if alphaStart := re.Request.URL.Query().Get("alpha_start"); alphaStart != "" {
-		if alphaEnd := re.Request.URL.Query().Get("alpha_end"); alphaEnd != "" {
+	if alphaStart := query.Get("alpha_start"); alphaStart != "" {
+		if alphaEnd := query.Get("alpha_end"); alphaEnd != "" {
 			alphaFilter := "((name >= {:alpha_upper_start} && name < {:alpha_upper_end}) || (name >= {:alpha_start} && name < {:alpha_end}))"
 			if filter != "" {
 				filter = filter + " && " + alphaFilter
@@ -533,13 +626,29 @@ func handleContactsList(re *core.RequestEvent, app *pocketbase.PocketBase) error
 		}
 	}
 
+	// Custom field filters (cf_<key>=value)
+	cfClauses, cfParams := customFieldListFilter(app, "contact", query)
+	for _, clause := range cfClauses {
+		if filter != "" {
+			filter = filter + " && " + clause
+		} else {
+			filter = clause
+		}
+	}
+
 	params := map[string]any{
 		"status":   status,
 		"search":   search,
 		"emailIdx": utils.BlindIndex(strings.ToLower(strings.TrimSpace(search))),
 	}
-	if alphaStart := re.Request.URL.Query().Get("alpha_start"); alphaStart != "" {
-		if alphaEnd := re.Request.URL.Query().Get("alpha_end"); alphaEnd != "" {
+	for k, v := range cfParams {
+		params[k] = v
+	}
+	for k, v := range blindParams {
+		params[k] = v
+	}
+	if alphaStart := query.Get("alpha_start"); alphaStart != "" {
+		if alphaEnd := query.Get("alpha_end"); alphaEnd != "" {
 			params["alpha_start"] = alphaStart
 			params["alpha_end"] = alphaEnd
 			params["alpha_upper_start"] = strings.ToUpper(alphaStart)
@@ -550,38 +659,7 @@ func handleContactsList(re *core.RequestEvent, app *pocketbase.PocketBase) error
 		params[fmt.Sprintf("hid%d", i)] = cid
 	}
 
-	// Get total count
-	allRecords, _ := app.FindRecordsByFilter(utils.CollectionContacts, filter, "", 0, 0, params)
-	totalItems := len(allRecords)
-
-	// Get paginated records
-	offset := (page - 1) * perPage
-	records, err := app.FindRecordsByFilter(utils.CollectionContacts, filter, sort, perPage, offset, params)
-	if err != nil {
-		return utils.DataResponse(re, map[string]any{
-			"items":      []any{},
-			"page":       page,
-			"perPage":    perPage,
-			"totalItems": 0,
-			"totalPages": 0,
-		})
-	}
-
-	baseURL := getBaseURL()
-	items := make([]map[string]any, len(records))
-	for i, r := range records {
-		items[i] = buildContactResponse(r, app, baseURL)
-	}
-
-	totalPages := (totalItems + perPage - 1) / perPage
-
-	return utils.DataResponse(re, map[string]any{
-		"items":      items,
-		"page":       page,
-		"perPage":    perPage,
-		"totalItems": totalItems,
-		"totalPages": totalPages,
-	})
+	return filter, params, true
 }
 
 // handleContactGet returns a single contact by ID
@@ -723,6 +801,9 @@ func handleContactCreate(re *core.RequestEvent, app *pocketbase.PocketBase) erro
 	if v, ok := input["accessibility_requirements_other"].(string); ok {
 		record.Set("accessibility_requirements_other", v)
 	}
+	if err := applyCustomFields(app, record, "contact", input["custom_fields"], true); err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
 
 	if err := app.Save(record); err != nil {
 		log.Printf("[ContactCreate] Failed to save: %v", err)
@@ -775,6 +856,12 @@ func handleContactUpdate(re *core.RequestEvent, app *pocketbase.PocketBase) erro
 		if len(existing) > 0 {
 			return utils.BadRequestResponse(re, "A contact with this email already exists")
 		}
+		// A bounce or complaint was about the old address
+		if strings.ToLower(utils.DecryptField(record.GetString("email"))) != email {
+			record.Set("email_bounce_status", "")
+			record.Set("email_bounce_detail", "")
+			record.Set("email_bounce_at", "")
+		}
 		record.Set("email", email)
 	}
 	if v, ok := input["personal_email"].(string); ok {
@@ -848,6 +935,11 @@ func handleContactUpdate(re *core.RequestEvent, app *pocketbase.PocketBase) erro
 	if v, ok := input["accessibility_requirements_other"].(string); ok {
 		record.Set("accessibility_requirements_other", v)
 	}
+	if v, ok := input["custom_fields"]; ok {
+		if err := applyCustomFields(app, record, "contact", v, false); err != nil {
+			return utils.BadRequestResponse(re, err.Error())
+		}
+	}
 
 	// Decrypt PII fields before save so PocketBase validation passes
 	// (encrypted values like "enc:..." fail EmailField validation).
@@ -1042,49 +1134,12 @@ func handleOrganisationsList(re *core.RequestEvent, app *pocketbase.PocketBase)
 	if perPage < 1 || perPage > 500 {
 		perPage = 50
 	}
-	search := re.Request.URL.Query().Get("search")
-	status := re.Request.URL.Query().Get("status")
 	sort := re.Request.URL.Query().Get("sort")
 	if sort == "" {
 		sort = "name"
 	}
 
-	// Build filter
-	filter := ""
-	if status != "" {
-		filter = "status = {:status}"
-	}
-	if search != "" {
-		searchFilter := "name ~ {:search}"
-		if filter != "" {
-			filter = filter + " && " + searchFilter
-		} else {
-			filter = searchFilter
-		}
-	}
-	if alphaStart := re.Request.URL.Query().Get("alpha_start"); alphaStart != "" {
-		if alphaEnd := re.Request.URL.Query().Get("alpha_end"); alphaEnd != "" {
-			alphaFilter := "((name >= {:alpha_upper_start} && name < {:alpha_upper_end}) || (name >= {:alpha_start} && name < {:alpha_end}))"
-			if filter != "" {
-				filter = filter + " && " + alphaFilter
-			} else {
-				filter = alphaFilter
-			}
-		}
-	}
-
-	params := map[string]any{
-		"status": status,
-		"search": search,
-	}
-	if alphaStart := re.Request.URL.Query().Get("alpha_start"); alphaStart != "" {
-		if alphaEnd := re.Request.URL.Query().Get("alpha_end"); alphaEnd != "" {
-			params["alpha_start"] = alphaStart
-			params["alpha_end"] = alphaEnd
-			params["alpha_upper_start"] = strings.ToUpper(alphaStart)
-			params["alpha_upper_end"] = strings.ToUpper(alphaEnd)
-		}
-	}
+	filter, params := organisationsListFilter(app, re.Request.URL.Query())
 
 	// Get total count
 	allRecords, _ := app.FindRecordsByFilter(utils.CollectionOrganisations, filter, "", 0, 0, params)
@@ -1120,6 +1175,65 @@ func handleOrganisationsList(re *core.RequestEvent, app *pocketbase.PocketBase)
 	})
 }
 
+// organisationsListFilter builds the organisations list filter from query params.
+// Shared by the list and export endpoints.
+func organisationsListFilter(app *pocketbase.PocketBase, query url.Values) (string, map[string]any) {
+	search := query.Get("search")
+	status := query.Get("status")
+
+	// Build filter
+	filter := ""
+	if status != "" {
+		filter = "status = {:status}"
+	}
+	if search != "" {
+		searchFilter := "name ~ {:search}"
+		if filter != "" {
+			filter = filter + " && " + searchFilter
+		} else {
+			filter = searchFilter
+		}
+	}
+	if alphaStart := query.Get("alpha_start"); alphaStart != "" {
+		if alphaEnd := query.Get("alpha_end"); alphaEnd != "" {
+			alphaFilter := "((name >= {:alpha_upper_start} && name < {:alpha_upper_end}) || (name >= {:alpha_start} && name < {:alpha_end}))"
+			if filter != "" {
+				filter = filter + " && " + alphaFilter
+			} else {
+				filter = alphaFilter
+			}
+		}
+	}
+
+	// Custom field filters (cf_<key>=value)
+	cfClauses, cfParams := customFieldListFilter(app, "organisation", query)
+	for _, clause := range cfClauses {
+		if filter != "" {
+			filter = filter + " && " + clause
+		} else {
+			filter = clause
+		}
+	}
+
+	params := map[string]any{
+		"status": status,
+		"search": search,
+	}
+	for k, v := range cfParams {
+		params[k] = v
+	}
+	if alphaStart := query.Get("alpha_start"); alphaStart != "" {
+		if alphaEnd := query.Get("alpha_end"); alphaEnd != "" {
+			params["alpha_start"] = alphaStart
+			params["alpha_end"] = alphaEnd
+			params["alpha_upper_start"] = strings.ToUpper(alphaStart)
+			params["alpha_upper_end"] = strings.ToUpper(alphaEnd)
+		}
+	}
+
+	return filter, params
+}
+
 // handleOrganisationGet returns a single organisation by ID
 func handleOrganisationGet(re *core.RequestEvent, app *pocketbase.PocketBase) error {
 	id := re.Request.PathValue("id")
@@ -1192,6 +1306,9 @@ func handleOrganisationCreate(re *core.RequestEvent, app *pocketbase.PocketBase)
 	} else {
 		record.Set("source", "manual")
 	}
+	if err := applyCustomFields(app, record, "organisation", input["custom_fields"], true); err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
 
 	if err := app.Save(record); err != nil {
 		log.Printf("[OrganisationCreate] Failed to save: %v", err)
@@ -1250,6 +1367,11 @@ func handleOrganisationUpdate(re *core.RequestEvent, app *pocketbase.PocketBase)
 	if v, ok := input["status"].(string); ok {
 		record.Set("status", v)
 	}
+	if v, ok := input["custom_fields"]; ok {
+		if err := applyCustomFields(app, record, "organisation", v, false); err != nil {
+			return utils.BadRequestResponse(re, err.Error())
+		}
+	}
 
 	if err := app.Save(record); err != nil {
 		log.Printf("[OrganisationUpdate] Failed to save: %v", err)
@@ -1828,6 +1950,9 @@ func handleContactsMerge(re *core.RequestEvent, app *pocketbase.PocketBase) erro
 		allContacts[mid] = record
 	}
 
+	// Snapshot the primary before any field selections are applied (for undo)
+	primarySnapshot := primaryRecord.FieldsData()
+
 	// Build merged values from field_selections
 	scalarFields := []string{
 		"first_name", "last_name", "email", "personal_email", "phone", "pronouns", "bio", "job_title",
@@ -1882,12 +2007,36 @@ func handleContactsMerge(re *core.RequestEvent, app *pocketbase.PocketBase) erro
 	}
 	primaryRecord.Set("source_ids", mergedSourceIDs)
 
+	// Merge custom fields — values already on the primary win
+	mergedCustomFields := map[string]any{}
+	for _, mid := range input.MergedIDs {
+		for k, v := range recordCustomFields(allContacts[mid]) {
+			mergedCustomFields[k] = v
+		}
+	}
+	for k, v := range recordCustomFields(primaryRecord) {
+		mergedCustomFields[k] = v
+	}
+	primaryRecord.Set("custom_fields", mergedCustomFields)
+
+	// Merge consent — a withdrawal on any contact wins, so the merge can't re-enable sends
+	consentRecords := []*core.Record{primaryRecord}
+	for _, mid := range input.MergedIDs {
+		consentRecords = append(consentRecords, allContacts[mid])
+	}
+	if consent := mergeContactConsent(consentRecords); len(consent) > 0 {
+		primaryRecord.Set("consent", consent)
+	}
+
 	// Execute in transaction
 	activitiesReassigned := 0
+	history := newMergeHistory()
+	var mergeRecord *core.Record
 
 	err = app.RunInTransaction(func(txApp core.App) error {
 		for _, mid := range input.MergedIDs {
 			record := allContacts[mid]
+			history.MergedSnapshots = append(history.MergedSnapshots, record.FieldsData())
 
 			// Reassign activities to primary
 			activities, _ := txApp.FindRecordsByFilter(
@@ -1900,6 +2049,7 @@ func handleContactsMerge(re *core.RequestEvent, app *pocketbase.PocketBase) erro
 				if err := txApp.Save(activity); err != nil {
 					return fmt.Errorf("failed to reassign activity %s: %w", activity.Id, err)
 				}
+				history.reassigned("activities", activity.Id, "contact", mid)
 				activitiesReassigned++
 			}
 
@@ -1918,7 +2068,22 @@ func handleContactsMerge(re *core.RequestEvent, app *pocketbase.PocketBase) erro
 					map[string]any{"glId": guestListID, "primaryId": input.PrimaryID},
 				)
 				if len(existing) > 0 {
-					// Primary already in this guest list — delete the duplicate
+					// Primary already in this guest list — move the duplicate's engagement
+					// events to the kept item (they cascade-delete with it), then delete it
+					itemEvents, _ := txApp.FindRecordsByFilter(
+						utils.CollectionEmailEvents,
+						"guest_list_item = {:itemId}", "", 0, 0,
+						map[string]any{"itemId": item.Id},
+					)
+					for _, event := range itemEvents {
+						event.Set("guest_list_item", existing[0].Id)
+						if err := txApp.Save(event); err != nil {
+							return fmt.Errorf("failed to reassign email event %s: %w", event.Id, err)
+						}
+						history.reassigned(utils.CollectionEmailEvents, event.Id, "guest_list_item", item.Id)
+					}
+
+					history.deleted("guest_list_items", item.FieldsData())
 					if err := txApp.Delete(item); err != nil {
 						return fmt.Errorf("failed to delete duplicate guest list item %s: %w", item.Id, err)
 					}
@@ -1928,6 +2093,7 @@ func handleContactsMerge(re *core.RequestEvent, app *pocketbase.PocketBase) erro
 					if err := txApp.Save(item); err != nil {
 						return fmt.Errorf("failed to reassign guest list item %s: %w", item.Id, err)
 					}
+					history.reassigned("guest_list_items", item.Id, "contact", mid)
 				}
 			}
 
@@ -1947,6 +2113,7 @@ func handleContactsMerge(re *core.RequestEvent, app *pocketbase.PocketBase) erro
 					otherID = contactB
 				}
 				if otherID == input.PrimaryID {
+					history.deleted("contact_links", link.FieldsData())
 					if err := txApp.Delete(link); err != nil {
 						return fmt.Errorf("failed to delete self-referencing link %s: %w", link.Id, err)
 					}
@@ -1954,17 +2121,49 @@ func handleContactsMerge(re *core.RequestEvent, app *pocketbase.PocketBase) erro
 				}
 
 				// Reassign: point the merged contact's side to primary
+				linkSnapshot := link.FieldsData()
+				linkField := "contact_b"
 				if contactA == mid {
-					link.Set("contact_a", input.PrimaryID)
-				} else {
-					link.Set("contact_b", input.PrimaryID)
+					linkField = "contact_a"
 				}
+				link.Set(linkField, input.PrimaryID)
 				if err := txApp.Save(link); err != nil {
 					// May fail if a link between primary and otherID already exists — delete instead
 					if err2 := txApp.Delete(link); err2 != nil {
 						return fmt.Errorf("failed to handle contact link %s: %w", link.Id, err)
 					}
+					history.deleted("contact_links", linkSnapshot)
+					continue
 				}
+				history.reassigned("contact_links", link.Id, linkField, mid)
+			}
+
+			// Move the consent proof trail to primary (it would cascade-delete with the contact)
+			consentEvents, _ := txApp.FindRecordsByFilter(
+				utils.CollectionConsentEvents,
+				"contact = {:contactId}", "", 0, 0,
+				map[string]any{"contactId": mid},
+			)
+			for _, event := range consentEvents {
+				event.Set("contact", input.PrimaryID)
+				if err := txApp.Save(event); err != nil {
+					return fmt.Errorf("failed to reassign consent event %s: %w", event.Id, err)
+				}
+				history.reassigned(utils.CollectionConsentEvents, event.Id, "contact", mid)
+			}
+
+			// Move the engagement timeline to primary (it would cascade-delete with the contact)
+			emailEvents, _ := txApp.FindRecordsByFilter(
+				utils.CollectionEmailEvents,
+				"contact = {:contactId}", "", 0, 0,
+				map[string]any{"contactId": mid},
+			)
+			for _, event := range emailEvents {
+				event.Set("contact", input.PrimaryID)
+				if err := txApp.Save(event); err != nil {
+					return fmt.Errorf("failed to reassign email event %s: %w", event.Id, err)
+				}
+				history.reassigned(utils.CollectionEmailEvents, event.Id, "contact", mid)
 			}
 
 			// Delete the merged contact (now safe — no more references)
@@ -1985,6 +2184,16 @@ func handleContactsMerge(re *core.RequestEvent, app *pocketbase.PocketBase) erro
 			return fmt.Errorf("failed to save primary contact: %w", err)
 		}
 
+		// Record merge history so the merge can be undone within the retention window
+		mergedBy := ""
+		if re.Auth != nil {
+			mergedBy = re.Auth.GetString("email")
+		}
+		mergeRecord, err = saveMergeHistory(txApp, input.PrimaryID, input.MergedIDs, primarySnapshot, history, mergedBy)
+		if err != nil {
+			return fmt.Errorf("failed to save merge history: %w", err)
+		}
+
 		return nil
 	})
 
@@ -1999,15 +2208,275 @@ func handleContactsMerge(re *core.RequestEvent, app *pocketbase.PocketBase) erro
 			"primary_id":            input.PrimaryID,
 			"merged_ids":            input.MergedIDs,
 			"activities_reassigned": activitiesReassigned,
+			"merge_id":              mergeRecord.Id,
 		}, "")
 
 	return utils.DataResponse(re, map[string]any{
 		"id":                    input.PrimaryID,
+		"merge_id":              mergeRecord.Id,
 		"activities_reassigned": activitiesReassigned,
 		"contacts_deleted":      len(input.MergedIDs),
+		"undo_expires_at":       mergeRecord.GetString("expires_at"),
+	})
+}
+
+// MergeOrganisationsInput is the request payload for merging organisations
+type MergeOrganisationsInput struct {
+	PrimaryID       string            `json:"primary_id"`
+	MergedIDs       []string          `json:"merged_ids"`
+	FieldSelections map[string]string `json:"field_selections"` // field -> source organisation ID
+	MergedTags      []string          `json:"merged_tags"`
+}
+
+// handleOrganisationsMerge merges multiple organisations into a single primary organisation.
+// Contacts, guest lists and activities are re-pointed to the primary; projections are emitted
+// by the record hooks (contact upserts, primary upsert, merged org deletes).
+func handleOrganisationsMerge(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	var input MergeOrganisationsInput
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+
+	// Validate input
+	if input.PrimaryID == "" {
+		return utils.BadRequestResponse(re, "primary_id is required")
+	}
+	if len(input.MergedIDs) == 0 {
+		return utils.BadRequestResponse(re, "At least one merged_id is required")
+	}
+	for _, mid := range input.MergedIDs {
+		if mid == input.PrimaryID {
+			return utils.BadRequestResponse(re, "primary_id cannot be in merged_ids")
+		}
+	}
+
+	// Load all organisations
+	primaryRecord, err := app.FindRecordById(utils.CollectionOrganisations, input.PrimaryID)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Primary organisation not found")
+	}
+
+	allOrgs := map[string]*core.Record{input.PrimaryID: primaryRecord}
+	for _, mid := range input.MergedIDs {
+		record, err := app.FindRecordById(utils.CollectionOrganisations, mid)
+		if err != nil {
+			return utils.NotFoundResponse(re, fmt.Sprintf("Organisation %s not found", mid))
+		}
+		allOrgs[mid] = record
+	}
+
+	// Build merged values from field_selections
+	scalarFields := []string{
+		"name", "website", "linkedin",
+		"description_short", "description_medium", "description_long",
+		"industry", "status", "source",
+		"hubspot_company_id", "hubspot_synced_at",
+	}
+
+	for _, field := range scalarFields {
+		sourceID, ok := input.FieldSelections[field]
+		if !ok {
+			continue
+		}
+		sourceRecord, ok := allOrgs[sourceID]
+		if !ok {
+			return utils.BadRequestResponse(re, fmt.Sprintf("Invalid source organisation for field %s", field))
+		}
+		primaryRecord.Set(field, sourceRecord.GetString(field))
+	}
+
+	// Logo URLs: explicit selection wins, otherwise keep the primary's and fall back to
+	// the first merged org that has logos (record or DAM cache) so logos aren't lost
+	if sourceID, ok := input.FieldSelections["logo_urls"]; ok {
+		sourceRecord, ok := allOrgs[sourceID]
+		if !ok {
+			return utils.BadRequestResponse(re, "Invalid source organisation for field logo_urls")
+		}
+		primaryRecord.Set("logo_urls", orgLogoURLs(sourceRecord))
+	} else if len(orgLogoURLs(primaryRecord)) == 0 {
+		for _, mid := range input.MergedIDs {
+			if logos := orgLogoURLs(allOrgs[mid]); len(logos) > 0 {
+				primaryRecord.Set("logo_urls", logos)
+				break
+			}
+		}
+	}
+
+	if input.MergedTags != nil {
+		primaryRecord.Set("tags", input.MergedTags)
+	}
+
+	// Union the contacts JSON (array of {name, linkedin, email}) across all orgs
+	mergedOrgContacts := []map[string]any{}
+	seenOrgContacts := map[string]bool{}
+	for _, id := range append([]string{input.PrimaryID}, input.MergedIDs...) {
+		var list []map[string]any
+		b, _ := json.Marshal(allOrgs[id].Get("contacts"))
+		if err := json.Unmarshal(b, &list); err != nil {
+			continue
+		}
+		for _, c := range list {
+			email, _ := c["email"].(string)
+			name, _ := c["name"].(string)
+			key := strings.ToLower(strings.TrimSpace(email))
+			if key == "" {
+				key = strings.ToLower(strings.TrimSpace(name))
+			}
+			if key != "" && seenOrgContacts[key] {
+				continue
+			}
+			seenOrgContacts[key] = true
+			mergedOrgContacts = append(mergedOrgContacts, c)
+		}
+	}
+	primaryRecord.Set("contacts", mergedOrgContacts)
+
+	// Deep merge source_ids from all organisations (PocketBase returns types.JSONRaw for JSON fields)
+	mergedSourceIDs := map[string]any{}
+	for _, record := range allOrgs {
+		if sourceIDs := record.Get("source_ids"); sourceIDs != nil {
+			var m map[string]any
+			b, _ := json.Marshal(sourceIDs)
+			if err := json.Unmarshal(b, &m); err == nil {
+				for k, v := range m {
+					mergedSourceIDs[k] = v
+				}
+			}
+		}
+	}
+	primaryRecord.Set("source_ids", mergedSourceIDs)
+
+	// Merge custom fields — values already on the primary win
+	mergedCustomFields := map[string]any{}
+	for _, mid := range input.MergedIDs {
+		for k, v := range recordCustomFields(allOrgs[mid]) {
+			mergedCustomFields[k] = v
+		}
+	}
+	for k, v := range recordCustomFields(primaryRecord) {
+		mergedCustomFields[k] = v
+	}
+	primaryRecord.Set("custom_fields", mergedCustomFields)
+
+	// Guest lists show the org's inverted logo from DAM — fetch once for the primary
+	primaryLogoURL := ""
+	if damURL := os.Getenv("DAM_PUBLIC_URL"); damURL != "" {
+		primaryLogoURL = fetchDAMOrgLogo(damURL, input.PrimaryID)
+	}
+
+	// Execute in transaction
+	contactsReassigned := 0
+	guestListsReassigned := 0
+	activitiesReassigned := 0
+
+	err = app.RunInTransaction(func(txApp core.App) error {
+		// Save the primary first so re-pointed records pick up the merged name
+		if err := txApp.Save(primaryRecord); err != nil {
+			return fmt.Errorf("failed to save primary organisation: %w", err)
+		}
+		primaryName := primaryRecord.GetString("name")
+
+		for _, mid := range input.MergedIDs {
+			// Reassign contacts (PII must be decrypted before save, hooks re-encrypt)
+			contacts, _ := txApp.FindRecordsByFilter(
+				utils.CollectionContacts,
+				"organisation = {:id}",
+				"", 0, 0,
+				map[string]any{"id": mid},
+			)
+			for _, contact := range contacts {
+				for _, field := range []string{"email", "personal_email", "phone", "bio", "location"} {
+					if v := contact.GetString(field); v != "" {
+						contact.Set(field, utils.DecryptField(v))
+					}
+				}
+				contact.Set("organisation", input.PrimaryID)
+				if err := txApp.Save(contact); err != nil {
+					return fmt.Errorf("failed to reassign contact %s: %w", contact.Id, err)
+				}
+				contactsReassigned++
+			}
+
+			// Reassign guest lists (organisation is a plain text field with denormalised name/logo)
+			guestLists, _ := txApp.FindRecordsByFilter(
+				utils.CollectionGuestLists,
+				"organisation = {:id}",
+				"", 0, 0,
+				map[string]any{"id": mid},
+			)
+			for _, gl := range guestLists {
+				gl.Set("organisation", input.PrimaryID)
+				gl.Set("organisation_name", primaryName)
+				if primaryLogoURL != "" {
+					gl.Set("organisation_logo_url", primaryLogoURL)
+				}
+				if err := txApp.Save(gl); err != nil {
+					return fmt.Errorf("failed to reassign guest list %s: %w", gl.Id, err)
+				}
+				guestListsReassigned++
+			}
+
+			// Reassign activities
+			activities, _ := txApp.FindRecordsByFilter(
+				utils.CollectionActivities,
+				"organisation = {:id}",
+				"", 0, 0,
+				map[string]any{"id": mid},
+			)
+			for _, activity := range activities {
+				activity.Set("organisation", input.PrimaryID)
+				if err := txApp.Save(activity); err != nil {
+					return fmt.Errorf("failed to reassign activity %s: %w", activity.Id, err)
+				}
+				activitiesReassigned++
+			}
+
+			// Delete the merged organisation (now safe — no more references)
+			if err := txApp.Delete(allOrgs[mid]); err != nil {
+				return fmt.Errorf("failed to delete organisation %s: %w", mid, err)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("[OrganisationMerge] Transaction failed: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to merge organisations")
+	}
+
+	utils.LogFromRequest(app, re, "merge", "organisations", input.PrimaryID, "success",
+		map[string]any{
+			"primary_id":             input.PrimaryID,
+			"merged_ids":             input.MergedIDs,
+			"contacts_reassigned":    contactsReassigned,
+			"guest_lists_reassigned": guestListsReassigned,
+			"activities_reassigned":  activitiesReassigned,
+		}, "")
+
+	return utils.DataResponse(re, map[string]any{
+		"id":                     input.PrimaryID,
+		"contacts_reassigned":    contactsReassigned,
+		"guest_lists_reassigned": guestListsReassigned,
+		"activities_reassigned":  activitiesReassigned,
+		"organisations_deleted":  len(input.MergedIDs),
 	})
 }
 
+// orgLogoURLs returns an organisation's logo_urls, falling back to the DAM logo cache
+func orgLogoURLs(r *core.Record) []map[string]any {
+	var logos []map[string]any
+	b, _ := json.Marshal(r.Get("logo_urls"))
+	json.Unmarshal(b, &logos)
+	if len(logos) > 0 {
+		return logos
+	}
+	if cached, ok := GetDAMLogoURLs(r.Id); ok {
+		return cached
+	}
+	return nil
+}
+
 // --- Response Builders ---
 
 // buildContactResponse builds a contact response object
@@ -2041,6 +2510,10 @@ func buildContactResponse(r *core.Record, app *pocketbase.PocketBase, baseURL st
 		"dietary_requirements_other":        r.GetString("dietary_requirements_other"),
 		"accessibility_requirements":        r.Get("accessibility_requirements"),
 		"accessibility_requirements_other":  r.GetString("accessibility_requirements_other"),
+		"consent":        buildConsentResponse(r),
+		"email_bounce_status": r.GetString("email_bounce_status"),
+		"email_bounce_detail": r.GetString("email_bounce_detail"),
+		"email_bounce_at":     r.GetString("email_bounce_at"),
 		"created":        r.GetString("created"),
 		"updated":        r.GetString("updated"),
 	}
@@ -2088,6 +2561,8 @@ func buildContactResponse(r *core.Record, app *pocketbase.PocketBase, baseURL st
 		}
 	}
 
+	data["custom_fields"] = recordCustomFields(r)
+
 	return data
 }
 
@@ -2180,6 +2655,7 @@ func buildOrganisationResponse(r *core.Record, baseURL string) map[string]any {
 		"industry":           r.GetString("industry"),
 		"status":             r.GetString("status"),
 		"source":             r.GetString("source"),
+		"custom_fields":      recordCustomFields(r),
 		"created":            r.GetString("created"),
 		"updated":            r.GetString("updated"),
 	}
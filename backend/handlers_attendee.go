@@ -224,6 +224,7 @@ func handleAttendeeProfile(re *core.RequestEvent, app *pocketbase.PocketBase) er
 		"dietary_requirements_other":    contact.GetString("dietary_requirements_other"),
 		"accessibility_requirements":       contact.Get("accessibility_requirements"),
 		"accessibility_requirements_other": contact.GetString("accessibility_requirements_other"),
+		"consent":                       buildConsentResponse(contact),
 	})
 }
 
@@ -269,6 +270,21 @@ func handleAttendeeProfileUpdate(re *core.RequestEvent, app *pocketbase.PocketBa
 		updatedFields++
 	}
 
+	// Communication preferences, e.g. "consent": {"marketing": false}
+	var consentChanges []consentChange
+	if raw, ok := input["consent"]; ok {
+		b, _ := json.Marshal(raw)
+		var changes map[string]bool
+		if err := json.Unmarshal(b, &changes); err != nil {
+			return utils.BadRequestResponse(re, "consent must be an object of category -> true/false")
+		}
+		if err := validateConsentInput(changes); err != nil {
+			return utils.BadRequestResponse(re, err.Error())
+		}
+		consentChanges = applyConsentChanges(contact, changes, "attendee_portal")
+		updatedFields++
+	}
+
 	if updatedFields == 0 {
 		return utils.BadRequestResponse(re, "No valid fields to update")
 	}
@@ -280,6 +296,11 @@ func handleAttendeeProfileUpdate(re *core.RequestEvent, app *pocketbase.PocketBa
 		return utils.InternalErrorResponse(re, "Failed to update profile")
 	}
 
+	if len(consentChanges) > 0 {
+		logConsentEvents(app, contact.Id, consentChanges, consentProofFromRequest(re, "attendee_portal", "attendee_profile"))
+		syncMarketingConsentToMailchimp(app, contact, consentChanges, "attendee_portal")
+	}
+
 	utils.LogFromRequest(app, re, "attendee_profile_update", utils.CollectionContacts, contact.Id, "success", nil, "")
 
 	return utils.SuccessResponse(re, "Profile updated")
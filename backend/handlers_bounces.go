@@ -0,0 +1,96 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const emailEventsMaxBody = 5 << 20
+
+// handleEmailEventsWebhook receives bounce and complaint notifications, either as JSON in the
+// generic format (one event, an array, or {"events": [...]}) or as a raw DSN/ARF report sent
+// with Content-Type message/rfc822. Requires EMAIL_EVENTS_WEBHOOK_SECRET: X-Webhook-Signature
+// is the hex HMAC-SHA256 of the body, as for the activity webhook.
+func handleEmailEventsWebhook(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	secret := os.Getenv("EMAIL_EVENTS_WEBHOOK_SECRET")
+	if secret == "" {
+		// Unsigned events could suppress any address, so the endpoint is off until configured
+		return re.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Email events webhook not configured"})
+	}
+
+	bodyBytes, err := io.ReadAll(io.LimitReader(re.Request.Body, emailEventsMaxBody))
+	if err != nil {
+		return utils.BadRequestResponse(re, "Failed to read request body")
+	}
+
+	signature := re.Request.Header.Get("X-Webhook-Signature")
+	if signature == "" {
+		log.Printf("[EmailEventsWebhook] Missing signature from %s", re.RealIP())
+		return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Missing signature"})
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(bodyBytes)
+	expectedSig := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expectedSig)) {
+		log.Printf("[EmailEventsWebhook] Invalid signature from %s", re.RealIP())
+		return re.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid signature"})
+	}
+
+	var events []emailFeedback
+	mediaType, _, _ := mime.ParseMediaType(re.Request.Header.Get("Content-Type"))
+	if mediaType == "message/rfc822" {
+		events, err = parseBounceReport(bodyBytes)
+		if err != nil {
+			return utils.BadRequestResponse(re, "Invalid report: "+err.Error())
+		}
+	} else {
+		if strings.HasPrefix(strings.TrimSpace(string(bodyBytes)), "[") {
+			err = json.Unmarshal(bodyBytes, &events)
+		} else {
+			var wrapper struct {
+				emailFeedback
+				Events []emailFeedback `json:"events"`
+			}
+			err = json.Unmarshal(bodyBytes, &wrapper)
+			events = wrapper.Events
+			if events == nil {
+				events = []emailFeedback{wrapper.emailFeedback}
+			}
+		}
+		if err != nil {
+			return utils.BadRequestResponse(re, "Invalid request body")
+		}
+	}
+
+	results := []any{}
+	for i := range events {
+		if err := normaliseEmailFeedback(&events[i]); err != nil {
+			results = append(results, map[string]any{"email": events[i].Email, "error": err.Error()})
+			continue
+		}
+		result, err := processEmailFeedback(app, events[i], "webhook")
+		if err != nil {
+			log.Printf("[EmailEventsWebhook] Failed to process %s for %s: %v", events[i].Type, events[i].Email, err)
+			results = append(results, map[string]any{"email": events[i].Email, "error": "Failed to process event"})
+			continue
+		}
+		results = append(results, result)
+	}
+
+	return utils.DataResponse(re, map[string]any{
+		"received": len(events),
+		"results":  results,
+	})
+}
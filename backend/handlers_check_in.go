@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func buildCheckInItemResponse(r *core.Record) map[string]any {
+	return map[string]any{
+		"id":                        r.Id,
+		"contact_id":                r.GetString("contact"),
+		"contact_name":              r.GetString("contact_name"),
+		"contact_organisation_name": r.GetString("contact_organisation_name"),
+		"invite_status":             r.GetString("invite_status"),
+		"rsvp_plus_one":             r.GetBool("rsvp_plus_one"),
+		"rsvp_plus_one_name":        strings.TrimSpace(r.GetString("rsvp_plus_one_name") + " " + r.GetString("rsvp_plus_one_last_name")),
+		"plus_one_of":               r.GetString("plus_one_of"),
+		"checked_in_at":             r.GetString("checked_in_at"),
+		"checked_in_by":             r.GetString("checked_in_by"),
+		"check_in_method":           r.GetString("check_in_method"),
+		"plus_one_checked_in_at":    r.GetString("plus_one_checked_in_at"),
+	}
+}
+
+// handleGuestListCheckInList returns the door list: accepted guests with their arrival, and
+// totals. Search with q (name); all=true includes guests who haven't accepted.
+func handleGuestListCheckInList(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if _, err := app.FindRecordById(utils.CollectionGuestLists, id); err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+
+	filter := "guest_list = {:id}"
+	params := dbx.Params{"id": id}
+	query := re.Request.URL.Query()
+	if query.Get("all") != "true" {
+		filter += " && invite_status = 'accepted'"
+	}
+	limit := 0
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		filter += " && (contact_name ~ {:q} || contact_organisation_name ~ {:q} || rsvp_plus_one_name ~ {:q})"
+		params["q"] = q
+		limit = 50
+	}
+
+	records, err := app.FindRecordsByFilter(utils.CollectionGuestListItems, filter, "contact_name", limit, 0, params)
+	if err != nil {
+		records = nil
+	}
+	items := make([]map[string]any, len(records))
+	for i, r := range records {
+		items[i] = buildCheckInItemResponse(r)
+	}
+
+	// Totals always cover the whole list, whatever the search
+	accepted, err := app.FindRecordsByFilter(utils.CollectionGuestListItems,
+		"guest_list = {:id} && invite_status = 'accepted'", "", 0, 0, dbx.Params{"id": id})
+	if err != nil {
+		accepted = nil
+	}
+	stats := map[string]int{"expected": len(accepted), "checked_in": 0, "plus_ones_expected": 0, "plus_ones_checked_in": 0}
+	for _, r := range accepted {
+		if !r.GetDateTime("checked_in_at").IsZero() {
+			stats["checked_in"]++
+		}
+		if r.GetBool("rsvp_plus_one") {
+			stats["plus_ones_expected"]++
+			if !r.GetDateTime("plus_one_checked_in_at").IsZero() {
+				stats["plus_ones_checked_in"]++
+			}
+		}
+	}
+
+	return utils.DataResponse(re, map[string]any{"items": items, "stats": stats})
+}
+
+// handleGuestListCheckIn checks a guest in, by a scanned QR code or an item_id picked from
+// the door list. plus_one also records their plus-one's arrival. Guests who haven't
+// accepted can still be checked in; the response says so, so the door can decide.
+func handleGuestListCheckIn(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	guestList, err := app.FindRecordById(utils.CollectionGuestLists, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+
+	var input struct {
+		Code    string `json:"code"`
+		ItemID  string `json:"item_id"`
+		PlusOne bool   `json:"plus_one"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid JSON")
+	}
+
+	itemID, method := input.ItemID, checkInMethodSearch
+	if input.Code != "" {
+		scannedID, ok := parseCheckInCode(input.Code)
+		if !ok {
+			return utils.BadRequestResponse(re, "This code isn't valid")
+		}
+		itemID, method = scannedID, checkInMethodQR
+	}
+	if itemID == "" {
+		return utils.BadRequestResponse(re, "code or item_id is required")
+	}
+
+	item, err := app.FindRecordById(utils.CollectionGuestListItems, itemID)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Guest not found")
+	}
+	if item.GetString("guest_list") != id {
+		return utils.BadRequestResponse(re, "This guest is on a different guest list")
+	}
+
+	alreadyCheckedIn := !item.GetDateTime("checked_in_at").IsZero()
+	by := ""
+	if re.Auth != nil {
+		by = re.Auth.GetString("email")
+	}
+	changed, err := checkInItem(app, guestList, item, method, by, input.PlusOne)
+	if err != nil {
+		log.Printf("[CheckIn] Failed to check in item %s: %v", item.Id, err)
+		return utils.InternalErrorResponse(re, "Failed to check in guest")
+	}
+
+	if changed {
+		utils.LogFromRequest(app, re, "check_in", utils.CollectionGuestListItems, item.Id, "success", map[string]any{
+			"guest_list": id,
+			"method":     method,
+			"plus_one":   input.PlusOne,
+		}, "")
+	}
+
+	warning := ""
+	if item.GetString("invite_status") != "accepted" {
+		warning = "This guest hasn't accepted"
+	}
+	return utils.DataResponse(re, map[string]any{
+		"item":               buildCheckInItemResponse(item),
+		"already_checked_in": alreadyCheckedIn,
+		"warning":            warning,
+	})
+}
+
+// handleGuestListCheckInUndo reverses a check-in made in error
+func handleGuestListCheckInUndo(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	item, err := app.FindRecordById(utils.CollectionGuestListItems, re.Request.PathValue("itemId"))
+	if err != nil || item.GetString("guest_list") != id {
+		return utils.NotFoundResponse(re, "Guest not found")
+	}
+	if item.GetDateTime("checked_in_at").IsZero() {
+		return utils.BadRequestResponse(re, "This guest hasn't checked in")
+	}
+
+	if err := undoCheckIn(app, item); err != nil {
+		log.Printf("[CheckIn] Failed to undo check-in for item %s: %v", item.Id, err)
+		return utils.InternalErrorResponse(re, "Failed to undo check-in")
+	}
+
+	utils.LogFromRequest(app, re, "check_in_undo", utils.CollectionGuestListItems, item.Id, "success",
+		map[string]any{"guest_list": id}, "")
+
+	return utils.DataResponse(re, buildCheckInItemResponse(item))
+}
+
+// handlePublicCheckInQR serves a guest's QR code image for their confirmation email
+func handlePublicCheckInQR(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	code := re.Request.PathValue("code")
+	if _, ok := parseCheckInCode(code); !ok {
+		return utils.NotFoundResponse(re, "Not found")
+	}
+
+	png, err := checkInQRPNG(code)
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Failed to render code")
+	}
+
+	re.Response.Header().Set("Content-Type", "image/png")
+	re.Response.Header().Set("Cache-Control", "public, max-age=86400")
+	re.Response.WriteHeader(http.StatusOK)
+	re.Response.Write(png)
+	return nil
+}
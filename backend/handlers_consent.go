@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// buildConsentResponse lists every category with the contact's current answer
+// ("unknown" when they've never given one)
+func buildConsentResponse(contact *core.Record) map[string]any {
+	states := contactConsent(contact)
+	categories := make(map[string]any, len(consentCategories))
+	for _, category := range consentCategories {
+		state, ok := states[category]
+		if !ok {
+			state = consentState{Status: "unknown"}
+		}
+		categories[category] = state
+	}
+	return categories
+}
+
+// handleContactConsentGet returns a contact's consent and its change history (newest first)
+func handleContactConsentGet(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Contact ID required")
+	}
+
+	contact, err := app.FindRecordById(utils.CollectionContacts, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Contact not found")
+	}
+
+	events, err := app.FindRecordsByFilter(utils.CollectionConsentEvents, "contact = {:id}", "-created", 100, 0, dbx.Params{"id": id})
+	if err != nil {
+		events = nil
+	}
+
+	history := make([]map[string]any, 0, len(events))
+	for _, e := range events {
+		history = append(history, map[string]any{
+			"id":          e.Id,
+			"category":    e.GetString("category"),
+			"status":      e.GetString("status"),
+			"source":      e.GetString("source"),
+			"form":        e.GetString("form"),
+			"ip_address":  e.GetString("ip_address"),
+			"user_agent":  e.GetString("user_agent"),
+			"recorded_by": e.GetString("recorded_by"),
+			"created":     e.GetString("created"),
+		})
+	}
+
+	return utils.DataResponse(re, map[string]any{
+		"consent": buildConsentResponse(contact),
+		"history": history,
+	})
+}
+
+// handleContactConsentUpdate records consent changes made by an admin,
+// e.g. {"marketing": false, "event_invites": true}
+func handleContactConsentUpdate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Contact ID required")
+	}
+
+	contact, err := app.FindRecordById(utils.CollectionContacts, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Contact not found")
+	}
+
+	var changes map[string]bool
+	if err := json.NewDecoder(re.Request.Body).Decode(&changes); err != nil {
+		return utils.BadRequestResponse(re, "Body must be a JSON object of category -> true/false")
+	}
+	if err := validateConsentInput(changes); err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+
+	applied, err := updateContactConsent(app, contact, changes, consentProofFromRequest(re, "admin", "crm_admin"))
+	if err != nil {
+		log.Printf("[Consent] Failed to update contact %s: %v", id, err)
+		return utils.InternalErrorResponse(re, "Failed to update consent")
+	}
+
+	if len(applied) > 0 {
+		utils.LogFromRequest(app, re, "consent_update", utils.CollectionContacts, id, "success",
+			map[string]any{"changes": applied}, "")
+	}
+
+	return utils.DataResponse(re, map[string]any{
+		"consent": buildConsentResponse(contact),
+		"changed": applied,
+	})
+}
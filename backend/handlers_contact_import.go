@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func buildContactImportResponse(r *core.Record) map[string]any {
+	headers, mapping, options := contactImportSettings(r)
+	return map[string]any{
+		"id":                r.Id,
+		"filename":          r.GetString("filename"),
+		"status":            r.GetString("status"),
+		"headers":           headers,
+		"row_count":         r.GetInt("row_count"),
+		"mapping":           mapping,
+		"options":           options,
+		"summary":           r.Get("summary"),
+		"records_processed": r.GetInt("records_processed"),
+		"records_created":   r.GetInt("records_created"),
+		"records_updated":   r.GetInt("records_updated"),
+		"records_skipped":   r.GetInt("records_skipped"),
+		"orgs_created":      r.GetInt("orgs_created"),
+		"errors":            r.Get("errors"),
+		"created_by":        r.GetString("created_by"),
+		"started_at":        r.GetString("started_at"),
+		"completed_at":      r.GetString("completed_at"),
+		"created":           r.GetString("created"),
+	}
+}
+
+// contactImportTargets lists the fields a column can be mapped to, including custom fields
+func contactImportTargets(app core.App) []map[string]string {
+	targets := make([]map[string]string, 0, len(contactImportFields))
+	for _, f := range contactImportFields {
+		targets = append(targets, map[string]string{"key": f.Key, "label": f.Label})
+	}
+	for _, def := range sortedCustomFieldDefinitions(app, "contact") {
+		targets = append(targets, map[string]string{"key": "custom_fields." + def.Key, "label": def.Label})
+	}
+	return targets
+}
+
+// previewContactImport runs the dry run for a draft import, stores the summary and
+// builds the response
+func previewContactImport(re *core.RequestEvent, app *pocketbase.PocketBase, record *core.Record) error {
+	rows, err := decodeContactImportRows(record)
+	if err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+	headers, mapping, options := contactImportSettings(record)
+	defs := loadCustomFieldDefinitions(app, "contact")
+
+	response := buildContactImportResponse(record)
+	response["fields"] = contactImportTargets(app)
+
+	// Without a usable mapping, return the suggestion so the UI can start from it
+	if err := validateContactImportMapping(headers, mapping, defs); err != nil {
+		response["mapping_error"] = err.Error()
+		return utils.DataResponse(re, response)
+	}
+
+	summary, plans, err := dryRunContactImport(app, record.Id, headers, rows, mapping, options)
+	if err != nil {
+		return utils.InternalErrorResponse(re, err.Error())
+	}
+
+	record.Set("summary", summary)
+	if err := app.Save(record); err != nil {
+		log.Printf("[ContactImport] Failed to save summary: %v", err)
+	}
+
+	response["summary"] = summary
+	response["rows"] = plans
+	response["rows_truncated"] = summary.Total > len(plans)
+	return utils.DataResponse(re, response)
+}
+
+// handleContactImportCreate uploads a CSV (multipart "file"), suggests a column mapping
+// and returns a dry run. Optional "mapping" and "options" form values are JSON.
+func handleContactImportCreate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	if err := re.Request.ParseMultipartForm(10 << 20); err != nil {
+		return utils.BadRequestResponse(re, "Failed to parse form data")
+	}
+
+	file, header, err := re.Request.FormFile("file")
+	if err != nil {
+		return utils.BadRequestResponse(re, "CSV file is required")
+	}
+	defer file.Close()
+
+	headers, rows, err := parseContactImportCSV(csv.NewReader(file))
+	if err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+
+	mapping := suggestContactImportMapping(headers, loadCustomFieldDefinitions(app, "contact"))
+	if raw := re.Request.FormValue("mapping"); raw != "" {
+		mapping = map[string]string{}
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			return utils.BadRequestResponse(re, "mapping must be a JSON object of column -> field")
+		}
+	}
+	options := defaultContactImportOptions()
+	if raw := re.Request.FormValue("options"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &options); err != nil {
+			return utils.BadRequestResponse(re, "options must be a JSON object")
+		}
+	}
+
+	encoded, err := encodeContactImportRows(rows)
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Failed to store import data")
+	}
+
+	collection, err := app.FindCollectionByNameOrId(utils.CollectionContactImports)
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Collection not found")
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("filename", header.Filename)
+	record.Set("status", "draft")
+	record.Set("headers", headers)
+	record.Set("rows", encoded)
+	record.Set("row_count", len(rows))
+	record.Set("mapping", mapping)
+	record.Set("options", options)
+	if re.Auth != nil {
+		record.Set("created_by", re.Auth.GetString("email"))
+	}
+	if err := app.Save(record); err != nil {
+		log.Printf("[ContactImport] Failed to create import: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to create import")
+	}
+
+	utils.LogFromRequest(app, re, "create", utils.CollectionContactImports, record.Id, "success",
+		map[string]any{"filename": header.Filename, "rows": len(rows)}, "")
+
+	return previewContactImport(re, app, record)
+}
+
+// handleContactImportPreview updates the mapping/options of a draft import and re-runs the dry run
+func handleContactImportPreview(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Import ID required")
+	}
+
+	record, err := app.FindRecordById(utils.CollectionContactImports, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Import not found")
+	}
+	if record.GetString("status") != "draft" {
+		return utils.BadRequestResponse(re, "Import has already been run")
+	}
+
+	var input struct {
+		Mapping map[string]string `json:"mapping"`
+		Options *json.RawMessage  `json:"options"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+
+	if input.Mapping != nil {
+		record.Set("mapping", input.Mapping)
+	}
+	if input.Options != nil {
+		_, _, options := contactImportSettings(record)
+		if err := json.Unmarshal(*input.Options, &options); err != nil {
+			return utils.BadRequestResponse(re, "options must be a JSON object")
+		}
+		record.Set("options", options)
+	}
+
+	return previewContactImport(re, app, record)
+}
+
+// handleContactImportRun starts a draft import as a background job
+func handleContactImportRun(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Import ID required")
+	}
+
+	record, err := app.FindRecordById(utils.CollectionContactImports, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Import not found")
+	}
+	if record.GetString("status") != "draft" {
+		return utils.BadRequestResponse(re, "Import has already been run")
+	}
+
+	headers, mapping, options := contactImportSettings(record)
+	if err := validateContactImportMapping(headers, mapping, loadCustomFieldDefinitions(app, "contact")); err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+
+	// Claim it with a conditional update so a double-submit can't run the import twice
+	res, err := app.DB().Update(utils.CollectionContactImports,
+		dbx.Params{"status": "running", "started_at": types.NowDateTime().String()},
+		dbx.HashExp{"id": record.Id, "status": "draft"}).Execute()
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Failed to start import")
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return utils.BadRequestResponse(re, "Import has already been run")
+	}
+	if record, err = app.FindRecordById(utils.CollectionContactImports, id); err != nil {
+		return utils.InternalErrorResponse(re, "Failed to start import")
+	}
+
+	utils.LogFromRequest(app, re, "contact_import_run", utils.CollectionContactImports, record.Id, "success",
+		map[string]any{"mapping": mapping, "options": options, "rows": record.GetInt("row_count")}, "")
+
+	go runContactImport(app, record.Id)
+
+	return utils.DataResponse(re, buildContactImportResponse(record))
+}
+
+// handleContactImportsList returns recent imports (newest first)
+func handleContactImportsList(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	records, err := app.FindRecordsByFilter(utils.CollectionContactImports, "", "-created", 50, 0, nil)
+	if err != nil {
+		return utils.DataResponse(re, map[string]any{"items": []any{}})
+	}
+
+	items := make([]map[string]any, 0, len(records))
+	for _, r := range records {
+		items = append(items, buildContactImportResponse(r))
+	}
+
+	return utils.DataResponse(re, map[string]any{"items": items})
+}
+
+// handleContactImportGet returns an import's state — poll this for progress while running
+func handleContactImportGet(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Import ID required")
+	}
+
+	record, err := app.FindRecordById(utils.CollectionContactImports, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Import not found")
+	}
+
+	return utils.DataResponse(re, buildContactImportResponse(record))
+}
+
+// handleContactImportDelete discards an import that hasn't been run
+func handleContactImportDelete(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Import ID required")
+	}
+
+	record, err := app.FindRecordById(utils.CollectionContactImports, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Import not found")
+	}
+	if record.GetString("status") == "running" {
+		return utils.BadRequestResponse(re, "Import is still running")
+	}
+
+	if err := app.Delete(record); err != nil {
+		return utils.InternalErrorResponse(re, "Failed to delete import")
+	}
+
+	utils.LogFromRequest(app, re, "delete", utils.CollectionContactImports, id, "success", nil, "")
+
+	return utils.SuccessResponse(re, "Import deleted")
+}
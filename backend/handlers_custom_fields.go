@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+func buildCustomFieldDefinitionResponse(r *core.Record) map[string]any {
+	def := newCustomFieldDefinition(r)
+	if def.Options == nil {
+		def.Options = []string{}
+	}
+	return map[string]any{
+		"id":         def.ID,
+		"entity":     def.Entity,
+		"key":        def.Key,
+		"label":      def.Label,
+		"type":       def.Type,
+		"options":    def.Options,
+		"required":   def.Required,
+		"archived":   def.Archived,
+		"sort_order": r.GetInt("sort_order"),
+		"created":    r.GetString("created"),
+		"updated":    r.GetString("updated"),
+	}
+}
+
+// handleCustomFieldsList returns custom field definitions, optionally filtered by entity
+func handleCustomFieldsList(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	filter := ""
+	params := map[string]any{}
+	if entity := re.Request.URL.Query().Get("entity"); entity != "" {
+		filter = "entity = {:entity}"
+		params["entity"] = entity
+	}
+
+	records, err := app.FindRecordsByFilter(utils.CollectionCustomFieldDefinitions, filter, "entity,sort_order,label", 0, 0, params)
+	if err != nil {
+		return utils.DataResponse(re, map[string]any{"items": []any{}})
+	}
+
+	items := make([]map[string]any, 0, len(records))
+	for _, r := range records {
+		items = append(items, buildCustomFieldDefinitionResponse(r))
+	}
+
+	return utils.DataResponse(re, map[string]any{"items": items})
+}
+
+// handleCustomFieldCreate defines a new custom field on contacts or organisations
+func handleCustomFieldCreate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	var input struct {
+		Entity    string   `json:"entity"`
+		Key       string   `json:"key"`
+		Label     string   `json:"label"`
+		Type      string   `json:"type"`
+		Options   []string `json:"options"`
+		Required  bool     `json:"required"`
+		SortOrder int      `json:"sort_order"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+
+	input.Key = strings.TrimSpace(input.Key)
+	input.Label = strings.TrimSpace(input.Label)
+	if input.Entity != "contact" && input.Entity != "organisation" {
+		return utils.BadRequestResponse(re, "entity must be 'contact' or 'organisation'")
+	}
+	if !customFieldKeyPattern.MatchString(input.Key) {
+		return utils.BadRequestResponse(re, "key must be lowercase letters, numbers and underscores, starting with a letter")
+	}
+	if input.Label == "" {
+		return utils.BadRequestResponse(re, "label is required")
+	}
+	switch input.Type {
+	case "text", "number", "date", "boolean":
+	case "select", "multi_select":
+		if len(input.Options) == 0 {
+			return utils.BadRequestResponse(re, "options are required for select fields")
+		}
+	default:
+		return utils.BadRequestResponse(re, "type must be one of: text, number, date, select, multi_select, boolean")
+	}
+
+	collection, err := app.FindCollectionByNameOrId(utils.CollectionCustomFieldDefinitions)
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Collection not found")
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("entity", input.Entity)
+	record.Set("key", input.Key)
+	record.Set("label", input.Label)
+	record.Set("type", input.Type)
+	record.Set("options", input.Options)
+	record.Set("required", input.Required)
+	record.Set("sort_order", input.SortOrder)
+
+	if err := app.Save(record); err != nil {
+		log.Printf("[CustomFieldCreate] Failed to save: %v", err)
+		return utils.BadRequestResponse(re, "Failed to create custom field (key must be unique)")
+	}
+
+	utils.LogFromRequest(app, re, "create", utils.CollectionCustomFieldDefinitions, record.Id, "success",
+		map[string]any{"entity": input.Entity, "key": input.Key, "type": input.Type}, "")
+
+	return utils.DataResponse(re, buildCustomFieldDefinitionResponse(record))
+}
+
+// handleCustomFieldUpdate updates a definition. Key, entity and type are immutable
+// because existing values are stored against them.
+func handleCustomFieldUpdate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Custom field ID required")
+	}
+
+	record, err := app.FindRecordById(utils.CollectionCustomFieldDefinitions, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Custom field not found")
+	}
+
+	var input map[string]any
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+
+	if v, ok := input["label"].(string); ok {
+		if strings.TrimSpace(v) == "" {
+			return utils.BadRequestResponse(re, "label is required")
+		}
+		record.Set("label", strings.TrimSpace(v))
+	}
+	if v, ok := input["options"].([]any); ok {
+		t := record.GetString("type")
+		if (t == "select" || t == "multi_select") && len(v) == 0 {
+			return utils.BadRequestResponse(re, "options are required for select fields")
+		}
+		record.Set("options", v)
+	}
+	if v, ok := input["required"].(bool); ok {
+		record.Set("required", v)
+	}
+	if v, ok := input["archived"].(bool); ok {
+		record.Set("archived", v)
+	}
+	if v, ok := input["sort_order"].(float64); ok {
+		record.Set("sort_order", int(v))
+	}
+
+	if err := app.Save(record); err != nil {
+		log.Printf("[CustomFieldUpdate] Failed to save: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to update custom field")
+	}
+
+	utils.LogFromRequest(app, re, "update", utils.CollectionCustomFieldDefinitions, record.Id, "success", input, "")
+
+	return utils.DataResponse(re, buildCustomFieldDefinitionResponse(record))
+}
+
+// handleCustomFieldDelete removes a definition and strips its values from every record
+func handleCustomFieldDelete(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Custom field ID required")
+	}
+
+	record, err := app.FindRecordById(utils.CollectionCustomFieldDefinitions, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Custom field not found")
+	}
+
+	key := record.GetString("key")
+	collection := utils.CollectionContacts
+	if record.GetString("entity") == "organisation" {
+		collection = utils.CollectionOrganisations
+	}
+
+	stripped := 0
+	err = app.RunInTransaction(func(txApp core.App) error {
+		records, err := txApp.FindRecordsByFilter(collection, "custom_fields."+key+" != null", "", 0, 0, nil)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			values := recordCustomFields(r)
+			delete(values, key)
+			r.Set("custom_fields", values)
+			if collection == utils.CollectionContacts {
+				// Decrypt PII before save so encryption hooks re-encrypt (and validation passes)
+				for _, field := range []string{"email", "personal_email", "phone", "bio", "location"} {
+					if v := r.GetString(field); v != "" {
+						r.Set(field, utils.DecryptField(v))
+					}
+				}
+			}
+			if err := txApp.Save(r); err != nil {
+				return err
+			}
+			stripped++
+		}
+		return txApp.Delete(record)
+	})
+	if err != nil {
+		log.Printf("[CustomFieldDelete] Failed: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to delete custom field")
+	}
+
+	utils.LogFromRequest(app, re, "delete", utils.CollectionCustomFieldDefinitions, id, "success",
+		map[string]any{"key": key, "records_updated": stripped}, "")
+
+	return utils.SuccessResponse(re, "Custom field deleted successfully")
+}
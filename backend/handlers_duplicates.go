@@ -0,0 +1,134 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// handleDuplicatesList returns the duplicate review queue, highest score first.
+// Each item includes both contacts in full so the frontend can open the
+// existing merge dialog and submit to POST /api/contacts/merge.
+func handleDuplicatesList(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	page, _ := strconv.Atoi(re.Request.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(re.Request.URL.Query().Get("perPage"))
+	if perPage < 1 || perPage > 100 {
+		perPage = 25
+	}
+	status := re.Request.URL.Query().Get("status")
+	if status == "" {
+		status = "pending"
+	}
+
+	filter := "status = {:status}"
+	params := map[string]any{"status": status}
+
+	totalItems, _ := app.CountRecords(utils.CollectionDuplicateCandidates, dbx.HashExp{"status": status})
+
+	offset := (page - 1) * perPage
+	records, err := app.FindRecordsByFilter(utils.CollectionDuplicateCandidates, filter, "-score,-created", perPage, offset, params)
+	if err != nil {
+		return utils.DataResponse(re, map[string]any{
+			"items":      []any{},
+			"page":       page,
+			"perPage":    perPage,
+			"totalItems": 0,
+			"totalPages": 0,
+		})
+	}
+
+	baseURL := getBaseURL()
+	items := make([]map[string]any, 0, len(records))
+	for _, r := range records {
+		contactA, errA := app.FindRecordById(utils.CollectionContacts, r.GetString("contact_a"))
+		contactB, errB := app.FindRecordById(utils.CollectionContacts, r.GetString("contact_b"))
+		if errA != nil || errB != nil {
+			continue // Cascade delete normally removes these, skip any stragglers
+		}
+		items = append(items, map[string]any{
+			"id":          r.Id,
+			"score":       r.GetFloat("score"),
+			"reasons":     r.Get("reasons"),
+			"status":      r.GetString("status"),
+			"reviewed_by": r.GetString("reviewed_by"),
+			"reviewed_at": r.GetString("reviewed_at"),
+			"contact_a":   buildContactResponse(contactA, app, baseURL),
+			"contact_b":   buildContactResponse(contactB, app, baseURL),
+			"created":     r.GetString("created"),
+		})
+	}
+
+	totalPages := (int(totalItems) + perPage - 1) / perPage
+
+	return utils.DataResponse(re, map[string]any{
+		"items":      items,
+		"page":       page,
+		"perPage":    perPage,
+		"totalItems": totalItems,
+		"totalPages": totalPages,
+	})
+}
+
+// handleDuplicateDismiss marks a candidate pair as not-a-duplicate.
+// Dismissed pairs are kept so later scans don't propose them again.
+func handleDuplicateDismiss(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Candidate ID required")
+	}
+
+	record, err := app.FindRecordById(utils.CollectionDuplicateCandidates, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Duplicate candidate not found")
+	}
+
+	record.Set("status", "dismissed")
+	record.Set("reviewed_at", time.Now().UTC().Format(time.RFC3339))
+	if re.Auth != nil {
+		record.Set("reviewed_by", re.Auth.GetString("email"))
+	}
+
+	if err := app.Save(record); err != nil {
+		log.Printf("[DuplicateDismiss] Failed to save: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to dismiss candidate")
+	}
+
+	utils.LogFromRequest(app, re, "duplicate_dismiss", utils.CollectionDuplicateCandidates, id, "success",
+		map[string]any{
+			"contact_a": record.GetString("contact_a"),
+			"contact_b": record.GetString("contact_b"),
+		}, "")
+
+	return utils.SuccessResponse(re, "Duplicate dismissed")
+}
+
+// handleDuplicatesScan triggers a full duplicate scan in the background.
+// Returns 409 while another scan (manual or nightly) is still running.
+func handleDuplicatesScan(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	// Claim the scan here rather than in the goroutine so a clash can be reported
+	if !duplicateScanRunning.CompareAndSwap(false, true) {
+		return re.JSON(http.StatusConflict, map[string]string{"error": "A duplicate scan is already running"})
+	}
+
+	go func() {
+		defer duplicateScanRunning.Store(false)
+		if _, err := scanDuplicates(app); err != nil {
+			log.Printf("[Duplicates] Scan failed: %v", err)
+		}
+	}()
+
+	utils.LogFromRequest(app, re, "duplicate_scan", utils.CollectionDuplicateCandidates, "", "success", nil, "")
+
+	return utils.DataResponse(re, map[string]any{
+		"status": "started",
+	})
+}
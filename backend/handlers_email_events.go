@@ -0,0 +1,171 @@
+package main
+
+import (
+	"math"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// emailTimelineLimit caps the events returned for one contact
+const emailTimelineLimit = 200
+
+func buildEmailEventResponse(r *core.Record, listNames map[string]string) map[string]any {
+	return map[string]any{
+		"id":              r.Id,
+		"type":            r.GetString("type"),
+		"kind":            r.GetString("kind"),
+		"subject":         r.GetString("subject"),
+		"message":         r.GetString("message"),
+		"guest_list":      r.GetString("guest_list"),
+		"guest_list_name": listNames[r.GetString("guest_list")],
+		"guest_list_item": r.GetString("guest_list_item"),
+		"url":             r.GetString("url"),
+		"detail":          r.GetString("detail"),
+		"created":         r.GetString("created"),
+	}
+}
+
+// handleContactEmailEvents returns a contact's email timeline, newest first.
+// Filter: guest_list.
+func handleContactEmailEvents(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if _, err := app.FindRecordById(utils.CollectionContacts, id); err != nil {
+		return utils.NotFoundResponse(re, "Contact not found")
+	}
+
+	filter := "contact = {:id}"
+	params := dbx.Params{"id": id}
+	if gl := re.Request.URL.Query().Get("guest_list"); gl != "" {
+		filter += " && guest_list = {:guestList}"
+		params["guestList"] = gl
+	}
+
+	records, err := app.FindRecordsByFilter(utils.CollectionEmailEvents, filter, "-created", emailTimelineLimit, 0, params)
+	if err != nil {
+		return utils.DataResponse(re, []any{})
+	}
+
+	listNames := map[string]string{}
+	for _, r := range records {
+		glID := r.GetString("guest_list")
+		if _, ok := listNames[glID]; ok || glID == "" {
+			continue
+		}
+		if gl, err := app.FindRecordById(utils.CollectionGuestLists, glID); err == nil {
+			listNames[glID] = gl.GetString("name")
+		} else {
+			listNames[glID] = ""
+		}
+	}
+
+	items := make([]map[string]any, len(records))
+	for i, r := range records {
+		items[i] = buildEmailEventResponse(r, listNames)
+	}
+	return utils.DataResponse(re, items)
+}
+
+type emailEventCount struct {
+	Kind       string `db:"kind"`
+	Type       string `db:"type"`
+	Events     int    `db:"events"`
+	Recipients int    `db:"recipients"`
+}
+
+// emailEventRate is recipients over sent, to three decimal places
+func emailEventRate(n, sent int) float64 {
+	if sent == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(sent)*1000) / 1000
+}
+
+// handleGuestListEmailStats totals a guest list's email events: per type, per email kind,
+// and as open, click, RSVP and bounce rates against the guests sent to. Recipients are
+// distinct guest list items, so a guest opening three times counts once.
+func handleGuestListEmailStats(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if _, err := app.FindRecordById(utils.CollectionGuestLists, id); err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+	params := dbx.Params{"id": id}
+
+	var byKind []emailEventCount
+	err := app.DB().NewQuery(`
+		SELECT kind, type, COUNT(*) AS events, COUNT(DISTINCT guest_list_item) AS recipients
+		FROM email_events
+		WHERE guest_list = {:id}
+		GROUP BY kind, type
+	`).Bind(params).All(&byKind)
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Failed to load email stats")
+	}
+
+	// Recipients are counted again across kinds: one guest can get an invite and a follow-up
+	var byType []emailEventCount
+	err = app.DB().NewQuery(`
+		SELECT '' AS kind, type, COUNT(*) AS events, COUNT(DISTINCT guest_list_item) AS recipients
+		FROM email_events
+		WHERE guest_list = {:id}
+		GROUP BY type
+	`).Bind(params).All(&byType)
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Failed to load email stats")
+	}
+
+	// RSVPs that followed an email, as opposed to the public link
+	var converted int
+	err = app.DB().NewQuery(`
+		SELECT COUNT(DISTINCT guest_list_item)
+		FROM email_events
+		WHERE guest_list = {:id} AND type = 'rsvp' AND kind != ''
+	`).Bind(params).Row(&converted)
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Failed to load email stats")
+	}
+
+	emptyTotals := func() map[string]any {
+		totals := map[string]any{}
+		for _, t := range emailEventTypes {
+			totals[t] = map[string]int{"events": 0, "recipients": 0}
+		}
+		return totals
+	}
+
+	totals := emptyTotals()
+	recipients := map[string]int{}
+	for _, c := range byType {
+		totals[c.Type] = map[string]int{"events": c.Events, "recipients": c.Recipients}
+		recipients[c.Type] = c.Recipients
+	}
+
+	kinds := map[string]any{}
+	for _, c := range byKind {
+		if c.Kind == "" {
+			continue // RSVPs through the public link
+		}
+		k, ok := kinds[c.Kind].(map[string]any)
+		if !ok {
+			k = emptyTotals()
+			kinds[c.Kind] = k
+		}
+		k[c.Type] = map[string]int{"events": c.Events, "recipients": c.Recipients}
+	}
+
+	sent := recipients[emailEventSent]
+	return utils.DataResponse(re, map[string]any{
+		"guest_list":     id,
+		"totals":         totals,
+		"by_kind":        kinds,
+		"rsvp_converted": converted,
+		"rates": map[string]float64{
+			"open":   emailEventRate(recipients[emailEventOpened], sent),
+			"click":  emailEventRate(recipients[emailEventClicked], sent),
+			"rsvp":   emailEventRate(converted, sent),
+			"bounce": emailEventRate(recipients[emailEventBounced], sent),
+		},
+	})
+}
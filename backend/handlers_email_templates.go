@@ -0,0 +1,412 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+var errEmailTemplateEmpty = errors.New("subject and html are required")
+
+func buildEmailTemplateResponse(r *core.Record) map[string]any {
+	return map[string]any{
+		"id":         r.Id,
+		"key":        r.GetString("key"),
+		"name":       r.GetString("name"),
+		"guest_list": r.GetString("guest_list"),
+		"theme":      r.GetString("theme"),
+		"subject":    r.GetString("subject"),
+		"html":       r.GetString("html"),
+		"text":       r.GetString("text"),
+		"version":    r.GetInt("version"),
+		"active":     r.GetBool("active"),
+		"created_by": r.GetString("created_by"),
+		"created":    r.GetString("created"),
+		"updated":    r.GetString("updated"),
+	}
+}
+
+func buildEmailTemplateVersionResponse(r *core.Record) map[string]any {
+	return map[string]any{
+		"id":         r.Id,
+		"template":   r.GetString("template"),
+		"key":        r.GetString("key"),
+		"version":    r.GetInt("version"),
+		"subject":    r.GetString("subject"),
+		"html":       r.GetString("html"),
+		"text":       r.GetString("text"),
+		"created_by": r.GetString("created_by"),
+		"created":    r.GetString("created"),
+	}
+}
+
+// handleEmailTemplateKeys returns each templatable email with its built-in copy and the
+// variables it can use, for starting a new override
+func handleEmailTemplateKeys(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	items := make([]map[string]any, 0, len(emailTemplateKeys))
+	for _, key := range emailTemplateKeys {
+		src := defaultEmailTemplates[key]
+		items = append(items, map[string]any{
+			"key":       key,
+			"label":     src.Label,
+			"subject":   src.Subject,
+			"html":      strings.TrimSpace(src.HTML),
+			"text":      strings.TrimSpace(src.Text),
+			"rsvp":      src.RSVP,
+			"variables": emailTemplateVariables[key],
+		})
+	}
+	return utils.DataResponse(re, map[string]any{"items": items})
+}
+
+// handleEmailTemplatesList returns the overrides. Filters: key, guest_list, theme.
+func handleEmailTemplatesList(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	query := re.Request.URL.Query()
+	filters := []string{}
+	params := dbx.Params{}
+	for _, key := range []string{"key", "guest_list", "theme"} {
+		if v := query.Get(key); v != "" {
+			filters = append(filters, key+" = {:"+key+"}")
+			params[key] = v
+		}
+	}
+
+	records, err := app.FindRecordsByFilter(utils.CollectionEmailTemplates, strings.Join(filters, " && "), "key,-updated", 0, 0, params)
+	if err != nil {
+		return utils.DataResponse(re, map[string]any{"items": []any{}})
+	}
+
+	items := make([]map[string]any, 0, len(records))
+	for _, r := range records {
+		items = append(items, buildEmailTemplateResponse(r))
+	}
+	return utils.DataResponse(re, map[string]any{"items": items})
+}
+
+// handleEmailTemplateGet returns a template with its version history (newest first)
+func handleEmailTemplateGet(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	record, err := app.FindRecordById(utils.CollectionEmailTemplates, re.Request.PathValue("id"))
+	if err != nil {
+		return utils.NotFoundResponse(re, "Template not found")
+	}
+
+	data := buildEmailTemplateResponse(record)
+	versions := []map[string]any{}
+	records, _ := app.FindRecordsByFilter(utils.CollectionEmailTemplateVersions,
+		"template = {:id}", "-version", 0, 0, dbx.Params{"id": record.Id})
+	for _, v := range records {
+		entry := buildEmailTemplateVersionResponse(v)
+		delete(entry, "html")
+		delete(entry, "text")
+		versions = append(versions, entry)
+	}
+	data["versions"] = versions
+
+	return utils.DataResponse(re, data)
+}
+
+// handleEmailTemplateVersionGet returns one version's content, e.g. to see what a message
+// in the outbox was rendered from
+func handleEmailTemplateVersionGet(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	record, err := app.FindRecordById(utils.CollectionEmailTemplateVersions, re.Request.PathValue("id"))
+	if err != nil {
+		return utils.NotFoundResponse(re, "Version not found")
+	}
+	return utils.DataResponse(re, buildEmailTemplateVersionResponse(record))
+}
+
+// handleEmailTemplateCreate saves a new override. New templates are active unless
+// "active": false is passed; activating one deactivates the others for the same email and scope.
+func handleEmailTemplateCreate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	var input struct {
+		Key       string `json:"key"`
+		Name      string `json:"name"`
+		GuestList string `json:"guest_list"`
+		Theme     string `json:"theme"`
+		Subject   string `json:"subject"`
+		HTML      string `json:"html"`
+		Text      string `json:"text"`
+		Active    *bool  `json:"active"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+
+	if !slices.Contains(emailTemplateKeys, input.Key) {
+		return utils.BadRequestResponse(re, "Unknown template key")
+	}
+	if input.GuestList != "" && input.Theme != "" {
+		return utils.BadRequestResponse(re, "A template can be scoped to a guest list or a theme, not both")
+	}
+	src := emailTemplateSource{Subject: input.Subject, HTML: input.HTML, Text: input.Text}
+	if err := validateEmailTemplate(app, input.Key, input.GuestList, src); err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+
+	collection, err := app.FindCollectionByNameOrId(utils.CollectionEmailTemplates)
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Collection not found")
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("key", input.Key)
+	record.Set("name", strings.TrimSpace(input.Name))
+	record.Set("guest_list", input.GuestList)
+	record.Set("theme", input.Theme)
+	record.Set("subject", input.Subject)
+	record.Set("html", input.HTML)
+	record.Set("text", input.Text)
+	record.Set("active", input.Active == nil || *input.Active)
+	createdBy := ""
+	if re.Auth != nil {
+		createdBy = re.Auth.GetString("email")
+	}
+	record.Set("created_by", createdBy)
+
+	if err := saveEmailTemplate(app, record, true, createdBy); err != nil {
+		log.Printf("[EmailTemplateCreate] Failed to save: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to create template")
+	}
+
+	utils.LogFromRequest(app, re, "create", utils.CollectionEmailTemplates, record.Id, "success",
+		map[string]any{"key": input.Key, "guest_list": input.GuestList, "theme": input.Theme}, "")
+
+	return utils.DataResponse(re, buildEmailTemplateResponse(record))
+}
+
+// handleEmailTemplateUpdate updates a template. Changing the subject, HTML or text creates a
+// new version; name and active don't.
+func handleEmailTemplateUpdate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	record, err := app.FindRecordById(utils.CollectionEmailTemplates, re.Request.PathValue("id"))
+	if err != nil {
+		return utils.NotFoundResponse(re, "Template not found")
+	}
+
+	var input map[string]any
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+
+	src := emailTemplateSource{
+		Subject: record.GetString("subject"),
+		HTML:    record.GetString("html"),
+		Text:    record.GetString("text"),
+	}
+	if v, ok := input["subject"].(string); ok {
+		src.Subject = v
+	}
+	if v, ok := input["html"].(string); ok {
+		src.HTML = v
+	}
+	if v, ok := input["text"].(string); ok {
+		src.Text = v
+	}
+	contentChanged := src.Subject != record.GetString("subject") ||
+		src.HTML != record.GetString("html") ||
+		src.Text != record.GetString("text")
+	if contentChanged {
+		if err := validateEmailTemplate(app, record.GetString("key"), record.GetString("guest_list"), src); err != nil {
+			return utils.BadRequestResponse(re, err.Error())
+		}
+		record.Set("subject", src.Subject)
+		record.Set("html", src.HTML)
+		record.Set("text", src.Text)
+	}
+	if v, ok := input["name"].(string); ok {
+		record.Set("name", strings.TrimSpace(v))
+	}
+	if v, ok := input["active"].(bool); ok {
+		record.Set("active", v)
+	}
+
+	createdBy := ""
+	if re.Auth != nil {
+		createdBy = re.Auth.GetString("email")
+	}
+	if err := saveEmailTemplate(app, record, contentChanged, createdBy); err != nil {
+		log.Printf("[EmailTemplateUpdate] Failed to save: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to update template")
+	}
+
+	changes := map[string]any{"version": record.GetInt("version")}
+	for _, key := range []string{"name", "active"} {
+		if v, ok := input[key]; ok {
+			changes[key] = v
+		}
+	}
+	changes["content_changed"] = contentChanged
+	utils.LogFromRequest(app, re, "update", utils.CollectionEmailTemplates, record.Id, "success", changes, "")
+
+	return utils.DataResponse(re, buildEmailTemplateResponse(record))
+}
+
+// handleEmailTemplateDelete deletes an override; its versions are kept for the outbox history
+func handleEmailTemplateDelete(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	record, err := app.FindRecordById(utils.CollectionEmailTemplates, re.Request.PathValue("id"))
+	if err != nil {
+		return utils.NotFoundResponse(re, "Template not found")
+	}
+
+	if err := app.Delete(record); err != nil {
+		log.Printf("[EmailTemplateDelete] Failed to delete: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to delete template")
+	}
+
+	utils.LogFromRequest(app, re, "delete", utils.CollectionEmailTemplates, record.Id, "success",
+		map[string]any{"key": record.GetString("key"), "name": record.GetString("name")}, "")
+
+	return utils.SuccessResponse(re, "Template deleted successfully")
+}
+
+// handleEmailTemplatePreview renders an unsaved template with sample data (event details
+// from guest_list if given). Without subject/html it renders whatever currently applies to
+// that guest list, override or built-in. The plain-text part is returned alongside the HTML.
+func handleEmailTemplatePreview(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	var input struct {
+		Key       string `json:"key"`
+		GuestList string `json:"guest_list"`
+		Subject   string `json:"subject"`
+		HTML      string `json:"html"`
+		Text      string `json:"text"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+	if !slices.Contains(emailTemplateKeys, input.Key) {
+		return utils.BadRequestResponse(re, "Unknown template key")
+	}
+
+	theme, guestList := previewEmailTheme(app, input.GuestList)
+	data := sampleEmailTemplateData(input.Key, theme, guestList)
+
+	var rendered renderedEmail
+	if input.Subject == "" && input.HTML == "" {
+		r, err := renderEmailTemplate(app, input.Key, theme.GuestListID, theme.ThemeID, data)
+		if err != nil {
+			return utils.BadRequestResponse(re, err.Error())
+		}
+		rendered = r
+	} else {
+		r, err := executeEmailTemplate(emailTemplateSource{Subject: input.Subject, HTML: input.HTML, Text: input.Text}, data)
+		if err != nil {
+			return utils.BadRequestResponse(re, err.Error())
+		}
+		rendered = r
+	}
+
+	return utils.DataResponse(re, map[string]any{
+		"subject":          rendered.Subject,
+		"html":             wrapTemplateHTML(input.Key, rendered.HTML, theme, ""),
+		"text":             wrapEmailText(rendered.Text, ""),
+		"template_version": rendered.TemplateVersion,
+	})
+}
+
+// handleEmailTemplatePreviewPage renders a saved template as a browser-viewable page,
+// like the public RSVP email preview. ?guest_list= supplies real event details.
+func handleEmailTemplatePreviewPage(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	record, err := app.FindRecordById(utils.CollectionEmailTemplates, re.Request.PathValue("id"))
+	if err != nil {
+		return utils.NotFoundResponse(re, "Template not found")
+	}
+
+	guestListID := re.Request.URL.Query().Get("guest_list")
+	if guestListID == "" {
+		guestListID = record.GetString("guest_list")
+	}
+	theme, guestList := previewEmailTheme(app, guestListID)
+
+	key := record.GetString("key")
+	rendered, err := executeEmailTemplate(emailTemplateSource{
+		Subject: record.GetString("subject"),
+		HTML:    record.GetString("html"),
+		Text:    record.GetString("text"),
+	}, sampleEmailTemplateData(key, theme, guestList))
+	if err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+
+	re.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	re.Response.WriteHeader(http.StatusOK)
+	re.Response.Write([]byte(wrapTemplateHTML(key, rendered.HTML, theme, "")))
+	return nil
+}
+
+// previewEmailTheme returns the email theme for a guest list, or the default theme
+func previewEmailTheme(app *pocketbase.PocketBase, guestListID string) (EmailTheme, *core.Record) {
+	if guestListID != "" {
+		if gl, err := app.FindRecordById(utils.CollectionGuestLists, guestListID); err == nil {
+			return buildEmailTheme(app, gl), gl
+		}
+	}
+	return defaultEmailTheme(), nil
+}
+
+// validateEmailTemplate checks a template parses and renders with sample data, which
+// catches unknown variables
+func validateEmailTemplate(app *pocketbase.PocketBase, key, guestListID string, src emailTemplateSource) error {
+	if strings.TrimSpace(src.Subject) == "" || strings.TrimSpace(src.HTML) == "" {
+		return errEmailTemplateEmpty
+	}
+	theme, guestList := previewEmailTheme(app, guestListID)
+	_, err := executeEmailTemplate(src, sampleEmailTemplateData(key, theme, guestList))
+	return err
+}
+
+// saveEmailTemplate saves a template, snapshotting a new version when its content changed
+// and deactivating other templates for the same email and scope when it's active
+func saveEmailTemplate(app *pocketbase.PocketBase, record *core.Record, contentChanged bool, createdBy string) error {
+	return app.RunInTransaction(func(txApp core.App) error {
+		if contentChanged {
+			record.Set("version", record.GetInt("version")+1)
+		}
+		if err := txApp.Save(record); err != nil {
+			return err
+		}
+
+		if record.GetBool("active") {
+			others, err := txApp.FindRecordsByFilter(utils.CollectionEmailTemplates,
+				"id != {:id} && key = {:key} && guest_list = {:guest_list} && theme = {:theme} && active = true", "", 0, 0,
+				dbx.Params{
+					"id":         record.Id,
+					"key":        record.GetString("key"),
+					"guest_list": record.GetString("guest_list"),
+					"theme":      record.GetString("theme"),
+				})
+			if err != nil {
+				return err
+			}
+			for _, other := range others {
+				other.Set("active", false)
+				if err := txApp.Save(other); err != nil {
+					return err
+				}
+			}
+		}
+
+		if !contentChanged {
+			return nil
+		}
+		collection, err := txApp.FindCollectionByNameOrId(utils.CollectionEmailTemplateVersions)
+		if err != nil {
+			return err
+		}
+		version := core.NewRecord(collection)
+		version.Set("template", record.Id)
+		version.Set("key", record.GetString("key"))
+		version.Set("version", record.GetInt("version"))
+		version.Set("subject", record.GetString("subject"))
+		version.Set("html", record.GetString("html"))
+		version.Set("text", record.GetString("text"))
+		version.Set("created_by", createdBy)
+		return txApp.Save(version)
+	})
+}
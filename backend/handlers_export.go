@@ -0,0 +1,521 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// exportBatchSize is how many records are loaded per query while streaming an export
+const exportBatchSize = 500
+
+// errExportColumnForbidden is returned when a non-admin requests a PII column
+var errExportColumnForbidden = errors.New("column is only available to admins")
+
+// exportColumn is a selectable export column. PII columns are only available to admins.
+type exportColumn struct {
+	Key    string
+	Header string
+	PII    bool
+}
+
+var contactExportColumns = []exportColumn{
+	{Key: "id", Header: "ID"},
+	{Key: "first_name", Header: "First name"},
+	{Key: "last_name", Header: "Last name"},
+	{Key: "preferred_name", Header: "Preferred name"},
+	{Key: "email", Header: "Email", PII: true},
+	{Key: "personal_email", Header: "Personal email", PII: true},
+	{Key: "phone", Header: "Phone", PII: true},
+	{Key: "pronouns", Header: "Pronouns"},
+	{Key: "job_title", Header: "Job title"},
+	{Key: "organisation", Header: "Organisation"},
+	{Key: "linkedin", Header: "LinkedIn"},
+	{Key: "instagram", Header: "Instagram"},
+	{Key: "website", Header: "Website"},
+	{Key: "location", Header: "Location", PII: true},
+	{Key: "bio", Header: "Bio", PII: true},
+	{Key: "tags", Header: "Tags"},
+	{Key: "roles", Header: "Roles"},
+	{Key: "status", Header: "Status"},
+	{Key: "source", Header: "Source"},
+	{Key: "degrees", Header: "Degrees"},
+	{Key: "relationship", Header: "Relationship"},
+	{Key: "dietary_requirements", Header: "Dietary requirements"},
+	{Key: "dietary_requirements_other", Header: "Dietary requirements (other)"},
+	{Key: "accessibility_requirements", Header: "Accessibility requirements"},
+	{Key: "accessibility_requirements_other", Header: "Accessibility requirements (other)"},
+	{Key: "created", Header: "Created"},
+	{Key: "updated", Header: "Updated"},
+}
+
+var organisationExportColumns = []exportColumn{
+	{Key: "id", Header: "ID"},
+	{Key: "name", Header: "Name"},
+	{Key: "website", Header: "Website"},
+	{Key: "linkedin", Header: "LinkedIn"},
+	{Key: "industry", Header: "Industry"},
+	{Key: "tags", Header: "Tags"},
+	{Key: "status", Header: "Status"},
+	{Key: "source", Header: "Source"},
+	{Key: "description_short", Header: "Description (short)"},
+	{Key: "created", Header: "Created"},
+	{Key: "updated", Header: "Updated"},
+}
+
+// guestListExportColumns come before one column per custom RSVP question
+var guestListExportColumns = []exportColumn{
+	{Key: "contact_name", Header: "Name"},
+	{Key: "contact_job_title", Header: "Job title"},
+	{Key: "contact_organisation_name", Header: "Organisation"},
+	{Key: "invite_round", Header: "Invite round"},
+	{Key: "invite_status", Header: "Invite status"},
+	{Key: "rsvp_status", Header: "RSVP"},
+	{Key: "rsvp_responded_at", Header: "Responded"},
+	{Key: "rsvp_plus_one", Header: "Plus-one"},
+	{Key: "rsvp_plus_one_name", Header: "Plus-one name"},
+	{Key: "rsvp_plus_one_dietary", Header: "Plus-one dietary requirements"},
+	{Key: "rsvp_comments", Header: "Comments"},
+	{Key: "checked_in_at", Header: "Checked in"},
+}
+
+// exportRowWriter is implemented by the CSV and XLSX output formats
+type exportRowWriter interface {
+	WriteRow(cells []string) error
+	Close() error
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) WriteRow(cells []string) error {
+	safe := make([]string, len(cells))
+	for i, cell := range cells {
+		safe[i] = utils.SpreadsheetSafeCell(cell)
+	}
+	return c.w.Write(safe)
+}
+
+func (c *csvRowWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// selectExportColumns resolves the ?columns= list (comma-separated keys) against the
+// available columns plus custom fields. Without a list, all non-PII columns are exported,
+// plus PII columns for admins.
+func selectExportColumns(app core.App, base []exportColumn, entity, requested string, isAdmin bool) ([]exportColumn, error) {
+	available := append([]exportColumn{}, base...)
+	for _, def := range sortedCustomFieldDefinitions(app, entity) {
+		available = append(available, exportColumn{Key: "custom_fields." + def.Key, Header: def.Label})
+	}
+
+	if strings.TrimSpace(requested) == "" {
+		columns := []exportColumn{}
+		for _, col := range available {
+			if !col.PII || isAdmin {
+				columns = append(columns, col)
+			}
+		}
+		return columns, nil
+	}
+
+	byKey := map[string]exportColumn{}
+	for _, col := range available {
+		byKey[col.Key] = col
+	}
+
+	columns := []exportColumn{}
+	for _, key := range strings.Split(requested, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		col, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", key)
+		}
+		if col.PII && !isAdmin {
+			return nil, fmt.Errorf("%w: %s", errExportColumnForbidden, key)
+		}
+		columns = append(columns, col)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns selected")
+	}
+	return columns, nil
+}
+
+// sortedCustomFieldDefinitions returns active definitions in display order
+func sortedCustomFieldDefinitions(app core.App, entity string) []customFieldDefinition {
+	records, err := app.FindRecordsByFilter(
+		utils.CollectionCustomFieldDefinitions,
+		"entity = {:entity} && archived = false",
+		"sort_order,label", 0, 0,
+		map[string]any{"entity": entity},
+	)
+	if err != nil {
+		return nil
+	}
+	defs := make([]customFieldDefinition, 0, len(records))
+	for _, r := range records {
+		defs = append(defs, newCustomFieldDefinition(r))
+	}
+	return defs
+}
+
+// startExport validates the format and writes the download headers
+func startExport(re *core.RequestEvent, name, format string) (exportRowWriter, error) {
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102-1504"), format)
+	re.Response.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	re.Response.Header().Set("Cache-Control", "no-store")
+
+	switch format {
+	case "xlsx":
+		re.Response.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		re.Response.WriteHeader(http.StatusOK)
+		return utils.NewXLSXWriter(re.Response, name)
+	default:
+		re.Response.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		re.Response.WriteHeader(http.StatusOK)
+		return &csvRowWriter{w: csv.NewWriter(re.Response)}, nil
+	}
+}
+
+func exportHeaders(columns []exportColumn) []string {
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = col.Header
+	}
+	return headers
+}
+
+func exportColumnKeys(columns []exportColumn) []string {
+	keys := make([]string, len(columns))
+	for i, col := range columns {
+		keys[i] = col.Key
+	}
+	return keys
+}
+
+// contactExportRow renders one contact. orgNames caches organisation lookups across rows.
+func contactExportRow(app *pocketbase.PocketBase, r *core.Record, columns []exportColumn, orgNames map[string]string) []string {
+	row := make([]string, len(columns))
+	for i, col := range columns {
+		switch {
+		case col.PII:
+			row[i] = utils.DecryptField(r.GetString(col.Key))
+		case col.Key == "organisation":
+			orgID := r.GetString("organisation")
+			if orgID == "" {
+				continue
+			}
+			name, ok := orgNames[orgID]
+			if !ok {
+				if org, err := app.FindRecordById(utils.CollectionOrganisations, orgID); err == nil {
+					name = org.GetString("name")
+				}
+				orgNames[orgID] = name
+			}
+			row[i] = name
+		default:
+			row[i] = customFieldString(segmentRecordValue(r, col.Key))
+		}
+	}
+	return row
+}
+
+// handleContactsExport streams contacts as CSV or XLSX for the current list filter
+// (same query params as GET /api/contacts) or a saved segment (?segment=<id>)
+func handleContactsExport(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	query := re.Request.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		return utils.BadRequestResponse(re, "format must be csv or xlsx")
+	}
+
+	isAdmin := utils.IsAdmin(re.Auth)
+	columns, err := selectExportColumns(app, contactExportColumns, "contact", query.Get("columns"), isAdmin)
+	if err != nil {
+		if errors.Is(err, errExportColumnForbidden) {
+			return utils.ForbiddenResponse(re, err.Error())
+		}
+		return utils.BadRequestResponse(re, err.Error())
+	}
+
+	// Resolve the source before writing headers so errors can still be returned as JSON
+	segmentID := query.Get("segment")
+	var segmentContacts []*core.Record
+	filter, params, ok := "", map[string]any{}, true
+	if segmentID != "" {
+		segment, contacts, err := resolveSegment(app, segmentID)
+		if segment == nil {
+			return utils.NotFoundResponse(re, "Segment not found")
+		}
+		if err != nil {
+			return utils.BadRequestResponse(re, err.Error())
+		}
+		segmentContacts = contacts
+	} else {
+		filter, params, ok = contactsListFilter(app, query)
+	}
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = "name"
+	}
+
+	w, err := startExport(re, "contacts", format)
+	if err != nil {
+		log.Printf("[ContactsExport] Failed to start export: %v", err)
+		return nil
+	}
+
+	rows := 0
+	orgNames := map[string]string{}
+	writeErr := w.WriteRow(exportHeaders(columns))
+
+	if segmentID != "" {
+		for _, r := range segmentContacts {
+			if writeErr != nil {
+				break
+			}
+			writeErr = w.WriteRow(contactExportRow(app, r, columns, orgNames))
+			rows++
+		}
+	} else if ok {
+		for offset := 0; writeErr == nil; offset += exportBatchSize {
+			records, err := app.FindRecordsByFilter(utils.CollectionContacts, filter, sort, exportBatchSize, offset, params)
+			if err != nil || len(records) == 0 {
+				break
+			}
+			for _, r := range records {
+				if writeErr = w.WriteRow(contactExportRow(app, r, columns, orgNames)); writeErr != nil {
+					break
+				}
+				rows++
+			}
+			if len(records) < exportBatchSize {
+				break
+			}
+		}
+	}
+
+	if err := w.Close(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+
+	status, errMsg := "success", ""
+	if writeErr != nil {
+		log.Printf("[ContactsExport] Export interrupted after %d rows: %v", rows, writeErr)
+		status, errMsg = "error", writeErr.Error()
+	}
+
+	// Record who exported what — the filter, columns and whether PII left the system
+	exportFilter := map[string]any{}
+	for k, v := range query {
+		if k != "columns" && k != "format" {
+			exportFilter[k] = strings.Join(v, ",")
+		}
+	}
+	includesPII := false
+	for _, col := range columns {
+		if col.PII {
+			includesPII = true
+			break
+		}
+	}
+	utils.LogFromRequest(app, re, "export", utils.CollectionContacts, "", status,
+		map[string]any{
+			"format":       format,
+			"columns":      exportColumnKeys(columns),
+			"filter":       exportFilter,
+			"segment_id":   segmentID,
+			"rows":         rows,
+			"includes_pii": includesPII,
+		}, errMsg)
+
+	return nil
+}
+
+// handleOrganisationsExport streams organisations as CSV or XLSX for the current list filter
+func handleOrganisationsExport(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	query := re.Request.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		return utils.BadRequestResponse(re, "format must be csv or xlsx")
+	}
+
+	columns, err := selectExportColumns(app, organisationExportColumns, "organisation", query.Get("columns"), utils.IsAdmin(re.Auth))
+	if err != nil {
+		if errors.Is(err, errExportColumnForbidden) {
+			return utils.ForbiddenResponse(re, err.Error())
+		}
+		return utils.BadRequestResponse(re, err.Error())
+	}
+
+	filter, params := organisationsListFilter(app, query)
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = "name"
+	}
+
+	w, err := startExport(re, "organisations", format)
+	if err != nil {
+		log.Printf("[OrganisationsExport] Failed to start export: %v", err)
+		return nil
+	}
+
+	rows := 0
+	writeErr := w.WriteRow(exportHeaders(columns))
+	for offset := 0; writeErr == nil; offset += exportBatchSize {
+		records, err := app.FindRecordsByFilter(utils.CollectionOrganisations, filter, sort, exportBatchSize, offset, params)
+		if err != nil || len(records) == 0 {
+			break
+		}
+		for _, r := range records {
+			row := make([]string, len(columns))
+			for i, col := range columns {
+				row[i] = customFieldString(segmentRecordValue(r, col.Key))
+			}
+			if writeErr = w.WriteRow(row); writeErr != nil {
+				break
+			}
+			rows++
+		}
+		if len(records) < exportBatchSize {
+			break
+		}
+	}
+
+	if err := w.Close(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+
+	status, errMsg := "success", ""
+	if writeErr != nil {
+		log.Printf("[OrganisationsExport] Export interrupted after %d rows: %v", rows, writeErr)
+		status, errMsg = "error", writeErr.Error()
+	}
+
+	exportFilter := map[string]any{}
+	for k, v := range query {
+		if k != "columns" && k != "format" {
+			exportFilter[k] = strings.Join(v, ",")
+		}
+	}
+	utils.LogFromRequest(app, re, "export", utils.CollectionOrganisations, "", status,
+		map[string]any{
+			"format":  format,
+			"columns": exportColumnKeys(columns),
+			"filter":  exportFilter,
+			"rows":    rows,
+		}, errMsg)
+
+	return nil
+}
+
+// handleGuestListExport streams a guest list's guests and their RSVPs as CSV or XLSX, with
+// a column for each of the guest list's custom RSVP questions
+func handleGuestListExport(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	guestList, err := app.FindRecordById(utils.CollectionGuestLists, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+
+	format := re.Request.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		return utils.BadRequestResponse(re, "format must be csv or xlsx")
+	}
+
+	columns := append([]exportColumn{}, guestListExportColumns...)
+	sessions := guestListSessions(guestList)
+	if len(sessions) > 0 {
+		columns = append(columns, exportColumn{Key: "rsvp_sessions", Header: "Sessions"})
+	}
+	for _, q := range guestListRSVPQuestions(guestList) {
+		columns = append(columns, exportColumn{Key: "rsvp_answers." + q.ID, Header: q.Label})
+	}
+
+	records, err := app.FindRecordsByFilter(utils.CollectionGuestListItems, "guest_list = {:id}", "sort_order,created", 0, 0,
+		map[string]any{"id": id})
+	if err != nil {
+		records = nil
+	}
+
+	w, err := startExport(re, "guest-list", format)
+	if err != nil {
+		log.Printf("[GuestListExport] Failed to start export: %v", err)
+		return nil
+	}
+
+	rows := 0
+	writeErr := w.WriteRow(exportHeaders(columns))
+	for _, r := range records {
+		if writeErr != nil {
+			break
+		}
+		answers := itemRSVPAnswers(r)
+		row := make([]string, len(columns))
+		for i, col := range columns {
+			if qID, ok := strings.CutPrefix(col.Key, "rsvp_answers."); ok {
+				row[i] = customFieldString(answers[qID])
+				continue
+			}
+			switch col.Key {
+			case "rsvp_plus_one":
+				if r.GetBool("rsvp_plus_one") {
+					row[i] = "Yes"
+				}
+			case "rsvp_plus_one_name":
+				row[i] = strings.TrimSpace(r.GetString("rsvp_plus_one_name") + " " + r.GetString("rsvp_plus_one_last_name"))
+			case "rsvp_sessions":
+				var names []string
+				for _, s := range pickedSessions(sessions, itemRSVPSessions(r)) {
+					names = append(names, s.Name)
+				}
+				row[i] = strings.Join(names, ", ")
+			default:
+				row[i] = customFieldString(segmentRecordValue(r, col.Key))
+			}
+		}
+		writeErr = w.WriteRow(row)
+		rows++
+	}
+
+	if err := w.Close(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+
+	status, errMsg := "success", ""
+	if writeErr != nil {
+		log.Printf("[GuestListExport] Export interrupted after %d rows: %v", rows, writeErr)
+		status, errMsg = "error", writeErr.Error()
+	}
+	utils.LogFromRequest(app, re, "export", utils.CollectionGuestLists, id, status,
+		map[string]any{
+			"format":  format,
+			"columns": exportColumnKeys(columns),
+			"rows":    rows,
+		}, errMsg)
+
+	return nil
+}
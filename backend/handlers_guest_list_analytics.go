@@ -0,0 +1,233 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// --- Guest List Analytics ---
+// Everything is computed from the guest list items as they are now: a guest counts as
+// invited once an invite has been sent (invited_at), and as responded once rsvp_status is
+// set. Invites sent before invited_at was recorded only left invite_status behind, so an
+// item without invited_at counts as invited once it has any invite_status. That includes
+// guests who RSVP'd through the public link without an invite.
+
+// responseTimeBuckets splits invite-to-RSVP times for the distribution chart
+var responseTimeBuckets = []struct {
+	Label string
+	Max   time.Duration
+}{
+	{"under_1h", time.Hour},
+	{"1h_24h", 24 * time.Hour},
+	{"1d_3d", 3 * 24 * time.Hour},
+	{"3d_7d", 7 * 24 * time.Hour},
+	{"over_7d", 0},
+}
+
+// funnelCounts is one row of a breakdown, and the totals
+type funnelCounts struct {
+	Key        string `json:"key"`
+	Total      int    `json:"total"`
+	Invited    int    `json:"invited"`
+	Opened     int    `json:"opened"`
+	Clicked    int    `json:"clicked"`
+	Responded  int    `json:"responded"`
+	Accepted   int    `json:"accepted"`
+	Declined   int    `json:"declined"`
+	Waitlisted int    `json:"waitlisted"`
+}
+
+func (c *funnelCounts) add(item *core.Record) {
+	c.Total++
+	if itemInvited(item) {
+		c.Invited++
+	}
+	if item.GetBool("invite_opened") {
+		c.Opened++
+	}
+	if item.GetBool("invite_clicked") {
+		c.Clicked++
+	}
+	switch item.GetString("rsvp_status") {
+	case "accepted":
+		c.Responded++
+		c.Accepted++
+	case "declined":
+		c.Responded++
+		c.Declined++
+	case rsvpWaitlisted:
+		c.Responded++
+		c.Waitlisted++
+	}
+}
+
+// itemInvited reports whether a guest has been sent an invite, falling back to
+// invite_status ("invited" or any later status) when invited_at is empty
+func itemInvited(item *core.Record) bool {
+	return !item.GetDateTime("invited_at").IsZero() || item.GetString("invite_status") != ""
+}
+
+// funnelBreakdown groups items by key, largest group first. Items with no value are
+// grouped under "".
+type funnelBreakdown map[string]*funnelCounts
+
+func (b funnelBreakdown) add(key string, item *core.Record) {
+	c, ok := b[key]
+	if !ok {
+		c = &funnelCounts{Key: key}
+		b[key] = c
+	}
+	c.add(item)
+}
+
+func (b funnelBreakdown) rows() []*funnelCounts {
+	rows := make([]*funnelCounts, 0, len(b))
+	for _, c := range b {
+		rows = append(rows, c)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Total != rows[j].Total {
+			return rows[i].Total > rows[j].Total
+		}
+		return rows[i].Key < rows[j].Key
+	})
+	return rows
+}
+
+// analyticsRate is n over of, to three decimal places
+func analyticsRate(n, of int) float64 {
+	if of == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(of)*1000) / 1000
+}
+
+// handleGuestListAnalytics returns a guest list's RSVP funnel with response times,
+// breakdowns by invite round, organisation and referrer, plus-one uptake and a dietary
+// summary of accepted guests
+func handleGuestListAnalytics(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	guestList, err := app.FindRecordById(utils.CollectionGuestLists, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+
+	items, err := app.FindRecordsByFilter(utils.CollectionGuestListItems, "guest_list = {:id}", "", 0, 0,
+		map[string]any{"id": id})
+	if err != nil {
+		items = nil
+	}
+
+	totals := &funnelCounts{}
+	byRound := funnelBreakdown{}
+	byOrg := funnelBreakdown{}
+	byInvitedBy := funnelBreakdown{}
+
+	buckets := make(map[string]int, len(responseTimeBuckets))
+	for _, b := range responseTimeBuckets {
+		buckets[b.Label] = 0
+	}
+	var responseHours []float64
+
+	plusOnes := 0
+	dietary := map[string]int{}
+	dietaryOther := 0
+	dietaryNone := 0
+	plusOneDietary := 0
+
+	for _, item := range items {
+		totals.add(item)
+		byRound.add(item.GetString("invite_round"), item)
+		byOrg.add(item.GetString("contact_organisation_name"), item)
+		byInvitedBy.add(item.GetString("rsvp_invited_by"), item)
+
+		invitedAt := item.GetDateTime("invited_at").Time()
+		respondedAt := item.GetDateTime("rsvp_responded_at").Time()
+		if item.GetString("rsvp_status") != "" && !invitedAt.IsZero() && !respondedAt.IsZero() && !respondedAt.Before(invitedAt) {
+			elapsed := respondedAt.Sub(invitedAt)
+			responseHours = append(responseHours, elapsed.Hours())
+			for _, b := range responseTimeBuckets {
+				if b.Max == 0 || elapsed < b.Max {
+					buckets[b.Label]++
+					break
+				}
+			}
+		}
+
+		if item.GetString("rsvp_status") != "accepted" {
+			continue
+		}
+
+		if item.GetBool("rsvp_plus_one") {
+			plusOnes++
+			if item.GetString("rsvp_plus_one_dietary") != "" {
+				plusOneDietary++
+			}
+		}
+
+		// Dietary requirements live on the contact; older RSVPs kept free text on the item
+		var requirements []string
+		other := item.GetString("rsvp_dietary") != ""
+		if contactID := item.GetString("contact"); contactID != "" {
+			if contact, err := app.FindRecordById(utils.CollectionContacts, contactID); err == nil {
+				requirements = contact.GetStringSlice("dietary_requirements")
+				other = other || contact.GetString("dietary_requirements_other") != ""
+			}
+		}
+		for _, r := range requirements {
+			dietary[r]++
+		}
+		if other {
+			dietaryOther++
+		}
+		if len(requirements) == 0 && !other {
+			dietaryNone++
+		}
+	}
+
+	medianHours := 0.0
+	if n := len(responseHours); n > 0 {
+		sort.Float64s(responseHours)
+		if n%2 == 1 {
+			medianHours = responseHours[n/2]
+		} else {
+			medianHours = (responseHours[n/2-1] + responseHours[n/2]) / 2
+		}
+		medianHours = math.Round(medianHours*10) / 10
+	}
+
+	return utils.DataResponse(re, map[string]any{
+		"guest_list": id,
+		"name":       guestList.GetString("name"),
+		"funnel":     totals,
+		"rates": map[string]float64{
+			"open":     analyticsRate(totals.Opened, totals.Invited),
+			"click":    analyticsRate(totals.Clicked, totals.Invited),
+			"response": analyticsRate(totals.Responded, totals.Invited),
+			"accept":   analyticsRate(totals.Accepted, totals.Responded),
+		},
+		"response_time": map[string]any{
+			"buckets":      buckets,
+			"median_hours": medianHours,
+			"measured":     len(responseHours),
+		},
+		"by_invite_round": byRound.rows(),
+		"by_organisation": byOrg.rows(),
+		"by_invited_by":   byInvitedBy.rows(),
+		"plus_ones": map[string]any{
+			"accepted_with_plus_one": plusOnes,
+			"uptake":                 analyticsRate(plusOnes, totals.Accepted),
+			"with_dietary":           plusOneDietary,
+		},
+		"dietary": map[string]any{
+			"requirements": dietary,
+			"other":        dietaryOther,
+			"none":         dietaryNone,
+		},
+	})
+}
@@ -110,6 +110,14 @@ func handleGuestListGet(re *core.RequestEvent, app *pocketbase.PocketBase) error
 		"share_count":         shareCount,
 		"rsvp_enabled":            record.GetBool("rsvp_enabled"),
 		"rsvp_plus_ones_enabled":  record.GetBool("rsvp_plus_ones_enabled"),
+		"invite_attach_ics":       record.GetBool("invite_attach_ics"),
+		"rsvp_cadence":            record.Get("rsvp_cadence"),
+		"rsvp_questions":          guestListRSVPQuestions(record),
+		"sessions":                guestListSessionsResponse(app, record),
+		"rsvp_deadline":           record.GetString("rsvp_deadline"),
+		"capacity":                record.GetInt("capacity"),
+		"seats_taken":             guestListSeatsTaken(app, record.Id),
+		"waitlist_count":          waitlistLength(app, record.Id, ""),
 		"rsvp_generic_token":      record.GetString("rsvp_generic_token"),
 		"rsvp_generic_url":        rsvpGenericURL,
 		"landing_enabled":     record.GetBool("landing_enabled"),
@@ -201,6 +209,27 @@ func handleGuestListUpdate(re *core.RequestEvent, app *pocketbase.PocketBase) er
 	if v, ok := input["rsvp_plus_ones_enabled"].(bool); ok {
 		record.Set("rsvp_plus_ones_enabled", v)
 	}
+	if v, ok := input["invite_attach_ics"].(bool); ok {
+		record.Set("invite_attach_ics", v)
+	}
+	// A local date and time in the event's timezone, or RFC 3339; empty to remove
+	if v, ok := input["rsvp_deadline"].(string); ok {
+		if strings.TrimSpace(v) == "" {
+			record.Set("rsvp_deadline", "")
+		} else {
+			deadline, err := parseLocalDateTime(v, guestListLocation(app, record))
+			if err != nil {
+				return utils.BadRequestResponse(re, "Invalid RSVP deadline")
+			}
+			record.Set("rsvp_deadline", deadline.UTC())
+		}
+	}
+	if v, ok := input["capacity"].(float64); ok {
+		if v < 0 {
+			return utils.BadRequestResponse(re, "Capacity can't be negative")
+		}
+		record.Set("capacity", int(v))
+	}
 	if v, ok := input["landing_enabled"].(bool); ok {
 		record.Set("landing_enabled", v)
 	}
@@ -466,6 +495,11 @@ func handleGuestListClone(re *core.RequestEvent, app *pocketbase.PocketBase) err
 	newList.Set("program_description", source.GetString("program_description"))
 	newList.Set("program_title", source.GetString("program_title"))
 	newList.Set("rsvp_plus_ones_enabled", source.GetBool("rsvp_plus_ones_enabled"))
+	newList.Set("invite_attach_ics", source.GetBool("invite_attach_ics"))
+	newList.Set("rsvp_cadence", source.Get("rsvp_cadence"))
+	newList.Set("rsvp_questions", source.Get("rsvp_questions"))
+	newList.Set("sessions", source.Get("sessions"))
+	newList.Set("capacity", source.GetInt("capacity"))
 
 	if err := app.Save(newList); err != nil {
 		return utils.InternalErrorResponse(re, "Failed to create cloned list")
@@ -565,8 +599,19 @@ func handleGuestListItemsList(re *core.RequestEvent, app *pocketbase.PocketBase)
 			"rsvp_responded_at":        r.GetString("rsvp_responded_at"),
 			"rsvp_invited_by":          r.GetString("rsvp_invited_by"),
 			"rsvp_comments":            r.GetString("rsvp_comments"),
+			"rsvp_answers":             itemRSVPAnswers(r),
+			"rsvp_sessions":            itemRSVPSessions(r),
+			"rsvp_history":             itemRSVPHistory(r),
+			"invited_at":               r.GetString("invited_at"),
+			"waitlisted_at":            r.GetString("waitlisted_at"),
+			"waitlist_position":        waitlistPosition(app, r),
+			"plus_one_of":              r.GetString("plus_one_of"),
+			"checked_in_at":            r.GetString("checked_in_at"),
+			"plus_one_checked_in_at":   r.GetString("plus_one_checked_in_at"),
 			"invite_opened":            r.GetBool("invite_opened"),
 			"invite_clicked":           r.GetBool("invite_clicked"),
+			"email_status":             r.GetString("email_status"),
+			"email_error":              r.GetString("email_error"),
 			"created":                   r.GetString("created"),
 		}
 
@@ -742,17 +787,48 @@ func handleGuestListItemBulkAdd(re *core.RequestEvent, app *pocketbase.PocketBas
 
 	var input struct {
 		ContactIDs  []string `json:"contact_ids"`
+		SegmentID   string   `json:"segment_id"`
 		InviteRound string   `json:"invite_round"`
 	}
 	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
 		return utils.BadRequestResponse(re, "Invalid JSON")
 	}
 
-	if len(input.ContactIDs) == 0 {
-		return utils.BadRequestResponse(re, "contact_ids is required")
-	}
-	if len(input.ContactIDs) > 200 {
-		return utils.BadRequestResponse(re, "Maximum 200 contacts per bulk add")
+	// Contacts can come from a saved segment instead of an explicit list
+	if input.SegmentID != "" {
+		segment, contacts, err := resolveSegment(app, input.SegmentID)
+		if segment == nil {
+			return utils.NotFoundResponse(re, "Segment not found")
+		}
+		if err != nil {
+			return utils.BadRequestResponse(re, err.Error())
+		}
+		if len(contacts) > 1000 {
+			return utils.BadRequestResponse(re, "Segment has more than 1000 contacts — narrow it before adding")
+		}
+
+		// Skip contacts already on the list (guest_list + contact is unique)
+		existing := map[string]bool{}
+		items, _ := app.FindRecordsByFilter(utils.CollectionGuestListItems, "guest_list = {:id}", "", 0, 0, map[string]any{"id": listID})
+		for _, item := range items {
+			existing[item.GetString("contact")] = true
+		}
+		input.ContactIDs = nil
+		for _, c := range contacts {
+			if !existing[c.Id] {
+				input.ContactIDs = append(input.ContactIDs, c.Id)
+			}
+		}
+		if len(input.ContactIDs) == 0 {
+			return re.JSON(http.StatusOK, map[string]any{"added": 0})
+		}
+	} else {
+		if len(input.ContactIDs) == 0 {
+			return utils.BadRequestResponse(re, "contact_ids or segment_id is required")
+		}
+		if len(input.ContactIDs) > 200 {
+			return utils.BadRequestResponse(re, "Maximum 200 contacts per bulk add")
+		}
 	}
 
 	collection, err := app.FindCollectionByNameOrId(utils.CollectionGuestListItems)
@@ -825,7 +901,7 @@ func handleGuestListItemUpdate(re *core.RequestEvent, app *pocketbase.PocketBase
 	var inviteStatusChanged bool
 	var newInviteStatus string
 	if v, ok := input["invite_status"].(string); ok {
-		allowed := map[string]bool{"": true, "to_invite": true, "invited": true, "accepted": true, "declined": true, "no_show": true}
+		allowed := map[string]bool{"": true, "to_invite": true, "invited": true, "accepted": true, "declined": true, "no_show": true, rsvpWaitlisted: true}
 		if !allowed[v] {
 			return utils.BadRequestResponse(re, "Invalid invite_status value")
 		}
@@ -835,7 +911,7 @@ func handleGuestListItemUpdate(re *core.RequestEvent, app *pocketbase.PocketBase
 	}
 	_, rsvpExplicit := input["rsvp_status"]
 	if v, ok := input["rsvp_status"].(string); ok {
-		allowed := map[string]bool{"": true, "accepted": true, "declined": true}
+		allowed := map[string]bool{"": true, "accepted": true, "declined": true, rsvpWaitlisted: true}
 		if !allowed[v] {
 			return utils.BadRequestResponse(re, "Invalid rsvp_status value")
 		}
@@ -843,12 +919,16 @@ func handleGuestListItemUpdate(re *core.RequestEvent, app *pocketbase.PocketBase
 	}
 	// Sync rsvp_status when admin changes invite_status manually
 	if inviteStatusChanged && !rsvpExplicit {
-		if newInviteStatus == "accepted" || newInviteStatus == "declined" {
+		if newInviteStatus == "accepted" || newInviteStatus == "declined" || newInviteStatus == rsvpWaitlisted {
 			record.Set("rsvp_status", newInviteStatus)
 		} else {
 			record.Set("rsvp_status", "")
 		}
 	}
+	setItemWaitlisted(record, record.GetString("invite_status") == rsvpWaitlisted)
+	if re.Auth != nil {
+		appendRSVPChange(record, "", re.Auth.GetString("email"), "")
+	}
 	if v, ok := input["notes"].(string); ok {
 		record.Set("notes", v)
 	}
@@ -1269,18 +1349,20 @@ func handlePublicGuestListView(re *core.RequestEvent, app *pocketbase.PocketBase
 	items := make([]map[string]any, len(records))
 	for i, r := range records {
 		items[i] = map[string]any{
-			"id":           r.Id,
-			"name":         r.GetString("contact_name"),
-			"role":         r.GetString("contact_job_title"),
-			"company":      r.GetString("contact_organisation_name"),
-			"invite_round": r.GetString("invite_round"),
-			"linkedin":     r.GetString("contact_linkedin"),
-			"city":         r.GetString("contact_location"),
-			"degrees":      r.GetString("contact_degrees"),
-			"relationship": r.GetInt("contact_relationship"),
-			"notes":        r.GetString("notes"),
-			"client_notes": r.GetString("client_notes"),
-			"rsvp_status":  r.GetString("rsvp_status"),
+			"id":            r.Id,
+			"name":          r.GetString("contact_name"),
+			"role":          r.GetString("contact_job_title"),
+			"company":       r.GetString("contact_organisation_name"),
+			"invite_round":  r.GetString("invite_round"),
+			"linkedin":      r.GetString("contact_linkedin"),
+			"city":          r.GetString("contact_location"),
+			"degrees":       r.GetString("contact_degrees"),
+			"relationship":  r.GetInt("contact_relationship"),
+			"notes":         r.GetString("notes"),
+			"client_notes":  r.GetString("client_notes"),
+			"rsvp_status":   r.GetString("rsvp_status"),
+			"rsvp_answers":  itemRSVPAnswers(r),
+			"rsvp_sessions": itemRSVPSessions(r),
 		}
 	}
 
@@ -1315,6 +1397,8 @@ func handlePublicGuestListView(re *core.RequestEvent, app *pocketbase.PocketBase
 		"landing_content":      guestList.GetString("landing_content"),
 		"program_description":  guestList.GetString("program_description"),
 		"program_title":        guestList.GetString("program_title"),
+		"rsvp_questions":       guestListRSVPQuestions(guestList),
+		"sessions":             guestListSessions(guestList),
 	}
 
 	// Merge event projection details
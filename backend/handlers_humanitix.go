@@ -111,6 +111,17 @@ func (t *humanitixTicket) getAdditionalField(questionID string) string {
 	return ""
 }
 
+// applyHumanitixCustomFields copies mapped additional-field answers into contact custom fields
+func applyHumanitixCustomFields(record *core.Record, ticket *humanitixTicket, fieldMapping map[string]string, defs map[string]customFieldDefinition) {
+	for crmField, qid := range fieldMapping {
+		key, ok := strings.CutPrefix(crmField, "custom_fields.")
+		if !ok {
+			continue
+		}
+		setCustomFieldFromString(record, defs, key, ticket.getAdditionalField(qid))
+	}
+}
+
 // --- Humanitix API client ---
 
 func humanitixGet(path string) ([]byte, error) {
@@ -287,6 +298,9 @@ func runHumanitixSync(app *pocketbase.PocketBase, syncLogID, eventID string, fie
 
 	log.Printf("[Humanitix] === Starting sync for event %s (syncLog: %s) ===", eventID, syncLogID)
 
+	// Custom fields can be mapped as "custom_fields.<key>" → question ID
+	customFieldDefs := loadCustomFieldDefinitions(app, "contact")
+
 	if fieldMapping != nil {
 		log.Printf("[Humanitix] Field mapping: %v", fieldMapping)
 	} else {
@@ -622,6 +636,8 @@ func runHumanitixSync(app *pocketbase.PocketBase, syncLogID, eventID string, fie
 				record.Set("organisation", orgID)
 			}
 
+			applyHumanitixCustomFields(record, &ticket, fieldMapping, customFieldDefs)
+
 			// Decrypt all PII fields before save so encryption hooks can re-encrypt
 			// Without this, already-encrypted values get double-encrypted and exceed field max length
 			for _, piiField := range []string{"email", "personal_email", "phone", "bio", "location"} {
@@ -672,6 +688,7 @@ func runHumanitixSync(app *pocketbase.PocketBase, syncLogID, eventID string, fie
 			if accessibilityRaw != "" && !strings.EqualFold(accessibilityRaw, "none") && accessibilityRaw != "-" && !strings.EqualFold(accessibilityRaw, "n/a") && !strings.EqualFold(accessibilityRaw, "no") {
 				record.Set("accessibility_requirements_other", accessibilityRaw)
 			}
+			applyHumanitixCustomFields(record, &ticket, fieldMapping, customFieldDefs)
 
 			if err := app.Save(record); err != nil {
 				log.Printf("[Humanitix] [%d/%d] FAILED to create %s %s (%s): %v", processed, len(allTickets), firstName, lastName, email, err)
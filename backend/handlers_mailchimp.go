@@ -267,13 +267,37 @@ func handleMailchimpSettingsSave(re *core.RequestEvent, app *pocketbase.PocketBa
 
 // --- Sync handlers ---
 
-// handleMailchimpSync pushes all active contacts to Mailchimp
+// handleMailchimpSync pushes all active contacts to Mailchimp,
+// or only the members of a saved segment when segment_id is given
 func handleMailchimpSync(re *core.RequestEvent, app *pocketbase.PocketBase) error {
 	listID, mappings := getMailchimpSyncConfig(app)
 	if listID == "" {
 		return utils.BadRequestResponse(re, "Mailchimp list not configured")
 	}
 
+	// Body is optional — an empty body syncs all active contacts
+	var input struct {
+		SegmentID string `json:"segment_id"`
+	}
+	json.NewDecoder(re.Request.Body).Decode(&input)
+
+	if input.SegmentID != "" {
+		segment, records, err := resolveSegment(app, input.SegmentID)
+		if segment == nil {
+			return utils.NotFoundResponse(re, "Segment not found")
+		}
+		if err != nil {
+			return utils.BadRequestResponse(re, err.Error())
+		}
+
+		utils.LogFromRequest(app, re, "mailchimp_sync", "mailchimp", "", "success",
+			map[string]any{"segment_id": input.SegmentID, "contacts": len(records)}, "")
+
+		go syncContactsToMailchimp(app, listID, mappings, records)
+
+		return re.JSON(http.StatusAccepted, map[string]string{"message": "Sync started"})
+	}
+
 	utils.LogFromRequest(app, re, "mailchimp_sync", "mailchimp", "", "success", nil, "")
 
 	go runMailchimpSync(app, listID, mappings)
@@ -370,13 +394,11 @@ func handleMailchimpWebhook(re *core.RequestEvent, app *pocketbase.PocketBase) e
 	switch webhookType {
 	case "subscribe":
 		contact.Set("mailchimp_status", "subscribed")
-		contact.Set("email", utils.DecryptField(contact.GetString("email")))
-		app.Save(contact)
+		saveMailchimpConsentChange(app, re, contact, true)
 
 	case "unsubscribe":
 		contact.Set("mailchimp_status", "unsubscribed")
-		contact.Set("email", utils.DecryptField(contact.GetString("email")))
-		app.Save(contact)
+		saveMailchimpConsentChange(app, re, contact, false)
 
 	case "cleaned":
 		contact.Set("mailchimp_status", "cleaned")
@@ -431,6 +453,24 @@ func handleMailchimpWebhook(re *core.RequestEvent, app *pocketbase.PocketBase) e
 
 // --- Internal helpers ---
 
+// saveMailchimpConsentChange saves a contact after a subscribe/unsubscribe webhook,
+// recording it as a marketing consent change
+func saveMailchimpConsentChange(app *pocketbase.PocketBase, re *core.RequestEvent, contact *core.Record, granted bool) {
+	applied := applyConsentChanges(contact, map[string]bool{consentMarketing: granted}, "mailchimp")
+
+	contact.Set("email", utils.DecryptField(contact.GetString("email")))
+	if err := app.Save(contact); err != nil {
+		log.Printf("[Mailchimp] Failed to save contact %s: %v", contact.Id, err)
+		return
+	}
+
+	logConsentEvents(app, contact.Id, applied, consentProof{
+		Source:    "mailchimp",
+		Form:      "mailchimp_webhook",
+		IPAddress: re.Request.FormValue("data[ip_opt]"),
+	})
+}
+
 func runMailchimpSync(app *pocketbase.PocketBase, listID string, mappings []mergeFieldMapping) {
 	records, err := app.FindRecordsByFilter(
 		utils.CollectionContacts,
@@ -443,7 +483,11 @@ func runMailchimpSync(app *pocketbase.PocketBase, listID string, mappings []merg
 	}
 
 	log.Printf("[Mailchimp] Syncing %d active contacts to list %s", len(records), listID)
+	syncContactsToMailchimp(app, listID, mappings, records)
+}
 
+// syncContactsToMailchimp upserts the given contacts and stores their Mailchimp ID and status
+func syncContactsToMailchimp(app *pocketbase.PocketBase, listID string, mappings []mergeFieldMapping, records []*core.Record) {
 	synced, errors := 0, 0
 	for _, record := range records {
 		email := utils.DecryptField(record.GetString("email"))
@@ -485,6 +529,9 @@ func contactFieldValue(record *core.Record, field string) string {
 	case "location":
 		return utils.DecryptField(record.GetString("location"))
 	default:
+		if key, ok := strings.CutPrefix(field, "custom_fields."); ok {
+			return customFieldString(recordCustomFields(record)[key])
+		}
 		return record.GetString(field)
 	}
 }
@@ -507,6 +554,11 @@ func upsertMailchimpSubscriber(listID string, record *core.Record, email string,
 		"email_address": email,
 		"status_if_new": "subscribed",
 	}
+	// Never (re)subscribe someone who has withdrawn marketing consent
+	if !contactAllows(record, consentMarketing) {
+		payload["status_if_new"] = "unsubscribed"
+		payload["status"] = "unsubscribed"
+	}
 	if len(mergeFields) > 0 {
 		payload["merge_fields"] = mergeFields
 	}
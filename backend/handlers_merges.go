@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// mergeUndoRetentionDays is how long a contact merge can be undone.
+// After this the snapshots (which contain encrypted PII) are purged.
+const mergeUndoRetentionDays = 30
+
+// mergeReassignment records a single re-pointed reference so it can be reverted
+type mergeReassignment struct {
+	ID    string `json:"id"`
+	Field string `json:"field"`
+	From  string `json:"from"`
+}
+
+// mergeReferences holds the re-pointed and deleted records for one collection
+type mergeReferences struct {
+	Reassigned []mergeReassignment `json:"reassigned"`
+	Deleted    []map[string]any    `json:"deleted"`
+}
+
+// mergeHistory collects everything a merge changed while the transaction runs
+type mergeHistory struct {
+	MergedSnapshots []map[string]any
+	References      map[string]*mergeReferences
+}
+
+func newMergeHistory() *mergeHistory {
+	return &mergeHistory{
+		References: map[string]*mergeReferences{
+			"activities":       {},
+			"guest_list_items": {},
+			"contact_links":    {},
+			"consent_events":   {},
+			"email_events":     {},
+		},
+	}
+}
+
+// reassigned records that a reference field was re-pointed away from a merged contact
+func (h *mergeHistory) reassigned(collection, id, field, from string) {
+	refs := h.References[collection]
+	refs.Reassigned = append(refs.Reassigned, mergeReassignment{ID: id, Field: field, From: from})
+}
+
+// deleted records the full field data of a record removed during the merge
+func (h *mergeHistory) deleted(collection string, data map[string]any) {
+	refs := h.References[collection]
+	refs.Deleted = append(refs.Deleted, data)
+}
+
+// saveMergeHistory persists a contact_merges record inside the merge transaction
+func saveMergeHistory(txApp core.App, primaryID string, mergedIDs []string, primarySnapshot map[string]any, history *mergeHistory, mergedBy string) (*core.Record, error) {
+	collection, err := txApp.FindCollectionByNameOrId(utils.CollectionContactMerges)
+	if err != nil {
+		return nil, err
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("primary_id", primaryID)
+	record.Set("merged_ids", mergedIDs)
+	record.Set("primary_snapshot", primarySnapshot)
+	record.Set("merged_snapshots", history.MergedSnapshots)
+	record.Set("references", history.References)
+	record.Set("status", "completed")
+	record.Set("merged_by", mergedBy)
+	record.Set("expires_at", time.Now().UTC().AddDate(0, 0, mergeUndoRetentionDays).Format(time.RFC3339))
+
+	if err := txApp.Save(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// decodeJSONField unmarshals a JSON field (types.JSONRaw) into the given target
+func decodeJSONField(r *core.Record, field string, target any) error {
+	b, err := json.Marshal(r.Get(field))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, target)
+}
+
+// canUndoMerge reports whether a merge is still within its undo window
+func canUndoMerge(r *core.Record) bool {
+	if r.GetString("status") != "completed" {
+		return false
+	}
+	expiresAt := r.GetDateTime("expires_at")
+	return !expiresAt.IsZero() && time.Now().Before(expiresAt.Time())
+}
+
+// handleContactMergesList returns merge history, optionally filtered to a contact
+func handleContactMergesList(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	filter := ""
+	params := map[string]any{}
+	if contactID := re.Request.URL.Query().Get("contact"); contactID != "" {
+		filter = "primary_id = {:contactId} || merged_ids ~ {:contactId}"
+		params["contactId"] = contactID
+	}
+
+	records, err := app.FindRecordsByFilter(utils.CollectionContactMerges, filter, "-created", 100, 0, params)
+	if err != nil {
+		return utils.DataResponse(re, map[string]any{"items": []any{}})
+	}
+
+	items := make([]map[string]any, 0, len(records))
+	for _, r := range records {
+		var mergedIDs []string
+		decodeJSONField(r, "merged_ids", &mergedIDs)
+
+		// Names of the merged contacts, taken from the snapshots (empty once expired)
+		var snapshots []map[string]any
+		decodeJSONField(r, "merged_snapshots", &snapshots)
+		mergedNames := make([]string, 0, len(snapshots))
+		for _, snap := range snapshots {
+			first, _ := snap["first_name"].(string)
+			last, _ := snap["last_name"].(string)
+			mergedNames = append(mergedNames, strings.TrimSpace(first+" "+last))
+		}
+
+		items = append(items, map[string]any{
+			"id":           r.Id,
+			"primary_id":   r.GetString("primary_id"),
+			"merged_ids":   mergedIDs,
+			"merged_names": mergedNames,
+			"status":       r.GetString("status"),
+			"merged_by":    r.GetString("merged_by"),
+			"undone_by":    r.GetString("undone_by"),
+			"undone_at":    r.GetString("undone_at"),
+			"expires_at":   r.GetString("expires_at"),
+			"can_undo":     canUndoMerge(r),
+			"created":      r.GetString("created"),
+		})
+	}
+
+	return utils.DataResponse(re, map[string]any{"items": items})
+}
+
+// handleContactMergeUndo restores the merged contacts and reverts every re-pointed reference
+func handleContactMergeUndo(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Merge ID required")
+	}
+
+	mergeRecord, err := app.FindRecordById(utils.CollectionContactMerges, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Merge not found")
+	}
+
+	if !canUndoMerge(mergeRecord) {
+		if mergeRecord.GetString("status") == "undone" {
+			return utils.BadRequestResponse(re, "This merge has already been undone")
+		}
+		return utils.BadRequestResponse(re, "This merge is outside the undo window")
+	}
+
+	primaryID := mergeRecord.GetString("primary_id")
+	primaryRecord, err := app.FindRecordById(utils.CollectionContacts, primaryID)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Primary contact no longer exists")
+	}
+
+	var primarySnapshot map[string]any
+	var mergedSnapshots []map[string]any
+	var references map[string]*mergeReferences
+	if err := decodeJSONField(mergeRecord, "primary_snapshot", &primarySnapshot); err != nil {
+		return utils.InternalErrorResponse(re, "Merge snapshot is unreadable")
+	}
+	if err := decodeJSONField(mergeRecord, "merged_snapshots", &mergedSnapshots); err != nil {
+		return utils.InternalErrorResponse(re, "Merge snapshot is unreadable")
+	}
+	if err := decodeJSONField(mergeRecord, "references", &references); err != nil {
+		return utils.InternalErrorResponse(re, "Merge snapshot is unreadable")
+	}
+
+	restoredIDs := []string{}
+	skipped := 0
+
+	err = app.RunInTransaction(func(txApp core.App) error {
+		// 1. Restore the primary's pre-merge fields first so its email blind index
+		// is released before the merged contacts are recreated (unique index)
+		for k, v := range primarySnapshot {
+			if k == "id" || k == "created" || k == "updated" {
+				continue
+			}
+			primaryRecord.Set(k, v)
+		}
+		// Snapshot values are stored as-is (PII still encrypted), so skip validation —
+		// the encryption hook leaves already-encrypted values alone
+		if err := txApp.SaveNoValidate(primaryRecord); err != nil {
+			return fmt.Errorf("failed to restore primary contact: %w", err)
+		}
+
+		// 2. Recreate merged contacts with their original IDs
+		contactsCollection, err := txApp.FindCollectionByNameOrId(utils.CollectionContacts)
+		if err != nil {
+			return err
+		}
+		restored := []*core.Record{primaryRecord}
+		for _, snap := range mergedSnapshots {
+			record := core.NewRecord(contactsCollection)
+			record.Load(snap)
+			if err := txApp.SaveNoValidate(record); err != nil {
+				return fmt.Errorf("failed to restore contact %s: %w", record.Id, err)
+			}
+			restoredIDs = append(restoredIDs, record.Id)
+			restored = append(restored, record)
+		}
+
+		// Recreating the contacts runs duplicate detection, which would put the pair straight
+		// back in the review queue. Dismiss them instead — the admin has just split them.
+		undoneBy := ""
+		if re.Auth != nil {
+			undoneBy = re.Auth.GetString("email")
+		}
+		if err := dismissDuplicatePairs(txApp, restored, undoneBy); err != nil {
+			return err
+		}
+
+		// 3. Revert re-pointed references and recreate deleted ones. Guest list items come
+		// before email_events so events moved off a deleted item can point back at it.
+		for _, collection := range []string{"activities", "guest_list_items", "contact_links", "consent_events", "email_events"} {
+			refs := references[collection]
+			if refs == nil {
+				continue
+			}
+
+			for _, ra := range refs.Reassigned {
+				record, err := txApp.FindRecordById(collection, ra.ID)
+				if err != nil {
+					skipped++ // Deleted since the merge
+					continue
+				}
+				record.Set(ra.Field, ra.From)
+				if err := txApp.Save(record); err != nil {
+					return fmt.Errorf("failed to revert %s %s: %w", collection, ra.ID, err)
+				}
+			}
+
+			if len(refs.Deleted) == 0 {
+				continue
+			}
+			coll, err := txApp.FindCollectionByNameOrId(collection)
+			if err != nil {
+				return err
+			}
+			for _, snap := range refs.Deleted {
+				record := core.NewRecord(coll)
+				record.Load(snap)
+				if err := txApp.SaveNoValidate(record); err != nil {
+					return fmt.Errorf("failed to restore %s %s: %w", collection, record.Id, err)
+				}
+			}
+		}
+
+		// 4. Mark the merge as undone. The snapshots (decrypted PII) aren't needed any more.
+		mergeRecord.Set("primary_snapshot", nil)
+		mergeRecord.Set("merged_snapshots", nil)
+		mergeRecord.Set("references", nil)
+		mergeRecord.Set("status", "undone")
+		mergeRecord.Set("undone_at", time.Now().UTC().Format(time.RFC3339))
+		mergeRecord.Set("undone_by", undoneBy)
+		return txApp.Save(mergeRecord)
+	})
+
+	if err != nil {
+		log.Printf("[ContactMergeUndo] Transaction failed: %v", err)
+		utils.LogFromRequest(app, re, "merge_undo", "contacts", primaryID, "error", map[string]any{"merge_id": id}, err.Error())
+		return utils.InternalErrorResponse(re, "Failed to undo merge")
+	}
+
+	utils.LogFromRequest(app, re, "merge_undo", "contacts", primaryID, "success",
+		map[string]any{
+			"merge_id":     id,
+			"restored_ids": restoredIDs,
+			"skipped":      skipped,
+		}, "")
+
+	return utils.DataResponse(re, map[string]any{
+		"id":           primaryID,
+		"merge_id":     id,
+		"restored_ids": restoredIDs,
+		"skipped":      skipped,
+	})
+}
+
+// purgeExpiredMergeSnapshots clears snapshot data from merges past their undo window.
+// Undone merges are included: undo clears its own snapshots, but older ones may still hold them.
+func purgeExpiredMergeSnapshots(app *pocketbase.PocketBase) (int, error) {
+	records, err := app.FindRecordsByFilter(
+		utils.CollectionContactMerges,
+		"(status = 'completed' || status = 'undone') && expires_at < {:now}",
+		"", 0, 0,
+		map[string]any{"now": time.Now().UTC().Format("2006-01-02 15:04:05.000Z")},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, r := range records {
+		var snapshots []map[string]any
+		if r.GetString("status") == "undone" && (decodeJSONField(r, "merged_snapshots", &snapshots) != nil || len(snapshots) == 0) {
+			continue // Already cleared
+		}
+		r.Set("primary_snapshot", nil)
+		r.Set("merged_snapshots", nil)
+		r.Set("references", nil)
+		if r.GetString("status") == "completed" {
+			r.Set("status", "expired")
+		}
+		if err := app.Save(r); err != nil {
+			log.Printf("[MergeHistory] Failed to purge merge %s: %v", r.Id, err)
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// scheduleMergeHistoryCleanup purges expired merge snapshots once a day
+func scheduleMergeHistoryCleanup(app *pocketbase.PocketBase) {
+	// Wait for app to fully start
+	time.Sleep(2 * time.Minute)
+
+	for {
+		if purged, err := purgeExpiredMergeSnapshots(app); err != nil {
+			log.Printf("[MergeHistory] Cleanup failed: %v", err)
+		} else if purged > 0 {
+			log.Printf("[MergeHistory] Purged %d expired merge snapshots", purged)
+		}
+		time.Sleep(24 * time.Hour)
+	}
+}
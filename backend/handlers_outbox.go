@@ -0,0 +1,190 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func buildOutboxResponse(r *core.Record) map[string]any {
+	return map[string]any{
+		"id":               r.Id,
+		"kind":             r.GetString("kind"),
+		"to_email":         utils.DecryptField(r.GetString("to_email")),
+		"to_name":          r.GetString("to_name"),
+		"subject":          r.GetString("subject"),
+		"transactional":    r.GetBool("transactional"),
+		"guest_list_item":  r.GetString("guest_list_item"),
+		"status":           r.GetString("status"),
+		"attempts":         r.GetInt("attempts"),
+		"max_attempts":     r.GetInt("max_attempts"),
+		"next_attempt_at":  r.GetString("next_attempt_at"),
+		"sent_at":          r.GetString("sent_at"),
+		"last_error":       r.GetString("last_error"),
+		"provider":         r.GetString("provider"),
+		"template_version": r.GetString("template_version"),
+		"created":          r.GetString("created"),
+		"updated":          r.GetString("updated"),
+	}
+}
+
+// handleOutboxList returns queued and sent messages, newest first.
+// Filters: status, kind, guest_list_item, and email (exact match on the recipient).
+func handleOutboxList(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	query := re.Request.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(query.Get("perPage"))
+	if perPage < 1 || perPage > 500 {
+		perPage = 50
+	}
+
+	filters := []string{}
+	params := dbx.Params{}
+	for _, key := range []string{"status", "kind", "guest_list_item"} {
+		if v := query.Get(key); v != "" {
+			filters = append(filters, key+" = {:"+key+"}")
+			params[key] = v
+		}
+	}
+	if email := query.Get("email"); email != "" {
+		filters = append(filters, "to_email_index = {:idx}")
+		params["idx"] = utils.BlindIndex(normaliseEmail(email))
+	}
+	filter := strings.Join(filters, " && ")
+
+	allRecords, _ := app.FindRecordsByFilter(utils.CollectionEmailOutbox, filter, "", 0, 0, params)
+	totalItems := len(allRecords)
+
+	offset := (page - 1) * perPage
+	records, err := app.FindRecordsByFilter(utils.CollectionEmailOutbox, filter, "-created", perPage, offset, params)
+	if err != nil {
+		return utils.DataResponse(re, map[string]any{
+			"items":      []any{},
+			"page":       page,
+			"perPage":    perPage,
+			"totalItems": 0,
+			"totalPages": 0,
+		})
+	}
+
+	items := make([]map[string]any, len(records))
+	for i, r := range records {
+		items[i] = buildOutboxResponse(r)
+	}
+
+	return utils.DataResponse(re, map[string]any{
+		"items":      items,
+		"page":       page,
+		"perPage":    perPage,
+		"totalItems": totalItems,
+		"totalPages": (totalItems + perPage - 1) / perPage,
+	})
+}
+
+// handleOutboxGet returns one message with its send log, recipients and rendered HTML
+func handleOutboxGet(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	record, err := app.FindRecordById(utils.CollectionEmailOutbox, re.Request.PathValue("id"))
+	if err != nil {
+		return utils.NotFoundResponse(re, "Message not found")
+	}
+
+	data := buildOutboxResponse(record)
+	data["html"] = ""
+	if !slices.Contains(outboxRedactedKinds, record.GetString("kind")) {
+		data["html"] = utils.DecryptField(record.GetString("html"))
+	}
+
+	var entries []outboxLogEntry
+	decodeJSONField(record, "log", &entries)
+	if entries == nil {
+		entries = []outboxLogEntry{}
+	}
+	data["log"] = entries
+
+	if msg, err := outboxMessage(record); err == nil {
+		data["from"] = msg.From
+		data["to"] = msg.To
+		data["cc"] = msg.Cc
+		data["bcc"] = msg.Bcc
+		attachments := []string{}
+		for name := range msg.Attachments {
+			attachments = append(attachments, name)
+		}
+		data["attachments"] = attachments
+	}
+
+	return utils.DataResponse(re, data)
+}
+
+// handleOutboxRetry puts a failed or cancelled message back in the queue with a fresh
+// set of attempts
+func handleOutboxRetry(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	record, err := app.FindRecordById(utils.CollectionEmailOutbox, re.Request.PathValue("id"))
+	if err != nil {
+		return utils.NotFoundResponse(re, "Message not found")
+	}
+
+	status := record.GetString("status")
+	if status != outboxFailed && status != outboxCancelled {
+		return utils.BadRequestResponse(re, "Only failed or cancelled messages can be retried")
+	}
+	if slices.Contains(outboxRedactedKinds, record.GetString("kind")) {
+		return utils.BadRequestResponse(re, "Verification codes can't be resent; ask for a new code instead")
+	}
+
+	record.Set("status", outboxQueued)
+	record.Set("max_attempts", record.GetInt("attempts")+outboxMaxAttempts)
+	record.Set("next_attempt_at", types.NowDateTime())
+	if err := app.Save(record); err != nil {
+		log.Printf("[Outbox] Failed to requeue %s: %v", record.Id, err)
+		return utils.InternalErrorResponse(re, "Failed to retry message")
+	}
+	if itemID := record.GetString("guest_list_item"); itemID != "" {
+		setItemEmailStatus(app, itemID, outboxQueued, "")
+	}
+	wakeOutbox()
+
+	utils.LogFromRequest(app, re, "email_retry", utils.CollectionEmailOutbox, record.Id, "success",
+		map[string]any{"previous_status": status, "kind": record.GetString("kind")}, "")
+
+	return utils.DataResponse(re, buildOutboxResponse(record))
+}
+
+// handleOutboxCancel stops a queued message from being sent
+func handleOutboxCancel(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+
+	// Conditional update so a message the dispatcher has just claimed isn't cancelled mid-send
+	res, err := app.DB().Update(utils.CollectionEmailOutbox,
+		dbx.Params{"status": outboxCancelled, "updated": types.NowDateTime().String()},
+		dbx.HashExp{"id": id, "status": outboxQueued}).Execute()
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Failed to cancel message")
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return utils.BadRequestResponse(re, "Only queued messages can be cancelled")
+	}
+
+	record, err := app.FindRecordById(utils.CollectionEmailOutbox, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Message not found")
+	}
+	if itemID := record.GetString("guest_list_item"); itemID != "" {
+		setItemEmailStatus(app, itemID, "", "")
+	}
+
+	utils.LogFromRequest(app, re, "email_cancel", utils.CollectionEmailOutbox, id, "success",
+		map[string]any{"kind": record.GetString("kind")}, "")
+
+	return utils.DataResponse(re, buildOutboxResponse(record))
+}
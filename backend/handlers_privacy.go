@@ -0,0 +1,113 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// handleContactSubjectAccessExport returns everything held about a contact.
+// ?format=zip downloads one JSON file per section; the default is a single JSON document.
+func handleContactSubjectAccessExport(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Contact ID required")
+	}
+
+	contact, err := app.FindRecordById(utils.CollectionContacts, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Contact not found")
+	}
+
+	format := re.Request.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		return utils.BadRequestResponse(re, "format must be json or zip")
+	}
+
+	bundle := buildSubjectAccessBundle(app, contact)
+
+	utils.LogFromRequest(app, re, "subject_access_export", utils.CollectionContacts, id, "success", map[string]any{
+		"format":           format,
+		"activities":       len(bundle.Activities),
+		"guest_list_items": len(bundle.GuestListItems),
+		"audit_entries":    len(bundle.AuditLog),
+	}, "")
+
+	if format != "zip" {
+		return utils.DataResponse(re, bundle)
+	}
+
+	filename := fmt.Sprintf("subject-access-%s-%s.zip", id, time.Now().Format("20060102-1504"))
+	re.Response.Header().Set("Content-Type", "application/zip")
+	re.Response.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	re.Response.Header().Set("Cache-Control", "no-store")
+	re.Response.WriteHeader(http.StatusOK)
+
+	zw := zip.NewWriter(re.Response)
+	for _, section := range bundle.sections() {
+		w, err := zw.Create(section.Name + ".json")
+		if err != nil {
+			log.Printf("[Privacy] Failed to write %s: %v", section.Name, err)
+			break
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(section.Data); err != nil {
+			log.Printf("[Privacy] Failed to encode %s: %v", section.Name, err)
+			break
+		}
+	}
+	return zw.Close()
+}
+
+// handleContactErase anonymises a contact and scrubs its PII from related records and
+// audit entries. The body must repeat the contact ID as {"confirm": "<id>"}.
+func handleContactErase(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Contact ID required")
+	}
+
+	var input struct {
+		Confirm string `json:"confirm"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+	if input.Confirm != id {
+		return utils.BadRequestResponse(re, "confirm must match the contact ID")
+	}
+
+	contact, err := app.FindRecordById(utils.CollectionContacts, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Contact not found")
+	}
+	if !contact.GetDateTime("erased_at").IsZero() {
+		return utils.BadRequestResponse(re, "Contact has already been erased")
+	}
+
+	result, err := eraseContact(app, contact)
+	if err != nil {
+		log.Printf("[Privacy] Failed to erase contact %s: %v", id, err)
+		utils.LogFromRequest(app, re, "erase", utils.CollectionContacts, id, "failure", nil, err.Error())
+		return utils.InternalErrorResponse(re, "Failed to erase contact")
+	}
+
+	// Counts only — the entry is written after the scrub and holds no PII
+	utils.LogFromRequest(app, re, "erase", utils.CollectionContacts, id, "success", map[string]any{
+		"result": result,
+	}, "")
+
+	return utils.DataResponse(re, map[string]any{
+		"id":        id,
+		"erased_at": contact.GetString("erased_at"),
+		"result":    result,
+	})
+}
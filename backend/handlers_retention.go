@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func buildRetentionPolicyResponse(r *core.Record) map[string]any {
+	data := map[string]any{
+		"id":           r.Id,
+		"target":       r.GetString("target"),
+		"ttl_hours":    r.GetInt("ttl_hours"),
+		"enabled":      r.GetBool("enabled"),
+		"last_run_at":  r.GetString("last_run_at"),
+		"last_deleted": r.GetInt("last_deleted"),
+		"updated_by":   r.GetString("updated_by"),
+		"updated":      r.GetString("updated"),
+	}
+	if target, ok := findRetentionTarget(r.GetString("target")); ok {
+		data["label"] = target.Label
+		data["description"] = target.Description
+		data["collection"] = target.Collection
+	}
+	return data
+}
+
+// handleRetentionPoliciesList returns every retention policy with its target description
+func handleRetentionPoliciesList(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	records, err := app.FindRecordsByFilter(utils.CollectionRetentionPolicies, "", "target", 0, 0)
+	if err != nil {
+		return utils.DataResponse(re, map[string]any{"items": []any{}})
+	}
+
+	items := make([]map[string]any, 0, len(records))
+	for _, r := range records {
+		items = append(items, buildRetentionPolicyResponse(r))
+	}
+
+	return utils.DataResponse(re, map[string]any{"items": items})
+}
+
+// handleRetentionPolicyUpdate sets a policy's TTL and/or enabled flag
+func handleRetentionPolicyUpdate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	target := re.Request.PathValue("target")
+	if _, ok := findRetentionTarget(target); !ok {
+		return utils.NotFoundResponse(re, "Unknown retention target")
+	}
+
+	record, err := app.FindFirstRecordByFilter(utils.CollectionRetentionPolicies, "target = {:target}", dbx.Params{"target": target})
+	if err != nil {
+		return utils.NotFoundResponse(re, "Retention policy not found")
+	}
+
+	var input struct {
+		TTLHours *int  `json:"ttl_hours"`
+		Enabled  *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+
+	changes := map[string]any{}
+	if input.TTLHours != nil {
+		if *input.TTLHours < 1 {
+			return utils.BadRequestResponse(re, "ttl_hours must be at least 1")
+		}
+		changes["ttl_hours"] = map[string]any{"from": record.GetInt("ttl_hours"), "to": *input.TTLHours}
+		record.Set("ttl_hours", *input.TTLHours)
+	}
+	if input.Enabled != nil {
+		changes["enabled"] = map[string]any{"from": record.GetBool("enabled"), "to": *input.Enabled}
+		record.Set("enabled", *input.Enabled)
+	}
+	if re.Auth != nil {
+		record.Set("updated_by", re.Auth.GetString("email"))
+	}
+
+	if err := app.Save(record); err != nil {
+		log.Printf("[Retention] Failed to update policy %s: %v", target, err)
+		return utils.InternalErrorResponse(re, "Failed to update retention policy")
+	}
+
+	utils.LogFromRequest(app, re, "retention_policy_update", utils.CollectionRetentionPolicies, record.Id, "success", changes, "")
+
+	return utils.DataResponse(re, buildRetentionPolicyResponse(record))
+}
+
+// handleRetentionPreview reports what each policy would delete right now, without deleting
+func handleRetentionPreview(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	results, err := runRetentionPolicies(app, true, "manual")
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Failed to evaluate retention policies")
+	}
+
+	return utils.DataResponse(re, map[string]any{"dry_run": true, "items": results})
+}
+
+// handleRetentionRun applies the enabled policies now rather than waiting for the nightly job
+func handleRetentionRun(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	results, err := runRetentionPolicies(app, false, "manual")
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Failed to run retention policies")
+	}
+
+	deleted := 0
+	for _, r := range results {
+		deleted += r.Deleted
+	}
+	utils.LogFromRequest(app, re, "retention_purge", utils.CollectionRetentionPolicies, "", "success",
+		map[string]any{"trigger": "manual", "deleted": deleted}, "")
+
+	return utils.DataResponse(re, map[string]any{"dry_run": false, "items": results})
+}
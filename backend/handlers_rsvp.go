@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -114,6 +115,10 @@ func handlePublicRSVPInfo(re *core.RequestEvent, app *pocketbase.PocketBase) err
 		"program_description":    result.GuestList.GetString("program_description"),
 		"program_title":          result.GuestList.GetString("program_title"),
 		"plus_ones_enabled":      result.GuestList.GetBool("rsvp_plus_ones_enabled"),
+		"rsvp_questions":         guestListRSVPQuestions(result.GuestList),
+		"sessions":               publicSessionsResponse(app, result.GuestList),
+		"rsvp_deadline":          result.GuestList.GetString("rsvp_deadline"),
+		"rsvp_closed":            rsvpDeadlinePassed(result.GuestList),
 	}
 
 	// Merge event projection details
@@ -196,6 +201,16 @@ func handlePublicRSVPInfo(re *core.RequestEvent, app *pocketbase.PocketBase) err
 		rsvpStatus := result.Item.GetString("rsvp_status")
 		response["already_responded"] = rsvpStatus != ""
 		response["rsvp_status"] = rsvpStatus
+		response["can_change"] = !rsvpDeadlinePassed(result.GuestList)
+		response["can_cancel"] = rsvpStatus == "accepted" || rsvpStatus == rsvpWaitlisted
+		if rsvpStatus == rsvpWaitlisted {
+			response["waitlist_position"] = waitlistPosition(app, result.Item)
+		}
+		if result.Item.GetString("invite_status") == "accepted" {
+			if code := checkInCode(result.Item.Id); code != "" {
+				response["check_in_qr_url"] = checkInQRImageURL(code)
+			}
+		}
 
 		if rsvpStatus != "" {
 			response["rsvp_plus_one"] = result.Item.GetBool("rsvp_plus_one")
@@ -206,6 +221,8 @@ func handlePublicRSVPInfo(re *core.RequestEvent, app *pocketbase.PocketBase) err
 			response["rsvp_plus_one_email"] = result.Item.GetString("rsvp_plus_one_email")
 			response["rsvp_plus_one_dietary"] = result.Item.GetString("rsvp_plus_one_dietary")
 			response["rsvp_comments"] = result.Item.GetString("rsvp_comments")
+			response["rsvp_answers"] = itemRSVPAnswers(result.Item)
+			response["rsvp_sessions"] = itemRSVPSessions(result.Item)
 		}
 	}
 
@@ -231,6 +248,9 @@ type rsvpInput struct {
 	Response                     string   `json:"response"`
 	InvitedBy                    string   `json:"invited_by"`
 	Comments                     string   `json:"comments"`
+	Answers                      map[string]any `json:"answers"` // custom questions, keyed by question id
+	Sessions                     []string `json:"sessions"` // IDs of the sessions picked
+	Consent                      map[string]bool `json:"consent"` // optional communication preferences
 }
 
 func handlePublicRSVPSubmit(re *core.RequestEvent, app *pocketbase.PocketBase) error {
@@ -245,6 +265,10 @@ func handlePublicRSVPSubmit(re *core.RequestEvent, app *pocketbase.PocketBase) e
 		return re.JSON(http.StatusGone, map[string]string{"error": "RSVP is no longer available for this event"})
 	}
 
+	if rsvpDeadlinePassed(result.GuestList) {
+		return re.JSON(http.StatusGone, map[string]string{"error": "RSVPs for this event have closed"})
+	}
+
 	var input rsvpInput
 	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
 		return utils.BadRequestResponse(re, "Invalid JSON")
@@ -277,6 +301,21 @@ func handlePublicRSVPSubmit(re *core.RequestEvent, app *pocketbase.PocketBase) e
 	if len(input.Comments) > 2000 {
 		return utils.BadRequestResponse(re, "Comments must be 2000 characters or less")
 	}
+	if input.Consent != nil {
+		if err := validateConsentInput(input.Consent); err != nil {
+			return utils.BadRequestResponse(re, err.Error())
+		}
+	}
+	answers, err := validateRSVPAnswers(guestListRSVPQuestions(result.GuestList), input.Answers, input.Response)
+	if err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+	input.Answers = answers
+	sessions, err := validateRSVPSessions(guestListSessions(result.GuestList), input.Sessions, input.Response)
+	if err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+	input.Sessions = sessions
 
 	// Compose full name for backward compat
 	fullName := input.FirstName
@@ -292,6 +331,100 @@ func handlePublicRSVPSubmit(re *core.RequestEvent, app *pocketbase.PocketBase) e
 	return handleGenericRSVP(re, app, result, &input, fullName, now)
 }
 
+// handlePublicRSVPCancel cancels an RSVP from the guest's personal link. They're marked as
+// not attending, leaving the waitlist if they were on it, and their plus-one with them.
+// Allowed after the RSVP deadline, unlike changes.
+func handlePublicRSVPCancel(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	token := re.Request.PathValue("token")
+
+	result, err := lookupRSVPToken(app, token)
+	if err != nil {
+		return utils.NotFoundResponse(re, "RSVP link not found")
+	}
+	if result.Type != "personal" {
+		return utils.BadRequestResponse(re, "Please use the link from your invitation to cancel")
+	}
+	if !result.GuestList.GetBool("rsvp_enabled") {
+		return re.JSON(http.StatusGone, map[string]string{"error": "RSVP is no longer available for this event"})
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if re.Request.ContentLength != 0 {
+		if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+			return utils.BadRequestResponse(re, "Invalid JSON")
+		}
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	if len(input.Reason) > 1000 {
+		return utils.BadRequestResponse(re, "Reason must be 1000 characters or less")
+	}
+
+	item := result.Item
+	previous := item.GetString("rsvp_status")
+	if previous != "accepted" && previous != rsvpWaitlisted {
+		return utils.BadRequestResponse(re, "There's no RSVP to cancel")
+	}
+
+	item.Set("rsvp_status", "declined")
+	item.Set("invite_status", "declined")
+	item.Set("rsvp_plus_one", false)
+	item.Set("rsvp_responded_at", time.Now().UTC().Format(time.RFC3339))
+	setItemWaitlisted(item, false)
+	change := appendRSVPChange(item, rsvpChangeCancelled, rsvpChangeByGuest, input.Reason)
+
+	if err := app.Save(item); err != nil {
+		log.Printf("[RSVP] Failed to cancel RSVP for item %s: %v", item.Id, err)
+		return utils.InternalErrorResponse(re, "Failed to cancel RSVP")
+	}
+	recordRSVPEvent(app, item, "declined")
+
+	utils.LogAudit(app, utils.AuditEntry{
+		Action:       "rsvp_cancel",
+		ResourceType: utils.CollectionGuestListItems,
+		ResourceID:   item.Id,
+		IPAddress:    re.RealIP(),
+		UserAgent:    re.Request.UserAgent(),
+		Status:       "success",
+		Metadata:     map[string]any{"previous": previous, "reason": input.Reason},
+	})
+
+	notifyRSVPChangeAsync(app, result.GuestList, item, change)
+
+	// Take them off the Outlook calendar event if they were added to it
+	calendarEventID := result.GuestList.GetString("ms_calendar_event_id")
+	hostUserID := result.GuestList.GetString("event_host")
+	if previous == "accepted" && calendarEventID != "" && hostUserID != "" {
+		if contact, err := app.FindRecordById(utils.CollectionContacts, item.GetString("contact")); err == nil {
+			if email := utils.DecryptField(contact.GetString("email")); email != "" {
+				go func() {
+					if err := removeAttendeeFromCalendarEvent(app, hostUserID, calendarEventID, email); err != nil {
+						log.Printf("[Calendar] Failed to remove attendee %s from event: %v", email, err)
+					}
+				}()
+			}
+		}
+	}
+
+	return utils.DataResponse(re, map[string]any{"message": "Your RSVP has been cancelled"})
+}
+
+// recordRSVPConsent saves any communication preferences given on the RSVP form
+func recordRSVPConsent(re *core.RequestEvent, app *pocketbase.PocketBase, contactID string, input *rsvpInput, guestListID string) {
+	if len(input.Consent) == 0 || contactID == "" {
+		return
+	}
+	contact, err := app.FindRecordById(utils.CollectionContacts, contactID)
+	if err != nil {
+		return
+	}
+	proof := consentProofFromRequest(re, "rsvp", "rsvp:"+guestListID)
+	if _, err := updateContactConsent(app, contact, input.Consent, proof); err != nil {
+		log.Printf("[RSVP] Failed to record consent for contact %s: %v", contactID, err)
+	}
+}
+
 func setItemRSVPFields(item *core.Record, input *rsvpInput, fullName, now string) {
 	item.Set("rsvp_status", input.Response)
 	item.Set("rsvp_plus_one", input.PlusOne)
@@ -303,6 +436,8 @@ func setItemRSVPFields(item *core.Record, input *rsvpInput, fullName, now string
 	item.Set("rsvp_plus_one_dietary", input.PlusOneDietary)
 	item.Set("rsvp_responded_at", now)
 	item.Set("rsvp_comments", input.Comments)
+	item.Set("rsvp_answers", input.Answers)
+	item.Set("rsvp_sessions", input.Sessions)
 	item.Set("invite_status", input.Response) // sync invite_status
 }
 
@@ -446,9 +581,9 @@ func upsertPlusOneContact(app *pocketbase.PocketBase, input *rsvpInput) *core.Re
 	return newContact
 }
 
-// addPlusOneToGuestList adds the plus-one contact to the guest list with invite_round "maybe".
-// Skips if the contact is already on the list.
-func addPlusOneToGuestList(app *pocketbase.PocketBase, contact *core.Record, input *rsvpInput, listID string) {
+// addPlusOneToGuestList adds the plus-one contact to the guest list with invite_round "maybe",
+// linked to the guest who brought them. Skips if the contact is already on the list.
+func addPlusOneToGuestList(app *pocketbase.PocketBase, contact *core.Record, input *rsvpInput, listID, hostItemID string) {
 	if contact == nil {
 		return
 	}
@@ -484,6 +619,7 @@ func addPlusOneToGuestList(app *pocketbase.PocketBase, contact *core.Record, inp
 	record.Set("contact", contact.Id)
 	record.Set("invite_round", "maybe")
 	record.Set("invite_status", "")
+	record.Set("plus_one_of", hostItemID)
 	record.Set("sort_order", getNextSortOrder(app, listID))
 	if token, err := generateToken(); err == nil {
 		record.Set("rsvp_token", token)
@@ -555,7 +691,7 @@ func extractBCCEmails(guestList *core.Record) []string {
 
 // sendRSVPConfirmationAsync sends a confirmation email in the background if the response is "accepted".
 // Also adds the attendee to the Outlook calendar event if one is configured.
-func sendRSVPConfirmationAsync(app *pocketbase.PocketBase, result *rsvpLookupResult, input *rsvpInput, fullName string) {
+func sendRSVPConfirmationAsync(app *pocketbase.PocketBase, result *rsvpLookupResult, item *core.Record, input *rsvpInput, fullName string) {
 	if input.Response != "accepted" {
 		return
 	}
@@ -563,18 +699,11 @@ func sendRSVPConfirmationAsync(app *pocketbase.PocketBase, result *rsvpLookupRes
 
 	// Resolve event details from projection or guest list
 	eventName := gl.GetString("name")
-	var startDate, endDate, startTime, endTime, timezone, eventDescription string
 	if epID := gl.GetString("event_projection"); epID != "" {
 		if ep, err := app.FindRecordById(utils.CollectionEventProjections, epID); err == nil {
 			if n := ep.GetString("name"); n != "" {
 				eventName = n
 			}
-			startDate = ep.GetString("start_date")
-			endDate = ep.GetString("end_date")
-			startTime = ep.GetString("start_time")
-			endTime = ep.GetString("end_time")
-			timezone = ep.GetString("timezone")
-			eventDescription = ep.GetString("description")
 		}
 	}
 
@@ -584,32 +713,16 @@ func sendRSVPConfirmationAsync(app *pocketbase.PocketBase, result *rsvpLookupRes
 	bccEmails := extractBCCEmails(gl)
 	emailTheme := buildEmailTheme(app, gl)
 
-	// Build .ics calendar attachment
-	icsStartDate := startDate
-	if icsStartDate == "" {
-		icsStartDate = eventDate
-	}
-	icsStartTime := startTime
-	if icsStartTime == "" {
-		icsStartTime = eventTime
-	}
-	icsEndDate := endDate
-	if icsEndDate == "" {
-		icsEndDate = icsStartDate
-	}
-
-	var icsData []byte
-	icsEvent := buildICSEventFromGuestList(gl.Id, eventName, eventDescription, icsStartDate, icsEndDate, icsStartTime, endTime, timezone, eventLocation)
-	if icsEvent != nil {
-		icsData = generateICS(*icsEvent)
-	}
+	// Build .ics calendar attachment, with the guest's sessions
+	sessions := pickedSessions(guestListSessions(gl), input.Sessions)
+	icsData := guestListICS(app, gl, input.Sessions)
 
 	// Capture calendar event details for async goroutine
 	calendarEventID := gl.GetString("ms_calendar_event_id")
 	hostUserID := gl.GetString("event_host")
 
 	go func() {
-		if err := sendRSVPConfirmationEmail(app, input.Email, fullName, eventName, eventDate, eventTime, eventLocation, bccEmails, emailTheme, icsData); err != nil {
+		if err := sendRSVPConfirmationEmail(app, input.Email, fullName, eventName, eventDate, eventTime, eventLocation, bccEmails, emailTheme, sessions, icsData, item.Id, checkInCode(item.Id)); err != nil {
 			log.Printf("[RSVP] Failed to send confirmation email to %s: %v", input.Email, err)
 		}
 
@@ -633,9 +746,18 @@ func handlePersonalRSVP(re *core.RequestEvent, app *pocketbase.PocketBase, resul
 		item.Set("contact_name", fullName)
 	}
 
-	if err := app.Save(item); err != nil {
+	change, err := saveRSVPItem(app, result.GuestList, item, input)
+	if err != nil {
+		if errors.Is(err, errNoPlusOneSeat) {
+			return utils.BadRequestResponse(re, "The event is full, so we can't add a plus-one")
+		}
+		var full *sessionFullError
+		if errors.As(err, &full) {
+			return utils.BadRequestResponse(re, full.Error())
+		}
 		return utils.InternalErrorResponse(re, "Failed to save RSVP")
 	}
+	recordRSVPEvent(app, item, input.Response)
 
 	// Upsert the linked contact directly
 	if contactID := item.GetString("contact"); contactID != "" {
@@ -644,9 +766,14 @@ func handlePersonalRSVP(re *core.RequestEvent, app *pocketbase.PocketBase, resul
 		}
 	}
 
-	// Upsert plus-one as a contact and add to guest list as "maybe"
-	plusOneContact := upsertPlusOneContact(app, input)
-	addPlusOneToGuestList(app, plusOneContact, input, result.GuestList.Id)
+	recordRSVPConsent(re, app, item.GetString("contact"), input, result.GuestList.Id)
+
+	// Upsert plus-one as a contact and add to guest list as "maybe". A waitlisted guest's
+	// plus-one isn't added until the guest has a seat.
+	if input.Response != rsvpWaitlisted {
+		plusOneContact := upsertPlusOneContact(app, input)
+		addPlusOneToGuestList(app, plusOneContact, input, result.GuestList.Id, item.Id)
+	}
 
 	utils.LogAudit(app, utils.AuditEntry{
 		Action:       "rsvp_submit",
@@ -658,10 +785,13 @@ func handlePersonalRSVP(re *core.RequestEvent, app *pocketbase.PocketBase, resul
 		Metadata:     map[string]any{"type": "personal", "response": input.Response},
 	})
 
-	sendRSVPConfirmationAsync(app, result, input, fullName)
-	sendPlusOneNotificationAsync(app, result, input, fullName)
+	sendRSVPConfirmationAsync(app, result, item, input, fullName)
+	if input.Response != rsvpWaitlisted {
+		sendPlusOneNotificationAsync(app, result, input, fullName)
+	}
+	notifyRSVPChangeAsync(app, result.GuestList, item, change)
 
-	return re.JSON(http.StatusOK, map[string]string{"message": "RSVP submitted successfully"})
+	return rsvpSubmittedResponse(re, app, item)
 }
 
 func handleGenericRSVP(re *core.RequestEvent, app *pocketbase.PocketBase, result *rsvpLookupResult, input *rsvpInput, fullName, now string) error {
@@ -700,13 +830,27 @@ func handleGenericRSVP(re *core.RequestEvent, app *pocketbase.PocketBase, result
 			setItemRSVPFields(item, input, fullName, now)
 			item.Set("rsvp_invited_by", input.InvitedBy)
 
-			if err := app.Save(item); err != nil {
+			change, err := saveRSVPItem(app, result.GuestList, item, input)
+			if err != nil {
+				if errors.Is(err, errNoPlusOneSeat) {
+					return utils.BadRequestResponse(re, "The event is full, so we can't add a plus-one")
+				}
+				var full *sessionFullError
+				if errors.As(err, &full) {
+					return utils.BadRequestResponse(re, full.Error())
+				}
 				return utils.InternalErrorResponse(re, "Failed to save RSVP")
 			}
+			recordRSVPEvent(app, item, input.Response)
+
+			recordRSVPConsent(re, app, existingContact.Id, input, listID)
 
-			// Upsert plus-one as a contact and add to guest list as "maybe"
-			plusOneContact := upsertPlusOneContact(app, input)
-			addPlusOneToGuestList(app, plusOneContact, input, listID)
+			// Upsert plus-one as a contact and add to guest list as "maybe". A waitlisted guest's
+			// plus-one isn't added until the guest has a seat.
+			if input.Response != rsvpWaitlisted {
+				plusOneContact := upsertPlusOneContact(app, input)
+				addPlusOneToGuestList(app, plusOneContact, input, listID, item.Id)
+			}
 
 			utils.LogAudit(app, utils.AuditEntry{
 				Action:       "rsvp_submit",
@@ -718,10 +862,13 @@ func handleGenericRSVP(re *core.RequestEvent, app *pocketbase.PocketBase, result
 				Metadata:     map[string]any{"type": "generic", "response": input.Response, "matched_existing_item": true},
 			})
 
-			sendRSVPConfirmationAsync(app, result, input, fullName)
-			sendPlusOneNotificationAsync(app, result, input, fullName)
+			sendRSVPConfirmationAsync(app, result, item, input, fullName)
+			if input.Response != rsvpWaitlisted {
+				sendPlusOneNotificationAsync(app, result, input, fullName)
+			}
+			notifyRSVPChangeAsync(app, result.GuestList, item, change)
 
-			return re.JSON(http.StatusOK, map[string]string{"message": "RSVP submitted successfully"})
+			return rsvpSubmittedResponse(re, app, item)
 		}
 
 		// Contact exists but not on this list — create new item linking to existing contact
@@ -806,17 +953,27 @@ func createGuestListItemFromRSVP(re *core.RequestEvent, app *pocketbase.PocketBa
 	setItemRSVPFields(record, input, fullName, now)
 	record.Set("rsvp_invited_by", input.InvitedBy)
 
-	if err := app.Save(record); err != nil {
+	if _, err := saveRSVPItem(app, result.GuestList, record, input); err != nil {
+		var full *sessionFullError
+		if errors.As(err, &full) {
+			return utils.BadRequestResponse(re, full.Error())
+		}
 		log.Printf("[RSVP] Failed to create guest list item: %v", err)
 		if strings.Contains(err.Error(), "UNIQUE constraint") {
 			return utils.BadRequestResponse(re, "You have already RSVP'd for this event")
 		}
 		return utils.InternalErrorResponse(re, "Failed to save RSVP")
 	}
+	recordRSVPEvent(app, record, input.Response)
 
-	// Upsert plus-one as a contact and add to guest list as "maybe"
-	plusOneContact := upsertPlusOneContact(app, input)
-	addPlusOneToGuestList(app, plusOneContact, input, listID)
+	recordRSVPConsent(re, app, contact.Id, input, listID)
+
+	// Upsert plus-one as a contact and add to guest list as "maybe". A waitlisted guest's
+	// plus-one isn't added until the guest has a seat.
+	if input.Response != rsvpWaitlisted {
+		plusOneContact := upsertPlusOneContact(app, input)
+		addPlusOneToGuestList(app, plusOneContact, input, listID, record.Id)
+	}
 
 	utils.LogAudit(app, utils.AuditEntry{
 		Action:       "rsvp_submit",
@@ -828,10 +985,12 @@ func createGuestListItemFromRSVP(re *core.RequestEvent, app *pocketbase.PocketBa
 		Metadata:     map[string]any{"type": "generic", "response": input.Response, "contact_id": contact.Id, "new_contact": contact.GetString("status") == "pending"},
 	})
 
-	sendRSVPConfirmationAsync(app, result, input, fullName)
-	sendPlusOneNotificationAsync(app, result, input, fullName)
+	sendRSVPConfirmationAsync(app, result, record, input, fullName)
+	if input.Response != rsvpWaitlisted {
+		sendPlusOneNotificationAsync(app, result, input, fullName)
+	}
 
-	return re.JSON(http.StatusOK, map[string]string{"message": "RSVP submitted successfully"})
+	return rsvpSubmittedResponse(re, app, record)
 }
 
 // resolveProgramAvatars enriches landing_program items with current avatar URLs from contacts.
@@ -920,6 +1079,10 @@ func handlePublicRSVPForward(re *core.RequestEvent, app *pocketbase.PocketBase)
 		return re.JSON(http.StatusGone, map[string]string{"error": "RSVP is no longer available for this event"})
 	}
 
+	if rsvpDeadlinePassed(result.GuestList) {
+		return re.JSON(http.StatusGone, map[string]string{"error": "RSVPs for this event have closed"})
+	}
+
 	var input rsvpForwardInput
 	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
 		return utils.BadRequestResponse(re, "Invalid JSON")
@@ -1087,7 +1250,7 @@ func handlePublicRSVPForward(re *core.RequestEvent, app *pocketbase.PocketBase)
 		return utils.InternalErrorResponse(re, "Failed to forward invitation")
 	}
 
-	// Send email in background
+	// Queue the invitation email
 	eventName := result.GuestList.GetString("name")
 	if epID := result.GuestList.GetString("event_projection"); epID != "" {
 		if ep, err := app.FindRecordById(utils.CollectionEventProjections, epID); err == nil {
@@ -1103,7 +1266,8 @@ func handlePublicRSVPForward(re *core.RequestEvent, app *pocketbase.PocketBase)
 	eventTime := result.GuestList.GetString("event_time")
 	eventLocation := result.GuestList.GetString("event_location")
 	emailTheme := buildEmailTheme(app, result.GuestList)
-	go sendRSVPForwardEmail(app, input.RecipientEmail, input.RecipientName, input.ForwarderName, input.ForwarderEmail, rsvpURL, listDescription, eventName, eventDate, eventTime, eventLocation, emailTheme)
+	// Failures are logged by sendRSVPForwardEmail; the forward itself has already been saved
+	sendRSVPForwardEmail(app, input.RecipientEmail, input.RecipientName, input.ForwarderName, input.ForwarderEmail, rsvpURL, item.Id, listDescription, eventName, eventDate, eventTime, eventLocation, emailTheme)
 
 	utils.LogAudit(app, utils.AuditEntry{
 		Action:       "rsvp_forward",
@@ -1190,105 +1354,8 @@ func handleGuestListRSVPSendInvites(re *core.RequestEvent, app *pocketbase.Pocke
 		return utils.BadRequestResponse(re, "Invalid JSON")
 	}
 
-	// Get event name for email
-	eventName := ""
-	if epID := guestList.GetString("event_projection"); epID != "" {
-		if ep, err := app.FindRecordById(utils.CollectionEventProjections, epID); err == nil {
-			eventName = ep.GetString("name")
-		}
-	}
-	listName := guestList.GetString("name")
-	listDescription := guestList.GetString("description")
-	eventDate := guestList.GetString("event_date")
-	eventTime := guestList.GetString("event_time")
-	eventLocation := guestList.GetString("event_location")
-	emailTheme := buildEmailTheme(app, guestList)
-
-	// Find items to send to
-	var items []*core.Record
-	if len(input.ItemIDs) > 0 {
-		// Send to specific items
-		for _, itemID := range input.ItemIDs {
-			item, err := app.FindRecordById(utils.CollectionGuestListItems, itemID)
-			if err != nil {
-				continue
-			}
-			if item.GetString("guest_list") != id {
-				continue
-			}
-			items = append(items, item)
-		}
-	} else {
-		// Send to all items with invite_status = "to_invite" or empty
-		allItems, err := app.FindRecordsByFilter(
-			utils.CollectionGuestListItems,
-			"guest_list = {:id} && (invite_status = 'to_invite' || invite_status = '')",
-			"sort_order,created",
-			0, 0,
-			map[string]any{"id": id},
-		)
-		if err == nil {
-			items = allItems
-		}
-	}
-
-	sent := 0
-	skipped := 0
-
-	for _, item := range items {
-		contactID := item.GetString("contact")
-		if contactID == "" {
-			skipped++
-			continue
-		}
-
-		contact, err := app.FindRecordById(utils.CollectionContacts, contactID)
-		if err != nil {
-			skipped++
-			continue
-		}
-
-		email := utils.DecryptField(contact.GetString("email"))
-		if email == "" || !strings.Contains(email, "@") {
-			skipped++
-			continue
-		}
-
-		// Generate RSVP token if not already set
-		if item.GetString("rsvp_token") == "" {
-			token, err := generateToken()
-			if err != nil {
-				skipped++
-				continue
-			}
-			item.Set("rsvp_token", token)
-		}
-
-		// Update invite_status to "invited"
-		item.Set("invite_status", "invited")
-		if err := app.Save(item); err != nil {
-			log.Printf("[RSVP] Failed to save item %s: %v", item.Id, err)
-			skipped++
-			continue
-		}
-
-		// Build RSVP URL and send email
-		rsvpURL := fmt.Sprintf("%s/rsvp/%s", getPublicBaseURL(), item.GetString("rsvp_token"))
-		recipientName := contact.GetString("name")
-
-		go sendRSVPInviteEmail(app, email, recipientName, rsvpURL, item.GetString("rsvp_token"), listName, listDescription, eventName, eventDate, eventTime, eventLocation, emailTheme)
-		sent++
-	}
-
-	utils.LogFromRequest(app, re, "rsvp_send_invites", utils.CollectionGuestLists, id, "success", map[string]any{
-		"sent":    sent,
-		"skipped": skipped,
-	}, "")
-
-	return re.JSON(http.StatusOK, map[string]any{
-		"sent":    sent,
-		"skipped": skipped,
-	})
+	// Specific items, or everyone with invite_status = "to_invite" or empty
+	return sendManualRSVPRound(re, app, guestList, rsvpActionInvite, input.ItemIDs)
 }
 
 func handleGuestListRSVPSendFollowups(re *core.RequestEvent, app *pocketbase.PocketBase) error {
@@ -1301,77 +1368,33 @@ func handleGuestListRSVPSendFollowups(re *core.RequestEvent, app *pocketbase.Poc
 	if !guestList.GetBool("rsvp_enabled") {
 		return utils.BadRequestResponse(re, "RSVP is not enabled for this guest list")
 	}
-
-	// Get event name for email
-	eventName := ""
-	if epID := guestList.GetString("event_projection"); epID != "" {
-		if ep, err := app.FindRecordById(utils.CollectionEventProjections, epID); err == nil {
-			eventName = ep.GetString("name")
-		}
+	if rsvpDeadlinePassed(guestList) {
+		return utils.BadRequestResponse(re, "RSVPs for this guest list have closed")
 	}
-	listName := guestList.GetString("name")
-	listDescription := guestList.GetString("description")
-	eventDate := guestList.GetString("event_date")
-	eventTime := guestList.GetString("event_time")
-	eventLocation := guestList.GetString("event_location")
-	emailTheme := buildEmailTheme(app, guestList)
 
-	// Find items that were invited but haven't responded
-	items, err := app.FindRecordsByFilter(
-		utils.CollectionGuestListItems,
-		"guest_list = {:id} && invite_status = 'invited' && rsvp_status = ''",
-		"sort_order,created",
-		0, 0,
-		map[string]any{"id": id},
-	)
+	// Items that were invited but haven't responded
+	return sendManualRSVPRound(re, app, guestList, rsvpActionFollowUp, nil)
+}
+
+// sendManualRSVPRound sends a round from the admin buttons and records it alongside the
+// scheduled and cadence rounds
+func sendManualRSVPRound(re *core.RequestEvent, app *pocketbase.PocketBase, guestList *core.Record, action string, itemIDs []string) error {
+	round, err := newRSVPRound(app, guestList.Id, action, rsvpRoundManual)
 	if err != nil {
-		items = nil
+		return utils.InternalErrorResponse(re, "Collection not found")
 	}
-
-	sent := 0
-	skipped := 0
-
-	for _, item := range items {
-		contactID := item.GetString("contact")
-		if contactID == "" {
-			skipped++
-			continue
-		}
-
-		contact, err := app.FindRecordById(utils.CollectionContacts, contactID)
-		if err != nil {
-			skipped++
-			continue
-		}
-
-		email := utils.DecryptField(contact.GetString("email"))
-		if email == "" || !strings.Contains(email, "@") {
-			skipped++
-			continue
-		}
-
-		rsvpToken := item.GetString("rsvp_token")
-		if rsvpToken == "" {
-			skipped++
-			continue
-		}
-
-		rsvpURL := fmt.Sprintf("%s/rsvp/%s", getPublicBaseURL(), rsvpToken)
-		recipientName := contact.GetString("name")
-
-		go sendRSVPFollowUpEmail(app, email, recipientName, rsvpURL, rsvpToken, listName, listDescription, eventName, eventDate, eventTime, eventLocation, emailTheme)
-		sent++
+	if re.Auth != nil {
+		round.Set("created_by", re.Auth.GetString("email"))
 	}
 
-	utils.LogFromRequest(app, re, "rsvp_send_followups", utils.CollectionGuestLists, id, "success", map[string]any{
-		"sent":    sent,
-		"skipped": skipped,
-	}, "")
+	result := sendRSVPRound(app, guestList, action, rsvpRoundItems(app, guestList.Id, action, itemIDs))
+	finishRSVPRound(app, round, result)
 
-	return re.JSON(http.StatusOK, map[string]any{
-		"sent":    sent,
-		"skipped": skipped,
-	})
+	changes := result.counts()
+	changes["round"] = round.Id
+	utils.LogFromRequest(app, re, rsvpAuditActions[action], utils.CollectionGuestLists, guestList.Id, "success", changes, "")
+
+	return re.JSON(http.StatusOK, result.counts())
 }
 
 // handlePublicRSVPEmailPreview renders a browser-viewable preview of the RSVP invite email
@@ -1419,9 +1442,13 @@ func handlePublicRSVPEmailPreview(re *core.RequestEvent, app *pocketbase.PocketB
 
 	rsvpURL := fmt.Sprintf("%s/rsvp/%s", getPublicBaseURL(), token)
 	theme := buildEmailTheme(app, gl)
-	content := buildRSVPInviteContent(theme, eventContext, firstName, listDescription, eventDate, eventTime, eventLocation, rsvpURL)
+	rendered, err := renderEmailTemplate(app, emailKindRSVPInvite, theme.GuestListID, theme.ThemeID,
+		rsvpEmailData(theme, eventContext, firstName, listName, listDescription, eventDate, eventTime, eventLocation, rsvpURL))
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Failed to render email")
+	}
 
-	html := wrapRSVPEmailHTML(content, theme)
+	html := wrapRSVPEmailHTML(rendered.HTML, theme, "")
 
 	re.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
 	re.Response.WriteHeader(http.StatusOK)
@@ -1447,6 +1474,8 @@ func handleTrackOpen(re *core.RequestEvent, app *pocketbase.PocketBase) error {
 	)
 	if err == nil && len(items) > 0 {
 		item := items[0]
+		msg := trackedMessage(app, item, re.Request.URL.Query().Get("m"))
+		recordEmailEvent(app, emailEvent{Type: emailEventOpened, Message: msg, GuestListItem: item})
 		if !item.GetBool("invite_opened") {
 			item.Set("invite_opened", true)
 			if err := app.Save(item); err != nil {
@@ -1485,6 +1514,8 @@ func handleTrackClick(re *core.RequestEvent, app *pocketbase.PocketBase) error {
 	)
 	if err == nil && len(items) > 0 {
 		item := items[0]
+		msg := trackedMessage(app, item, re.Request.URL.Query().Get("m"))
+		recordEmailEvent(app, emailEvent{Type: emailEventClicked, Message: msg, GuestListItem: item, URL: dest})
 		if !item.GetBool("invite_clicked") {
 			item.Set("invite_clicked", true)
 			if err := app.Save(item); err != nil {
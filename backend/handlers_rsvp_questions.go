@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// handleGuestListRSVPQuestionsGet returns a guest list's custom RSVP questions
+func handleGuestListRSVPQuestionsGet(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	guestList, err := app.FindRecordById(utils.CollectionGuestLists, re.Request.PathValue("id"))
+	if err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+	return utils.DataResponse(re, map[string]any{"questions": guestListRSVPQuestions(guestList)})
+}
+
+// handleGuestListRSVPQuestionsUpdate replaces a guest list's questions, in display order.
+// Questions keep their id when it's passed back, so guests' answers stay with them.
+func handleGuestListRSVPQuestionsUpdate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	guestList, err := app.FindRecordById(utils.CollectionGuestLists, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+
+	var input struct {
+		Questions []rsvpQuestion `json:"questions"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid JSON")
+	}
+
+	questions, err := validateRSVPQuestions(input.Questions)
+	if err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+	if len(questions) == 0 {
+		guestList.Set("rsvp_questions", nil)
+	} else {
+		guestList.Set("rsvp_questions", questions)
+	}
+	if err := app.Save(guestList); err != nil {
+		log.Printf("[RSVPQuestions] Failed to save: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to update questions")
+	}
+
+	utils.LogFromRequest(app, re, "update", utils.CollectionGuestLists, id, "success",
+		map[string]any{"rsvp_questions": questions}, "")
+
+	return utils.DataResponse(re, map[string]any{"questions": guestListRSVPQuestions(guestList)})
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func buildRSVPRoundResponse(r *core.Record) map[string]any {
+	itemIDs := []string{}
+	decodeJSONField(r, "item_ids", &itemIDs)
+	return map[string]any{
+		"id":           r.Id,
+		"guest_list":   r.GetString("guest_list"),
+		"action":       r.GetString("action"),
+		"source":       r.GetString("source"),
+		"cadence_step": r.GetString("cadence_step"),
+		"status":       r.GetString("status"),
+		"send_at":      r.GetString("send_at"),
+		"timezone":     r.GetString("timezone"),
+		"item_ids":     itemIDs,
+		"sent":         r.GetInt("sent"),
+		"skipped":      r.GetInt("skipped"),
+		"opted_out":    r.GetInt("opted_out"),
+		"suppressed":   r.GetInt("suppressed"),
+		"error":        r.GetString("error"),
+		"created_by":   r.GetString("created_by"),
+		"started_at":   r.GetString("started_at"),
+		"completed_at": r.GetString("completed_at"),
+		"created":      r.GetString("created"),
+	}
+}
+
+// handleGuestListRSVPRounds returns a guest list's send history and scheduled sends, newest
+// first. Filter: status.
+func handleGuestListRSVPRounds(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if _, err := app.FindRecordById(utils.CollectionGuestLists, id); err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+
+	filter := "guest_list = {:id}"
+	params := dbx.Params{"id": id}
+	if status := re.Request.URL.Query().Get("status"); status != "" {
+		filter += " && status = {:status}"
+		params["status"] = status
+	}
+
+	records, err := app.FindRecordsByFilter(utils.CollectionRSVPRounds, filter, "-created", 0, 0, params)
+	if err != nil {
+		return utils.DataResponse(re, map[string]any{"items": []any{}})
+	}
+
+	items := make([]map[string]any, 0, len(records))
+	for _, r := range records {
+		items = append(items, buildRSVPRoundResponse(r))
+	}
+	return utils.DataResponse(re, map[string]any{"items": items})
+}
+
+// handleGuestListRSVPSchedule books a send for later. send_at is a local date and time in the
+// event's timezone ("2026-03-15T09:00"), or RFC 3339 with an offset. Without item_ids the
+// round goes to whoever is in the action's audience when it runs.
+func handleGuestListRSVPSchedule(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	guestList, err := app.FindRecordById(utils.CollectionGuestLists, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+
+	if !guestList.GetBool("rsvp_enabled") {
+		return utils.BadRequestResponse(re, "RSVP is not enabled for this guest list")
+	}
+
+	var input struct {
+		Action  string   `json:"action"`
+		SendAt  string   `json:"send_at"`
+		ItemIDs []string `json:"item_ids"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid JSON")
+	}
+
+	if !slices.Contains(rsvpActions, input.Action) {
+		return utils.BadRequestResponse(re, "action must be invite, followup or logistics")
+	}
+	loc := guestListLocation(app, guestList)
+	sendAt, err := parseLocalDateTime(input.SendAt, loc)
+	if err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+	if !sendAt.After(time.Now()) {
+		return utils.BadRequestResponse(re, "send_at must be in the future")
+	}
+
+	round, err := newRSVPRound(app, id, input.Action, rsvpRoundScheduled)
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Collection not found")
+	}
+	round.Set("status", rsvpRoundStatusScheduled)
+	round.Set("send_at", sendAt.UTC())
+	round.Set("timezone", loc.String())
+	if len(input.ItemIDs) > 0 {
+		round.Set("item_ids", input.ItemIDs)
+	}
+	if re.Auth != nil {
+		round.Set("created_by", re.Auth.GetString("email"))
+	}
+	if err := app.Save(round); err != nil {
+		log.Printf("[RSVPSchedule] Failed to save: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to schedule send")
+	}
+
+	utils.LogFromRequest(app, re, "rsvp_schedule", utils.CollectionGuestLists, id, "success", map[string]any{
+		"round":   round.Id,
+		"action":  input.Action,
+		"send_at": round.GetString("send_at"),
+		"items":   len(input.ItemIDs),
+	}, "")
+
+	return utils.DataResponse(re, buildRSVPRoundResponse(round))
+}
+
+// handleGuestListRSVPRoundCancel cancels a scheduled send that hasn't started
+func handleGuestListRSVPRoundCancel(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	round, err := app.FindRecordById(utils.CollectionRSVPRounds, re.Request.PathValue("roundId"))
+	if err != nil || round.GetString("guest_list") != id {
+		return utils.NotFoundResponse(re, "Scheduled send not found")
+	}
+
+	// Conditional so a round the scheduler has just claimed isn't marked cancelled
+	res, err := app.DB().Update(utils.CollectionRSVPRounds,
+		dbx.Params{"status": rsvpRoundStatusCancelled},
+		dbx.HashExp{"id": round.Id, "status": rsvpRoundStatusScheduled}).Execute()
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Failed to cancel send")
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return utils.BadRequestResponse(re, "Only scheduled sends that haven't started can be cancelled")
+	}
+
+	utils.LogFromRequest(app, re, "rsvp_schedule_cancel", utils.CollectionGuestLists, id, "success",
+		map[string]any{"round": round.Id, "action": round.GetString("action")}, "")
+
+	round.Set("status", rsvpRoundStatusCancelled)
+	return utils.DataResponse(re, buildRSVPRoundResponse(round))
+}
+
+// buildRSVPCadenceResponse returns the cadence with each before_event step's due time
+func buildRSVPCadenceResponse(app *pocketbase.PocketBase, guestList *core.Record) map[string]any {
+	loc := guestListLocation(app, guestList)
+	eventStart, hasStart := guestListEventStart(app, guestList)
+
+	steps := []map[string]any{}
+	for _, step := range guestListCadence(guestList) {
+		entry := map[string]any{
+			"id":           step.ID,
+			"name":         step.Name,
+			"action":       step.Action,
+			"trigger":      step.Trigger,
+			"offset_hours": step.OffsetHours,
+			"due_at":       "",
+		}
+		if step.Trigger == cadenceBeforeEvent && hasStart {
+			entry["due_at"] = cadenceStepDue(step, eventStart).In(loc).Format(time.RFC3339)
+		}
+		steps = append(steps, entry)
+	}
+
+	start := ""
+	if hasStart {
+		start = eventStart.In(loc).Format(time.RFC3339)
+	}
+	return map[string]any{
+		"steps":        steps,
+		"timezone":     loc.String(),
+		"event_start":  start,
+		"rsvp_enabled": guestList.GetBool("rsvp_enabled"),
+	}
+}
+
+// handleGuestListRSVPCadenceGet returns a guest list's automatic follow-up cadence
+func handleGuestListRSVPCadenceGet(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	guestList, err := app.FindRecordById(utils.CollectionGuestLists, re.Request.PathValue("id"))
+	if err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+	return utils.DataResponse(re, buildRSVPCadenceResponse(app, guestList))
+}
+
+// handleGuestListRSVPCadenceUpdate replaces a guest list's cadence. Steps keep their id
+// when it's passed back, so editing a step doesn't resend it to guests who already had it.
+func handleGuestListRSVPCadenceUpdate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	guestList, err := app.FindRecordById(utils.CollectionGuestLists, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+
+	var input struct {
+		Steps []rsvpCadenceStep `json:"steps"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid JSON")
+	}
+
+	steps, err := validateRSVPCadence(input.Steps)
+	if err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+	if len(steps) == 0 {
+		guestList.Set("rsvp_cadence", nil)
+	} else {
+		guestList.Set("rsvp_cadence", steps)
+	}
+	if err := app.Save(guestList); err != nil {
+		log.Printf("[RSVPCadence] Failed to save: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to update cadence")
+	}
+
+	utils.LogFromRequest(app, re, "update", utils.CollectionGuestLists, id, "success",
+		map[string]any{"rsvp_cadence": steps}, "")
+
+	return utils.DataResponse(re, buildRSVPCadenceResponse(app, guestList))
+}
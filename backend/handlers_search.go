@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	searchDefaultLimit = 20
+	searchMaxLimit     = 100
+)
+
+type searchIndexRow struct {
+	Entity   string  `db:"entity"`
+	RecordID string  `db:"record_id"`
+	Snippet  string  `db:"snippet"`
+	Rank     float64 `db:"rank"`
+}
+
+// searchHitFor builds the result entry for an indexed record
+func searchHitFor(app core.App, entity string, r *core.Record) map[string]any {
+	hit := map[string]any{"type": entity, "id": r.Id, "title": r.GetString("name")}
+
+	switch entity {
+	case searchEntityContact:
+		subtitle := r.GetString("job_title")
+		if orgID := r.GetString("organisation"); orgID != "" {
+			if org, err := app.FindRecordById(utils.CollectionOrganisations, orgID); err == nil {
+				subtitle = joinSearchSubtitle(subtitle, org.GetString("name"))
+			}
+		}
+		hit["subtitle"] = subtitle
+		hit["status"] = r.GetString("status")
+		hit["avatar_url"] = r.GetString("avatar_small_url")
+	case searchEntityOrganisation:
+		hit["subtitle"] = r.GetString("industry")
+		hit["status"] = r.GetString("status")
+		hit["logo_urls"] = orgLogoURLs(r)
+	case searchEntityGuestList:
+		hit["subtitle"] = joinSearchSubtitle(r.GetString("event_date"), r.GetString("event_location"))
+		hit["status"] = r.GetString("status")
+	}
+
+	return hit
+}
+
+func joinSearchSubtitle(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + " · " + b
+}
+
+// handleSearch returns ranked contact, organisation and guest list matches for ?q=.
+// ?types= limits the entities (comma-separated: contact, organisation, guest_list).
+// Guest lists are admin-only, so viewers get no guest list hits and guest list notes
+// are neither matched nor used for snippets.
+func handleSearch(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	query := re.Request.URL.Query()
+	admin := utils.IsAdmin(re.Auth)
+	q := strings.TrimSpace(query.Get("q"))
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 {
+		limit = searchDefaultLimit
+	}
+	if limit > searchMaxLimit {
+		limit = searchMaxLimit
+	}
+
+	types := map[string]bool{}
+	for _, t := range strings.Split(query.Get("types"), ",") {
+		switch t = strings.TrimSpace(t); t {
+		case searchEntityContact, searchEntityOrganisation, searchEntityGuestList:
+			types[t] = true
+		case "":
+		default:
+			return utils.BadRequestResponse(re, fmt.Sprintf("Unknown type %q", t))
+		}
+	}
+	if len(types) == 0 {
+		types = map[string]bool{searchEntityContact: true, searchEntityOrganisation: true, searchEntityGuestList: true}
+	}
+	if !admin {
+		delete(types, searchEntityGuestList)
+		if len(types) == 0 {
+			return utils.DataResponse(re, map[string]any{"items": []map[string]any{}})
+		}
+	}
+
+	items := []map[string]any{}
+	seen := map[string]bool{}
+
+	// Emails are encrypted so they aren't in the index — keep the exact blind-index match
+	if types[searchEntityContact] && strings.Contains(q, "@") {
+		if idx := utils.BlindIndex(strings.ToLower(q)); idx != "" {
+			records, _ := app.FindRecordsByFilter(utils.CollectionContacts,
+				"email_index = {:idx} || personal_email_index = {:idx}", "", 5, 0, dbx.Params{"idx": idx})
+			for _, r := range records {
+				hit := searchHitFor(app, searchEntityContact, r)
+				hit["matched"] = "email"
+				items = append(items, hit)
+				seen[searchEntityContact+":"+r.Id] = true
+			}
+		}
+	}
+
+	match := buildSearchMatchQuery(q)
+	if match == "" {
+		return utils.DataResponse(re, map[string]any{"items": items})
+	}
+	if !admin {
+		// Column filter keeps the notes column out of both matching and the snippet
+		match = "{title body tags} : (" + match + ")"
+	}
+
+	params := dbx.Params{"match": match, "limit": limit}
+	placeholders := []string{}
+	i := 0
+	for t := range types {
+		name := fmt.Sprintf("t%d", i)
+		placeholders = append(placeholders, "{:"+name+"}")
+		params[name] = t
+		i++
+	}
+
+	// bm25 weights: entity, record_id, title, body, tags, notes — lower rank is a better match
+	var rows []searchIndexRow
+	err := app.DB().NewQuery(`
+		SELECT entity, record_id,
+			snippet(search_index, -1, '', '', '…', 12) AS snippet,
+			bm25(search_index, 0.0, 0.0, 10.0, 1.0, 4.0, 1.0) AS rank
+		FROM search_index
+		WHERE search_index MATCH {:match} AND entity IN (` + strings.Join(placeholders, ", ") + `)
+		ORDER BY rank
+		LIMIT {:limit}
+	`).Bind(params).All(&rows)
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Search failed")
+	}
+
+	collections := map[string]string{
+		searchEntityContact:      utils.CollectionContacts,
+		searchEntityOrganisation: utils.CollectionOrganisations,
+		searchEntityGuestList:    utils.CollectionGuestLists,
+	}
+	for _, row := range rows {
+		key := row.Entity + ":" + row.RecordID
+		if seen[key] {
+			continue
+		}
+		r, err := app.FindRecordById(collections[row.Entity], row.RecordID)
+		if err != nil {
+			continue // stale index row
+		}
+		hit := searchHitFor(app, row.Entity, r)
+		hit["snippet"] = row.Snippet
+		hit["score"] = -row.Rank
+		items = append(items, hit)
+		seen[key] = true
+	}
+
+	return utils.DataResponse(re, map[string]any{"items": items})
+}
+
+// handleSearchReindex rebuilds the full-text index from scratch
+func handleSearchReindex(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	indexed, err := rebuildSearchIndex(app)
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Failed to rebuild search index")
+	}
+
+	utils.LogFromRequest(app, re, "api_call", "search_index", "", "success", map[string]any{"indexed": indexed}, "")
+
+	return utils.DataResponse(re, map[string]any{"indexed": indexed})
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// segmentPreviewSize is how many matching contacts the preview endpoint returns
+const segmentPreviewSize = 25
+
+func buildSegmentResponse(r *core.Record) map[string]any {
+	return map[string]any{
+		"id":                r.Id,
+		"name":              r.GetString("name"),
+		"description":       r.GetString("description"),
+		"filter":            r.Get("filter"),
+		"created_by":        r.GetString("created_by"),
+		"last_count":        r.GetInt("last_count"),
+		"last_evaluated_at": r.GetString("last_evaluated_at"),
+		"created":           r.GetString("created"),
+		"updated":           r.GetString("updated"),
+	}
+}
+
+// handleSegmentsList returns all saved segments
+func handleSegmentsList(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	records, err := app.FindRecordsByFilter(utils.CollectionSegments, "", "name", 0, 0, nil)
+	if err != nil {
+		return utils.DataResponse(re, map[string]any{"items": []any{}})
+	}
+
+	items := make([]map[string]any, 0, len(records))
+	for _, r := range records {
+		items = append(items, buildSegmentResponse(r))
+	}
+
+	return utils.DataResponse(re, map[string]any{"items": items})
+}
+
+// handleSegmentGet returns a single saved segment
+func handleSegmentGet(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Segment ID required")
+	}
+
+	record, err := app.FindRecordById(utils.CollectionSegments, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Segment not found")
+	}
+
+	return utils.DataResponse(re, buildSegmentResponse(record))
+}
+
+// handleSegmentCreate saves a new named segment after validating its filter
+func handleSegmentCreate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	var input struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Filter      any    `json:"filter"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return utils.BadRequestResponse(re, "Name is required")
+	}
+	if input.Filter == nil {
+		return utils.BadRequestResponse(re, "Filter is required")
+	}
+	if _, err := parseSegmentFilter(app, input.Filter); err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+
+	collection, err := app.FindCollectionByNameOrId(utils.CollectionSegments)
+	if err != nil {
+		return utils.InternalErrorResponse(re, "Collection not found")
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("name", input.Name)
+	record.Set("description", input.Description)
+	record.Set("filter", input.Filter)
+	if re.Auth != nil {
+		record.Set("created_by", re.Auth.GetString("email"))
+	}
+
+	if err := app.Save(record); err != nil {
+		log.Printf("[SegmentCreate] Failed to save: %v", err)
+		return utils.BadRequestResponse(re, "Failed to create segment (name must be unique)")
+	}
+
+	utils.LogFromRequest(app, re, "create", utils.CollectionSegments, record.Id, "success", map[string]any{"name": input.Name}, "")
+
+	return utils.DataResponse(re, buildSegmentResponse(record))
+}
+
+// handleSegmentUpdate updates a segment's name, description or filter
+func handleSegmentUpdate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Segment ID required")
+	}
+
+	record, err := app.FindRecordById(utils.CollectionSegments, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Segment not found")
+	}
+
+	var input map[string]any
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+
+	if v, ok := input["name"].(string); ok {
+		if strings.TrimSpace(v) == "" {
+			return utils.BadRequestResponse(re, "Name is required")
+		}
+		record.Set("name", strings.TrimSpace(v))
+	}
+	if v, ok := input["description"].(string); ok {
+		record.Set("description", v)
+	}
+	if v, ok := input["filter"]; ok {
+		if _, err := parseSegmentFilter(app, v); err != nil {
+			return utils.BadRequestResponse(re, err.Error())
+		}
+		record.Set("filter", v)
+	}
+
+	if err := app.Save(record); err != nil {
+		log.Printf("[SegmentUpdate] Failed to save: %v", err)
+		return utils.BadRequestResponse(re, "Failed to update segment (name must be unique)")
+	}
+
+	utils.LogFromRequest(app, re, "update", utils.CollectionSegments, record.Id, "success", input, "")
+
+	return utils.DataResponse(re, buildSegmentResponse(record))
+}
+
+// handleSegmentDelete deletes a saved segment
+func handleSegmentDelete(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Segment ID required")
+	}
+
+	record, err := app.FindRecordById(utils.CollectionSegments, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Segment not found")
+	}
+
+	if err := app.Delete(record); err != nil {
+		log.Printf("[SegmentDelete] Failed to delete: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to delete segment")
+	}
+
+	utils.LogFromRequest(app, re, "delete", utils.CollectionSegments, id, "success", map[string]any{"name": record.GetString("name")}, "")
+
+	return utils.SuccessResponse(re, "Segment deleted successfully")
+}
+
+// handleSegmentPreview evaluates an unsaved filter so the builder can show a live count
+func handleSegmentPreview(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	var input struct {
+		Filter any `json:"filter"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+	if input.Filter == nil {
+		return utils.BadRequestResponse(re, "Filter is required")
+	}
+
+	f, err := parseSegmentFilter(app, input.Filter)
+	if err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+
+	contacts, err := evaluateSegment(app, f)
+	if err != nil {
+		log.Printf("[SegmentPreview] Evaluation failed: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to evaluate segment")
+	}
+
+	baseURL := getBaseURL()
+	sample := make([]map[string]any, 0, segmentPreviewSize)
+	for i, c := range contacts {
+		if i >= segmentPreviewSize {
+			break
+		}
+		sample = append(sample, buildContactResponse(c, app, baseURL))
+	}
+
+	return utils.DataResponse(re, map[string]any{
+		"count": len(contacts),
+		"items": sample,
+	})
+}
+
+// handleSegmentContacts evaluates a saved segment and returns its members, paginated
+func handleSegmentContacts(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	if id == "" {
+		return utils.BadRequestResponse(re, "Segment ID required")
+	}
+
+	page, _ := strconv.Atoi(re.Request.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(re.Request.URL.Query().Get("perPage"))
+	if perPage < 1 || perPage > 500 {
+		perPage = 50
+	}
+
+	segment, contacts, err := resolveSegment(app, id)
+	if segment == nil {
+		return utils.NotFoundResponse(re, "Segment not found")
+	}
+	if err != nil {
+		log.Printf("[SegmentContacts] Evaluation failed for %s: %v", id, err)
+		return utils.BadRequestResponse(re, err.Error())
+	}
+
+	totalItems := len(contacts)
+	start := (page - 1) * perPage
+	end := start + perPage
+	if start > totalItems {
+		start = totalItems
+	}
+	if end > totalItems {
+		end = totalItems
+	}
+
+	baseURL := getBaseURL()
+	items := make([]map[string]any, 0, end-start)
+	for _, c := range contacts[start:end] {
+		items = append(items, buildContactResponse(c, app, baseURL))
+	}
+
+	totalPages := (totalItems + perPage - 1) / perPage
+
+	return utils.DataResponse(re, map[string]any{
+		"items":      items,
+		"page":       page,
+		"perPage":    perPage,
+		"totalItems": totalItems,
+		"totalPages": totalPages,
+	})
+}
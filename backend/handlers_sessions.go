@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// handleGuestListSessionsGet returns a guest list's sessions with their seats taken
+func handleGuestListSessionsGet(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	guestList, err := app.FindRecordById(utils.CollectionGuestLists, re.Request.PathValue("id"))
+	if err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+	return utils.DataResponse(re, map[string]any{"sessions": guestListSessionsResponse(app, guestList)})
+}
+
+// handleGuestListSessionsUpdate replaces a guest list's sessions, in display order. Sessions
+// keep their id when it's passed back, so guests who picked them stay booked in.
+func handleGuestListSessionsUpdate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	guestList, err := app.FindRecordById(utils.CollectionGuestLists, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+
+	var input struct {
+		Sessions []eventSession `json:"sessions"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid JSON")
+	}
+
+	sessions, err := validateSessions(input.Sessions)
+	if err != nil {
+		return utils.BadRequestResponse(re, err.Error())
+	}
+	if len(sessions) == 0 {
+		guestList.Set("sessions", nil)
+	} else {
+		guestList.Set("sessions", sessions)
+	}
+	if err := app.Save(guestList); err != nil {
+		log.Printf("[Sessions] Failed to save: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to update sessions")
+	}
+
+	utils.LogFromRequest(app, re, "update", utils.CollectionGuestLists, id, "success",
+		map[string]any{"sessions": sessions}, "")
+
+	return utils.DataResponse(re, map[string]any{"sessions": guestListSessionsResponse(app, guestList)})
+}
+
+// handleGuestListSessionAttendees returns the accepted guests booked into a session, with
+// their arrival, and the seats taken
+func handleGuestListSessionAttendees(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	guestList, err := app.FindRecordById(utils.CollectionGuestLists, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Guest list not found")
+	}
+
+	sessionID := re.Request.PathValue("sessionId")
+	sessions := guestListSessions(guestList)
+	idx := slices.IndexFunc(sessions, func(s eventSession) bool { return s.ID == sessionID })
+	if idx < 0 {
+		return utils.NotFoundResponse(re, "Session not found")
+	}
+
+	records, err := app.FindRecordsByFilter(utils.CollectionGuestListItems,
+		"guest_list = {:id} && invite_status = 'accepted'", "contact_name", 0, 0, dbx.Params{"id": id})
+	if err != nil {
+		records = nil
+	}
+	items := []map[string]any{}
+	for _, r := range records {
+		if slices.Contains(itemRSVPSessions(r), sessionID) {
+			items = append(items, buildCheckInItemResponse(r))
+		}
+	}
+
+	return utils.DataResponse(re, map[string]any{
+		"session": buildSessionResponse(sessions[idx], sessionSeatsTaken(app, id)[sessionID]),
+		"items":   items,
+		"total":   len(items),
+	})
+}
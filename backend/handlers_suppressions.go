@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ============================================================================
+// Public unsubscribe (signed link in the email footer + RFC 8058 one-click)
+// ============================================================================
+
+// writeUnsubscribePage renders a minimal standalone page for the unsubscribe flow
+func writeUnsubscribePage(re *core.RequestEvent, status int, body string) error {
+	page := fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <meta name="robots" content="noindex">
+    <title>Unsubscribe</title>
+</head>
+<body style="font-family: 'Helvetica Neue', Helvetica, Arial, sans-serif; color: #202020; background: #f8f8f8; margin: 0; padding: 48px 16px;">
+    <div style="max-width: 480px; margin: auto; background: #ffffff; padding: 32px; border-radius: 8px; text-align: center;">
+%s
+    </div>
+</body>
+</html>`, body)
+
+	re.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	re.Response.WriteHeader(status)
+	re.Response.Write([]byte(page))
+	return nil
+}
+
+// handlePublicUnsubscribePage shows a confirmation button rather than unsubscribing on GET,
+// so link scanners that prefetch URLs don't unsubscribe people
+func handlePublicUnsubscribePage(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	email, ok := utils.VerifyToken(unsubscribeTokenPurpose, re.Request.PathValue("token"))
+	if !ok {
+		return writeUnsubscribePage(re, http.StatusNotFound,
+			`<p style="font-size: 16px; margin: 0;">This unsubscribe link is invalid.</p>`)
+	}
+
+	if record := findEmailSuppression(app, email); record != nil {
+		return writeUnsubscribePage(re, http.StatusOK, fmt.Sprintf(
+			`<p style="font-size: 16px; margin: 0;"><strong>%s</strong> is already unsubscribed.</p>`, html.EscapeString(email)))
+	}
+
+	return writeUnsubscribePage(re, http.StatusOK, fmt.Sprintf(`
+        <h1 style="font-size: 24px; margin: 0 0 16px 0;">Unsubscribe</h1>
+        <p style="font-size: 16px; line-height: 1.5; margin: 0 0 24px 0;">
+            Stop sending invitations and updates to <strong>%s</strong>?
+        </p>
+        <form method="post">
+            <input type="hidden" name="List-Unsubscribe" value="One-Click">
+            <input type="hidden" name="from" value="page">
+            <button type="submit" style="background: #0d0d0d; color: #ffffff; border: 0; padding: 14px 32px; font-size: 16px; border-radius: 6px; cursor: pointer;">Unsubscribe</button>
+        </form>`, html.EscapeString(email)))
+}
+
+// handlePublicUnsubscribe handles both the confirmation form and RFC 8058 one-click POSTs
+// from mail clients. The address is suppressed for bulk email and its contacts' consent
+// is withdrawn. Transactional email (e.g. RSVP confirmations) still goes through.
+func handlePublicUnsubscribe(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	email, ok := utils.VerifyToken(unsubscribeTokenPurpose, re.Request.PathValue("token"))
+	if !ok {
+		return writeUnsubscribePage(re, http.StatusNotFound,
+			`<p style="font-size: 16px; margin: 0;">This unsubscribe link is invalid.</p>`)
+	}
+
+	source := "one_click"
+	if re.Request.FormValue("from") == "page" {
+		source = "unsubscribe_page"
+	}
+
+	record, err := addEmailSuppression(app, email, suppressionUnsubscribed, source, "", "")
+	if err != nil {
+		log.Printf("[Unsubscribe] Failed to suppress address: %v", err)
+		return writeUnsubscribePage(re, http.StatusInternalServerError,
+			`<p style="font-size: 16px; margin: 0;">Something went wrong. Please try again or reply to the email to unsubscribe.</p>`)
+	}
+
+	withdrawConsentForEmail(app, email, consentProofFromRequest(re, "unsubscribe_link", source))
+
+	utils.LogFromRequest(app, re, "unsubscribe", utils.CollectionEmailSuppressions, record.Id, "success",
+		map[string]any{"source": source}, "")
+
+	return writeUnsubscribePage(re, http.StatusOK, fmt.Sprintf(`
+        <h1 style="font-size: 24px; margin: 0 0 16px 0;">You're unsubscribed</h1>
+        <p style="font-size: 16px; line-height: 1.5; margin: 0;">
+            We won't send any more invitations or updates to <strong>%s</strong>.
+        </p>`, html.EscapeString(email)))
+}
+
+// ============================================================================
+// Admin suppression list
+// ============================================================================
+
+func buildSuppressionResponse(r *core.Record) map[string]any {
+	return map[string]any{
+		"id":                   r.Id,
+		"email":                utils.DecryptField(r.GetString("email")),
+		"reason":               r.GetString("reason"),
+		"blocks_transactional": suppressionBlocksTransactional(r.GetString("reason")),
+		"source":               r.GetString("source"),
+		"details":              r.GetString("details"),
+		"created_by":           r.GetString("created_by"),
+		"created":              r.GetString("created"),
+		"updated":              r.GetString("updated"),
+	}
+}
+
+// handleSuppressionsList returns the suppression list, newest first.
+// Filters: reason, and email (exact match via the blind index).
+func handleSuppressionsList(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	query := re.Request.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(query.Get("perPage"))
+	if perPage < 1 || perPage > 500 {
+		perPage = 50
+	}
+
+	filters := []string{}
+	params := dbx.Params{}
+	if reason := query.Get("reason"); reason != "" {
+		filters = append(filters, "reason = {:reason}")
+		params["reason"] = reason
+	}
+	if email := query.Get("email"); email != "" {
+		filters = append(filters, "email_index = {:idx}")
+		params["idx"] = utils.BlindIndex(normaliseEmail(email))
+	}
+	filter := strings.Join(filters, " && ")
+
+	allRecords, _ := app.FindRecordsByFilter(utils.CollectionEmailSuppressions, filter, "", 0, 0, params)
+	totalItems := len(allRecords)
+
+	offset := (page - 1) * perPage
+	records, err := app.FindRecordsByFilter(utils.CollectionEmailSuppressions, filter, "-created", perPage, offset, params)
+	if err != nil {
+		return utils.DataResponse(re, map[string]any{
+			"items":      []any{},
+			"page":       page,
+			"perPage":    perPage,
+			"totalItems": 0,
+			"totalPages": 0,
+		})
+	}
+
+	items := make([]map[string]any, len(records))
+	for i, r := range records {
+		items[i] = buildSuppressionResponse(r)
+	}
+
+	return utils.DataResponse(re, map[string]any{
+		"items":      items,
+		"page":       page,
+		"perPage":    perPage,
+		"totalItems": totalItems,
+		"totalPages": (totalItems + perPage - 1) / perPage,
+	})
+}
+
+// handleSuppressionCreate suppresses an address by hand, e.g. after a complaint by phone
+func handleSuppressionCreate(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	var input struct {
+		Email   string `json:"email"`
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	if err := json.NewDecoder(re.Request.Body).Decode(&input); err != nil {
+		return utils.BadRequestResponse(re, "Invalid request body")
+	}
+	if input.Reason == "" {
+		input.Reason = suppressionManual
+	}
+	if !slices.Contains(suppressionReasons, input.Reason) {
+		return utils.BadRequestResponse(re, "reason must be one of: "+strings.Join(suppressionReasons, ", "))
+	}
+	if !strings.Contains(input.Email, "@") {
+		return utils.BadRequestResponse(re, "A valid email is required")
+	}
+
+	createdBy := ""
+	if re.Auth != nil {
+		createdBy = re.Auth.GetString("email")
+	}
+
+	record, err := addEmailSuppression(app, input.Email, input.Reason, "admin", input.Details, createdBy)
+	if err != nil {
+		log.Printf("[Suppression] Failed to add suppression: %v", err)
+		return utils.InternalErrorResponse(re, "Failed to add suppression")
+	}
+
+	utils.LogFromRequest(app, re, "suppression_add", utils.CollectionEmailSuppressions, record.Id, "success",
+		map[string]any{"reason": record.GetString("reason")}, "")
+
+	return utils.DataResponse(re, buildSuppressionResponse(record))
+}
+
+// handleSuppressionDelete removes an address from the suppression list. Withdrawn consent
+// isn't restored — that has to be granted again separately.
+func handleSuppressionDelete(re *core.RequestEvent, app *pocketbase.PocketBase) error {
+	id := re.Request.PathValue("id")
+	record, err := app.FindRecordById(utils.CollectionEmailSuppressions, id)
+	if err != nil {
+		return utils.NotFoundResponse(re, "Suppression not found")
+	}
+
+	reason := record.GetString("reason")
+	if err := app.Delete(record); err != nil {
+		log.Printf("[Suppression] Failed to delete %s: %v", id, err)
+		return utils.InternalErrorResponse(re, "Failed to remove suppression")
+	}
+
+	utils.LogFromRequest(app, re, "suppression_remove", utils.CollectionEmailSuppressions, id, "success",
+		map[string]any{"reason": reason}, "")
+
+	return utils.SuccessResponse(re, "Suppression removed")
+}
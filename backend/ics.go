@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase/core"
 )
 
 // ICSEvent holds the data needed to generate an .ics calendar file.
@@ -17,60 +20,63 @@ type ICSEvent struct {
 	Timezone    string
 }
 
-// generateICS produces an RFC 5545 iCalendar file as bytes.
-func generateICS(event ICSEvent) []byte {
-	tz := event.Timezone
-	if tz == "" {
-		tz = "Australia/Sydney"
-	}
-
-	loc, err := time.LoadLocation(tz)
-	if err != nil {
-		loc = time.UTC
-		tz = "UTC"
-	}
-
-	start := event.Start.In(loc)
-	end := event.End.In(loc)
-
-	// If end is the same as start, default to 2 hours
-	if !end.After(start) {
-		end = start.Add(2 * time.Hour)
-	}
-
-	dtStart := start.Format("20060102T150405")
-	dtEnd := end.Format("20060102T150405")
+// generateICS produces an RFC 5545 iCalendar file as bytes, with one VEVENT per event.
+func generateICS(events ...ICSEvent) []byte {
 	dtStamp := time.Now().UTC().Format("20060102T150405Z")
 
-	uid := event.UID
-	if uid == "" {
-		uid = fmt.Sprintf("%d@crm.theoutlook.io", time.Now().UnixNano())
-	}
-
-	description := escapeICSText(event.Description)
-	summary := escapeICSText(event.Summary)
-	location := escapeICSText(event.Location)
-
 	var b strings.Builder
 	b.WriteString("BEGIN:VCALENDAR\r\n")
 	b.WriteString("VERSION:2.0\r\n")
 	b.WriteString("PRODID:-//The Outlook//CRM//EN\r\n")
 	b.WriteString("CALSCALE:GREGORIAN\r\n")
 	b.WriteString("METHOD:PUBLISH\r\n")
-	b.WriteString("BEGIN:VEVENT\r\n")
-	fmt.Fprintf(&b, "UID:%s\r\n", uid)
-	fmt.Fprintf(&b, "DTSTAMP:%s\r\n", dtStamp)
-	fmt.Fprintf(&b, "DTSTART;TZID=%s:%s\r\n", tz, dtStart)
-	fmt.Fprintf(&b, "DTEND;TZID=%s:%s\r\n", tz, dtEnd)
-	fmt.Fprintf(&b, "SUMMARY:%s\r\n", summary)
-	if description != "" {
-		fmt.Fprintf(&b, "DESCRIPTION:%s\r\n", description)
-	}
-	if location != "" {
-		fmt.Fprintf(&b, "LOCATION:%s\r\n", location)
-	}
-	b.WriteString("STATUS:CONFIRMED\r\n")
-	b.WriteString("END:VEVENT\r\n")
+	for i, event := range events {
+		tz := event.Timezone
+		if tz == "" {
+			tz = "Australia/Sydney"
+		}
+
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			loc = time.UTC
+			tz = "UTC"
+		}
+
+		start := event.Start.In(loc)
+		end := event.End.In(loc)
+
+		// If end is the same as start, default to 2 hours
+		if !end.After(start) {
+			end = start.Add(2 * time.Hour)
+		}
+
+		dtStart := start.Format("20060102T150405")
+		dtEnd := end.Format("20060102T150405")
+
+		uid := event.UID
+		if uid == "" {
+			uid = fmt.Sprintf("%d-%d@crm.theoutlook.io", time.Now().UnixNano(), i)
+		}
+
+		description := escapeICSText(event.Description)
+		summary := escapeICSText(event.Summary)
+		location := escapeICSText(event.Location)
+
+		b.WriteString("BEGIN:VEVENT\r\n")
+		fmt.Fprintf(&b, "UID:%s\r\n", uid)
+		fmt.Fprintf(&b, "DTSTAMP:%s\r\n", dtStamp)
+		fmt.Fprintf(&b, "DTSTART;TZID=%s:%s\r\n", tz, dtStart)
+		fmt.Fprintf(&b, "DTEND;TZID=%s:%s\r\n", tz, dtEnd)
+		fmt.Fprintf(&b, "SUMMARY:%s\r\n", summary)
+		if description != "" {
+			fmt.Fprintf(&b, "DESCRIPTION:%s\r\n", description)
+		}
+		if location != "" {
+			fmt.Fprintf(&b, "LOCATION:%s\r\n", location)
+		}
+		b.WriteString("STATUS:CONFIRMED\r\n")
+		b.WriteString("END:VEVENT\r\n")
+	}
 	b.WriteString("END:VCALENDAR\r\n")
 
 	return []byte(b.String())
@@ -115,6 +121,47 @@ func buildICSEventFromGuestList(guestListID, eventName, eventDescription, startD
 	}
 }
 
+// guestListICS builds the .ics attachment for a guest list's event, preferring the event
+// projection's dates and falling back to the guest list's own, plus an entry for each of
+// the sessions with the given IDs. Returns nil with nothing to put in a calendar.
+func guestListICS(app core.App, gl *core.Record, sessionIDs []string) []byte {
+	eventName := gl.GetString("name")
+	var startDate, endDate, startTime, endTime, timezone, eventDescription string
+	if epID := gl.GetString("event_projection"); epID != "" {
+		if ep, err := app.FindRecordById(utils.CollectionEventProjections, epID); err == nil {
+			if n := ep.GetString("name"); n != "" {
+				eventName = n
+			}
+			startDate = ep.GetString("start_date")
+			endDate = ep.GetString("end_date")
+			startTime = ep.GetString("start_time")
+			endTime = ep.GetString("end_time")
+			timezone = ep.GetString("timezone")
+			eventDescription = ep.GetString("description")
+		}
+	}
+
+	if startDate == "" {
+		startDate = gl.GetString("event_date")
+	}
+	if startTime == "" {
+		startTime = gl.GetString("event_time")
+	}
+	if endDate == "" {
+		endDate = startDate
+	}
+
+	var events []ICSEvent
+	if icsEvent := buildICSEventFromGuestList(gl.Id, eventName, eventDescription, startDate, endDate, startTime, endTime, timezone, gl.GetString("event_location")); icsEvent != nil {
+		events = append(events, *icsEvent)
+	}
+	events = append(events, sessionICSEvents(app, gl, pickedSessions(guestListSessions(gl), sessionIDs))...)
+	if len(events) == 0 {
+		return nil
+	}
+	return generateICS(events...)
+}
+
 // parseEventDateTime parses a date string and optional time string into a time.Time.
 // Supports ISO 8601 date (2026-03-15) and time formats (14:00, 14:00:00).
 func parseEventDateTime(dateStr, timeStr string) (time.Time, error) {
@@ -53,6 +53,20 @@ func main() {
 		},
 	})
 
+	// Register backfill-search-indexes command for the partial-match blind indexes
+	app.RootCmd.AddCommand(&cobra.Command{
+		Use:   "backfill-search-indexes",
+		Short: "Compute email domain, phone and location blind indexes for all contacts",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := app.Bootstrap(); err != nil {
+				log.Fatalf("Failed to bootstrap: %v", err)
+			}
+			if err := runPartialIndexBackfill(app); err != nil {
+				log.Fatalf("Backfill failed: %v", err)
+			}
+		},
+	})
+
 	// Register backfill-rsvp-tokens command for existing guest list items without tokens
 	app.RootCmd.AddCommand(&cobra.Command{
 		Use:   "backfill-rsvp-tokens",
@@ -176,6 +190,23 @@ func main() {
 		},
 	})
 
+	// Register detect-duplicates command to refresh the duplicate review queue
+	app.RootCmd.AddCommand(&cobra.Command{
+		Use:   "detect-duplicates",
+		Short: "Scan contacts for likely duplicates and update the review queue",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := app.Bootstrap(); err != nil {
+				log.Fatalf("Failed to bootstrap: %v", err)
+			}
+			fmt.Println("Scanning contacts for duplicates...")
+			result, err := runDuplicateDetection(app)
+			if err != nil {
+				log.Fatalf("Duplicate detection failed: %v", err)
+			}
+			fmt.Printf("Scanned %d contacts: %d matches (%d new, %d updated, %d pruned)\n", result.Scanned, result.Matches, result.Created, result.Updated, result.Pruned)
+		},
+	})
+
 	// OnServe hook - runs when the server starts
 	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
 		// Configure SendGrid SMTP
@@ -193,9 +224,33 @@ func main() {
 		// Serve frontend SPA
 		serveFrontend(e, app)
 
+		// Imports left running by a restart can't resume; fail them so they can be deleted
+		failInterruptedContactImports(app)
+
 		// Start the backup scheduler (runs at 3 AM AEST daily)
 		go scheduleBackups(app)
 
+		// Start the duplicate detection scheduler (runs at 4 AM AEST daily)
+		go scheduleDuplicateDetection(app)
+
+		// Purge contact merge snapshots once their undo window has passed
+		go scheduleMergeHistoryCleanup(app)
+
+		// Apply data retention policies (runs at 5 AM AEST daily)
+		go scheduleRetentionPurge(app)
+
+		// Send queued emails (retries with backoff, throttled per SMTP host)
+		go startEmailOutbox(app)
+
+		// Process bounce reports delivered to BOUNCE_MAILDIR, if set
+		go scheduleBounceMaildir(app)
+
+		// Run scheduled RSVP sends and guest list cadences (checks every minute)
+		go scheduleRSVPRounds(app)
+
+		// Backfill the full-text search index if it's empty
+		go ensureSearchIndex(app)
+
 		// Load DAM caches (avatars + logos) and persist URLs to records
 		go RefreshDAMAvatarCache()
 		go RefreshDAMLogoCache()
@@ -234,6 +289,15 @@ func main() {
 	// Register encryption hooks for PII fields
 	registerEncryptionHooks(app)
 
+	// Register duplicate detection for newly created contacts
+	registerDuplicateDetectionHooks(app)
+
+	// Keep the full-text search index in sync
+	registerSearchHooks(app)
+
+	// Promote waitlisted guests as seats free up
+	registerCapacityHooks(app)
+
 	// Sync Microsoft profile photo on OAuth login (runs synchronously so the
 	// auth response includes the updated avatar filename)
 	app.OnRecordAuthWithOAuth2Request("users").BindFunc(func(e *core.RecordAuthWithOAuth2RequestEvent) error {
@@ -352,6 +416,99 @@ func registerRoutes(e *core.ServeEvent, app *pocketbase.PocketBase) {
 		return handleDashboardStats(re, app)
 	}).BindFunc(utils.RequireAuth)
 
+	// Unified full-text search across contacts, organisations and guest lists
+	e.Router.GET("/api/search", func(re *core.RequestEvent) error {
+		return handleSearch(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAuth)
+
+	e.Router.POST("/api/admin/search/reindex", func(re *core.RequestEvent) error {
+		return handleSearchReindex(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	// Data retention policies (admin only)
+	e.Router.GET("/api/admin/retention", func(re *core.RequestEvent) error {
+		return handleRetentionPoliciesList(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.PATCH("/api/admin/retention/{target}", func(re *core.RequestEvent) error {
+		return handleRetentionPolicyUpdate(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.GET("/api/admin/retention/preview", func(re *core.RequestEvent) error {
+		return handleRetentionPreview(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/admin/retention/run", func(re *core.RequestEvent) error {
+		return handleRetentionRun(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	// Email suppression list (admin only)
+	e.Router.GET("/api/admin/suppressions", func(re *core.RequestEvent) error {
+		return handleSuppressionsList(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/admin/suppressions", func(re *core.RequestEvent) error {
+		return handleSuppressionCreate(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.DELETE("/api/admin/suppressions/{id}", func(re *core.RequestEvent) error {
+		return handleSuppressionDelete(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	// Email outbox and send log (admin only)
+	e.Router.GET("/api/admin/email-outbox", func(re *core.RequestEvent) error {
+		return handleOutboxList(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.GET("/api/admin/email-outbox/{id}", func(re *core.RequestEvent) error {
+		return handleOutboxGet(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/admin/email-outbox/{id}/retry", func(re *core.RequestEvent) error {
+		return handleOutboxRetry(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/admin/email-outbox/{id}/cancel", func(re *core.RequestEvent) error {
+		return handleOutboxCancel(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	// Email templates: overrides of the built-in copy, with version history (admin only)
+	e.Router.GET("/api/admin/email-templates/keys", func(re *core.RequestEvent) error {
+		return handleEmailTemplateKeys(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.GET("/api/admin/email-templates", func(re *core.RequestEvent) error {
+		return handleEmailTemplatesList(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/admin/email-templates", func(re *core.RequestEvent) error {
+		return handleEmailTemplateCreate(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/admin/email-templates/preview", func(re *core.RequestEvent) error {
+		return handleEmailTemplatePreview(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.GET("/api/admin/email-templates/{id}", func(re *core.RequestEvent) error {
+		return handleEmailTemplateGet(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.PATCH("/api/admin/email-templates/{id}", func(re *core.RequestEvent) error {
+		return handleEmailTemplateUpdate(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.DELETE("/api/admin/email-templates/{id}", func(re *core.RequestEvent) error {
+		return handleEmailTemplateDelete(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.GET("/api/admin/email-templates/{id}/preview", func(re *core.RequestEvent) error {
+		return handleEmailTemplatePreviewPage(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.GET("/api/admin/email-template-versions/{id}", func(re *core.RequestEvent) error {
+		return handleEmailTemplateVersionGet(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
 	// Contacts CRUD
 	e.Router.GET("/api/contacts", func(re *core.RequestEvent) error {
 		return handleContactsList(re, app)
@@ -378,6 +535,33 @@ func registerRoutes(e *core.ServeEvent, app *pocketbase.PocketBase) {
 		return handleContactsMerge(re, app)
 	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
 
+	// Contact merge history and undo
+	e.Router.GET("/api/contacts/merges", func(re *core.RequestEvent) error {
+		return handleContactMergesList(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/contacts/merges/{id}/undo", func(re *core.RequestEvent) error {
+		return handleContactMergeUndo(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	// Contact export (CSV/XLSX)
+	e.Router.GET("/api/contacts/export", func(re *core.RequestEvent) error {
+		return handleContactsExport(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAuth)
+
+	// Duplicate contact review queue
+	e.Router.GET("/api/contacts/duplicates", func(re *core.RequestEvent) error {
+		return handleDuplicatesList(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAuth)
+
+	e.Router.POST("/api/contacts/duplicates/scan", func(re *core.RequestEvent) error {
+		return handleDuplicatesScan(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/contacts/duplicates/{id}/dismiss", func(re *core.RequestEvent) error {
+		return handleDuplicateDismiss(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
 	// Contact avatar upload
 	e.Router.POST("/api/contacts/{id}/avatar", func(re *core.RequestEvent) error {
 		return handleContactAvatarUpload(re, app)
@@ -388,6 +572,11 @@ func registerRoutes(e *core.ServeEvent, app *pocketbase.PocketBase) {
 		return handleContactActivities(re, app)
 	}).BindFunc(utils.RequireAuth)
 
+	// Contact email timeline
+	e.Router.GET("/api/contacts/{id}/email-events", func(re *core.RequestEvent) error {
+		return handleContactEmailEvents(re, app)
+	}).BindFunc(utils.RequireAuth)
+
 	// Contact links
 	e.Router.GET("/api/contacts/{id}/links", func(re *core.RequestEvent) error {
 		return handleContactLinksList(re, app)
@@ -401,6 +590,24 @@ func registerRoutes(e *core.ServeEvent, app *pocketbase.PocketBase) {
 		return handleContactLinkDelete(re, app)
 	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
 
+	// Contact consent history and admin changes (admin only — history includes IPs)
+	e.Router.GET("/api/contacts/{id}/consent", func(re *core.RequestEvent) error {
+		return handleContactConsentGet(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.PATCH("/api/contacts/{id}/consent", func(re *core.RequestEvent) error {
+		return handleContactConsentUpdate(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	// Subject-access export and right-to-be-forgotten erasure (admin only)
+	e.Router.GET("/api/contacts/{id}/privacy/export", func(re *core.RequestEvent) error {
+		return handleContactSubjectAccessExport(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/contacts/{id}/privacy/erase", func(re *core.RequestEvent) error {
+		return handleContactErase(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
 	// Humanitix integration (admin only)
 	e.Router.GET("/api/admin/humanitix/events", func(re *core.RequestEvent) error {
 		return handleHumanitixEventsList(re, app)
@@ -502,6 +709,52 @@ func registerRoutes(e *core.ServeEvent, app *pocketbase.PocketBase) {
 		return handleAttendeeEmailLink(re, app)
 	}).BindFunc(utils.RateLimitPublic)
 
+	// Custom field definitions for contacts and organisations
+	e.Router.GET("/api/custom-fields", func(re *core.RequestEvent) error {
+		return handleCustomFieldsList(re, app)
+	}).BindFunc(utils.RequireAuth)
+
+	e.Router.POST("/api/custom-fields", func(re *core.RequestEvent) error {
+		return handleCustomFieldCreate(re, app)
+	}).BindFunc(utils.RequireAdmin)
+
+	e.Router.PATCH("/api/custom-fields/{id}", func(re *core.RequestEvent) error {
+		return handleCustomFieldUpdate(re, app)
+	}).BindFunc(utils.RequireAdmin)
+
+	e.Router.DELETE("/api/custom-fields/{id}", func(re *core.RequestEvent) error {
+		return handleCustomFieldDelete(re, app)
+	}).BindFunc(utils.RequireAdmin)
+
+	// Saved contact segments
+	e.Router.GET("/api/segments", func(re *core.RequestEvent) error {
+		return handleSegmentsList(re, app)
+	}).BindFunc(utils.RequireAuth)
+
+	e.Router.POST("/api/segments/preview", func(re *core.RequestEvent) error {
+		return handleSegmentPreview(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAuth)
+
+	e.Router.GET("/api/segments/{id}", func(re *core.RequestEvent) error {
+		return handleSegmentGet(re, app)
+	}).BindFunc(utils.RequireAuth)
+
+	e.Router.GET("/api/segments/{id}/contacts", func(re *core.RequestEvent) error {
+		return handleSegmentContacts(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAuth)
+
+	e.Router.POST("/api/segments", func(re *core.RequestEvent) error {
+		return handleSegmentCreate(re, app)
+	}).BindFunc(utils.RequireAdmin)
+
+	e.Router.PATCH("/api/segments/{id}", func(re *core.RequestEvent) error {
+		return handleSegmentUpdate(re, app)
+	}).BindFunc(utils.RequireAdmin)
+
+	e.Router.DELETE("/api/segments/{id}", func(re *core.RequestEvent) error {
+		return handleSegmentDelete(re, app)
+	}).BindFunc(utils.RequireAdmin)
+
 	// Organisations CRUD
 	e.Router.GET("/api/organisations", func(re *core.RequestEvent) error {
 		return handleOrganisationsList(re, app)
@@ -511,6 +764,10 @@ func registerRoutes(e *core.ServeEvent, app *pocketbase.PocketBase) {
 		return handleOrganisationGet(re, app)
 	}).BindFunc(utils.RequireAuth)
 
+	e.Router.GET("/api/organisations/export", func(re *core.RequestEvent) error {
+		return handleOrganisationsExport(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAuth)
+
 	e.Router.POST("/api/organisations", func(re *core.RequestEvent) error {
 		return handleOrganisationCreate(re, app)
 	}).BindFunc(utils.RequireAdmin)
@@ -523,6 +780,11 @@ func registerRoutes(e *core.ServeEvent, app *pocketbase.PocketBase) {
 		return handleOrganisationDelete(re, app)
 	}).BindFunc(utils.RequireAdmin)
 
+	// Merge organisations
+	e.Router.POST("/api/organisations/merge", func(re *core.RequestEvent) error {
+		return handleOrganisationsMerge(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
 	// Organisation logo upload token (for DAM uploads)
 	// Frontend requests a signed token, then uploads directly to DAM
 	e.Router.POST("/api/organisations/{id}/logo/{type}/token", func(re *core.RequestEvent) error {
@@ -540,6 +802,11 @@ func registerRoutes(e *core.ServeEvent, app *pocketbase.PocketBase) {
 		return handleActivityWebhook(re, app)
 	}).BindFunc(utils.RateLimitExternalAPI)
 
+	// Bounce and complaint notifications (generic JSON or raw DSN/ARF reports)
+	e.Router.POST("/api/webhooks/email-events", func(re *core.RequestEvent) error {
+		return handleEmailEventsWebhook(re, app)
+	}).BindFunc(utils.RateLimitExternalAPI)
+
 	// Avatar URL webhook receiver (from DAM - avatar variant URLs after processing)
 	e.Router.POST("/api/webhooks/avatar-urls", func(re *core.RequestEvent) error {
 		return handleAvatarURLWebhook(re, app)
@@ -579,6 +846,31 @@ func registerRoutes(e *core.ServeEvent, app *pocketbase.PocketBase) {
 		return handleImportPresenters(re, app)
 	}).BindFunc(utils.RequireAdmin)
 
+	// Generic CSV contact import: upload + dry run, adjust mapping, run in background
+	e.Router.GET("/api/import/contacts", func(re *core.RequestEvent) error {
+		return handleContactImportsList(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/import/contacts", func(re *core.RequestEvent) error {
+		return handleContactImportCreate(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.GET("/api/import/contacts/{id}", func(re *core.RequestEvent) error {
+		return handleContactImportGet(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/import/contacts/{id}/preview", func(re *core.RequestEvent) error {
+		return handleContactImportPreview(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/import/contacts/{id}/run", func(re *core.RequestEvent) error {
+		return handleContactImportRun(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.DELETE("/api/import/contacts/{id}", func(re *core.RequestEvent) error {
+		return handleContactImportDelete(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
 	// Event projection webhook (COPE - receive events from Events app)
 	eventReceiver := receiver.NewReceiver(receiver.Config{
 		WebhookSecret: os.Getenv("PROJECTION_WEBHOOK_SECRET"),
@@ -709,6 +1001,10 @@ func registerRoutes(e *core.ServeEvent, app *pocketbase.PocketBase) {
 		return handlePublicRSVPSubmit(re, app)
 	}).BindFunc(utils.RateLimitPublic)
 
+	e.Router.POST("/api/public/rsvp/{token}/cancel", func(re *core.RequestEvent) error {
+		return handlePublicRSVPCancel(re, app)
+	}).BindFunc(utils.RateLimitPublic)
+
 	e.Router.POST("/api/public/rsvp/{token}/forward", func(re *core.RequestEvent) error {
 		return handlePublicRSVPForward(re, app)
 	}).BindFunc(utils.RateLimitPublic)
@@ -726,6 +1022,20 @@ func registerRoutes(e *core.ServeEvent, app *pocketbase.PocketBase) {
 		return handleTrackClick(re, app)
 	}).BindFunc(utils.RateLimitPublic)
 
+	// Unsubscribe (signed link from the email footer; POST is RFC 8058 one-click)
+	e.Router.GET("/api/public/unsubscribe/{token}", func(re *core.RequestEvent) error {
+		return handlePublicUnsubscribePage(re, app)
+	}).BindFunc(utils.RateLimitPublic)
+
+	e.Router.POST("/api/public/unsubscribe/{token}", func(re *core.RequestEvent) error {
+		return handlePublicUnsubscribe(re, app)
+	}).BindFunc(utils.RateLimitPublic)
+
+	// Check-in QR image (signed code from the confirmation email)
+	e.Router.GET("/api/public/check-in/{code}/qr.png", func(re *core.RequestEvent) error {
+		return handlePublicCheckInQR(re, app)
+	}).BindFunc(utils.RateLimitPublic)
+
 	// Admin RSVP management
 	e.Router.POST("/api/guest-lists/{id}/rsvp/enable", func(re *core.RequestEvent) error {
 		return handleGuestListRSVPToggle(re, app)
@@ -739,6 +1049,74 @@ func registerRoutes(e *core.ServeEvent, app *pocketbase.PocketBase) {
 		return handleGuestListRSVPSendFollowups(re, app)
 	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
 
+	// Send history, scheduled sends and automatic cadences
+	e.Router.GET("/api/guest-lists/{id}/rsvp/rounds", func(re *core.RequestEvent) error {
+		return handleGuestListRSVPRounds(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/guest-lists/{id}/rsvp/schedule", func(re *core.RequestEvent) error {
+		return handleGuestListRSVPSchedule(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.DELETE("/api/guest-lists/{id}/rsvp/rounds/{roundId}", func(re *core.RequestEvent) error {
+		return handleGuestListRSVPRoundCancel(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.GET("/api/guest-lists/{id}/rsvp/cadence", func(re *core.RequestEvent) error {
+		return handleGuestListRSVPCadenceGet(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.PUT("/api/guest-lists/{id}/rsvp/cadence", func(re *core.RequestEvent) error {
+		return handleGuestListRSVPCadenceUpdate(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	// Custom RSVP questions
+	e.Router.GET("/api/guest-lists/{id}/rsvp/questions", func(re *core.RequestEvent) error {
+		return handleGuestListRSVPQuestionsGet(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.PUT("/api/guest-lists/{id}/rsvp/questions", func(re *core.RequestEvent) error {
+		return handleGuestListRSVPQuestionsUpdate(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	// Sessions guests pick when they RSVP
+	e.Router.GET("/api/guest-lists/{id}/sessions", func(re *core.RequestEvent) error {
+		return handleGuestListSessionsGet(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.PUT("/api/guest-lists/{id}/sessions", func(re *core.RequestEvent) error {
+		return handleGuestListSessionsUpdate(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.GET("/api/guest-lists/{id}/sessions/{sessionId}/attendees", func(re *core.RequestEvent) error {
+		return handleGuestListSessionAttendees(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.GET("/api/guest-lists/{id}/export", func(re *core.RequestEvent) error {
+		return handleGuestListExport(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.GET("/api/guest-lists/{id}/email-stats", func(re *core.RequestEvent) error {
+		return handleGuestListEmailStats(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.GET("/api/guest-lists/{id}/analytics", func(re *core.RequestEvent) error {
+		return handleGuestListAnalytics(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	// Event-day check-in
+	e.Router.GET("/api/guest-lists/{id}/check-in", func(re *core.RequestEvent) error {
+		return handleGuestListCheckInList(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.POST("/api/guest-lists/{id}/check-in", func(re *core.RequestEvent) error {
+		return handleGuestListCheckIn(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
+	e.Router.DELETE("/api/guest-lists/{id}/check-in/{itemId}", func(re *core.RequestEvent) error {
+		return handleGuestListCheckInUndo(re, app)
+	}).BindFunc(utils.RateLimitAuth).BindFunc(utils.RequireAdmin)
+
 	// Admin users list (for event host selection)
 	e.Router.GET("/api/admin-users", func(re *core.RequestEvent) error {
 		return handleListAdminUsers(re, app)
@@ -932,6 +1310,9 @@ func registerEncryptionHooks(app *pocketbase.PocketBase) {
 			e.Record.Set("personal_email_index", utils.BlindIndex(originalEmail))
 		}
 
+		// Partial-match indexes for email domain, phone and location search
+		setContactPartialIndexes(e.Record)
+
 		return e.Next()
 	})
 
@@ -968,10 +1349,32 @@ func registerEncryptionHooks(app *pocketbase.PocketBase) {
 			e.Record.Set("personal_email_index", utils.BlindIndex(originalEmail))
 		}
 
+		// Update partial-match indexes for email domain, phone and location search
+		setContactPartialIndexes(e.Record)
+
 		return e.Next()
 	})
 }
 
+// setContactPartialIndexes recomputes the partial-match blind indexes from a contact's
+// email, phone and location (encrypted or not). Reports whether any index changed.
+func setContactPartialIndexes(record *core.Record) bool {
+	indexes := map[string]string{
+		"email_domain_index":    utils.EmailDomainIndex(utils.DecryptField(record.GetString("email"))),
+		"phone_last4_index":     utils.PhoneLast4Index(utils.DecryptField(record.GetString("phone"))),
+		"location_tokens_index": utils.LocationTokensIndex(utils.DecryptField(record.GetString("location"))),
+	}
+
+	changed := false
+	for field, value := range indexes {
+		if record.GetString(field) != value {
+			record.Set(field, value)
+			changed = true
+		}
+	}
+	return changed
+}
+
 // registerAuditHooks sets up audit logging for CRUD operations and auth events
 func registerAuditHooks(app *pocketbase.PocketBase) {
 	// Collections to audit
@@ -1097,6 +1500,40 @@ func runPIIEncryptionMigration(app *pocketbase.PocketBase) error {
 	return nil
 }
 
+// runPartialIndexBackfill computes the partial-match blind indexes for existing contacts
+func runPartialIndexBackfill(app *pocketbase.PocketBase) error {
+	if !utils.IsEncryptionEnabled() {
+		return fmt.Errorf("ENCRYPTION_KEY not set - cannot compute blind indexes")
+	}
+
+	records, err := app.FindAllRecords("contacts")
+	if err != nil {
+		return fmt.Errorf("failed to fetch contacts: %w", err)
+	}
+
+	log.Printf("[BackfillIndexes] Found %d contacts to process", len(records))
+
+	updated := 0
+	skipped := 0
+	errCount := 0
+
+	for _, record := range records {
+		if !setContactPartialIndexes(record) {
+			skipped++
+			continue
+		}
+		if err := app.SaveNoValidate(record); err != nil {
+			log.Printf("[BackfillIndexes] Error: failed to save contact %s: %v", record.Id, err)
+			errCount++
+			continue
+		}
+		updated++
+	}
+
+	log.Printf("[BackfillIndexes] Complete: %d updated, %d unchanged, %d errors", updated, skipped, errCount)
+	return nil
+}
+
 // runKeyRotation re-encrypts all PII fields with the current key and recomputes blind indexes.
 // Used after rotating ENCRYPTION_KEY to migrate data from the previous key version.
 func runKeyRotation(app *pocketbase.PocketBase) error {
@@ -1177,6 +1614,9 @@ func runKeyRotation(app *pocketbase.PocketBase) error {
 				needsUpdate = true
 			}
 		}
+		if setContactPartialIndexes(record) {
+			needsUpdate = true
+		}
 
 		if needsUpdate {
 			if err := app.SaveNoValidate(record); err != nil {
@@ -0,0 +1,106 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		contactsCollection, err := app.FindCollectionByNameOrId("contacts")
+		if err != nil {
+			return err
+		}
+
+		collection := core.NewBaseCollection("duplicate_candidates")
+
+		// Pair is stored in alphabetical order (contact_a < contact_b) for dedup.
+		// Cascade delete so merging or deleting either contact clears the candidate.
+		collection.Fields.Add(&core.RelationField{
+			Id:            "dc_contact_a",
+			Name:          "contact_a",
+			Required:      true,
+			CollectionId:  contactsCollection.Id,
+			CascadeDelete: true,
+			MaxSelect:     1,
+		})
+
+		collection.Fields.Add(&core.RelationField{
+			Id:            "dc_contact_b",
+			Name:          "contact_b",
+			Required:      true,
+			CollectionId:  contactsCollection.Id,
+			CascadeDelete: true,
+			MaxSelect:     1,
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:   "dc_score",
+			Name: "score",
+		})
+
+		// List of matched signals, e.g. ["email", "name", "organisation"]
+		collection.Fields.Add(&core.JSONField{
+			Id:      "dc_reasons",
+			Name:    "reasons",
+			MaxSize: 2000,
+		})
+
+		collection.Fields.Add(&core.SelectField{
+			Id:        "dc_status",
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"pending", "dismissed"},
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "dc_reviewed_by",
+			Name: "reviewed_by",
+			Max:  200,
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:   "dc_reviewed_at",
+			Name: "reviewed_at",
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "dc_created",
+			Name:     "created",
+			OnCreate: true,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "dc_updated",
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+
+		collection.ListRule = types.Pointer("@request.auth.id != ''")
+		collection.ViewRule = types.Pointer("@request.auth.id != ''")
+		collection.CreateRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.UpdateRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.DeleteRule = types.Pointer("@request.auth.role = 'admin'")
+
+		collection.AddIndex("idx_duplicate_candidates_pair", true, "contact_a, contact_b", "")
+		collection.AddIndex("idx_duplicate_candidates_status", false, "status", "")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Created duplicate_candidates collection")
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("duplicate_candidates")
+		if err != nil {
+			return nil
+		}
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,109 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("contact_merges")
+
+		collection.Fields.Add(
+			// Plain text rather than a relation so history survives if the primary is later deleted
+			&core.TextField{
+				Id:       "cm_primary_id",
+				Name:     "primary_id",
+				Required: true,
+				Max:      50,
+			},
+			&core.JSONField{
+				Id:      "cm_merged_ids",
+				Name:    "merged_ids",
+				MaxSize: 5000,
+			},
+			// Primary contact fields as they were before the merge
+			&core.JSONField{
+				Id:      "cm_primary_snapshot",
+				Name:    "primary_snapshot",
+				MaxSize: 200000,
+			},
+			// Full field data of each merged (deleted) contact, PII still encrypted
+			&core.JSONField{
+				Id:      "cm_merged_snapshots",
+				Name:    "merged_snapshots",
+				MaxSize: 2000000,
+			},
+			// Re-pointed and deleted activities, guest_list_items and contact_links
+			&core.JSONField{
+				Id:      "cm_references",
+				Name:    "references",
+				MaxSize: 2000000,
+			},
+			&core.SelectField{
+				Id:        "cm_status",
+				Name:      "status",
+				Required:  true,
+				MaxSelect: 1,
+				Values:    []string{"completed", "undone", "expired"},
+			},
+			&core.TextField{
+				Id:   "cm_merged_by",
+				Name: "merged_by",
+				Max:  200,
+			},
+			&core.TextField{
+				Id:   "cm_undone_by",
+				Name: "undone_by",
+				Max:  200,
+			},
+			&core.DateField{
+				Id:   "cm_undone_at",
+				Name: "undone_at",
+			},
+			&core.DateField{
+				Id:   "cm_expires_at",
+				Name: "expires_at",
+			},
+			&core.AutodateField{
+				Id:       "cm_created",
+				Name:     "created",
+				OnCreate: true,
+			},
+			&core.AutodateField{
+				Id:       "cm_updated",
+				Name:     "updated",
+				OnCreate: true,
+				OnUpdate: true,
+			},
+		)
+
+		// Snapshots contain PII — admin only, accessed through the custom API
+		collection.ListRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.ViewRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.CreateRule = nil
+		collection.UpdateRule = nil
+		collection.DeleteRule = nil
+
+		collection.Indexes = []string{
+			"CREATE INDEX idx_contact_merges_primary ON contact_merges (primary_id)",
+			"CREATE INDEX idx_contact_merges_status ON contact_merges (status)",
+		}
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Created contact_merges collection")
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("contact_merges")
+		if err != nil {
+			return nil
+		}
+		return app.Delete(collection)
+	})
+}
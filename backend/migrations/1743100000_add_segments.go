@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("segments")
+
+		collection.Fields.Add(&core.TextField{
+			Id:       "seg_name",
+			Name:     "name",
+			Required: true,
+			Max:      200,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "seg_description",
+			Name: "description",
+			Max:  2000,
+		})
+
+		// Filter tree — see segmentFilter in segments.go for the format
+		collection.Fields.Add(&core.JSONField{
+			Id:       "seg_filter",
+			Name:     "filter",
+			Required: true,
+			MaxSize:  50000,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "seg_created_by",
+			Name: "created_by",
+			Max:  200,
+		})
+
+		// Cached member count from the last evaluation (for list views)
+		collection.Fields.Add(&core.NumberField{
+			Id:   "seg_last_count",
+			Name: "last_count",
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:   "seg_last_evaluated_at",
+			Name: "last_evaluated_at",
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "seg_created",
+			Name:     "created",
+			OnCreate: true,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "seg_updated",
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+
+		collection.ListRule = types.Pointer("@request.auth.id != ''")
+		collection.ViewRule = types.Pointer("@request.auth.id != ''")
+		collection.CreateRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.UpdateRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.DeleteRule = types.Pointer("@request.auth.role = 'admin'")
+
+		collection.AddIndex("idx_segments_name", true, "name", "")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Created segments collection")
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("segments")
+		if err != nil {
+			return nil
+		}
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,139 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Admin-defined custom field definitions
+		collection := core.NewBaseCollection("custom_field_definitions")
+
+		collection.Fields.Add(&core.SelectField{
+			Id:        "cfd_entity",
+			Name:      "entity",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"contact", "organisation"},
+		})
+
+		// Storage key inside the record's custom_fields JSON — immutable once created
+		collection.Fields.Add(&core.TextField{
+			Id:       "cfd_key",
+			Name:     "key",
+			Required: true,
+			Max:      50,
+			Pattern:  `^[a-z][a-z0-9_]*$`,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:       "cfd_label",
+			Name:     "label",
+			Required: true,
+			Max:      200,
+		})
+
+		collection.Fields.Add(&core.SelectField{
+			Id:        "cfd_type",
+			Name:      "type",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"text", "number", "date", "select", "multi_select", "boolean"},
+		})
+
+		// Allowed values for select and multi_select
+		collection.Fields.Add(&core.JSONField{
+			Id:      "cfd_options",
+			Name:    "options",
+			MaxSize: 10000,
+		})
+
+		collection.Fields.Add(&core.BoolField{
+			Id:   "cfd_required",
+			Name: "required",
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:   "cfd_sort_order",
+			Name: "sort_order",
+		})
+
+		// Archived fields are hidden from forms and no longer accept writes
+		collection.Fields.Add(&core.BoolField{
+			Id:   "cfd_archived",
+			Name: "archived",
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "cfd_created",
+			Name:     "created",
+			OnCreate: true,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "cfd_updated",
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+
+		collection.ListRule = types.Pointer("@request.auth.id != ''")
+		collection.ViewRule = types.Pointer("@request.auth.id != ''")
+		collection.CreateRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.UpdateRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.DeleteRule = types.Pointer("@request.auth.role = 'admin'")
+
+		collection.AddIndex("idx_custom_field_definitions_key", true, "entity, key", "")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// Values are stored per record as a {key: value} JSON object
+		contacts, err := app.FindCollectionByNameOrId("contacts")
+		if err != nil {
+			return err
+		}
+		contacts.Fields.Add(&core.JSONField{
+			Id:      "cont_custom_fields",
+			Name:    "custom_fields",
+			MaxSize: 50000,
+		})
+		if err := app.Save(contacts); err != nil {
+			return err
+		}
+
+		orgs, err := app.FindCollectionByNameOrId("organisations")
+		if err != nil {
+			return err
+		}
+		orgs.Fields.Add(&core.JSONField{
+			Id:      "org_custom_fields",
+			Name:    "custom_fields",
+			MaxSize: 50000,
+		})
+		if err := app.Save(orgs); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Created custom_field_definitions and added custom_fields to contacts and organisations")
+		return nil
+	}, func(app core.App) error {
+		if collection, err := app.FindCollectionByNameOrId("contacts"); err == nil {
+			collection.Fields.RemoveById("cont_custom_fields")
+			app.Save(collection)
+		}
+		if collection, err := app.FindCollectionByNameOrId("organisations"); err == nil {
+			collection.Fields.RemoveById("org_custom_fields")
+			app.Save(collection)
+		}
+		if collection, err := app.FindCollectionByNameOrId("custom_field_definitions"); err == nil {
+			return app.Delete(collection)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,168 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("contact_imports")
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "cimp_filename",
+			Name: "filename",
+			Max:  500,
+		})
+
+		collection.Fields.Add(&core.SelectField{
+			Id:        "cimp_status",
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"draft", "running", "completed", "failed"},
+		})
+
+		collection.Fields.Add(&core.JSONField{
+			Id:      "cimp_headers",
+			Name:    "headers",
+			MaxSize: 20000,
+		})
+
+		// Encrypted JSON of the uploaded data rows — contains PII, cleared once the import has run
+		collection.Fields.Add(&core.JSONField{
+			Id:      "cimp_rows",
+			Name:    "rows",
+			MaxSize: 32 << 20,
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:   "cimp_row_count",
+			Name: "row_count",
+		})
+
+		// CSV header -> contact field, see contactImportFields in contact_import.go
+		collection.Fields.Add(&core.JSONField{
+			Id:      "cimp_mapping",
+			Name:    "mapping",
+			MaxSize: 20000,
+		})
+
+		collection.Fields.Add(&core.JSONField{
+			Id:      "cimp_options",
+			Name:    "options",
+			MaxSize: 2000,
+		})
+
+		// Counts from the most recent dry run
+		collection.Fields.Add(&core.JSONField{
+			Id:      "cimp_summary",
+			Name:    "summary",
+			MaxSize: 2000,
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:   "cimp_records_processed",
+			Name: "records_processed",
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:   "cimp_records_created",
+			Name: "records_created",
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:   "cimp_records_updated",
+			Name: "records_updated",
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:   "cimp_records_skipped",
+			Name: "records_skipped",
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:   "cimp_orgs_created",
+			Name: "orgs_created",
+		})
+
+		collection.Fields.Add(&core.JSONField{
+			Id:      "cimp_errors",
+			Name:    "errors",
+			MaxSize: 50000,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "cimp_created_by",
+			Name: "created_by",
+			Max:  200,
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:   "cimp_started_at",
+			Name: "started_at",
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:   "cimp_completed_at",
+			Name: "completed_at",
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "cimp_created",
+			Name:     "created",
+			OnCreate: true,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "cimp_updated",
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+
+		// Admin only — access goes through the /api/import/contacts endpoints
+		collection.ListRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.ViewRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.CreateRule = nil
+		collection.UpdateRule = nil
+		collection.DeleteRule = nil
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// Allow "csv_import" as a contact source so imported contacts are identifiable
+		contacts, err := app.FindCollectionByNameOrId("contacts")
+		if err != nil {
+			return err
+		}
+		if sf, ok := contacts.Fields.GetByName("source").(*core.SelectField); ok {
+			hasSource := false
+			for _, v := range sf.Values {
+				if v == "csv_import" {
+					hasSource = true
+					break
+				}
+			}
+			if !hasSource {
+				sf.Values = append(sf.Values, "csv_import")
+				if err := app.Save(contacts); err != nil {
+					return err
+				}
+			}
+		}
+
+		log.Println("[Migration] Created contact_imports collection")
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("contact_imports")
+		if err != nil {
+			return nil
+		}
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// FTS5 index over non-encrypted contact, organisation and guest list text.
+		// Not a PocketBase collection — maintained by the hooks in search.go and
+		// backfilled on startup when empty.
+		_, err := app.DB().NewQuery(`
+			CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+				entity UNINDEXED,
+				record_id UNINDEXED,
+				title,
+				body,
+				tags,
+				tokenize = 'unicode61 remove_diacritics 2'
+			)
+		`).Execute()
+		if err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Created search_index FTS5 table")
+		return nil
+	}, func(app core.App) error {
+		_, err := app.DB().NewQuery("DROP TABLE IF EXISTS search_index").Execute()
+		return err
+	})
+}
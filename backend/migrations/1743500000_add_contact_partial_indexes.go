@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("contacts")
+		if err != nil {
+			return err
+		}
+
+		// Space-separated HMAC tokens for partial matching of encrypted fields
+		// (see utils.EmailDomainIndex, PhoneLast4Index and LocationTokensIndex)
+		if !fieldExists(collection, "email_domain_index") {
+			collection.Fields.Add(&core.TextField{
+				Id:   "cont_email_domain_index",
+				Name: "email_domain_index",
+				Max:  2500,
+			})
+		}
+
+		if !fieldExists(collection, "phone_last4_index") {
+			collection.Fields.Add(&core.TextField{
+				Id:   "cont_phone_last4_index",
+				Name: "phone_last4_index",
+				Max:  64,
+			})
+		}
+
+		if !fieldExists(collection, "location_tokens_index") {
+			collection.Fields.Add(&core.TextField{
+				Id:   "cont_location_tokens_index",
+				Name: "location_tokens_index",
+				Max:  15000,
+			})
+		}
+
+		collection.AddIndex("idx_contacts_phone_last4", false, "phone_last4_index", "")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Added partial-match blind index fields to contacts")
+		log.Println("[Migration] Run 'backfill-search-indexes' to populate them for existing contacts")
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("contacts")
+		if err != nil {
+			return nil
+		}
+		collection.RemoveIndex("idx_contacts_phone_last4")
+		collection.Fields.RemoveByName("email_domain_index")
+		collection.Fields.RemoveByName("phone_last4_index")
+		collection.Fields.RemoveByName("location_tokens_index")
+		return app.Save(collection)
+	})
+}
@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// auditActions are the action values written by handlers beyond the original CRUD/auth set.
+// audit_logs.action is a select field, so unknown values fail validation and the entry is dropped.
+var auditActions = []string{
+	"export", "merge", "merge_undo", "clone",
+	"duplicate_scan", "duplicate_dismiss", "contact_import_run",
+	"mailchimp_sync", "mailchimp_sync_contact", "mailchimp_settings_update", "mailchimp_webhook",
+	"rsvp_send_invites", "rsvp_send_followups",
+	"calendar_event_created", "calendar_send_all",
+	"attendee_login", "attendee_otp_sent", "attendee_email_link", "attendee_profile_update",
+}
+
+func init() {
+	m.Register(func(app core.App) error {
+		if err := extendAuditActions(app, auditActions); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Extended audit_logs action values")
+		return nil
+	}, func(app core.App) error {
+		return nil
+	})
+}
+
+// extendAuditActions appends any missing values to the audit_logs action select field
+func extendAuditActions(app core.App, actions []string) error {
+	collection, err := app.FindCollectionByNameOrId("audit_logs")
+	if err != nil {
+		return err
+	}
+
+	sf, ok := collection.Fields.GetByName("action").(*core.SelectField)
+	if !ok {
+		return nil
+	}
+	existing := map[string]bool{}
+	for _, v := range sf.Values {
+		existing[v] = true
+	}
+	for _, v := range actions {
+		if !existing[v] {
+			sf.Values = append(sf.Values, v)
+		}
+	}
+
+	return app.Save(collection)
+}
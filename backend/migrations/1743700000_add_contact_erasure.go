@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("contacts")
+		if err != nil {
+			return err
+		}
+
+		// Set when a contact has been anonymised under a right-to-be-forgotten request
+		if !fieldExists(collection, "erased_at") {
+			collection.Fields.Add(&core.DateField{
+				Id:   "cont_erased_at",
+				Name: "erased_at",
+			})
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+
+		if err := extendAuditActions(app, []string{"subject_access_export", "erase"}); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Added erased_at to contacts")
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("contacts")
+		if err != nil {
+			return nil
+		}
+		collection.Fields.RemoveByName("erased_at")
+		return app.Save(collection)
+	})
+}
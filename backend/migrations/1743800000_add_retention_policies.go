@@ -0,0 +1,147 @@
+package migrations
+
+import (
+	"log"
+	"slices"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// retentionPolicyDefaults seeds one policy per purge target (see retentionTargets in
+// retention.go). All start disabled so nothing is deleted until an admin opts in.
+var retentionPolicyDefaults = []struct {
+	Target   string
+	TTLHours int
+}{
+	{"audit_logs", 365 * 24},
+	{"projection_logs", 90 * 24},
+	{"projection_callbacks", 90 * 24},
+	{"humanitix_sync_log", 180 * 24},
+	{"attendee_otp_codes", 24},
+	{"guest_list_otp_codes", 24},
+	{"pending_contacts", 90 * 24},
+}
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("retention_policies")
+
+		targets := make([]string, len(retentionPolicyDefaults))
+		for i, d := range retentionPolicyDefaults {
+			targets[i] = d.Target
+		}
+
+		collection.Fields.Add(&core.SelectField{
+			Id:        "ret_target",
+			Name:      "target",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    targets,
+		})
+
+		min1 := 1.0
+		collection.Fields.Add(&core.NumberField{
+			Id:      "ret_ttl_hours",
+			Name:    "ttl_hours",
+			Min:     &min1,
+			OnlyInt: true,
+		})
+
+		collection.Fields.Add(&core.BoolField{
+			Id:   "ret_enabled",
+			Name: "enabled",
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:   "ret_last_run_at",
+			Name: "last_run_at",
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:   "ret_last_deleted",
+			Name: "last_deleted",
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "ret_updated_by",
+			Name: "updated_by",
+			Max:  200,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "ret_created",
+			Name:     "created",
+			OnCreate: true,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "ret_updated",
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+
+		// Admin only — changes go through /api/admin/retention so they're audited
+		collection.ListRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.ViewRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.CreateRule = nil
+		collection.UpdateRule = nil
+		collection.DeleteRule = nil
+
+		collection.AddIndex("idx_retention_policies_target", true, "target", "")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		for _, d := range retentionPolicyDefaults {
+			record := core.NewRecord(collection)
+			record.Set("target", d.Target)
+			record.Set("ttl_hours", d.TTLHours)
+			record.Set("enabled", false)
+			if err := app.Save(record); err != nil {
+				return err
+			}
+		}
+
+		if err := extendAuditActions(app, []string{"retention_purge", "retention_policy_update"}); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Created retention_policies collection")
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("retention_policies")
+		if err != nil {
+			return nil
+		}
+		return app.Delete(collection)
+	})
+}
+
+// addRetentionPolicy registers a purge target added after the initial seed: the target
+// is added to the select values and a disabled policy is created if there isn't one.
+func addRetentionPolicy(app core.App, target string, ttlHours int) error {
+	collection, err := app.FindCollectionByNameOrId("retention_policies")
+	if err != nil {
+		return err
+	}
+
+	if sf, ok := collection.Fields.GetByName("target").(*core.SelectField); ok && !slices.Contains(sf.Values, target) {
+		sf.Values = append(sf.Values, target)
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+	}
+
+	if _, err := app.FindFirstRecordByData(collection, "target", target); err == nil {
+		return nil
+	}
+	record := core.NewRecord(collection)
+	record.Set("target", target)
+	record.Set("ttl_hours", ttlHours)
+	record.Set("enabled", false)
+	return app.Save(record)
+}
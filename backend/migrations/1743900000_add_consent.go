@@ -0,0 +1,140 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		contacts, err := app.FindCollectionByNameOrId("contacts")
+		if err != nil {
+			return err
+		}
+
+		// Current answer per category: {"marketing": {"status", "source", "updated_at"}, ...}
+		// See consentState in consent.go. The proof for each change is in consent_events.
+		if !fieldExists(contacts, "consent") {
+			contacts.Fields.Add(&core.JSONField{
+				Id:      "cont_consent",
+				Name:    "consent",
+				MaxSize: 5000,
+			})
+			if err := app.Save(contacts); err != nil {
+				return err
+			}
+		}
+
+		collection := core.NewBaseCollection("consent_events")
+
+		// Cascade delete so erasing or deleting a contact removes its consent history
+		collection.Fields.Add(&core.RelationField{
+			Id:            "ce_contact",
+			Name:          "contact",
+			Required:      true,
+			CollectionId:  contacts.Id,
+			CascadeDelete: true,
+			MaxSelect:     1,
+		})
+
+		collection.Fields.Add(&core.SelectField{
+			Id:        "ce_category",
+			Name:      "category",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"marketing", "event_invites", "programme_updates"},
+		})
+
+		collection.Fields.Add(&core.SelectField{
+			Id:        "ce_status",
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"granted", "withdrawn"},
+		})
+
+		collection.Fields.Add(&core.SelectField{
+			Id:        "ce_source",
+			Name:      "source",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"admin", "attendee_portal", "rsvp", "mailchimp", "unsubscribe_link", "import"},
+		})
+
+		// Which form or integration captured the change, e.g. "attendee_profile" or "rsvp:<guest list id>"
+		collection.Fields.Add(&core.TextField{
+			Id:   "ce_form",
+			Name: "form",
+			Max:  200,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "ce_ip_address",
+			Name: "ip_address",
+			Max:  100,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "ce_user_agent",
+			Name: "user_agent",
+			Max:  500,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "ce_recorded_by",
+			Name: "recorded_by",
+			Max:  200,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "ce_created",
+			Name:     "created",
+			OnCreate: true,
+		})
+
+		// Append-only proof trail — written by the backend, readable by admins
+		collection.ListRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.ViewRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.CreateRule = nil
+		collection.UpdateRule = nil
+		collection.DeleteRule = nil
+
+		collection.AddIndex("idx_consent_events_contact", false, "contact", "")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// Contacts already unsubscribed in Mailchimp start with marketing withdrawn.
+		// Raw SQL so the contact hooks (encryption, webhooks) don't run for every row.
+		if _, err := app.DB().NewQuery(`
+			UPDATE contacts
+			SET consent = json_object('marketing', json_object('status', 'withdrawn', 'source', 'mailchimp', 'updated_at', updated))
+			WHERE mailchimp_status = 'unsubscribed'
+		`).Execute(); err != nil {
+			return err
+		}
+
+		if err := extendAuditActions(app, []string{"consent_update"}); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Added consent tracking to contacts")
+		return nil
+	}, func(app core.App) error {
+		if collection, err := app.FindCollectionByNameOrId("consent_events"); err == nil {
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+		contacts, err := app.FindCollectionByNameOrId("contacts")
+		if err != nil {
+			return nil
+		}
+		contacts.Fields.RemoveByName("consent")
+		return app.Save(contacts)
+	})
+}
@@ -0,0 +1,97 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		collection := core.NewBaseCollection("email_suppressions")
+
+		// Lookup key — blind index of the lowercased address, one row per address
+		collection.Fields.Add(&core.TextField{
+			Id:       "sup_email_index",
+			Name:     "email_index",
+			Required: true,
+			Max:      100,
+		})
+
+		// Encrypted address so admins can see who is suppressed
+		collection.Fields.Add(&core.TextField{
+			Id:   "sup_email",
+			Name: "email",
+			Max:  500,
+		})
+
+		// unsubscribed blocks bulk sends; hard_bounce and complaint block everything
+		collection.Fields.Add(&core.SelectField{
+			Id:        "sup_reason",
+			Name:      "reason",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"unsubscribed", "hard_bounce", "complaint", "manual"},
+		})
+
+		// Where the suppression came from, e.g. "one_click", "unsubscribe_page", "admin"
+		collection.Fields.Add(&core.TextField{
+			Id:   "sup_source",
+			Name: "source",
+			Max:  100,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "sup_details",
+			Name: "details",
+			Max:  1000,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "sup_created_by",
+			Name: "created_by",
+			Max:  200,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "sup_created",
+			Name:     "created",
+			OnCreate: true,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "sup_updated",
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+
+		// Managed through the admin suppression endpoints
+		collection.ListRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.ViewRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.CreateRule = nil
+		collection.UpdateRule = nil
+		collection.DeleteRule = nil
+
+		collection.AddIndex("idx_email_suppressions_email_index", true, "email_index", "")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		if err := extendAuditActions(app, []string{"unsubscribe", "suppression_add", "suppression_remove"}); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Created email_suppressions collection")
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("email_suppressions")
+		if err != nil {
+			return nil
+		}
+		return app.Delete(collection)
+	})
+}
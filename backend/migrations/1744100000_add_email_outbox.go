@@ -0,0 +1,203 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		items, err := app.FindCollectionByNameOrId("guest_list_items")
+		if err != nil {
+			return err
+		}
+
+		collection := core.NewBaseCollection("email_outbox")
+
+		// What the message is, e.g. "rsvp_invite" or "attendee_otp" (see emailKind* in outbox.go)
+		collection.Fields.Add(&core.TextField{
+			Id:       "eo_kind",
+			Name:     "kind",
+			Required: true,
+			Max:      50,
+		})
+
+		// Primary recipient, encrypted, with a blind index for lookups
+		collection.Fields.Add(&core.TextField{
+			Id:       "eo_to_email",
+			Name:     "to_email",
+			Required: true,
+			Max:      500,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "eo_to_email_index",
+			Name: "to_email_index",
+			Max:  100,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "eo_to_name",
+			Name: "to_name",
+			Max:  200,
+		})
+
+		// Encrypted JSON with the rest of the mailer.Message: from, to, cc, bcc, headers, attachments
+		collection.Fields.Add(&core.TextField{
+			Id:   "eo_envelope",
+			Name: "envelope",
+			Max:  2 << 20,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "eo_subject",
+			Name: "subject",
+			Max:  500,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "eo_html",
+			Name: "html",
+			Max:  500000,
+		})
+
+		// Transactional messages ignore unsubscribes (but not bounces or complaints)
+		collection.Fields.Add(&core.BoolField{
+			Id:   "eo_transactional",
+			Name: "transactional",
+		})
+
+		// The invite this message belongs to, if any — its delivery status is kept in sync
+		collection.Fields.Add(&core.RelationField{
+			Id:           "eo_guest_list_item",
+			Name:         "guest_list_item",
+			CollectionId: items.Id,
+			MaxSelect:    1,
+		})
+
+		collection.Fields.Add(&core.SelectField{
+			Id:        "eo_status",
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"queued", "sending", "sent", "failed", "suppressed", "cancelled"},
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:      "eo_attempts",
+			Name:    "attempts",
+			OnlyInt: true,
+		})
+
+		collection.Fields.Add(&core.NumberField{
+			Id:      "eo_max_attempts",
+			Name:    "max_attempts",
+			OnlyInt: true,
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:   "eo_next_attempt_at",
+			Name: "next_attempt_at",
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:   "eo_sent_at",
+			Name: "sent_at",
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "eo_last_error",
+			Name: "last_error",
+			Max:  2000,
+		})
+
+		// SMTP host the message was (last) sent through, for per-provider throttling and the log
+		collection.Fields.Add(&core.TextField{
+			Id:   "eo_provider",
+			Name: "provider",
+			Max:  200,
+		})
+
+		// One entry per attempt: [{"attempt", "at", "status", "provider", "error"}]
+		collection.Fields.Add(&core.JSONField{
+			Id:      "eo_log",
+			Name:    "log",
+			MaxSize: 50000,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "eo_created",
+			Name:     "created",
+			OnCreate: true,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "eo_updated",
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+
+		// Written by the backend only; admins read it through /api/admin/email-outbox
+		collection.ListRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.ViewRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.CreateRule = nil
+		collection.UpdateRule = nil
+		collection.DeleteRule = nil
+
+		collection.AddIndex("idx_email_outbox_due", false, "status, next_attempt_at", "")
+		collection.AddIndex("idx_email_outbox_item", false, "guest_list_item", "")
+		collection.AddIndex("idx_email_outbox_to", false, "to_email_index", "")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// Latest delivery outcome for the item's invite emails
+		if !fieldExists(items, "email_status") {
+			items.Fields.Add(&core.SelectField{
+				Id:        "gli_email_status",
+				Name:      "email_status",
+				MaxSelect: 1,
+				Values:    []string{"queued", "sent", "failed", "suppressed"},
+			})
+		}
+		if !fieldExists(items, "email_error") {
+			items.Fields.Add(&core.TextField{
+				Id:   "gli_email_error",
+				Name: "email_error",
+				Max:  2000,
+			})
+		}
+		if err := app.Save(items); err != nil {
+			return err
+		}
+
+		if err := addRetentionPolicy(app, "email_outbox", 180*24); err != nil {
+			return err
+		}
+
+		if err := extendAuditActions(app, []string{"email_retry", "email_cancel"}); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Created email_outbox collection")
+		return nil
+	}, func(app core.App) error {
+		if collection, err := app.FindCollectionByNameOrId("email_outbox"); err == nil {
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+		items, err := app.FindCollectionByNameOrId("guest_list_items")
+		if err != nil {
+			return nil
+		}
+		items.Fields.RemoveByName("email_status")
+		items.Fields.RemoveByName("email_error")
+		return app.Save(items)
+	})
+}
@@ -0,0 +1,85 @@
+package migrations
+
+import (
+	"log"
+	"slices"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		contacts, err := app.FindCollectionByNameOrId("contacts")
+		if err != nil {
+			return err
+		}
+
+		// Set when the primary email hard-bounces or the recipient reports spam;
+		// cleared when the email is changed
+		if !fieldExists(contacts, "email_bounce_status") {
+			contacts.Fields.Add(&core.SelectField{
+				Id:        "cont_email_bounce_status",
+				Name:      "email_bounce_status",
+				MaxSelect: 1,
+				Values:    []string{"bounced", "complained"},
+			})
+		}
+		if !fieldExists(contacts, "email_bounce_detail") {
+			contacts.Fields.Add(&core.TextField{
+				Id:   "cont_email_bounce_detail",
+				Name: "email_bounce_detail",
+				Max:  1000,
+			})
+		}
+		if !fieldExists(contacts, "email_bounce_at") {
+			contacts.Fields.Add(&core.DateField{
+				Id:   "cont_email_bounce_at",
+				Name: "email_bounce_at",
+			})
+		}
+		if err := app.Save(contacts); err != nil {
+			return err
+		}
+
+		// Bounces and complaints arrive after the message was sent, so both the outbox
+		// message and the guest list item get statuses for them
+		for _, target := range []struct{ collection, field string }{
+			{"email_outbox", "status"},
+			{"guest_list_items", "email_status"},
+		} {
+			collection, err := app.FindCollectionByNameOrId(target.collection)
+			if err != nil {
+				return err
+			}
+			sf, ok := collection.Fields.GetByName(target.field).(*core.SelectField)
+			if !ok {
+				continue
+			}
+			for _, v := range []string{"bounced", "complained"} {
+				if !slices.Contains(sf.Values, v) {
+					sf.Values = append(sf.Values, v)
+				}
+			}
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+
+		if err := extendAuditActions(app, []string{"email_bounce", "email_complaint"}); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Added bounce and complaint tracking")
+		return nil
+	}, func(app core.App) error {
+		contacts, err := app.FindCollectionByNameOrId("contacts")
+		if err != nil {
+			return nil
+		}
+		contacts.Fields.RemoveByName("email_bounce_status")
+		contacts.Fields.RemoveByName("email_bounce_detail")
+		contacts.Fields.RemoveByName("email_bounce_at")
+		return app.Save(contacts)
+	})
+}
@@ -0,0 +1,213 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		guestLists, err := app.FindCollectionByNameOrId("guest_lists")
+		if err != nil {
+			return err
+		}
+		themes, err := app.FindCollectionByNameOrId("themes")
+		if err != nil {
+			return err
+		}
+
+		templates := core.NewBaseCollection("email_templates")
+
+		// Which email this overrides — same values as the outbox kind (emailKind* in outbox.go)
+		templates.Fields.Add(&core.SelectField{
+			Id:        "et_key",
+			Name:      "key",
+			Required:  true,
+			MaxSelect: 1,
+			Values: []string{
+				"rsvp_invite", "rsvp_followup", "rsvp_forward", "rsvp_confirmation",
+				"otp", "attendee_otp", "share_notification", "plus_one_notification",
+			},
+		})
+
+		templates.Fields.Add(&core.TextField{
+			Id:   "et_name",
+			Name: "name",
+			Max:  200,
+		})
+
+		// Scope: a guest list, a theme, or neither for the global override. Guest list wins
+		// over theme, theme over global, global over the built-in copy.
+		templates.Fields.Add(&core.RelationField{
+			Id:            "et_guest_list",
+			Name:          "guest_list",
+			CollectionId:  guestLists.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+
+		templates.Fields.Add(&core.RelationField{
+			Id:            "et_theme",
+			Name:          "theme",
+			CollectionId:  themes.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+
+		// text/template source for the subject line
+		templates.Fields.Add(&core.TextField{
+			Id:       "et_subject",
+			Name:     "subject",
+			Required: true,
+			Max:      500,
+		})
+
+		// html/template source for the body; the branded wrapper is added around it
+		templates.Fields.Add(&core.TextField{
+			Id:       "et_html",
+			Name:     "html",
+			Required: true,
+			Max:      200000,
+		})
+
+		// Current version number; every content change snapshots a new email_template_versions row
+		templates.Fields.Add(&core.NumberField{
+			Id:      "et_version",
+			Name:    "version",
+			OnlyInt: true,
+		})
+
+		templates.Fields.Add(&core.BoolField{
+			Id:   "et_active",
+			Name: "active",
+		})
+
+		templates.Fields.Add(&core.TextField{
+			Id:   "et_created_by",
+			Name: "created_by",
+			Max:  200,
+		})
+
+		templates.Fields.Add(&core.AutodateField{
+			Id:       "et_created",
+			Name:     "created",
+			OnCreate: true,
+		})
+
+		templates.Fields.Add(&core.AutodateField{
+			Id:       "et_updated",
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+
+		// Managed through /api/admin/email-templates
+		templates.ListRule = types.Pointer("@request.auth.role = 'admin'")
+		templates.ViewRule = types.Pointer("@request.auth.role = 'admin'")
+		templates.CreateRule = nil
+		templates.UpdateRule = nil
+		templates.DeleteRule = nil
+
+		templates.AddIndex("idx_email_templates_scope", false, "key, guest_list, theme", "")
+
+		if err := app.Save(templates); err != nil {
+			return err
+		}
+
+		versions := core.NewBaseCollection("email_template_versions")
+
+		// Kept (unlinked) if the template is deleted so sent messages still show their copy
+		versions.Fields.Add(&core.RelationField{
+			Id:           "etv_template",
+			Name:         "template",
+			CollectionId: templates.Id,
+			MaxSelect:    1,
+		})
+
+		versions.Fields.Add(&core.TextField{
+			Id:   "etv_key",
+			Name: "key",
+			Max:  50,
+		})
+
+		versions.Fields.Add(&core.NumberField{
+			Id:      "etv_version",
+			Name:    "version",
+			OnlyInt: true,
+		})
+
+		versions.Fields.Add(&core.TextField{
+			Id:   "etv_subject",
+			Name: "subject",
+			Max:  500,
+		})
+
+		versions.Fields.Add(&core.TextField{
+			Id:   "etv_html",
+			Name: "html",
+			Max:  200000,
+		})
+
+		versions.Fields.Add(&core.TextField{
+			Id:   "etv_created_by",
+			Name: "created_by",
+			Max:  200,
+		})
+
+		versions.Fields.Add(&core.AutodateField{
+			Id:       "etv_created",
+			Name:     "created",
+			OnCreate: true,
+		})
+
+		versions.ListRule = types.Pointer("@request.auth.role = 'admin'")
+		versions.ViewRule = types.Pointer("@request.auth.role = 'admin'")
+		versions.CreateRule = nil
+		versions.UpdateRule = nil
+		versions.DeleteRule = nil
+
+		versions.AddIndex("idx_email_template_versions_template", false, "template, version", "")
+
+		if err := app.Save(versions); err != nil {
+			return err
+		}
+
+		// Which template version rendered each message (empty for the built-in copy)
+		outbox, err := app.FindCollectionByNameOrId("email_outbox")
+		if err != nil {
+			return err
+		}
+		if !fieldExists(outbox, "template_version") {
+			outbox.Fields.Add(&core.RelationField{
+				Id:           "eo_template_version",
+				Name:         "template_version",
+				CollectionId: versions.Id,
+				MaxSelect:    1,
+			})
+		}
+		if err := app.Save(outbox); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Created email_templates and email_template_versions collections")
+		return nil
+	}, func(app core.App) error {
+		if outbox, err := app.FindCollectionByNameOrId("email_outbox"); err == nil {
+			outbox.Fields.RemoveByName("template_version")
+			if err := app.Save(outbox); err != nil {
+				return err
+			}
+		}
+		for _, name := range []string{"email_template_versions", "email_templates"} {
+			if collection, err := app.FindCollectionByNameOrId(name); err == nil {
+				if err := app.Delete(collection); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
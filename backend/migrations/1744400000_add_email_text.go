@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Optional text/template source for the plain-text part; generated from the HTML when empty
+		for _, target := range []struct{ collection, id string }{
+			{"email_templates", "et_text"},
+			{"email_template_versions", "etv_text"},
+		} {
+			collection, err := app.FindCollectionByNameOrId(target.collection)
+			if err != nil {
+				return err
+			}
+			if !fieldExists(collection, "text") {
+				collection.Fields.Add(&core.TextField{
+					Id:   target.id,
+					Name: "text",
+					Max:  100000,
+				})
+			}
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+
+		// Attach the event's .ics to invites as well as confirmations
+		guestLists, err := app.FindCollectionByNameOrId("guest_lists")
+		if err != nil {
+			return err
+		}
+		if !fieldExists(guestLists, "invite_attach_ics") {
+			guestLists.Fields.Add(&core.BoolField{
+				Id:   "gl_invite_attach_ics",
+				Name: "invite_attach_ics",
+			})
+		}
+		if err := app.Save(guestLists); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Added plain-text email templates and invite .ics option")
+		return nil
+	}, func(app core.App) error {
+		for _, name := range []string{"email_templates", "email_template_versions"} {
+			if collection, err := app.FindCollectionByNameOrId(name); err == nil {
+				collection.Fields.RemoveByName("text")
+				if err := app.Save(collection); err != nil {
+					return err
+				}
+			}
+		}
+		guestLists, err := app.FindCollectionByNameOrId("guest_lists")
+		if err != nil {
+			return nil
+		}
+		guestLists.Fields.RemoveByName("invite_attach_ics")
+		return app.Save(guestLists)
+	})
+}
@@ -0,0 +1,229 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		guestLists, err := app.FindCollectionByNameOrId("guest_lists")
+		if err != nil {
+			return err
+		}
+
+		collection := core.NewBaseCollection("rsvp_rounds")
+
+		collection.Fields.Add(&core.RelationField{
+			Id:            "rr_guest_list",
+			Name:          "guest_list",
+			CollectionId:  guestLists.Id,
+			Required:      true,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+
+		// invite: not yet invited; followup: invited without a response; logistics: accepted
+		collection.Fields.Add(&core.SelectField{
+			Id:        "rr_action",
+			Name:      "action",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"invite", "followup", "logistics"},
+		})
+
+		// manual: the send buttons; scheduled: a send booked for send_at; cadence: a step of
+		// the guest list's rsvp_cadence
+		collection.Fields.Add(&core.SelectField{
+			Id:        "rr_source",
+			Name:      "source",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"manual", "scheduled", "cadence"},
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "rr_cadence_step",
+			Name: "cadence_step",
+			Max:  50,
+		})
+
+		collection.Fields.Add(&core.SelectField{
+			Id:        "rr_status",
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"scheduled", "sending", "sent", "cancelled", "failed"},
+		})
+
+		// When a scheduled round is due (UTC), and the timezone it was entered in
+		collection.Fields.Add(&core.DateField{
+			Id:   "rr_send_at",
+			Name: "send_at",
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "rr_timezone",
+			Name: "timezone",
+			Max:  100,
+		})
+
+		// Scheduled rounds: the items chosen when scheduling (empty for the whole audience).
+		// Completed rounds: the items that were emailed.
+		collection.Fields.Add(&core.JSONField{
+			Id:      "rr_item_ids",
+			Name:    "item_ids",
+			MaxSize: 200000,
+		})
+
+		for _, name := range []string{"sent", "skipped", "opted_out", "suppressed"} {
+			collection.Fields.Add(&core.NumberField{
+				Id:      "rr_" + name,
+				Name:    name,
+				OnlyInt: true,
+			})
+		}
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "rr_error",
+			Name: "error",
+			Max:  2000,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "rr_created_by",
+			Name: "created_by",
+			Max:  200,
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:   "rr_started_at",
+			Name: "started_at",
+		})
+
+		collection.Fields.Add(&core.DateField{
+			Id:   "rr_completed_at",
+			Name: "completed_at",
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "rr_created",
+			Name:     "created",
+			OnCreate: true,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "rr_updated",
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		})
+
+		// Managed through /api/guest-lists/{id}/rsvp/*
+		collection.ListRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.ViewRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.CreateRule = nil
+		collection.UpdateRule = nil
+		collection.DeleteRule = nil
+
+		collection.AddIndex("idx_rsvp_rounds_guest_list", false, "guest_list, created", "")
+		collection.AddIndex("idx_rsvp_rounds_due", false, "status, send_at", "")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		// Automatic follow-ups: [{"id", "name", "action", "trigger", "offset_hours"}]
+		if !fieldExists(guestLists, "rsvp_cadence") {
+			guestLists.Fields.Add(&core.JSONField{
+				Id:      "gl_rsvp_cadence",
+				Name:    "rsvp_cadence",
+				MaxSize: 20000,
+			})
+		}
+		if err := app.Save(guestLists); err != nil {
+			return err
+		}
+
+		items, err := app.FindCollectionByNameOrId("guest_list_items")
+		if err != nil {
+			return err
+		}
+		if !fieldExists(items, "invited_at") {
+			items.Fields.Add(&core.DateField{
+				Id:   "gli_invited_at",
+				Name: "invited_at",
+			})
+		}
+		// Cadence step IDs already sent to this guest, so each step goes out once
+		if !fieldExists(items, "cadence_sent") {
+			items.Fields.Add(&core.JSONField{
+				Id:      "gli_cadence_sent",
+				Name:    "cadence_sent",
+				MaxSize: 5000,
+			})
+		}
+		if err := app.Save(items); err != nil {
+			return err
+		}
+
+		// Invites already sent through the outbox get their send time. Raw SQL so the item
+		// hooks don't run for every row.
+		if _, err := app.DB().NewQuery(`
+			UPDATE guest_list_items
+			SET invited_at = (
+				SELECT MIN(created) FROM email_outbox
+				WHERE email_outbox.guest_list_item = guest_list_items.id AND email_outbox.kind = 'rsvp_invite'
+			)
+			WHERE invite_status = 'invited' AND (invited_at = '' OR invited_at IS NULL)
+				AND EXISTS (
+					SELECT 1 FROM email_outbox
+					WHERE email_outbox.guest_list_item = guest_list_items.id AND email_outbox.kind = 'rsvp_invite'
+				)
+		`).Execute(); err != nil {
+			return err
+		}
+
+		// The day-of logistics email can be overridden like the others
+		templates, err := app.FindCollectionByNameOrId("email_templates")
+		if err != nil {
+			return err
+		}
+		if sf, ok := templates.Fields.GetByName("key").(*core.SelectField); ok {
+			sf.Values = append(sf.Values, "rsvp_logistics")
+			if err := app.Save(templates); err != nil {
+				return err
+			}
+		}
+
+		if err := extendAuditActions(app, []string{"rsvp_send_logistics", "rsvp_schedule", "rsvp_schedule_cancel"}); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Created rsvp_rounds collection and guest list cadences")
+		return nil
+	}, func(app core.App) error {
+		if collection, err := app.FindCollectionByNameOrId("rsvp_rounds"); err == nil {
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+		if guestLists, err := app.FindCollectionByNameOrId("guest_lists"); err == nil {
+			guestLists.Fields.RemoveByName("rsvp_cadence")
+			if err := app.Save(guestLists); err != nil {
+				return err
+			}
+		}
+		if items, err := app.FindCollectionByNameOrId("guest_list_items"); err == nil {
+			items.Fields.RemoveByName("invited_at")
+			items.Fields.RemoveByName("cadence_sent")
+			if err := app.Save(items); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
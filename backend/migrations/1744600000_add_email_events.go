@@ -0,0 +1,133 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		outbox, err := app.FindCollectionByNameOrId("email_outbox")
+		if err != nil {
+			return err
+		}
+		guestLists, err := app.FindCollectionByNameOrId("guest_lists")
+		if err != nil {
+			return err
+		}
+		items, err := app.FindCollectionByNameOrId("guest_list_items")
+		if err != nil {
+			return err
+		}
+		contacts, err := app.FindCollectionByNameOrId("contacts")
+		if err != nil {
+			return err
+		}
+
+		collection := core.NewBaseCollection("email_events")
+
+		// Unlinked (not deleted) when the outbox message is purged; kind and subject are
+		// copied so the timeline still reads
+		collection.Fields.Add(&core.RelationField{
+			Id:           "ee_message",
+			Name:         "message",
+			CollectionId: outbox.Id,
+			MaxSelect:    1,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "ee_kind",
+			Name: "kind",
+			Max:  50,
+		})
+
+		collection.Fields.Add(&core.TextField{
+			Id:   "ee_subject",
+			Name: "subject",
+			Max:  500,
+		})
+
+		collection.Fields.Add(&core.SelectField{
+			Id:        "ee_type",
+			Name:      "type",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"sent", "opened", "clicked", "bounced", "soft_bounced", "complained", "rsvp"},
+		})
+
+		collection.Fields.Add(&core.RelationField{
+			Id:            "ee_guest_list",
+			Name:          "guest_list",
+			CollectionId:  guestLists.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+
+		collection.Fields.Add(&core.RelationField{
+			Id:            "ee_guest_list_item",
+			Name:          "guest_list_item",
+			CollectionId:  items.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+
+		collection.Fields.Add(&core.RelationField{
+			Id:            "ee_contact",
+			Name:          "contact",
+			CollectionId:  contacts.Id,
+			MaxSelect:     1,
+			CascadeDelete: true,
+		})
+
+		// Clicked link
+		collection.Fields.Add(&core.TextField{
+			Id:   "ee_url",
+			Name: "url",
+			Max:  2000,
+		})
+
+		// Bounce diagnostic, or the RSVP response
+		collection.Fields.Add(&core.TextField{
+			Id:   "ee_detail",
+			Name: "detail",
+			Max:  1000,
+		})
+
+		collection.Fields.Add(&core.AutodateField{
+			Id:       "ee_created",
+			Name:     "created",
+			OnCreate: true,
+		})
+
+		// Written by the backend only; read through the contact and guest list endpoints
+		collection.ListRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.ViewRule = types.Pointer("@request.auth.role = 'admin'")
+		collection.CreateRule = nil
+		collection.UpdateRule = nil
+		collection.DeleteRule = nil
+
+		collection.AddIndex("idx_email_events_contact", false, "contact, created", "")
+		collection.AddIndex("idx_email_events_guest_list", false, "guest_list, type", "")
+		collection.AddIndex("idx_email_events_message", false, "message", "")
+
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		if err := addRetentionPolicy(app, "email_events", 730*24); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Created email_events collection")
+		return nil
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("email_events")
+		if err != nil {
+			return nil
+		}
+		return app.Delete(collection)
+	})
+}
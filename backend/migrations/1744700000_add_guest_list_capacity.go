@@ -0,0 +1,96 @@
+package migrations
+
+import (
+	"log"
+	"slices"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		guestLists, err := app.FindCollectionByNameOrId("guest_lists")
+		if err != nil {
+			return err
+		}
+
+		// Seats, counting plus-ones; 0 means no limit
+		min0 := 0.0
+		if !fieldExists(guestLists, "capacity") {
+			guestLists.Fields.Add(&core.NumberField{
+				Id:      "gl_capacity",
+				Name:    "capacity",
+				OnlyInt: true,
+				Min:     &min0,
+			})
+		}
+		if err := app.Save(guestLists); err != nil {
+			return err
+		}
+
+		items, err := app.FindCollectionByNameOrId("guest_list_items")
+		if err != nil {
+			return err
+		}
+		for _, name := range []string{"invite_status", "rsvp_status"} {
+			if sf, ok := items.Fields.GetByName(name).(*core.SelectField); ok && !slices.Contains(sf.Values, "waitlisted") {
+				sf.Values = append(sf.Values, "waitlisted")
+			}
+		}
+		// Waitlist order: guests are promoted oldest first
+		if !fieldExists(items, "waitlisted_at") {
+			items.Fields.Add(&core.DateField{
+				Id:   "gli_waitlisted_at",
+				Name: "waitlisted_at",
+			})
+		}
+		// The guest whose RSVP brought this plus-one, so their seat isn't counted twice
+		if !fieldExists(items, "plus_one_of") {
+			items.Fields.Add(&core.RelationField{
+				Id:           "gli_plus_one_of",
+				Name:         "plus_one_of",
+				CollectionId: items.Id,
+				MaxSelect:    1,
+			})
+		}
+		items.AddIndex("idx_gli_waitlist", false, "guest_list, invite_status, waitlisted_at", "")
+		if err := app.Save(items); err != nil {
+			return err
+		}
+
+		templates, err := app.FindCollectionByNameOrId("email_templates")
+		if err != nil {
+			return err
+		}
+		if sf, ok := templates.Fields.GetByName("key").(*core.SelectField); ok {
+			sf.Values = append(sf.Values, "rsvp_promoted")
+			if err := app.Save(templates); err != nil {
+				return err
+			}
+		}
+
+		if err := extendAuditActions(app, []string{"rsvp_waitlist_promote"}); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Added guest list capacity and waitlist")
+		return nil
+	}, func(app core.App) error {
+		if guestLists, err := app.FindCollectionByNameOrId("guest_lists"); err == nil {
+			guestLists.Fields.RemoveByName("capacity")
+			if err := app.Save(guestLists); err != nil {
+				return err
+			}
+		}
+		if items, err := app.FindCollectionByNameOrId("guest_list_items"); err == nil {
+			items.RemoveIndex("idx_gli_waitlist")
+			items.Fields.RemoveByName("waitlisted_at")
+			items.Fields.RemoveByName("plus_one_of")
+			if err := app.Save(items); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		items, err := app.FindCollectionByNameOrId("guest_list_items")
+		if err != nil {
+			return err
+		}
+
+		if !fieldExists(items, "checked_in_at") {
+			items.Fields.Add(&core.DateField{
+				Id:   "gli_checked_in_at",
+				Name: "checked_in_at",
+			})
+		}
+		// Email of the admin who checked the guest in
+		if !fieldExists(items, "checked_in_by") {
+			items.Fields.Add(&core.TextField{
+				Id:   "gli_checked_in_by",
+				Name: "checked_in_by",
+				Max:  200,
+			})
+		}
+		// qr: scanned from the confirmation email; search: found by name at the door
+		if !fieldExists(items, "check_in_method") {
+			items.Fields.Add(&core.SelectField{
+				Id:        "gli_check_in_method",
+				Name:      "check_in_method",
+				MaxSelect: 1,
+				Values:    []string{"qr", "search"},
+			})
+		}
+		if !fieldExists(items, "plus_one_checked_in_at") {
+			items.Fields.Add(&core.DateField{
+				Id:   "gli_plus_one_checked_in_at",
+				Name: "plus_one_checked_in_at",
+			})
+		}
+		if err := app.Save(items); err != nil {
+			return err
+		}
+
+		if err := extendAuditActions(app, []string{"check_in", "check_in_undo"}); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Added guest list check-in fields")
+		return nil
+	}, func(app core.App) error {
+		items, err := app.FindCollectionByNameOrId("guest_list_items")
+		if err != nil {
+			return nil
+		}
+		for _, name := range []string{"checked_in_at", "checked_in_by", "check_in_method", "plus_one_checked_in_at"} {
+			items.Fields.RemoveByName(name)
+		}
+		return app.Save(items)
+	})
+}
@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		guestLists, err := app.FindCollectionByNameOrId("guest_lists")
+		if err != nil {
+			return err
+		}
+
+		// The guest list's own RSVP questions, asked after the standard ones
+		if !fieldExists(guestLists, "rsvp_questions") {
+			guestLists.Fields.Add(&core.JSONField{
+				Id:      "gl_rsvp_questions",
+				Name:    "rsvp_questions",
+				MaxSize: 50000,
+			})
+		}
+		if err := app.Save(guestLists); err != nil {
+			return err
+		}
+
+		items, err := app.FindCollectionByNameOrId("guest_list_items")
+		if err != nil {
+			return err
+		}
+
+		// Answers keyed by question id
+		if !fieldExists(items, "rsvp_answers") {
+			items.Fields.Add(&core.JSONField{
+				Id:      "gli_rsvp_answers",
+				Name:    "rsvp_answers",
+				MaxSize: 50000,
+			})
+		}
+		if err := app.Save(items); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Added custom RSVP questions")
+		return nil
+	}, func(app core.App) error {
+		if guestLists, err := app.FindCollectionByNameOrId("guest_lists"); err == nil {
+			guestLists.Fields.RemoveByName("rsvp_questions")
+			if err := app.Save(guestLists); err != nil {
+				return err
+			}
+		}
+		if items, err := app.FindCollectionByNameOrId("guest_list_items"); err == nil {
+			items.Fields.RemoveByName("rsvp_answers")
+			if err := app.Save(items); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		guestLists, err := app.FindCollectionByNameOrId("guest_lists")
+		if err != nil {
+			return err
+		}
+
+		// After this, guests can no longer respond or change their RSVP, only cancel
+		if !fieldExists(guestLists, "rsvp_deadline") {
+			guestLists.Fields.Add(&core.DateField{
+				Id:   "gl_rsvp_deadline",
+				Name: "rsvp_deadline",
+			})
+		}
+		if err := app.Save(guestLists); err != nil {
+			return err
+		}
+
+		items, err := app.FindCollectionByNameOrId("guest_list_items")
+		if err != nil {
+			return err
+		}
+
+		// Every response, change and cancellation, oldest first
+		if !fieldExists(items, "rsvp_history") {
+			items.Fields.Add(&core.JSONField{
+				Id:      "gli_rsvp_history",
+				Name:    "rsvp_history",
+				MaxSize: 50000,
+			})
+		}
+		if err := app.Save(items); err != nil {
+			return err
+		}
+
+		templates, err := app.FindCollectionByNameOrId("email_templates")
+		if err != nil {
+			return err
+		}
+		if sf, ok := templates.Fields.GetByName("key").(*core.SelectField); ok {
+			sf.Values = append(sf.Values, "rsvp_changed")
+			if err := app.Save(templates); err != nil {
+				return err
+			}
+		}
+
+		if err := extendAuditActions(app, []string{"rsvp_cancel"}); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Added RSVP deadline and change history")
+		return nil
+	}, func(app core.App) error {
+		if guestLists, err := app.FindCollectionByNameOrId("guest_lists"); err == nil {
+			guestLists.Fields.RemoveByName("rsvp_deadline")
+			if err := app.Save(guestLists); err != nil {
+				return err
+			}
+		}
+		if items, err := app.FindCollectionByNameOrId("guest_list_items"); err == nil {
+			items.Fields.RemoveByName("rsvp_history")
+			if err := app.Save(items); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
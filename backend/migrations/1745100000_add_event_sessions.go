@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		guestLists, err := app.FindCollectionByNameOrId("guest_lists")
+		if err != nil {
+			return err
+		}
+
+		// Dinners, workshops and tours guests can pick from, each with its own time, place
+		// and capacity
+		if !fieldExists(guestLists, "sessions") {
+			guestLists.Fields.Add(&core.JSONField{
+				Id:      "gl_sessions",
+				Name:    "sessions",
+				MaxSize: 50000,
+			})
+		}
+		if err := app.Save(guestLists); err != nil {
+			return err
+		}
+
+		items, err := app.FindCollectionByNameOrId("guest_list_items")
+		if err != nil {
+			return err
+		}
+
+		// IDs of the sessions the guest picked
+		if !fieldExists(items, "rsvp_sessions") {
+			items.Fields.Add(&core.JSONField{
+				Id:      "gli_rsvp_sessions",
+				Name:    "rsvp_sessions",
+				MaxSize: 5000,
+			})
+		}
+		if err := app.Save(items); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Added guest list sessions")
+		return nil
+	}, func(app core.App) error {
+		if guestLists, err := app.FindCollectionByNameOrId("guest_lists"); err == nil {
+			guestLists.Fields.RemoveByName("sessions")
+			if err := app.Save(guestLists); err != nil {
+				return err
+			}
+		}
+		if items, err := app.FindCollectionByNameOrId("guest_list_items"); err == nil {
+			items.Fields.RemoveByName("rsvp_sessions")
+			if err := app.Save(items); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Duplicate scans and dismissals. A no-op where extend_audit_actions already added them.
+		if err := extendAuditActions(app, []string{"duplicate_scan", "duplicate_dismiss"}); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Registered duplicate review audit actions")
+		return nil
+	}, func(app core.App) error {
+		// Values are left in place: existing audit entries may use them
+		return nil
+	})
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// CSV contact imports. A no-op where extend_audit_actions already added it.
+		if err := extendAuditActions(app, []string{"contact_import_run"}); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Registered contact import audit action")
+		return nil
+	}, func(app core.App) error {
+		// Values are left in place: existing audit entries may use them
+		return nil
+	})
+}
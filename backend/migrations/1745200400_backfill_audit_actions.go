@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// baselineAuditActions are logged by handlers that predate extendAuditActions. 1743600000
+// registered most of them but missed rsvp_submit and rsvp_forward, whose entries were
+// rejected; values that are already present are skipped.
+var baselineAuditActions = []string{
+	"merge", "clone",
+	"mailchimp_sync", "mailchimp_sync_contact", "mailchimp_settings_update", "mailchimp_webhook",
+	"rsvp_submit", "rsvp_forward", "rsvp_send_invites", "rsvp_send_followups",
+	"calendar_event_created", "calendar_send_all",
+	"attendee_login", "attendee_otp_sent", "attendee_email_link", "attendee_profile_update",
+}
+
+func init() {
+	m.Register(func(app core.App) error {
+		if err := extendAuditActions(app, baselineAuditActions); err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Backfilled baseline audit actions")
+		return nil
+	}, func(app core.App) error {
+		// Values are left in place: existing audit entries may use them
+		return nil
+	})
+}
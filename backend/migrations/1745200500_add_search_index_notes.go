@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"log"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		// Guest list notes move out of body into their own column so search can leave
+		// them out for non-admins. FTS5 tables can't be altered, so the index is rebuilt;
+		// search.go backfills it on startup when empty.
+		if _, err := app.DB().NewQuery("DROP TABLE IF EXISTS search_index").Execute(); err != nil {
+			return err
+		}
+		_, err := app.DB().NewQuery(`
+			CREATE VIRTUAL TABLE search_index USING fts5(
+				entity UNINDEXED,
+				record_id UNINDEXED,
+				title,
+				body,
+				tags,
+				notes,
+				tokenize = 'unicode61 remove_diacritics 2'
+			)
+		`).Execute()
+		if err != nil {
+			return err
+		}
+
+		log.Println("[Migration] Added notes column to search_index")
+		return nil
+	}, func(app core.App) error {
+		if _, err := app.DB().NewQuery("DROP TABLE IF EXISTS search_index").Execute(); err != nil {
+			return err
+		}
+		_, err := app.DB().NewQuery(`
+			CREATE VIRTUAL TABLE search_index USING fts5(
+				entity UNINDEXED,
+				record_id UNINDEXED,
+				title,
+				body,
+				tags,
+				tokenize = 'unicode61 remove_diacritics 2'
+			)
+		`).Execute()
+		return err
+	})
+}
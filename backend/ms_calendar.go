@@ -208,6 +208,32 @@ func addAttendeeToCalendarEvent(app *pocketbase.PocketBase, hostUserID, calendar
 	return patchEventAttendees(token, calendarEventID, existing)
 }
 
+// removeAttendeeFromCalendarEvent takes an attendee off an existing calendar event.
+func removeAttendeeFromCalendarEvent(app *pocketbase.PocketBase, hostUserID, calendarEventID, email string) error {
+	token, err := getValidMicrosoftToken(app, hostUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get token: %w", err)
+	}
+
+	existing, err := getEventAttendees(token, calendarEventID)
+	if err != nil {
+		return fmt.Errorf("failed to get existing attendees: %w", err)
+	}
+
+	emailLower := strings.ToLower(email)
+	remaining := make([]graphAttendee, 0, len(existing))
+	for _, a := range existing {
+		if strings.ToLower(a.EmailAddress.Address) != emailLower {
+			remaining = append(remaining, a)
+		}
+	}
+	if len(remaining) == len(existing) {
+		return nil
+	}
+
+	return patchEventAttendees(token, calendarEventID, remaining)
+}
+
 // addMultipleAttendeesToCalendarEvent bulk-adds attendees with a single PATCH.
 func addMultipleAttendeesToCalendarEvent(app *pocketbase.PocketBase, hostUserID, calendarEventID string, attendees []attendeeInfo) (added, skipped int, err error) {
 	token, err := getValidMicrosoftToken(app, hostUserID)
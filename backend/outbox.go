@@ -0,0 +1,547 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// --- Email Outbox ---
+// Every outbound email is written to email_outbox and sent by a small worker pool, so a
+// restart or SMTP hiccup mid-batch doesn't lose messages. Failed sends are retried with
+// exponential backoff; once max_attempts is reached the message is marked failed and, for
+// invites, the failure is shown on the guest list item. Delivery is at-least-once: a message
+// that was mid-send when the process stopped is sent again on startup.
+//
+// The recipient, envelope and html are encrypted at rest. Login and verification codes
+// lose their content once they've been sent (or given up on).
+//
+// Sends are spaced per SMTP host to stay under provider limits: EMAIL_RATE_LIMIT sets the
+// default messages per minute and EMAIL_RATE_LIMITS overrides it per host, e.g.
+// "smtp.gmail.com=20,email-smtp.ap-southeast-2.amazonaws.com=600".
+
+const (
+	emailKindOTP               = "otp"
+	emailKindShareNotification = "share_notification"
+	emailKindAttendeeOTP       = "attendee_otp"
+	emailKindRSVPInvite        = "rsvp_invite"
+	emailKindRSVPForward       = "rsvp_forward"
+	emailKindRSVPConfirmation  = "rsvp_confirmation"
+	emailKindRSVPFollowUp      = "rsvp_followup"
+	emailKindRSVPLogistics     = "rsvp_logistics"
+	emailKindRSVPPromoted      = "rsvp_promoted"
+	emailKindPlusOne           = "plus_one_notification"
+	emailKindRSVPChanged       = "rsvp_changed"
+
+	outboxQueued     = "queued"
+	outboxSending    = "sending"
+	outboxSent       = "sent"
+	outboxFailed     = "failed"
+	outboxSuppressed = "suppressed"
+	outboxCancelled  = "cancelled"
+
+	outboxPollInterval      = 10 * time.Second
+	outboxBatchSize         = 50
+	outboxMaxAttempts       = 6
+	outboxBaseBackoff       = time.Minute // doubled after each failed attempt
+	outboxMaxBackoff        = 6 * time.Hour
+	outboxLogMax            = 20 // attempts kept in a message's log
+	outboxDefaultWorkers    = 2
+	outboxDefaultRatePerMin = 60
+)
+
+// outboxRedactedKinds are messages whose body is a login or verification code. Their
+// content is dropped once they're no longer queued, and never shown in the admin UI.
+var outboxRedactedKinds = []string{emailKindOTP, emailKindAttendeeOTP}
+
+// outboxWake nudges the dispatcher so new messages go out without waiting for the next poll
+var outboxWake = make(chan struct{}, 1)
+
+// outboxOptions describes a message being queued
+type outboxOptions struct {
+	ID              string // pre-generated record ID, so tracking links can name the message
+	Kind            string
+	Transactional   bool   // see deliverEmail
+	GuestListItem   string // item whose email_status tracks this message, if any
+	TemplateVersion string // email_template_versions row that rendered it, if any
+}
+
+// outboxEnvelope is the part of a mailer.Message not stored in its own field.
+// Attachments are base64-encoded.
+type outboxEnvelope struct {
+	From        mail.Address      `json:"from"`
+	To          []mail.Address    `json:"to"`
+	Cc          []mail.Address    `json:"cc,omitempty"`
+	Bcc         []mail.Address    `json:"bcc,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Text        string            `json:"text,omitempty"`
+	Attachments map[string]string `json:"attachments,omitempty"`
+}
+
+// outboxLogEntry is one send attempt in a message's log
+type outboxLogEntry struct {
+	Attempt  int    `json:"attempt"`
+	At       string `json:"at"`
+	Status   string `json:"status"`
+	Provider string `json:"provider"`
+	Error    string `json:"error,omitempty"`
+}
+
+// enqueueEmail stores a message for the outbox workers to send
+func enqueueEmail(app core.App, msg *mailer.Message, opts outboxOptions) (*core.Record, error) {
+	if len(msg.To) == 0 {
+		return nil, errors.New("email has no recipients")
+	}
+
+	collection, err := app.FindCollectionByNameOrId(utils.CollectionEmailOutbox)
+	if err != nil {
+		return nil, err
+	}
+
+	env := outboxEnvelope{
+		From:    msg.From,
+		To:      msg.To,
+		Cc:      msg.Cc,
+		Bcc:     msg.Bcc,
+		Headers: msg.Headers,
+		Text:    msg.Text,
+	}
+	if len(msg.Attachments) > 0 {
+		env.Attachments = make(map[string]string, len(msg.Attachments))
+		for name, r := range msg.Attachments {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read attachment %s: %w", name, err)
+			}
+			env.Attachments[name] = base64.StdEncoding.EncodeToString(data)
+		}
+	}
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := utils.Encrypt(string(raw))
+	if err != nil {
+		return nil, err
+	}
+	toEmail, err := utils.Encrypt(msg.To[0].Address)
+	if err != nil {
+		return nil, err
+	}
+	html, err := utils.Encrypt(msg.HTML)
+	if err != nil {
+		return nil, err
+	}
+
+	record := core.NewRecord(collection)
+	if opts.ID != "" {
+		record.Id = opts.ID
+	}
+	record.Set("kind", opts.Kind)
+	record.Set("to_email", toEmail)
+	record.Set("to_email_index", utils.BlindIndex(normaliseEmail(msg.To[0].Address)))
+	record.Set("to_name", msg.To[0].Name)
+	record.Set("envelope", envelope)
+	record.Set("subject", msg.Subject)
+	record.Set("html", html)
+	record.Set("transactional", opts.Transactional)
+	record.Set("guest_list_item", opts.GuestListItem)
+	record.Set("template_version", opts.TemplateVersion)
+	record.Set("status", outboxQueued)
+	record.Set("max_attempts", outboxMaxAttempts)
+	record.Set("next_attempt_at", types.NowDateTime())
+	if err := app.Save(record); err != nil {
+		return nil, err
+	}
+
+	if opts.GuestListItem != "" {
+		setItemEmailStatus(app, opts.GuestListItem, outboxQueued, "")
+	}
+
+	wakeOutbox()
+	return record, nil
+}
+
+func wakeOutbox() {
+	select {
+	case outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// outboxMessage rebuilds the mailer.Message for a queued record
+func outboxMessage(record *core.Record) (*mailer.Message, error) {
+	raw, err := utils.Decrypt(record.GetString("envelope"))
+	if err != nil {
+		return nil, err
+	}
+	var env outboxEnvelope
+	if err := json.Unmarshal([]byte(raw), &env); err != nil {
+		return nil, err
+	}
+	// Messages queued before html was encrypted are stored as-is, which Decrypt passes through
+	html, err := utils.Decrypt(record.GetString("html"))
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &mailer.Message{
+		From:    env.From,
+		To:      env.To,
+		Cc:      env.Cc,
+		Bcc:     env.Bcc,
+		Subject: record.GetString("subject"),
+		HTML:    html,
+		Text:    env.Text,
+		Headers: env.Headers,
+	}
+	if len(env.Attachments) > 0 {
+		msg.Attachments = make(map[string]io.Reader, len(env.Attachments))
+		for name, encoded := range env.Attachments {
+			data, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("invalid attachment %s: %w", name, err)
+			}
+			msg.Attachments[name] = bytes.NewReader(data)
+		}
+	}
+	return msg, nil
+}
+
+// redactOutboxBody drops a message's content, keeping its recipients and headers so the
+// outbox still shows who it went to
+func redactOutboxBody(record *core.Record) error {
+	raw, err := utils.Decrypt(record.GetString("envelope"))
+	if err != nil {
+		return err
+	}
+	var env outboxEnvelope
+	if err := json.Unmarshal([]byte(raw), &env); err != nil {
+		return err
+	}
+	env.Text = ""
+	env.Attachments = nil
+	b, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	envelope, err := utils.Encrypt(string(b))
+	if err != nil {
+		return err
+	}
+	record.Set("envelope", envelope)
+	record.Set("html", "")
+	return nil
+}
+
+// encryptOutboxBodies drops the content of login and verification codes that are no longer
+// queued, and encrypts the html of messages stored before it was encrypted
+func encryptOutboxBodies(app core.App) {
+	codes, err := app.FindRecordsByFilter(utils.CollectionEmailOutbox,
+		"(kind = 'otp' || kind = 'attendee_otp') && status != 'queued' && html != ''", "", 0, 0)
+	if err != nil {
+		log.Printf("[Outbox] Failed to find sent codes: %v", err)
+	}
+	for _, record := range codes {
+		if err := redactOutboxBody(record); err != nil {
+			log.Printf("[Outbox] Failed to redact message %s: %v", record.Id, err)
+			continue
+		}
+		if err := app.Save(record); err != nil {
+			log.Printf("[Outbox] Failed to update message %s: %v", record.Id, err)
+		}
+	}
+
+	if !utils.IsEncryptionEnabled() {
+		return
+	}
+	records, err := app.FindRecordsByFilter(utils.CollectionEmailOutbox,
+		"html != '' && html !~ 'enc:%'", "", 0, 0)
+	if err != nil {
+		log.Printf("[Outbox] Failed to find unencrypted messages: %v", err)
+		return
+	}
+	for _, record := range records {
+		html, err := utils.Encrypt(record.GetString("html"))
+		if err != nil {
+			log.Printf("[Outbox] Failed to encrypt message %s: %v", record.Id, err)
+			continue
+		}
+		record.Set("html", html)
+		if err := app.Save(record); err != nil {
+			log.Printf("[Outbox] Failed to update message %s: %v", record.Id, err)
+		}
+	}
+	if len(records) > 0 {
+		log.Printf("[Outbox] Encrypted %d stored message bodies", len(records))
+	}
+}
+
+// setItemEmailStatus records the latest delivery outcome on a guest list item
+func setItemEmailStatus(app core.App, itemID, status, errMsg string) {
+	item, err := app.FindRecordById(utils.CollectionGuestListItems, itemID)
+	if err != nil {
+		return
+	}
+	item.Set("email_status", status)
+	item.Set("email_error", errMsg)
+	if err := app.Save(item); err != nil {
+		log.Printf("[Outbox] Failed to update email status on item %s: %v", itemID, err)
+	}
+}
+
+// outboxBackoff returns the wait before retrying after the given (1-based) attempt
+func outboxBackoff(attempt int) time.Duration {
+	if attempt > 16 {
+		return outboxMaxBackoff
+	}
+	return min(outboxBaseBackoff<<(attempt-1), outboxMaxBackoff)
+}
+
+// --- Throttling ---
+
+// sendThrottle spaces sends to each SMTP host evenly to stay under its rate limit
+type sendThrottle struct {
+	mu           sync.Mutex
+	next         map[string]time.Time
+	limits       map[string]int // messages per minute by host
+	defaultLimit int
+}
+
+// newSendThrottle reads EMAIL_RATE_LIMIT and EMAIL_RATE_LIMITS
+func newSendThrottle() *sendThrottle {
+	t := &sendThrottle{
+		next:         map[string]time.Time{},
+		limits:       map[string]int{},
+		defaultLimit: outboxDefaultRatePerMin,
+	}
+	if n, err := strconv.Atoi(os.Getenv("EMAIL_RATE_LIMIT")); err == nil && n > 0 {
+		t.defaultLimit = n
+	}
+	for _, pair := range strings.Split(os.Getenv("EMAIL_RATE_LIMITS"), ",") {
+		host, limit, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(limit)); err == nil && n > 0 {
+			t.limits[strings.ToLower(strings.TrimSpace(host))] = n
+		}
+	}
+	return t
+}
+
+// wait blocks until the provider's next send slot
+func (t *sendThrottle) wait(provider string) {
+	limit, ok := t.limits[provider]
+	if !ok {
+		limit = t.defaultLimit
+	}
+	interval := time.Minute / time.Duration(limit)
+
+	t.mu.Lock()
+	now := time.Now()
+	slot := t.next[provider]
+	if slot.Before(now) {
+		slot = now
+	}
+	t.next[provider] = slot.Add(interval)
+	t.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
+
+// smtpProvider identifies the configured mail transport for throttling and the send log
+func smtpProvider(app core.App) string {
+	smtp := app.Settings().SMTP
+	if !smtp.Enabled || smtp.Host == "" {
+		return "sendmail"
+	}
+	return strings.ToLower(smtp.Host)
+}
+
+// --- Workers ---
+
+// startEmailOutbox requeues messages interrupted by a restart, then dispatches due
+// messages to the worker pool (EMAIL_OUTBOX_WORKERS, default 2) until the process exits
+func startEmailOutbox(app *pocketbase.PocketBase) {
+	if _, err := app.DB().Update(utils.CollectionEmailOutbox,
+		dbx.Params{"status": outboxQueued}, dbx.HashExp{"status": outboxSending}).Execute(); err != nil {
+		log.Printf("[Outbox] Failed to requeue interrupted messages: %v", err)
+	}
+	encryptOutboxBodies(app)
+
+	workers := outboxDefaultWorkers
+	if n, err := strconv.Atoi(os.Getenv("EMAIL_OUTBOX_WORKERS")); err == nil && n > 0 {
+		workers = n
+	}
+
+	throttle := newSendThrottle()
+	jobs := make(chan string)
+	for range workers {
+		go func() {
+			for id := range jobs {
+				processOutboxMessage(app, id, throttle)
+			}
+		}()
+	}
+	log.Printf("[Outbox] Started %d workers", workers)
+
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	for {
+		// A full batch means there may be more waiting, so go again straight away
+		if dispatchDueOutbox(app, jobs) == outboxBatchSize {
+			continue
+		}
+		select {
+		case <-ticker.C:
+		case <-outboxWake:
+		}
+	}
+}
+
+// dispatchDueOutbox claims due messages and hands them to the workers, returning how many
+// were found. Blocks while every worker is busy.
+func dispatchDueOutbox(app core.App, jobs chan<- string) int {
+	var ids []string
+	err := app.DB().Select("id").From(utils.CollectionEmailOutbox).
+		Where(dbx.HashExp{"status": outboxQueued}).
+		AndWhere(dbx.NewExp("[[next_attempt_at]] <= {:now}", dbx.Params{"now": types.NowDateTime().String()})).
+		OrderBy("next_attempt_at ASC").
+		Limit(outboxBatchSize).
+		Column(&ids)
+	if err != nil {
+		log.Printf("[Outbox] Failed to load due messages: %v", err)
+		return 0
+	}
+
+	for _, id := range ids {
+		// Claim before handing off so an admin cancel can't race the send
+		res, err := app.DB().Update(utils.CollectionEmailOutbox,
+			dbx.Params{"status": outboxSending}, dbx.HashExp{"id": id, "status": outboxQueued}).Execute()
+		if err != nil {
+			continue
+		}
+		if n, _ := res.RowsAffected(); n != 1 {
+			continue
+		}
+		jobs <- id
+	}
+	return len(ids)
+}
+
+// processOutboxMessage sends one claimed message and records the outcome
+func processOutboxMessage(app *pocketbase.PocketBase, id string, throttle *sendThrottle) {
+	record, err := app.FindRecordById(utils.CollectionEmailOutbox, id)
+	if err != nil {
+		log.Printf("[Outbox] Message %s disappeared before sending: %v", id, err)
+		return
+	}
+
+	provider := smtpProvider(app)
+	msg, err := outboxMessage(record)
+	if err == nil {
+		// Lets bounce reports that quote the original headers be matched to this message
+		if msg.Headers == nil {
+			msg.Headers = map[string]string{}
+		}
+		msg.Headers[outboxIDHeader] = record.Id
+
+		throttle.wait(provider)
+		err = deliverEmail(app, msg, record.GetBool("transactional"))
+	} else {
+		// Can't be rebuilt, so retrying won't help
+		record.Set("max_attempts", record.GetInt("attempts")+1)
+	}
+
+	recordOutboxAttempt(app, record, provider, err)
+}
+
+// recordOutboxAttempt updates a message after a send attempt: sent, suppressed, queued for
+// retry, or failed for good once max_attempts is reached
+func recordOutboxAttempt(app core.App, record *core.Record, provider string, sendErr error) {
+	attempts := record.GetInt("attempts") + 1
+	now := types.NowDateTime()
+
+	status := outboxSent
+	errMsg := ""
+	switch {
+	case sendErr == nil:
+		record.Set("sent_at", now)
+	case errors.Is(sendErr, errEmailSuppressed):
+		status = outboxSuppressed
+		errMsg = sendErr.Error()
+	case attempts >= record.GetInt("max_attempts"):
+		status = outboxFailed
+		errMsg = sendErr.Error()
+	default:
+		status = outboxQueued
+		errMsg = sendErr.Error()
+		record.Set("next_attempt_at", now.Add(outboxBackoff(attempts)))
+	}
+
+	var entries []outboxLogEntry
+	decodeJSONField(record, "log", &entries)
+	entries = append(entries, outboxLogEntry{
+		Attempt:  attempts,
+		At:       now.String(),
+		Status:   status,
+		Provider: provider,
+		Error:    errMsg,
+	})
+	if len(entries) > outboxLogMax {
+		entries = entries[len(entries)-outboxLogMax:]
+	}
+
+	// Codes are single-use and expire, so there's nothing worth keeping once they're out
+	if status != outboxQueued && slices.Contains(outboxRedactedKinds, record.GetString("kind")) {
+		if err := redactOutboxBody(record); err != nil {
+			log.Printf("[Outbox] Failed to redact message %s: %v", record.Id, err)
+		}
+	}
+
+	record.Set("attempts", attempts)
+	record.Set("status", status)
+	record.Set("last_error", errMsg)
+	record.Set("provider", provider)
+	record.Set("log", entries)
+	if err := app.Save(record); err != nil {
+		log.Printf("[Outbox] Failed to update message %s: %v", record.Id, err)
+	}
+
+	switch status {
+	case outboxSent:
+		log.Printf("[Outbox] Sent %s %s (attempt %d)", record.GetString("kind"), record.Id, attempts)
+	case outboxQueued:
+		log.Printf("[Outbox] Attempt %d for %s %s failed, retrying at %s: %v", attempts, record.GetString("kind"), record.Id, record.GetString("next_attempt_at"), sendErr)
+	default:
+		log.Printf("[Outbox] %s %s %s after %d attempts: %v", record.GetString("kind"), record.Id, status, attempts, sendErr)
+	}
+
+	if status == outboxSent {
+		recordEmailEvent(app, emailEvent{Type: emailEventSent, Message: record})
+	}
+
+	// The item keeps showing "queued" while retries are pending
+	if itemID := record.GetString("guest_list_item"); itemID != "" && status != outboxQueued {
+		setItemEmailStatus(app, itemID, status, errMsg)
+	}
+}
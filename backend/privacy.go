@@ -0,0 +1,448 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// --- Subject Access & Erasure ---
+// A subject-access bundle gathers everything the CRM holds about one contact, with PII
+// decrypted. Erasure anonymises the contact in place (so guest list history and counts
+// stay intact), scrubs PII from related records and audit entries, and removes records
+// that only exist to describe the person.
+
+const erasedContactName = "Erased contact"
+
+// contactErasureKeepFields survive erasure — everything else on the contact is cleared
+var contactErasureKeepFields = map[string]bool{
+	"id": true, "created": true, "updated": true,
+	"name": true, "first_name": true, "last_name": true, "email": true,
+	"status": true, "source": true, "erased_at": true,
+}
+
+// guestListItemPIIFields are the denormalised contact details and free text on guest list items
+var guestListItemPIIFields = []string{
+	"contact_job_title", "contact_organisation_name", "contact_linkedin", "contact_location",
+	"notes", "client_notes", "rsvp_dietary", "rsvp_plus_one_name", "rsvp_plus_one_dietary",
+	"rsvp_comments", "rsvp_answers", "rsvp_history", "rsvp_invited_by", "rsvp_token",
+}
+
+// subjectAccessBundle is the full export for one contact. Each section becomes a file in the ZIP.
+type subjectAccessBundle struct {
+	GeneratedAt    string           `json:"generated_at"`
+	Profile        map[string]any   `json:"profile"`
+	Activities     []map[string]any `json:"activities"`
+	GuestListItems []map[string]any `json:"guest_list_items"`
+	ContactLinks   []map[string]any `json:"contact_links"`
+	Merges         []map[string]any `json:"merges"`
+	ConsentEvents  []map[string]any `json:"consent_events"`
+	EmailEvents    []map[string]any `json:"email_events"`
+	Suppressions   []map[string]any `json:"email_suppressions"`
+	AuditLog       []map[string]any `json:"audit_log"`
+}
+
+// sections returns the bundle split into named parts for the ZIP download
+func (b *subjectAccessBundle) sections() []struct {
+	Name string
+	Data any
+} {
+	return []struct {
+		Name string
+		Data any
+	}{
+		{"profile", b.Profile},
+		{"activities", b.Activities},
+		{"guest_list_items", b.GuestListItems},
+		{"contact_links", b.ContactLinks},
+		{"merges", b.Merges},
+		{"consent_events", b.ConsentEvents},
+		{"email_events", b.EmailEvents},
+		{"email_suppressions", b.Suppressions},
+		{"audit_log", b.AuditLog},
+	}
+}
+
+// decryptDeep decrypts "enc:" strings anywhere inside a decoded JSON value
+func decryptDeep(v any) any {
+	switch t := v.(type) {
+	case string:
+		if strings.HasPrefix(t, "enc:") {
+			return utils.DecryptField(t)
+		}
+		return t
+	case map[string]any:
+		for k, item := range t {
+			t[k] = decryptDeep(item)
+		}
+		return t
+	case []any:
+		for i, item := range t {
+			t[i] = decryptDeep(item)
+		}
+		return t
+	}
+	return v
+}
+
+// recordExportData returns a record's fields as plain JSON values with PII decrypted
+// and blind indexes dropped
+func recordExportData(r *core.Record) map[string]any {
+	data := map[string]any{}
+	b, err := json.Marshal(r.FieldsData())
+	if err == nil {
+		_ = json.Unmarshal(b, &data)
+	}
+	for k := range data {
+		if strings.HasSuffix(k, "_index") {
+			delete(data, k)
+		}
+	}
+	decryptDeep(data)
+	return data
+}
+
+// contactRelatedRecords finds the records that reference a contact, keyed by collection
+func contactRelatedRecords(app core.App, contactID string) map[string][]*core.Record {
+	params := dbx.Params{"id": contactID}
+	related := map[string][]*core.Record{}
+
+	queries := []struct {
+		collection string
+		filter     string
+	}{
+		{utils.CollectionActivities, "contact = {:id}"},
+		{utils.CollectionGuestListItems, "contact = {:id}"},
+		{utils.CollectionContactLinks, "contact_a = {:id} || contact_b = {:id}"},
+		{utils.CollectionContactMerges, "primary_id = {:id} || merged_ids ~ {:id}"},
+		{utils.CollectionDuplicateCandidates, "contact_a = {:id} || contact_b = {:id}"},
+		{utils.CollectionAttendeeOTPCodes, "contact = {:id}"},
+		{utils.CollectionConsentEvents, "contact = {:id}"},
+		{utils.CollectionEmailEvents, "contact = {:id}"},
+	}
+	for _, q := range queries {
+		records, err := app.FindRecordsByFilter(q.collection, q.filter, "", 0, 0, params)
+		if err != nil {
+			continue
+		}
+		related[q.collection] = records
+	}
+	return related
+}
+
+// mergeSnapshotsWithout returns a merge's merged_snapshots minus the given contact's entry
+func mergeSnapshotsWithout(merge *core.Record, contactID string) []map[string]any {
+	var snapshots []map[string]any
+	decodeJSONField(merge, "merged_snapshots", &snapshots)
+	others := []map[string]any{}
+	for _, snap := range snapshots {
+		if id, _ := snap["id"].(string); id != contactID {
+			others = append(others, snap)
+		}
+	}
+	return others
+}
+
+// mergeReferencesWithout returns a merge's references minus the snapshots of deleted
+// records that belonged to the given contact
+func mergeReferencesWithout(merge *core.Record, contactID string) map[string]*mergeReferences {
+	var references map[string]*mergeReferences
+	if err := decodeJSONField(merge, "references", &references); err != nil || references == nil {
+		return nil
+	}
+	for _, refs := range references {
+		if refs == nil {
+			continue
+		}
+		kept := []map[string]any{}
+		for _, snap := range refs.Deleted {
+			if snap["contact"] == contactID || snap["contact_a"] == contactID || snap["contact_b"] == contactID {
+				continue
+			}
+			kept = append(kept, snap)
+		}
+		refs.Deleted = kept
+	}
+	return references
+}
+
+// contactAuditResourceIDs returns the contact's ID plus the IDs of related records whose
+// audit entries may carry the contact's data
+func contactAuditResourceIDs(contactID string, related map[string][]*core.Record) []any {
+	ids := []any{contactID}
+	for _, coll := range []string{utils.CollectionActivities, utils.CollectionGuestListItems, utils.CollectionContactLinks, utils.CollectionAttendeeOTPCodes} {
+		for _, r := range related[coll] {
+			ids = append(ids, r.Id)
+		}
+	}
+	return ids
+}
+
+// buildSubjectAccessBundle collects everything held about a contact
+func buildSubjectAccessBundle(app core.App, contact *core.Record) *subjectAccessBundle {
+	related := contactRelatedRecords(app, contact.Id)
+
+	bundle := &subjectAccessBundle{
+		GeneratedAt:    time.Now().UTC().Format(time.RFC3339),
+		Profile:        recordExportData(contact),
+		Activities:     []map[string]any{},
+		GuestListItems: []map[string]any{},
+		ContactLinks:   []map[string]any{},
+		Merges:         []map[string]any{},
+		ConsentEvents:  []map[string]any{},
+		EmailEvents:    []map[string]any{},
+		Suppressions:   []map[string]any{},
+		AuditLog:       []map[string]any{},
+	}
+
+	if orgID := contact.GetString("organisation"); orgID != "" {
+		if org, err := app.FindRecordById(utils.CollectionOrganisations, orgID); err == nil {
+			bundle.Profile["organisation_name"] = org.GetString("name")
+		}
+	}
+
+	for _, r := range related[utils.CollectionActivities] {
+		bundle.Activities = append(bundle.Activities, recordExportData(r))
+	}
+
+	// Guest list items carry the RSVP answers; add the list name for context
+	for _, r := range related[utils.CollectionGuestListItems] {
+		data := recordExportData(r)
+		if gl, err := app.FindRecordById(utils.CollectionGuestLists, r.GetString("guest_list")); err == nil {
+			data["guest_list_name"] = gl.GetString("name")
+			data["event_date"] = gl.GetString("event_date")
+		}
+		bundle.GuestListItems = append(bundle.GuestListItems, data)
+	}
+
+	for _, r := range related[utils.CollectionContactLinks] {
+		bundle.ContactLinks = append(bundle.ContactLinks, recordExportData(r))
+	}
+
+	// Merges into another contact only give the subject their own snapshot, not the primary's
+	for _, r := range related[utils.CollectionContactMerges] {
+		data := recordExportData(r)
+		if r.GetString("primary_id") != contact.Id {
+			own := []any{}
+			snapshots, _ := data["merged_snapshots"].([]any)
+			for _, snap := range snapshots {
+				if m, ok := snap.(map[string]any); ok && m["id"] == contact.Id {
+					own = append(own, snap)
+				}
+			}
+			data["merged_snapshots"] = own
+			delete(data, "primary_snapshot")
+			delete(data, "references")
+		}
+		bundle.Merges = append(bundle.Merges, data)
+	}
+
+	for _, r := range related[utils.CollectionConsentEvents] {
+		bundle.ConsentEvents = append(bundle.ConsentEvents, recordExportData(r))
+	}
+
+	for _, r := range related[utils.CollectionEmailEvents] {
+		bundle.EmailEvents = append(bundle.EmailEvents, recordExportData(r))
+	}
+
+	// Suppressions are keyed by address rather than contact
+	for _, field := range []string{"email", "personal_email"} {
+		if r := findEmailSuppression(app, utils.DecryptField(contact.GetString(field))); r != nil {
+			bundle.Suppressions = append(bundle.Suppressions, recordExportData(r))
+		}
+	}
+
+	var logs []*core.Record
+	err := app.RecordQuery("audit_logs").
+		AndWhere(dbx.In("resource_id", contactAuditResourceIDs(contact.Id, related)...)).
+		OrderBy("created ASC").
+		All(&logs)
+	if err == nil {
+		for _, r := range logs {
+			bundle.AuditLog = append(bundle.AuditLog, recordExportData(r))
+		}
+	}
+
+	return bundle
+}
+
+// contactErasureResult counts what an erasure touched
+type contactErasureResult struct {
+	ActivitiesScrubbed     int `json:"activities_scrubbed"`
+	GuestListItemsScrubbed int `json:"guest_list_items_scrubbed"`
+	ContactLinksDeleted    int `json:"contact_links_deleted"`
+	MergesDeleted          int `json:"merges_deleted"`
+	MergesScrubbed         int `json:"merges_scrubbed"`
+	DuplicatesDeleted      int `json:"duplicates_deleted"`
+	OTPCodesDeleted        int `json:"otp_codes_deleted"`
+	ConsentEventsDeleted   int `json:"consent_events_deleted"`
+	EmailEventsDeleted     int `json:"email_events_deleted"`
+	OutboxMessagesDeleted  int `json:"outbox_messages_deleted"`
+	AuditEntriesScrubbed   int `json:"audit_entries_scrubbed"`
+}
+
+// anonymiseContact clears every field not in contactErasureKeepFields and replaces the
+// name and email with placeholders. email is required and unique, so it becomes an
+// address under the reserved .invalid TLD.
+func anonymiseContact(contact *core.Record) {
+	for _, f := range contact.Collection().Fields {
+		if contactErasureKeepFields[f.GetName()] {
+			continue
+		}
+		contact.Set(f.GetName(), nil)
+	}
+
+	contact.Set("name", erasedContactName)
+	contact.Set("first_name", "Erased")
+	contact.Set("last_name", "Contact")
+	contact.Set("email", "erased-"+contact.Id+"@erased.invalid")
+	contact.Set("status", "archived")
+	contact.Set("erased_at", time.Now().UTC().Format(time.RFC3339))
+}
+
+// eraseContact anonymises a contact and scrubs or deletes its related records in one
+// transaction. The contact update is archived, so the webhook hooks send delete
+// projections to hub consumers and DAM.
+func eraseContact(app *pocketbase.PocketBase, contact *core.Record) (*contactErasureResult, error) {
+	result := &contactErasureResult{}
+	mailchimpEmail := utils.DecryptField(contact.GetString("email"))
+	hadMailchimp := contact.GetString("mailchimp_id") != "" || contact.GetString("mailchimp_status") != ""
+
+	err := app.RunInTransaction(func(txApp core.App) error {
+		related := contactRelatedRecords(txApp, contact.Id)
+		auditIDs := contactAuditResourceIDs(contact.Id, related)
+
+		for _, r := range related[utils.CollectionActivities] {
+			r.Set("metadata", map[string]any{})
+			if err := txApp.Save(r); err != nil {
+				return err
+			}
+			result.ActivitiesScrubbed++
+		}
+
+		for _, r := range related[utils.CollectionGuestListItems] {
+			r.Set("contact_name", erasedContactName)
+			for _, field := range guestListItemPIIFields {
+				r.Set(field, nil)
+			}
+			if err := txApp.Save(r); err != nil {
+				return err
+			}
+			result.GuestListItemsScrubbed++
+		}
+
+		// Merges into this contact are deleted; merges of this contact into another (since
+		// undone) keep the other contact's history with this contact's snapshot removed
+		for _, r := range related[utils.CollectionContactMerges] {
+			if r.GetString("primary_id") == contact.Id {
+				if err := txApp.Delete(r); err != nil {
+					return err
+				}
+				result.MergesDeleted++
+				continue
+			}
+			r.Set("merged_snapshots", mergeSnapshotsWithout(r, contact.Id))
+			r.Set("references", mergeReferencesWithout(r, contact.Id))
+			if err := txApp.Save(r); err != nil {
+				return err
+			}
+			result.MergesScrubbed++
+		}
+
+		deletes := []struct {
+			collection string
+			count      *int
+		}{
+			{utils.CollectionContactLinks, &result.ContactLinksDeleted},
+			{utils.CollectionDuplicateCandidates, &result.DuplicatesDeleted},
+			{utils.CollectionAttendeeOTPCodes, &result.OTPCodesDeleted},
+			{utils.CollectionConsentEvents, &result.ConsentEventsDeleted},
+			{utils.CollectionEmailEvents, &result.EmailEventsDeleted},
+		}
+		for _, d := range deletes {
+			for _, r := range related[d.collection] {
+				if err := txApp.Delete(r); err != nil {
+					return err
+				}
+				*d.count++
+			}
+		}
+
+		// Outbox messages hold the address and rendered content; queued ones are dropped too
+		itemIDs := []any{}
+		for _, r := range related[utils.CollectionGuestListItems] {
+			itemIDs = append(itemIDs, r.Id)
+		}
+		emailIndexes := []any{}
+		for _, field := range []string{"email_index", "personal_email_index"} {
+			if idx := contact.GetString(field); idx != "" {
+				emailIndexes = append(emailIndexes, idx)
+			}
+		}
+		res, err := txApp.DB().Delete(utils.CollectionEmailOutbox, dbx.Or(
+			dbx.In("to_email_index", emailIndexes...),
+			dbx.In("guest_list_item", itemIDs...),
+		)).Execute()
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			result.OutboxMessagesDeleted = int(n)
+		}
+
+		anonymiseContact(contact)
+		if err := txApp.Save(contact); err != nil {
+			return err
+		}
+
+		// Direct update so the scrub doesn't itself create audit entries
+		res, err = txApp.DB().Update("audit_logs", dbx.Params{
+			"changes":    `{"erased":true}`,
+			"metadata":   nil,
+			"ip_address": "",
+			"user_agent": "",
+		}, dbx.In("resource_id", auditIDs...)).Execute()
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			result.AuditEntriesScrubbed = int(n)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if hadMailchimp && mailchimpEmail != "" {
+		go deleteMailchimpMemberPermanently(app, mailchimpEmail)
+	}
+
+	return result, nil
+}
+
+// deleteMailchimpMemberPermanently removes an erased contact from the Mailchimp audience
+// so the address can't be re-imported from there
+func deleteMailchimpMemberPermanently(app *pocketbase.PocketBase, email string) {
+	listID, _ := getMailchimpSyncConfig(app)
+	if listID == "" {
+		return
+	}
+
+	path := "/lists/" + listID + "/members/" + mailchimpSubscriberHash(email) + "/actions/delete-permanent"
+	_, status, err := mailchimpRequest("POST", path, nil)
+	if err != nil {
+		log.Printf("[Privacy] Mailchimp permanent delete failed: %v", err)
+		return
+	}
+	if status >= 300 && status != 404 {
+		log.Printf("[Privacy] Mailchimp permanent delete returned %d", status)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// --- Data Retention ---
+// Each retention_policies record sets a TTL for one purge target. The daily job (and the
+// admin "run now" endpoint) deletes records older than the TTL and writes one
+// "retention_purge" audit entry per target listing the deleted IDs.
+
+const (
+	retentionPurgeHour   = 5   // 5 AM AEST, after duplicate detection
+	retentionDeleteBatch = 200 // Records loaded per delete batch
+	retentionAuditIDMax  = 500 // Deleted IDs kept in the audit entry
+	retentionSampleMax   = 20  // IDs shown per target in the dry-run report
+)
+
+// retentionTarget describes what a policy purges. Age is measured from AgeField.
+type retentionTarget struct {
+	Key         string
+	Label       string
+	Description string
+	Collection  string
+	AgeField    string
+	Where       dbx.Expression // extra condition beyond the age cutoff, nil for none
+}
+
+var retentionTargets = []retentionTarget{
+	{
+		Key: "audit_logs", Label: "Audit log", Collection: "audit_logs", AgeField: "created",
+		Description: "Audit entries older than the TTL",
+	},
+	{
+		Key: "projection_logs", Label: "Projection log", Collection: "projection_logs", AgeField: "created",
+		Description: "Projection runs older than the TTL",
+	},
+	{
+		Key: "projection_callbacks", Label: "Projection callbacks", Collection: "projection_callbacks", AgeField: "received_at",
+		Description: "Consumer callbacks received longer ago than the TTL",
+	},
+	{
+		Key: "humanitix_sync_log", Label: "Humanitix sync log", Collection: utils.CollectionHumanitixSyncLog, AgeField: "started_at",
+		Description: "Finished Humanitix syncs started longer ago than the TTL",
+		Where:       dbx.NewExp("[[status]] != 'running'"),
+	},
+	{
+		Key: "attendee_otp_codes", Label: "Attendee login codes", Collection: utils.CollectionAttendeeOTPCodes, AgeField: "expires_at",
+		Description: "Attendee portal OTP codes that expired longer ago than the TTL",
+	},
+	{
+		Key: "guest_list_otp_codes", Label: "Guest list share codes", Collection: utils.CollectionGuestListOTPCodes, AgeField: "expires_at",
+		Description: "Guest list share OTP codes that expired longer ago than the TTL",
+	},
+	{
+		Key: "email_outbox", Label: "Email send log", Collection: utils.CollectionEmailOutbox, AgeField: "created",
+		Description: "Sent, failed, bounced, suppressed and cancelled emails created longer ago than the TTL",
+		Where:       dbx.NewExp("[[status]] IN ('sent', 'failed', 'bounced', 'complained', 'suppressed', 'cancelled')"),
+	},
+	{
+		Key: "email_events", Label: "Email engagement events", Collection: utils.CollectionEmailEvents, AgeField: "created",
+		Description: "Send, open, click, bounce and RSVP events recorded longer ago than the TTL",
+	},
+	{
+		Key: "pending_contacts", Label: "Unconfirmed contacts", Collection: utils.CollectionContacts, AgeField: "created",
+		Description: "Contacts still pending after the TTL that aren't on any guest list",
+		Where: dbx.And(
+			dbx.NewExp("[[status]] = 'pending'"),
+			dbx.NewExp("[[id]] NOT IN (SELECT [[contact]] FROM {{guest_list_items}} WHERE [[contact]] != '')"),
+		),
+	},
+}
+
+func findRetentionTarget(key string) (retentionTarget, bool) {
+	for _, t := range retentionTargets {
+		if t.Key == key {
+			return t, true
+		}
+	}
+	return retentionTarget{}, false
+}
+
+// retentionTargetResult is one target's line in a purge or dry-run report
+type retentionTargetResult struct {
+	Target    string   `json:"target"`
+	Label     string   `json:"label"`
+	Enabled   bool     `json:"enabled"`
+	TTLHours  int      `json:"ttl_hours"`
+	Cutoff    string   `json:"cutoff"`
+	Matched   int      `json:"matched"`
+	Deleted   int      `json:"deleted"`
+	Failed    int      `json:"failed"`
+	SampleIDs []string `json:"sample_ids,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// retentionCutoff returns the oldest timestamp a policy keeps, in the DB's datetime format
+func retentionCutoff(ttlHours int) string {
+	cutoff, _ := types.ParseDateTime(time.Now().UTC().Add(-time.Duration(ttlHours) * time.Hour))
+	return cutoff.String()
+}
+
+// retentionExpiredIDs returns the IDs of records a target would purge at the given cutoff
+func retentionExpiredIDs(app core.App, target retentionTarget, cutoff string) ([]string, error) {
+	query := app.DB().Select("id").From(target.Collection).
+		Where(dbx.NewExp("[["+target.AgeField+"]] != '' AND [["+target.AgeField+"]] < {:cutoff}", dbx.Params{"cutoff": cutoff}))
+	if target.Where != nil {
+		query.AndWhere(target.Where)
+	}
+
+	var ids []string
+	if err := query.OrderBy(target.AgeField + " ASC").Column(&ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// purgeRetentionTarget deletes expired records in batches. Records go through app.Delete
+// so hooks (webhooks, search index, cascades) run as they would for a manual delete.
+func purgeRetentionTarget(app core.App, target retentionTarget, ids []string) (deleted []string, failed int) {
+	for start := 0; start < len(ids); start += retentionDeleteBatch {
+		end := min(start+retentionDeleteBatch, len(ids))
+
+		records, err := app.FindRecordsByIds(target.Collection, ids[start:end])
+		if err != nil {
+			log.Printf("[Retention] Failed to load %s batch: %v", target.Key, err)
+			failed += end - start
+			continue
+		}
+
+		for _, r := range records {
+			if err := app.Delete(r); err != nil {
+				log.Printf("[Retention] Failed to delete %s %s: %v", target.Collection, r.Id, err)
+				failed++
+				continue
+			}
+			deleted = append(deleted, r.Id)
+		}
+	}
+	return deleted, failed
+}
+
+// runRetentionPolicies applies every enabled policy. A dry run deletes nothing and reports
+// on disabled policies too, so admins can see a TTL's effect before enabling it.
+// trigger ("schedule" or "manual") goes in the audit entry.
+func runRetentionPolicies(app *pocketbase.PocketBase, dryRun bool, trigger string) ([]retentionTargetResult, error) {
+	filter := "enabled = true"
+	if dryRun {
+		filter = ""
+	}
+	policies, err := app.FindRecordsByFilter(utils.CollectionRetentionPolicies, filter, "target", 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load retention policies: %w", err)
+	}
+
+	results := []retentionTargetResult{}
+	for _, policy := range policies {
+		target, ok := findRetentionTarget(policy.GetString("target"))
+		if !ok {
+			continue
+		}
+		ttl := policy.GetInt("ttl_hours")
+		if ttl < 1 {
+			continue
+		}
+
+		result := retentionTargetResult{
+			Target:   target.Key,
+			Label:    target.Label,
+			Enabled:  policy.GetBool("enabled"),
+			TTLHours: ttl,
+			Cutoff:   retentionCutoff(ttl),
+		}
+
+		ids, err := retentionExpiredIDs(app, target, result.Cutoff)
+		if err != nil {
+			log.Printf("[Retention] Failed to query %s: %v", target.Key, err)
+			result.Error = "query failed"
+			results = append(results, result)
+			continue
+		}
+		result.Matched = len(ids)
+
+		if dryRun {
+			result.SampleIDs = ids[:min(len(ids), retentionSampleMax)]
+			results = append(results, result)
+			continue
+		}
+
+		deleted, failed := purgeRetentionTarget(app, target, ids)
+		result.Deleted = len(deleted)
+		result.Failed = failed
+
+		policy.Set("last_run_at", time.Now().UTC().Format(time.RFC3339))
+		policy.Set("last_deleted", result.Deleted)
+		if err := app.Save(policy); err != nil {
+			log.Printf("[Retention] Failed to update policy %s: %v", target.Key, err)
+		}
+
+		if result.Deleted > 0 || result.Failed > 0 {
+			status := "success"
+			if result.Failed > 0 {
+				status = "failure"
+			}
+			utils.LogAudit(app, utils.AuditEntry{
+				Action:       "retention_purge",
+				ResourceType: target.Collection,
+				Changes: map[string]any{
+					"target":      target.Key,
+					"trigger":     trigger,
+					"ttl_hours":   ttl,
+					"cutoff":      result.Cutoff,
+					"deleted":     result.Deleted,
+					"failed":      result.Failed,
+					"deleted_ids": deleted[:min(len(deleted), retentionAuditIDMax)],
+				},
+				Status: status,
+			})
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// scheduleRetentionPurge runs the enabled retention policies daily at retentionPurgeHour (AEST)
+func scheduleRetentionPurge(app *pocketbase.PocketBase) {
+	// Wait for app to fully start
+	time.Sleep(90 * time.Second)
+
+	loc, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		log.Printf("[Retention] Warning: Could not load timezone, using UTC: %v", err)
+		loc = time.UTC
+	}
+
+	for {
+		now := time.Now().In(loc)
+		next := time.Date(now.Year(), now.Month(), now.Day(), retentionPurgeHour, 0, 0, 0, loc)
+		if now.After(next) {
+			next = next.Add(24 * time.Hour)
+		}
+
+		duration := time.Until(next)
+		log.Printf("[Retention] Next purge scheduled for %s (in %v)", next.Format("2006-01-02 15:04 MST"), duration.Round(time.Minute))
+
+		time.Sleep(duration)
+
+		results, err := runRetentionPolicies(app, false, "schedule")
+		if err != nil {
+			log.Printf("[Retention] ERROR: %v", err)
+			continue
+		}
+		for _, r := range results {
+			if r.Deleted > 0 || r.Failed > 0 {
+				log.Printf("[Retention] %s: deleted %d, failed %d", r.Target, r.Deleted, r.Failed)
+			}
+		}
+	}
+}
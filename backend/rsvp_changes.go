@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// --- RSVP changes ---
+// Guests change their RSVP by submitting again from the same link, or cancel it, until the
+// guest list's rsvp_deadline. Cancelling is still allowed after the deadline: organisers
+// would rather know. Every response, change and cancellation is added to the item's
+// rsvp_history, and changes to an earlier response are emailed to the guest list's
+// rsvp_bcc_contacts. Capacity follows on its own: seats given up promote the waitlist
+// (registerCapacityHooks), and a guest who accepts again is checked like a new one.
+
+const (
+	rsvpChangeResponded = "responded"
+	rsvpChangeChanged   = "changed"
+	rsvpChangeCancelled = "cancelled"
+
+	rsvpChangeByGuest = "guest"
+
+	rsvpHistoryMax = 50
+)
+
+// rsvpTrackedFields are the item fields an RSVP change is recorded for
+var rsvpTrackedFields = []string{
+	"rsvp_status", "rsvp_plus_one", "rsvp_plus_one_name", "rsvp_plus_one_last_name",
+	"rsvp_plus_one_email", "rsvp_plus_one_dietary", "rsvp_comments", "rsvp_answers",
+	"rsvp_sessions",
+}
+
+// rsvpFieldLabels name tracked fields in change notifications
+var rsvpFieldLabels = map[string]string{
+	"rsvp_status":             "Response",
+	"rsvp_plus_one":           "Plus-one",
+	"rsvp_plus_one_name":      "Plus-one name",
+	"rsvp_plus_one_last_name": "Plus-one name",
+	"rsvp_plus_one_email":     "Plus-one email",
+	"rsvp_plus_one_dietary":   "Plus-one dietary requirements",
+	"rsvp_comments":           "Comments",
+	"rsvp_answers":            "Answers to questions",
+	"rsvp_sessions":           "Sessions",
+}
+
+// rsvpStatusLabels describe an rsvp_status to people
+var rsvpStatusLabels = map[string]string{
+	"":             "No response",
+	"accepted":     "Attending",
+	"declined":     "Not attending",
+	rsvpWaitlisted: "Waitlisted",
+}
+
+// rsvpChange is one entry of an item's rsvp_history
+type rsvpChange struct {
+	At     string   `json:"at"`
+	Action string   `json:"action"`
+	By     string   `json:"by"` // "guest", or the admin's email
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Fields []string `json:"fields"`
+	Note   string   `json:"note,omitempty"`
+}
+
+// rsvpDeadlinePassed reports whether a guest list's RSVPs have closed
+func rsvpDeadlinePassed(gl *core.Record) bool {
+	deadline := gl.GetDateTime("rsvp_deadline")
+	return !deadline.IsZero() && time.Now().After(deadline.Time())
+}
+
+// rsvpValueEqual compares two field values by their JSON, treating every empty value alike
+func rsvpValueEqual(a, b any) bool {
+	normalise := func(v any) string {
+		raw, _ := json.Marshal(v)
+		switch s := string(raw); s {
+		case "null", `""`, "{}", "[]", "false":
+			return ""
+		default:
+			return s
+		}
+	}
+	return normalise(a) == normalise(b)
+}
+
+// appendRSVPChange compares an item's RSVP fields with what was last saved and, if any
+// changed, adds an entry to its rsvp_history. Call it just before saving. action may be
+// empty, to record the first response as responded and later ones as changed.
+func appendRSVPChange(item *core.Record, action, by, note string) *rsvpChange {
+	original := item.Original()
+	var fields []string
+	for _, f := range rsvpTrackedFields {
+		if !rsvpValueEqual(original.Get(f), item.Get(f)) {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 && action != rsvpChangeCancelled {
+		return nil
+	}
+
+	from := original.GetString("rsvp_status")
+	if action == "" {
+		action = rsvpChangeChanged
+		if from == "" {
+			action = rsvpChangeResponded
+		}
+	}
+	change := &rsvpChange{
+		At:     time.Now().UTC().Format(time.RFC3339),
+		Action: action,
+		By:     by,
+		From:   from,
+		To:     item.GetString("rsvp_status"),
+		Fields: fields,
+		Note:   note,
+	}
+
+	history := itemRSVPHistory(item)
+	history = append(history, *change)
+	if len(history) > rsvpHistoryMax {
+		history = history[len(history)-rsvpHistoryMax:]
+	}
+	item.Set("rsvp_history", history)
+	return change
+}
+
+// itemRSVPHistory decodes a guest list item's rsvp_history, never nil
+func itemRSVPHistory(item *core.Record) []rsvpChange {
+	var history []rsvpChange
+	if err := decodeJSONField(item, "rsvp_history", &history); err != nil || history == nil {
+		return []rsvpChange{}
+	}
+	return history
+}
+
+// notifyRSVPChangeAsync emails a change to an earlier response to the guest list's
+// rsvp_bcc_contacts. First responses aren't sent: those contacts are already copied on
+// the confirmation.
+func notifyRSVPChangeAsync(app *pocketbase.PocketBase, gl, item *core.Record, change *rsvpChange) {
+	if change == nil || change.Action == rsvpChangeResponded {
+		return
+	}
+	toEmails := extractBCCEmails(gl)
+	if len(toEmails) == 0 {
+		return
+	}
+
+	eventName := gl.GetString("name")
+	if epID := gl.GetString("event_projection"); epID != "" {
+		if ep, err := app.FindRecordById(utils.CollectionEventProjections, epID); err == nil {
+			if n := ep.GetString("name"); n != "" {
+				eventName = n
+			}
+		}
+	}
+
+	var changed []string
+	for _, f := range change.Fields {
+		if label := rsvpFieldLabels[f]; !slices.Contains(changed, label) {
+			changed = append(changed, label)
+		}
+	}
+
+	data := map[string]any{
+		"guest_name":     item.GetString("contact_name"),
+		"event_name":     eventName,
+		"action":         change.Action,
+		"from_status":    rsvpStatusLabels[change.From],
+		"to_status":      rsvpStatusLabels[change.To],
+		"changed":        strings.Join(changed, ", "),
+		"note":           change.Note,
+		"guest_list_url": "https://crm.theoutlook.io/guest-lists/" + gl.Id,
+	}
+
+	go func() {
+		if err := sendRSVPChangedEmail(app, gl.Id, data, toEmails); err != nil {
+			log.Printf("[RSVP] Failed to send change notification for item %s: %v", item.Id, err)
+		}
+	}()
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+)
+
+// --- Custom RSVP questions ---
+// A guest list can ask its own questions on the RSVP form (rsvp_questions), after the
+// standard dietary, accessibility, plus-one and comments fields. Answers are stored on the
+// guest list item (rsvp_answers) keyed by question id, so a question can be reworded
+// without losing them. Values are checked with the same rules as contact custom fields.
+
+const (
+	rsvpQuestionsMax     = 30
+	rsvpQuestionLabelMax = 300
+	rsvpQuestionHelpMax  = 1000
+	rsvpQuestionOptsMax  = 50
+	rsvpAnswerTextMax    = 2000
+)
+
+// rsvpQuestionTypes are the custom field types plus long_text, a text answer shown as a
+// multi-line box
+var rsvpQuestionTypes = []string{"text", "long_text", "number", "date", "select", "multi_select", "boolean"}
+
+// rsvpQuestion is one guest-list-defined RSVP question
+type rsvpQuestion struct {
+	ID       string   `json:"id"`
+	Label    string   `json:"label"`
+	HelpText string   `json:"help_text"`
+	Type     string   `json:"type"`
+	Options  []string `json:"options"`
+	Required bool     `json:"required"`
+	// Only asked of guests who accept, e.g. shirt size
+	AcceptedOnly bool `json:"accepted_only"`
+	// Only asked when an earlier question was answered with one of the given values
+	ShowIf *rsvpQuestionCondition `json:"show_if"`
+}
+
+// rsvpQuestionCondition makes a question depend on an earlier select, multi_select or
+// boolean question. Boolean answers match "true" or "false".
+type rsvpQuestionCondition struct {
+	QuestionID string   `json:"question_id"`
+	Values     []string `json:"values"`
+}
+
+// fieldDefinition is the custom field definition a question's answers are checked against
+func (q rsvpQuestion) fieldDefinition() customFieldDefinition {
+	fieldType := q.Type
+	if fieldType == "long_text" {
+		fieldType = "text"
+	}
+	return customFieldDefinition{Key: q.ID, Label: q.Label, Type: fieldType, Options: q.Options, Required: q.Required}
+}
+
+// guestListRSVPQuestions returns a guest list's questions, never nil
+func guestListRSVPQuestions(gl *core.Record) []rsvpQuestion {
+	var questions []rsvpQuestion
+	if err := decodeJSONField(gl, "rsvp_questions", &questions); err != nil || questions == nil {
+		return []rsvpQuestion{}
+	}
+	return questions
+}
+
+// validateRSVPQuestions checks the questions and gives new ones an ID. A show_if must point
+// at an earlier question, which keeps conditions from going round in circles.
+func validateRSVPQuestions(questions []rsvpQuestion) ([]rsvpQuestion, error) {
+	if len(questions) > rsvpQuestionsMax {
+		return nil, fmt.Errorf("a guest list can have at most %d questions", rsvpQuestionsMax)
+	}
+	byID := map[string]rsvpQuestion{}
+	for i := range questions {
+		q := &questions[i]
+		q.Label = strings.TrimSpace(q.Label)
+		q.HelpText = strings.TrimSpace(q.HelpText)
+		if q.Label == "" {
+			return nil, fmt.Errorf("question %d needs a label", i+1)
+		}
+		if len(q.Label) > rsvpQuestionLabelMax {
+			return nil, fmt.Errorf("%s: label must be %d characters or less", q.Label, rsvpQuestionLabelMax)
+		}
+		if len(q.HelpText) > rsvpQuestionHelpMax {
+			return nil, fmt.Errorf("%s: help text must be %d characters or less", q.Label, rsvpQuestionHelpMax)
+		}
+		if !slices.Contains(rsvpQuestionTypes, q.Type) {
+			return nil, fmt.Errorf("%s: type must be one of %s", q.Label, strings.Join(rsvpQuestionTypes, ", "))
+		}
+
+		if q.Type == "select" || q.Type == "multi_select" {
+			options := []string{}
+			for _, opt := range q.Options {
+				opt = strings.TrimSpace(opt)
+				if opt == "" {
+					continue
+				}
+				if slices.ContainsFunc(options, func(o string) bool { return strings.EqualFold(o, opt) }) {
+					return nil, fmt.Errorf("%s: option %q is listed twice", q.Label, opt)
+				}
+				options = append(options, opt)
+			}
+			if len(options) == 0 {
+				return nil, fmt.Errorf("%s needs at least one option", q.Label)
+			}
+			if len(options) > rsvpQuestionOptsMax {
+				return nil, fmt.Errorf("%s can have at most %d options", q.Label, rsvpQuestionOptsMax)
+			}
+			q.Options = options
+		} else {
+			q.Options = nil
+		}
+
+		if q.ShowIf != nil {
+			parent, ok := byID[q.ShowIf.QuestionID]
+			if !ok {
+				return nil, fmt.Errorf("%s: show_if must refer to an earlier question", q.Label)
+			}
+			if len(q.ShowIf.Values) == 0 {
+				return nil, fmt.Errorf("%s: show_if needs at least one value", q.Label)
+			}
+			for _, v := range q.ShowIf.Values {
+				switch parent.Type {
+				case "select", "multi_select":
+					if _, ok := matchCustomFieldOption(parent.fieldDefinition(), v); !ok {
+						return nil, fmt.Errorf("%s: %q is not an option of %s", q.Label, v, parent.Label)
+					}
+				case "boolean":
+					if v != "true" && v != "false" {
+						return nil, fmt.Errorf("%s: show_if values for %s must be true or false", q.Label, parent.Label)
+					}
+				default:
+					return nil, fmt.Errorf("%s: show_if can only depend on a select, multi-select or yes/no question", q.Label)
+				}
+			}
+		}
+
+		if q.ID == "" {
+			q.ID = security.RandomString(10)
+		}
+		if _, ok := byID[q.ID]; ok {
+			return nil, fmt.Errorf("duplicate question id %s", q.ID)
+		}
+		byID[q.ID] = *q
+	}
+	return questions, nil
+}
+
+// rsvpQuestionShown reports whether a question is asked, given the answers so far
+func rsvpQuestionShown(q rsvpQuestion, answers map[string]any, response string) bool {
+	if q.AcceptedOnly && response != "accepted" {
+		return false
+	}
+	if q.ShowIf == nil {
+		return true
+	}
+
+	var given []string
+	switch v := answers[q.ShowIf.QuestionID].(type) {
+	case nil:
+		return false
+	case bool:
+		given = []string{strconv.FormatBool(v)}
+	case []string:
+		given = v
+	case []any:
+		for _, item := range v {
+			given = append(given, fmt.Sprint(item))
+		}
+	default:
+		given = []string{fmt.Sprint(v)}
+	}
+	for _, want := range q.ShowIf.Values {
+		for _, g := range given {
+			if strings.EqualFold(g, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateRSVPAnswers checks a guest's answers against the questions and returns them as
+// stored. Answers to questions that aren't shown, or no longer exist, are dropped; required
+// questions are only enforced when they're shown.
+func validateRSVPAnswers(questions []rsvpQuestion, input map[string]any, response string) (map[string]any, error) {
+	answers := map[string]any{}
+	for _, q := range questions {
+		if !rsvpQuestionShown(q, answers, response) {
+			continue
+		}
+		if raw := input[q.ID]; raw != nil && raw != "" {
+			v, err := coerceCustomFieldValue(q.fieldDefinition(), raw)
+			if err != nil {
+				return nil, err
+			}
+			if s, ok := v.(string); ok && len(s) > rsvpAnswerTextMax {
+				return nil, fmt.Errorf("%s must be %d characters or less", q.Label, rsvpAnswerTextMax)
+			}
+			if !customFieldIsEmpty(v) {
+				answers[q.ID] = v
+			}
+		}
+		if q.Required && customFieldIsEmpty(answers[q.ID]) {
+			return nil, fmt.Errorf("%s is required", q.Label)
+		}
+	}
+	return answers, nil
+}
+
+// itemRSVPAnswers decodes a guest list item's answers, never nil
+func itemRSVPAnswers(item *core.Record) map[string]any {
+	answers := map[string]any{}
+	decodeJSONField(item, "rsvp_answers", &answers)
+	if answers == nil {
+		answers = map[string]any{}
+	}
+	return answers
+}
@@ -0,0 +1,635 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// --- RSVP send rounds ---
+// Every batch of invites, follow-ups or logistics emails is recorded in rsvp_rounds: sends
+// from the admin buttons, sends scheduled for a later time in the event's timezone, and the
+// steps of a guest list's cadence (rsvp_cadence). scheduleRSVPRounds runs the scheduled
+// rounds when they're due and checks every cadence once a minute. All rounds go through the
+// same consent and suppression checks as the buttons, and the outbox does the sending.
+// A round interrupted by a restart is marked failed on startup rather than run again.
+
+const (
+	rsvpActionInvite    = "invite"
+	rsvpActionFollowUp  = "followup"
+	rsvpActionLogistics = "logistics"
+
+	rsvpRoundManual    = "manual"
+	rsvpRoundScheduled = "scheduled"
+	rsvpRoundCadence   = "cadence"
+
+	rsvpRoundStatusScheduled = "scheduled"
+	rsvpRoundStatusSending   = "sending"
+	rsvpRoundStatusSent      = "sent"
+	rsvpRoundStatusCancelled = "cancelled"
+	rsvpRoundStatusFailed    = "failed"
+
+	// Cadence triggers: hours after each guest's invite, or hours before the event starts
+	cadenceAfterInvite = "after_invite"
+	cadenceBeforeEvent = "before_event"
+
+	rsvpRoundInterval    = time.Minute
+	rsvpCadenceMaxSteps  = 10
+	rsvpDefaultTimezone  = "Australia/Sydney"
+	rsvpRoundBatchLimit  = 20
+	rsvpCadenceMaxOffset = 24 * 90
+)
+
+var rsvpActions = []string{rsvpActionInvite, rsvpActionFollowUp, rsvpActionLogistics}
+
+// rsvpAudienceFilters select the guests a round goes to when no items are given
+var rsvpAudienceFilters = map[string]string{
+	rsvpActionInvite:    "(invite_status = 'to_invite' || invite_status = '')",
+	rsvpActionFollowUp:  "invite_status = 'invited' && rsvp_status = ''",
+	rsvpActionLogistics: "rsvp_status = 'accepted'",
+}
+
+// rsvpAuditActions are the audit actions for each kind of round
+var rsvpAuditActions = map[string]string{
+	rsvpActionInvite:    "rsvp_send_invites",
+	rsvpActionFollowUp:  "rsvp_send_followups",
+	rsvpActionLogistics: "rsvp_send_logistics",
+}
+
+// rsvpRoundResult counts what happened to each guest in a round
+type rsvpRoundResult struct {
+	Sent       int
+	Skipped    int
+	OptedOut   int
+	Suppressed int
+	ItemIDs    []string // items emailed
+}
+
+func (r rsvpRoundResult) counts() map[string]any {
+	return map[string]any{
+		"sent":       r.Sent,
+		"skipped":    r.Skipped,
+		"opted_out":  r.OptedOut,
+		"suppressed": r.Suppressed,
+	}
+}
+
+// rsvpCadenceStep is one automatic send in a guest list's cadence, e.g. a follow-up to
+// non-responders 120 hours after their invite, or logistics to accepted guests 8 hours
+// before the event
+type rsvpCadenceStep struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Action      string `json:"action"`
+	Trigger     string `json:"trigger"`
+	OffsetHours int    `json:"offset_hours"`
+}
+
+// inRSVPAudience reports whether an item chosen by ID belongs in a round. Invites can go to
+// anyone picked (e.g. to resend); follow-ups and logistics only to their audience.
+func inRSVPAudience(item *core.Record, action string) bool {
+	switch action {
+	case rsvpActionFollowUp:
+		return item.GetString("invite_status") == "invited" && item.GetString("rsvp_status") == ""
+	case rsvpActionLogistics:
+		return item.GetString("rsvp_status") == "accepted"
+	}
+	return true
+}
+
+// rsvpRoundItems returns the items a round sends to: the given items that are in the
+// action's audience, or the whole audience if none are given
+func rsvpRoundItems(app core.App, guestListID, action string, itemIDs []string) []*core.Record {
+	if len(itemIDs) == 0 {
+		items, err := app.FindRecordsByFilter(
+			utils.CollectionGuestListItems,
+			"guest_list = {:id} && "+rsvpAudienceFilters[action],
+			"sort_order,created",
+			0, 0,
+			dbx.Params{"id": guestListID},
+		)
+		if err != nil {
+			return nil
+		}
+		return items
+	}
+
+	var items []*core.Record
+	for _, itemID := range itemIDs {
+		item, err := app.FindRecordById(utils.CollectionGuestListItems, itemID)
+		if err != nil || item.GetString("guest_list") != guestListID {
+			continue
+		}
+		if inRSVPAudience(item, action) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// sendRSVPRound queues one round of emails for a guest list. Invites give each guest an RSVP
+// token and mark them invited; a guest whose invite can't be queued is left as it was so the
+// next send picks them up.
+func sendRSVPRound(app *pocketbase.PocketBase, guestList *core.Record, action string, items []*core.Record) rsvpRoundResult {
+	// Get event name for email
+	eventName := ""
+	if epID := guestList.GetString("event_projection"); epID != "" {
+		if ep, err := app.FindRecordById(utils.CollectionEventProjections, epID); err == nil {
+			eventName = ep.GetString("name")
+		}
+	}
+	listName := guestList.GetString("name")
+	listDescription := guestList.GetString("description")
+	eventDate := guestList.GetString("event_date")
+	eventTime := guestList.GetString("event_time")
+	eventLocation := guestList.GetString("event_location")
+	emailTheme := buildEmailTheme(app, guestList)
+
+	// Optional calendar attachment, the same file the confirmation email carries
+	var icsData []byte
+	if action == rsvpActionInvite && guestList.GetBool("invite_attach_ics") {
+		icsData = guestListICS(app, guestList, nil)
+	}
+
+	result := rsvpRoundResult{ItemIDs: []string{}}
+	for _, item := range items {
+		contactID := item.GetString("contact")
+		if contactID == "" {
+			result.Skipped++
+			continue
+		}
+
+		contact, err := app.FindRecordById(utils.CollectionContacts, contactID)
+		if err != nil {
+			result.Skipped++
+			continue
+		}
+
+		email := utils.DecryptField(contact.GetString("email"))
+		if email == "" || !strings.Contains(email, "@") {
+			result.Skipped++
+			continue
+		}
+
+		// Logistics go to guests who've accepted, so only bounces and complaints stop them
+		transactional := action == rsvpActionLogistics
+		if !transactional && !contactAllows(contact, consentEventInvites) {
+			result.OptedOut++
+			continue
+		}
+
+		if isEmailSuppressed(app, email, transactional) {
+			result.Suppressed++
+			continue
+		}
+
+		recipientName := contact.GetString("name")
+
+		switch action {
+		case rsvpActionInvite:
+			// Generate RSVP token if not already set
+			if item.GetString("rsvp_token") == "" {
+				token, err := generateToken()
+				if err != nil {
+					result.Skipped++
+					continue
+				}
+				item.Set("rsvp_token", token)
+			}
+
+			// Update invite_status to "invited"
+			previousStatus := item.GetString("invite_status")
+			previousInvitedAt := item.GetString("invited_at")
+			item.Set("invite_status", "invited")
+			item.Set("invited_at", types.NowDateTime())
+			if err := app.Save(item); err != nil {
+				log.Printf("[RSVP] Failed to save item %s: %v", item.Id, err)
+				result.Skipped++
+				continue
+			}
+
+			// Build RSVP URL and queue the email (the outbox sends and retries it)
+			rsvpURL := fmt.Sprintf("%s/rsvp/%s", getPublicBaseURL(), item.GetString("rsvp_token"))
+
+			if err := sendRSVPInviteEmail(app, email, recipientName, rsvpURL, item.GetString("rsvp_token"), item.Id, listName, listDescription, eventName, eventDate, eventTime, eventLocation, emailTheme, icsData); err != nil {
+				// Not queued, so leave the item to be picked up by the next send
+				item.Set("invite_status", previousStatus)
+				item.Set("invited_at", previousInvitedAt)
+				if err := app.Save(item); err != nil {
+					log.Printf("[RSVP] Failed to reset item %s: %v", item.Id, err)
+				}
+				result.Skipped++
+				continue
+			}
+
+		case rsvpActionFollowUp:
+			rsvpToken := item.GetString("rsvp_token")
+			if rsvpToken == "" {
+				result.Skipped++
+				continue
+			}
+
+			rsvpURL := fmt.Sprintf("%s/rsvp/%s", getPublicBaseURL(), rsvpToken)
+
+			if err := sendRSVPFollowUpEmail(app, email, recipientName, rsvpURL, rsvpToken, item.Id, listName, listDescription, eventName, eventDate, eventTime, eventLocation, emailTheme); err != nil {
+				result.Skipped++
+				continue
+			}
+
+		case rsvpActionLogistics:
+			rsvpURL := ""
+			if rsvpToken := item.GetString("rsvp_token"); rsvpToken != "" {
+				rsvpURL = fmt.Sprintf("%s/rsvp/%s", getPublicBaseURL(), rsvpToken)
+			}
+
+			if err := sendRSVPLogisticsEmail(app, email, recipientName, rsvpURL, item.Id, listName, listDescription, eventName, eventDate, eventTime, eventLocation, emailTheme); err != nil {
+				result.Skipped++
+				continue
+			}
+
+		default:
+			result.Skipped++
+			continue
+		}
+
+		result.Sent++
+		result.ItemIDs = append(result.ItemIDs, item.Id)
+	}
+
+	return result
+}
+
+// newRSVPRound returns an unsaved round for a guest list
+func newRSVPRound(app core.App, guestListID, action, source string) (*core.Record, error) {
+	collection, err := app.FindCollectionByNameOrId(utils.CollectionRSVPRounds)
+	if err != nil {
+		return nil, err
+	}
+	round := core.NewRecord(collection)
+	round.Set("guest_list", guestListID)
+	round.Set("action", action)
+	round.Set("source", source)
+	return round, nil
+}
+
+// finishRSVPRound records a round's result
+func finishRSVPRound(app core.App, round *core.Record, result rsvpRoundResult) {
+	round.Set("status", rsvpRoundStatusSent)
+	round.Set("item_ids", result.ItemIDs)
+	for key, v := range result.counts() {
+		round.Set(key, v)
+	}
+	if round.GetDateTime("started_at").IsZero() {
+		round.Set("started_at", types.NowDateTime())
+	}
+	round.Set("completed_at", types.NowDateTime())
+	if err := app.Save(round); err != nil {
+		log.Printf("[RSVPRounds] Failed to record round for %s: %v", round.GetString("guest_list"), err)
+	}
+}
+
+// failRSVPRound marks a scheduled round that couldn't run
+func failRSVPRound(app core.App, round *core.Record, reason string) {
+	round.Set("status", rsvpRoundStatusFailed)
+	round.Set("error", reason)
+	round.Set("completed_at", types.NowDateTime())
+	if err := app.Save(round); err != nil {
+		log.Printf("[RSVPRounds] Failed to update round %s: %v", round.Id, err)
+	}
+}
+
+// logRSVPRound writes the audit entry for a round the scheduler ran
+func logRSVPRound(app *pocketbase.PocketBase, round *core.Record, result rsvpRoundResult) {
+	changes := result.counts()
+	changes["round"] = round.Id
+	changes["source"] = round.GetString("source")
+	if step := round.GetString("cadence_step"); step != "" {
+		changes["cadence_step"] = step
+	}
+	utils.LogAudit(app, utils.AuditEntry{
+		Action:       rsvpAuditActions[round.GetString("action")],
+		ResourceType: utils.CollectionGuestLists,
+		ResourceID:   round.GetString("guest_list"),
+		Changes:      changes,
+		Status:       "success",
+	})
+}
+
+// --- Event timing ---
+
+// guestListLocation returns the event's timezone: the projection's, or Sydney
+func guestListLocation(app core.App, gl *core.Record) *time.Location {
+	tz := rsvpDefaultTimezone
+	if epID := gl.GetString("event_projection"); epID != "" {
+		if ep, err := app.FindRecordById(utils.CollectionEventProjections, epID); err == nil && ep.GetString("timezone") != "" {
+			tz = ep.GetString("timezone")
+		}
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// guestListEventStart returns when the event starts, from the projection's dates or the
+// guest list's own, read as local time in the event's timezone
+func guestListEventStart(app core.App, gl *core.Record) (time.Time, bool) {
+	startDate, startTime := gl.GetString("event_date"), gl.GetString("event_time")
+	if epID := gl.GetString("event_projection"); epID != "" {
+		if ep, err := app.FindRecordById(utils.CollectionEventProjections, epID); err == nil && ep.GetString("start_date") != "" {
+			startDate, startTime = ep.GetString("start_date"), ep.GetString("start_time")
+		}
+	}
+	if startDate == "" {
+		return time.Time{}, false
+	}
+
+	// Dates with an offset are absolute
+	if t, err := time.Parse(time.RFC3339, startDate); err == nil {
+		return t, true
+	}
+	t, err := parseEventDateTime(startDate, startTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+	loc := guestListLocation(app, gl)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc), true
+}
+
+// parseLocalDateTime parses a send time: RFC 3339 as given, or a local date and time
+// ("2026-03-15T09:00", "2026-03-15 09:00") in loc
+func parseLocalDateTime(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date and time: %s", s)
+}
+
+// --- Cadence ---
+
+// guestListCadence returns a guest list's cadence steps
+func guestListCadence(gl *core.Record) []rsvpCadenceStep {
+	var steps []rsvpCadenceStep
+	if err := decodeJSONField(gl, "rsvp_cadence", &steps); err != nil {
+		return nil
+	}
+	return steps
+}
+
+// validateRSVPCadence checks the steps and gives new ones an ID. Follow-ups and logistics
+// only: invites are always sent deliberately.
+func validateRSVPCadence(steps []rsvpCadenceStep) ([]rsvpCadenceStep, error) {
+	if len(steps) > rsvpCadenceMaxSteps {
+		return nil, fmt.Errorf("a cadence can have at most %d steps", rsvpCadenceMaxSteps)
+	}
+	seen := map[string]bool{}
+	for i := range steps {
+		step := &steps[i]
+		step.Name = strings.TrimSpace(step.Name)
+		if step.Action != rsvpActionFollowUp && step.Action != rsvpActionLogistics {
+			return nil, errors.New("action must be followup or logistics")
+		}
+		if step.Trigger != cadenceAfterInvite && step.Trigger != cadenceBeforeEvent {
+			return nil, errors.New("trigger must be after_invite or before_event")
+		}
+		if step.Action == rsvpActionLogistics && step.Trigger != cadenceBeforeEvent {
+			return nil, errors.New("logistics steps must be timed before the event")
+		}
+		if step.OffsetHours < 1 || step.OffsetHours > rsvpCadenceMaxOffset {
+			return nil, fmt.Errorf("offset_hours must be between 1 and %d", rsvpCadenceMaxOffset)
+		}
+		if step.ID == "" {
+			step.ID = security.RandomString(10)
+		}
+		if seen[step.ID] {
+			return nil, fmt.Errorf("duplicate step id %s", step.ID)
+		}
+		seen[step.ID] = true
+	}
+	return steps, nil
+}
+
+// cadenceStepDue returns when a before_event step is due; after_invite steps are due per guest
+func cadenceStepDue(step rsvpCadenceStep, eventStart time.Time) time.Time {
+	return eventStart.Add(-time.Duration(step.OffsetHours) * time.Hour)
+}
+
+// cadenceStepItems returns the guests a step is due for now and hasn't been sent to
+func cadenceStepItems(app core.App, gl *core.Record, step rsvpCadenceStep, eventStart time.Time, hasStart bool, now time.Time) []*core.Record {
+	// No point chasing a response once RSVPs have closed
+	if step.Action == rsvpActionFollowUp && rsvpDeadlinePassed(gl) {
+		return nil
+	}
+
+	var due time.Time
+	if step.Trigger == cadenceBeforeEvent {
+		if !hasStart {
+			return nil
+		}
+		due = cadenceStepDue(step, eventStart)
+		if now.Before(due) {
+			return nil
+		}
+	}
+
+	var items []*core.Record
+	for _, item := range rsvpRoundItems(app, gl.Id, step.Action, nil) {
+		var sent []string
+		decodeJSONField(item, "cadence_sent", &sent)
+		if slices.Contains(sent, step.ID) {
+			continue
+		}
+
+		invitedAt := item.GetDateTime("invited_at")
+		switch step.Trigger {
+		case cadenceAfterInvite:
+			// Guests invited before invite times were recorded have nothing to count from
+			if invitedAt.IsZero() || now.Before(invitedAt.Time().Add(time.Duration(step.OffsetHours)*time.Hour)) {
+				continue
+			}
+		case cadenceBeforeEvent:
+			// A reminder isn't sent to someone invited after it was due
+			if step.Action == rsvpActionFollowUp && !invitedAt.IsZero() && invitedAt.Time().After(due) {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// markCadenceSent records on each item that a step has been handled, whether or not an email
+// went out (a suppressed guest isn't retried every minute)
+func markCadenceSent(app core.App, items []*core.Record, stepID string) {
+	for _, item := range items {
+		var sent []string
+		decodeJSONField(item, "cadence_sent", &sent)
+		item.Set("cadence_sent", append(sent, stepID))
+		if err := app.Save(item); err != nil {
+			log.Printf("[RSVPRounds] Failed to mark cadence step on %s: %v", item.Id, err)
+		}
+	}
+}
+
+// runGuestListCadence runs the steps of one guest list's cadence that are due. Nothing is
+// sent once the event has started.
+func runGuestListCadence(app *pocketbase.PocketBase, gl *core.Record, now time.Time) int {
+	steps := guestListCadence(gl)
+	if len(steps) == 0 {
+		return 0
+	}
+	eventStart, hasStart := guestListEventStart(app, gl)
+	if hasStart && !now.Before(eventStart) {
+		return 0
+	}
+
+	rounds := 0
+	for _, step := range steps {
+		items := cadenceStepItems(app, gl, step, eventStart, hasStart, now)
+		if len(items) == 0 {
+			continue
+		}
+
+		round, err := newRSVPRound(app, gl.Id, step.Action, rsvpRoundCadence)
+		if err != nil {
+			log.Printf("[RSVPRounds] Failed to start cadence round for %s: %v", gl.Id, err)
+			return rounds
+		}
+		round.Set("cadence_step", step.ID)
+		round.Set("status", rsvpRoundStatusSending)
+		round.Set("started_at", types.NowDateTime())
+		if step.Trigger == cadenceBeforeEvent {
+			round.Set("send_at", cadenceStepDue(step, eventStart))
+		}
+		if err := app.Save(round); err != nil {
+			log.Printf("[RSVPRounds] Failed to start cadence round for %s: %v", gl.Id, err)
+			return rounds
+		}
+
+		// Marked first so a failure part-way through can't send a step twice
+		markCadenceSent(app, items, step.ID)
+		result := sendRSVPRound(app, gl, step.Action, items)
+		finishRSVPRound(app, round, result)
+		logRSVPRound(app, round, result)
+		rounds++
+	}
+	return rounds
+}
+
+// runScheduledRSVPRound sends a scheduled round that's due
+func runScheduledRSVPRound(app *pocketbase.PocketBase, id string) {
+	// Claim it first so an admin cancel can't race the send
+	res, err := app.DB().Update(utils.CollectionRSVPRounds,
+		dbx.Params{"status": rsvpRoundStatusSending, "started_at": types.NowDateTime().String()},
+		dbx.HashExp{"id": id, "status": rsvpRoundStatusScheduled}).Execute()
+	if err != nil {
+		log.Printf("[RSVPRounds] Failed to claim round %s: %v", id, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return
+	}
+	round, err := app.FindRecordById(utils.CollectionRSVPRounds, id)
+	if err != nil {
+		return
+	}
+
+	gl, err := app.FindRecordById(utils.CollectionGuestLists, round.GetString("guest_list"))
+	if err != nil {
+		failRSVPRound(app, round, "Guest list not found")
+		return
+	}
+	if !gl.GetBool("rsvp_enabled") {
+		failRSVPRound(app, round, "RSVP is not enabled for this guest list")
+		return
+	}
+
+	var itemIDs []string
+	decodeJSONField(round, "item_ids", &itemIDs)
+	action := round.GetString("action")
+	result := sendRSVPRound(app, gl, action, rsvpRoundItems(app, gl.Id, action, itemIDs))
+	finishRSVPRound(app, round, result)
+	logRSVPRound(app, round, result)
+}
+
+// runDueRSVPRounds sends the scheduled rounds that are due, then the cadence steps
+func runDueRSVPRounds(app *pocketbase.PocketBase) int {
+	ran := 0
+
+	var ids []string
+	err := app.DB().Select("id").From(utils.CollectionRSVPRounds).
+		Where(dbx.HashExp{"status": rsvpRoundStatusScheduled}).
+		AndWhere(dbx.NewExp("[[send_at]] <= {:now}", dbx.Params{"now": types.NowDateTime().String()})).
+		OrderBy("send_at ASC").
+		Limit(rsvpRoundBatchLimit).
+		Column(&ids)
+	if err != nil {
+		log.Printf("[RSVPRounds] Failed to load due rounds: %v", err)
+	}
+	for _, id := range ids {
+		runScheduledRSVPRound(app, id)
+		ran++
+	}
+
+	lists, err := app.FindRecordsByFilter(utils.CollectionGuestLists, "rsvp_enabled = true", "", 0, 0)
+	if err != nil {
+		return ran
+	}
+	now := time.Now()
+	for _, gl := range lists {
+		ran += runGuestListCadence(app, gl, now)
+	}
+	return ran
+}
+
+// failInterruptedRSVPRounds marks rounds left sending by a restart as failed. They aren't
+// run again: some of their emails may already be in the outbox, and a second run would send
+// those guests a duplicate.
+func failInterruptedRSVPRounds(app core.App) {
+	res, err := app.DB().Update(utils.CollectionRSVPRounds,
+		dbx.Params{
+			"status":       rsvpRoundStatusFailed,
+			"error":        "Interrupted by a restart; some emails may have been sent",
+			"completed_at": types.NowDateTime().String(),
+		},
+		dbx.HashExp{"status": rsvpRoundStatusSending}).Execute()
+	if err != nil {
+		log.Printf("[RSVPRounds] Failed to recover interrupted rounds: %v", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("[RSVPRounds] Marked %d interrupted rounds as failed", n)
+	}
+}
+
+// scheduleRSVPRounds checks for due rounds and cadence steps every minute
+func scheduleRSVPRounds(app *pocketbase.PocketBase) {
+	failInterruptedRSVPRounds(app)
+
+	// Wait for app to fully start
+	time.Sleep(60 * time.Second)
+
+	for {
+		if n := runDueRSVPRounds(app); n > 0 {
+			log.Printf("[RSVPRounds] Ran %d rounds", n)
+		}
+		time.Sleep(rsvpRoundInterval)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"unicode"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// --- Full-Text Search Index ---
+// search_index is an FTS5 table (see migrations 1743400000 and 1745200500) holding one row
+// per contact, organisation and guest list. Only non-encrypted fields are indexed — email,
+// phone, bio and location stay out so the index never holds plaintext PII. Guest list notes
+// go in their own notes column, which only admins search.
+
+const (
+	searchEntityContact      = "contact"
+	searchEntityOrganisation = "organisation"
+	searchEntityGuestList    = "guest_list"
+)
+
+// searchDocument is the indexed text for one record
+type searchDocument struct {
+	Title string
+	Body  string
+	Tags  string
+	Notes string // Admin-only (guest list notes)
+}
+
+func joinSearchText(parts ...string) string {
+	kept := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func searchListText(r *core.Record, field string) string {
+	list, _ := normaliseSegmentValue(r.Get(field)).([]string)
+	return strings.Join(list, " ")
+}
+
+// contactSearchDocument indexes names, job title, organisation name, tags/roles, notes,
+// text custom fields and, in the admin-only notes column, the contact's guest list notes
+func contactSearchDocument(app core.App, r *core.Record) searchDocument {
+	orgName := ""
+	if orgID := r.GetString("organisation"); orgID != "" {
+		if org, err := app.FindRecordById(utils.CollectionOrganisations, orgID); err == nil {
+			orgName = org.GetString("name")
+		}
+	}
+
+	body := []string{r.GetString("job_title"), orgName, r.GetString("notes")}
+	for _, v := range recordCustomFields(r) {
+		if s := customFieldString(v); s != "" {
+			body = append(body, s)
+		}
+	}
+
+	notes := []string{}
+	items, err := app.FindRecordsByFilter(utils.CollectionGuestListItems, "contact = {:id}", "", 0, 0, dbx.Params{"id": r.Id})
+	if err == nil {
+		for _, item := range items {
+			notes = append(notes, item.GetString("notes"), item.GetString("client_notes"))
+		}
+	}
+
+	name := r.GetString("name")
+	if name == "" {
+		name = strings.TrimSpace(r.GetString("first_name") + " " + r.GetString("last_name"))
+	}
+
+	return searchDocument{
+		Title: joinSearchText(name, r.GetString("preferred_name")),
+		Body:  joinSearchText(body...),
+		Tags:  joinSearchText(searchListText(r, "tags"), searchListText(r, "roles")),
+		Notes: joinSearchText(notes...),
+	}
+}
+
+func organisationSearchDocument(r *core.Record) searchDocument {
+	body := []string{
+		r.GetString("industry"),
+		r.GetString("description_short"),
+		r.GetString("description_medium"),
+		r.GetString("website"),
+	}
+	for _, v := range recordCustomFields(r) {
+		if s := customFieldString(v); s != "" {
+			body = append(body, s)
+		}
+	}
+
+	return searchDocument{
+		Title: r.GetString("name"),
+		Body:  joinSearchText(body...),
+		Tags:  searchListText(r, "tags"),
+	}
+}
+
+func guestListSearchDocument(r *core.Record) searchDocument {
+	return searchDocument{
+		Title: r.GetString("name"),
+		Body: joinSearchText(
+			r.GetString("description"),
+			r.GetString("organisation_name"),
+			r.GetString("event_location"),
+			r.GetString("landing_headline"),
+		),
+	}
+}
+
+func buildSearchDocument(app core.App, entity string, r *core.Record) searchDocument {
+	switch entity {
+	case searchEntityContact:
+		return contactSearchDocument(app, r)
+	case searchEntityOrganisation:
+		return organisationSearchDocument(r)
+	default:
+		return guestListSearchDocument(r)
+	}
+}
+
+// indexSearchRecord replaces the index row for a record
+func indexSearchRecord(app core.App, entity string, r *core.Record) {
+	doc := buildSearchDocument(app, entity, r)
+
+	err := app.RunInTransaction(func(txApp core.App) error {
+		if _, err := txApp.DB().NewQuery("DELETE FROM search_index WHERE entity = {:entity} AND record_id = {:id}").
+			Bind(dbx.Params{"entity": entity, "id": r.Id}).Execute(); err != nil {
+			return err
+		}
+		_, err := txApp.DB().NewQuery("INSERT INTO search_index (entity, record_id, title, body, tags, notes) VALUES ({:entity}, {:id}, {:title}, {:body}, {:tags}, {:notes})").
+			Bind(dbx.Params{"entity": entity, "id": r.Id, "title": doc.Title, "body": doc.Body, "tags": doc.Tags, "notes": doc.Notes}).Execute()
+		return err
+	})
+	if err != nil {
+		log.Printf("[Search] Failed to index %s %s: %v", entity, r.Id, err)
+	}
+}
+
+func removeSearchRecord(app core.App, entity, id string) {
+	if _, err := app.DB().NewQuery("DELETE FROM search_index WHERE entity = {:entity} AND record_id = {:id}").
+		Bind(dbx.Params{"entity": entity, "id": id}).Execute(); err != nil {
+		log.Printf("[Search] Failed to remove %s %s: %v", entity, id, err)
+	}
+}
+
+// reindexContactByID refreshes a contact's row, e.g. after its guest list notes change
+func reindexContactByID(app core.App, contactID string) {
+	if contactID == "" {
+		return
+	}
+	contact, err := app.FindRecordById(utils.CollectionContacts, contactID)
+	if err != nil {
+		removeSearchRecord(app, searchEntityContact, contactID)
+		return
+	}
+	indexSearchRecord(app, searchEntityContact, contact)
+}
+
+// rebuildSearchIndex clears and repopulates the whole index
+func rebuildSearchIndex(app core.App) (int, error) {
+	sources := []struct {
+		collection string
+		entity     string
+	}{
+		{utils.CollectionContacts, searchEntityContact},
+		{utils.CollectionOrganisations, searchEntityOrganisation},
+		{utils.CollectionGuestLists, searchEntityGuestList},
+	}
+
+	indexed := 0
+	err := app.RunInTransaction(func(txApp core.App) error {
+		if _, err := txApp.DB().NewQuery("DELETE FROM search_index").Execute(); err != nil {
+			return err
+		}
+		for _, src := range sources {
+			records, err := txApp.FindAllRecords(src.collection)
+			if err != nil {
+				return err
+			}
+			for _, r := range records {
+				indexSearchRecord(txApp, src.entity, r)
+				indexed++
+			}
+		}
+		return nil
+	})
+	return indexed, err
+}
+
+// ensureSearchIndex backfills the index on startup when it's empty (first deploy, restored backup)
+func ensureSearchIndex(app *pocketbase.PocketBase) {
+	var count int
+	if err := app.DB().NewQuery("SELECT COUNT(*) FROM search_index").Row(&count); err != nil {
+		log.Printf("[Search] Index unavailable: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	indexed, err := rebuildSearchIndex(app)
+	if err != nil {
+		log.Printf("[Search] Backfill failed: %v", err)
+		return
+	}
+	log.Printf("[Search] Backfilled index with %d records", indexed)
+}
+
+// registerSearchHooks keeps search_index in sync with record changes
+func registerSearchHooks(app *pocketbase.PocketBase) {
+	indexed := map[string]string{
+		utils.CollectionContacts:      searchEntityContact,
+		utils.CollectionOrganisations: searchEntityOrganisation,
+		utils.CollectionGuestLists:    searchEntityGuestList,
+	}
+
+	for coll, ent := range indexed {
+		entity := ent // capture for closure
+
+		app.OnRecordAfterCreateSuccess(coll).BindFunc(func(e *core.RecordEvent) error {
+			indexSearchRecord(e.App, entity, e.Record)
+			return e.Next()
+		})
+
+		app.OnRecordAfterUpdateSuccess(coll).BindFunc(func(e *core.RecordEvent) error {
+			indexSearchRecord(e.App, entity, e.Record)
+			return e.Next()
+		})
+
+		app.OnRecordAfterDeleteSuccess(coll).BindFunc(func(e *core.RecordEvent) error {
+			removeSearchRecord(e.App, entity, e.Record.Id)
+			return e.Next()
+		})
+	}
+
+	// Contacts carry their organisation's name, so org updates reindex its contacts
+	app.OnRecordAfterUpdateSuccess(utils.CollectionOrganisations).BindFunc(func(e *core.RecordEvent) error {
+		orgID := e.Record.Id
+		go func() {
+			contacts, err := app.FindRecordsByFilter(utils.CollectionContacts, "organisation = {:id}", "", 0, 0, dbx.Params{"id": orgID})
+			if err != nil {
+				return
+			}
+			for _, c := range contacts {
+				indexSearchRecord(app, searchEntityContact, c)
+			}
+		}()
+		return e.Next()
+	})
+
+	// Guest list notes are indexed on the contact they're about
+	reindexItemContact := func(e *core.RecordEvent) error {
+		reindexContactByID(e.App, e.Record.GetString("contact"))
+		return e.Next()
+	}
+	app.OnRecordAfterCreateSuccess(utils.CollectionGuestListItems).BindFunc(reindexItemContact)
+	app.OnRecordAfterUpdateSuccess(utils.CollectionGuestListItems).BindFunc(reindexItemContact)
+	app.OnRecordAfterDeleteSuccess(utils.CollectionGuestListItems).BindFunc(reindexItemContact)
+}
+
+// buildSearchMatchQuery turns free text into an FTS5 query: every word must match,
+// as a prefix. Punctuation is dropped so user input can't inject FTS syntax.
+func buildSearchMatchQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) > 10 {
+		words = words[:10]
+	}
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, `"`+w+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// segmentFilter is a node in a saved segment's filter tree. A node is either a
+// group (match + conditions) or a single condition on one of:
+//   - a contact field:            {"field": "tags", "op": "has", "value": "vip"}
+//   - an organisation attribute:  {"field": "organisation.industry", "op": "eq", "value": "technology"}
+//   - a custom field:             {"field": "custom_fields.cohort", "op": "eq", "value": "2025"}
+//   - activity history:           {"activity": {"type": "event_attended"}, "op": "gte", "value": 2}
+//   - guest-list membership:      {"guest_list": {"id": "abc", "rsvp_status": "accepted"}, "op": "member"}
+//
+// Any node can set "not": true to negate it. Example — attended ≥2 events,
+// submitted a CFP, not archived:
+//
+//	{"match": "all", "conditions": [
+//	  {"activity": {"type": "event_attended"}, "op": "gte", "value": 2},
+//	  {"activity": {"type": "cfp_submitted"}, "op": "exists"},
+//	  {"field": "status", "op": "neq", "value": "archived"}
+//	]}
+type segmentFilter struct {
+	Match      string          `json:"match,omitempty"` // all, any (groups only)
+	Conditions []segmentFilter `json:"conditions,omitempty"`
+	Not        bool            `json:"not,omitempty"`
+
+	Field string `json:"field,omitempty"`
+	Op    string `json:"op,omitempty"`
+	Value any    `json:"value,omitempty"`
+
+	Activity  *segmentActivityFilter  `json:"activity,omitempty"`
+	GuestList *segmentGuestListFilter `json:"guest_list,omitempty"`
+}
+
+// segmentActivityFilter narrows which activities are counted for an activity condition
+type segmentActivityFilter struct {
+	Type      string `json:"type,omitempty"`
+	SourceApp string `json:"source_app,omitempty"`
+	Since     string `json:"since,omitempty"` // ISO date, inclusive
+	Until     string `json:"until,omitempty"` // ISO date, exclusive
+}
+
+// segmentGuestListFilter narrows which guest list items count as membership.
+// An empty ID matches membership of any guest list.
+type segmentGuestListFilter struct {
+	ID           string `json:"id,omitempty"`
+	InviteStatus string `json:"invite_status,omitempty"`
+	RSVPStatus   string `json:"rsvp_status,omitempty"`
+}
+
+const segmentMaxDepth = 5
+
+var (
+	segmentFieldOps = map[string]bool{
+		"eq": true, "neq": true, "contains": true, "not_contains": true,
+		"has": true, "not_has": true, "in": true, "not_in": true,
+		"empty": true, "not_empty": true,
+		"gt": true, "gte": true, "lt": true, "lte": true,
+	}
+	segmentCountOps = map[string]bool{
+		"exists": true, "not_exists": true,
+		"eq": true, "neq": true, "gt": true, "gte": true, "lt": true, "lte": true,
+	}
+	segmentMembershipOps = map[string]bool{"member": true, "not_member": true}
+
+	// PII fields are stored encrypted and must be decrypted before comparison
+	segmentPIIFields = map[string]bool{"email": true, "personal_email": true, "phone": true, "bio": true, "location": true}
+)
+
+// parseSegmentFilter decodes and validates a filter tree from request JSON or a stored record
+func parseSegmentFilter(app core.App, raw any) (*segmentFilter, error) {
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	var f segmentFilter
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	contacts, err := app.FindCollectionByNameOrId(utils.CollectionContacts)
+	if err != nil {
+		return nil, err
+	}
+	orgs, err := app.FindCollectionByNameOrId(utils.CollectionOrganisations)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateSegmentFilter(&f, contacts, orgs, 0); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func validateSegmentFilter(f *segmentFilter, contacts, orgs *core.Collection, depth int) error {
+	if depth > segmentMaxDepth {
+		return fmt.Errorf("filter is nested more than %d levels deep", segmentMaxDepth)
+	}
+
+	switch {
+	case f.isGroup():
+		if f.Match != "" && f.Match != "all" && f.Match != "any" {
+			return fmt.Errorf("match must be 'all' or 'any'")
+		}
+		for i := range f.Conditions {
+			if err := validateSegmentFilter(&f.Conditions[i], contacts, orgs, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case f.Activity != nil:
+		if !segmentCountOps[f.Op] {
+			return fmt.Errorf("unsupported operator %q for activity condition", f.Op)
+		}
+		if f.Op != "exists" && f.Op != "not_exists" {
+			if _, ok := segmentNumber(f.Value); !ok {
+				return fmt.Errorf("activity condition requires a numeric value")
+			}
+		}
+		return nil
+
+	case f.GuestList != nil:
+		if !segmentMembershipOps[f.Op] {
+			return fmt.Errorf("unsupported operator %q for guest list condition", f.Op)
+		}
+		return nil
+
+	case f.Field != "":
+		if !segmentFieldOps[f.Op] {
+			return fmt.Errorf("unsupported operator %q for field %s", f.Op, f.Field)
+		}
+		if orgField, ok := strings.CutPrefix(f.Field, "organisation."); ok {
+			if !segmentFieldExists(orgs, orgField) {
+				return fmt.Errorf("unknown organisation field %q", orgField)
+			}
+			return nil
+		}
+		if !segmentFieldExists(contacts, f.Field) {
+			return fmt.Errorf("unknown contact field %q", f.Field)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("condition must have a field, activity or guest_list")
+}
+
+// segmentFieldExists accepts collection fields and custom_fields.<key> paths
+func segmentFieldExists(collection *core.Collection, field string) bool {
+	if key, ok := strings.CutPrefix(field, "custom_fields."); ok {
+		return key != ""
+	}
+	return collection.Fields.GetByName(field) != nil
+}
+
+func (f *segmentFilter) isGroup() bool {
+	return f.Match != "" || len(f.Conditions) > 0
+}
+
+// segmentEvaluator holds the lookups a filter tree needs, loaded once per evaluation
+type segmentEvaluator struct {
+	app            *pocketbase.PocketBase
+	orgs           map[string]*core.Record
+	activityCounts map[*segmentFilter]map[string]int
+	memberships    map[*segmentFilter]map[string]bool
+}
+
+// evaluateSegment returns every contact matching the filter, sorted by name
+func evaluateSegment(app *pocketbase.PocketBase, f *segmentFilter) ([]*core.Record, error) {
+	e := &segmentEvaluator{
+		app:            app,
+		orgs:           map[string]*core.Record{},
+		activityCounts: map[*segmentFilter]map[string]int{},
+		memberships:    map[*segmentFilter]map[string]bool{},
+	}
+	if err := e.prefetch(f); err != nil {
+		return nil, err
+	}
+
+	contacts, err := app.FindRecordsByFilter(utils.CollectionContacts, "", "name", 0, 0, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contacts: %w", err)
+	}
+
+	matched := []*core.Record{}
+	for _, c := range contacts {
+		if e.matches(f, c) {
+			matched = append(matched, c)
+		}
+	}
+	return matched, nil
+}
+
+// prefetch loads activity counts and guest list memberships for every such condition in the tree
+func (e *segmentEvaluator) prefetch(f *segmentFilter) error {
+	for i := range f.Conditions {
+		if err := e.prefetch(&f.Conditions[i]); err != nil {
+			return err
+		}
+	}
+
+	if f.Activity != nil {
+		clauses := []string{}
+		params := map[string]any{}
+		if f.Activity.Type != "" {
+			clauses = append(clauses, "type = {:type}")
+			params["type"] = f.Activity.Type
+		}
+		if f.Activity.SourceApp != "" {
+			clauses = append(clauses, "source_app = {:source_app}")
+			params["source_app"] = f.Activity.SourceApp
+		}
+		// occurred_at is optional on older activities — fall back to created
+		if f.Activity.Since != "" {
+			clauses = append(clauses, "((occurred_at != '' && occurred_at >= {:since}) || (occurred_at = '' && created >= {:since}))")
+			params["since"] = f.Activity.Since
+		}
+		if f.Activity.Until != "" {
+			clauses = append(clauses, "((occurred_at != '' && occurred_at < {:until}) || (occurred_at = '' && created < {:until}))")
+			params["until"] = f.Activity.Until
+		}
+
+		activities, err := e.app.FindRecordsByFilter(utils.CollectionActivities, strings.Join(clauses, " && "), "", 0, 0, params)
+		if err != nil {
+			return fmt.Errorf("failed to load activities: %w", err)
+		}
+		counts := map[string]int{}
+		for _, a := range activities {
+			if cid := a.GetString("contact"); cid != "" {
+				counts[cid]++
+			}
+		}
+		e.activityCounts[f] = counts
+	}
+
+	if f.GuestList != nil {
+		clauses := []string{}
+		params := map[string]any{}
+		if f.GuestList.ID != "" {
+			clauses = append(clauses, "guest_list = {:guest_list}")
+			params["guest_list"] = f.GuestList.ID
+		}
+		if f.GuestList.InviteStatus != "" {
+			clauses = append(clauses, "invite_status = {:invite_status}")
+			params["invite_status"] = f.GuestList.InviteStatus
+		}
+		if f.GuestList.RSVPStatus != "" {
+			clauses = append(clauses, "rsvp_status = {:rsvp_status}")
+			params["rsvp_status"] = f.GuestList.RSVPStatus
+		}
+
+		items, err := e.app.FindRecordsByFilter(utils.CollectionGuestListItems, strings.Join(clauses, " && "), "", 0, 0, params)
+		if err != nil {
+			return fmt.Errorf("failed to load guest list items: %w", err)
+		}
+		members := map[string]bool{}
+		for _, item := range items {
+			if cid := item.GetString("contact"); cid != "" {
+				members[cid] = true
+			}
+		}
+		e.memberships[f] = members
+	}
+
+	return nil
+}
+
+func (e *segmentEvaluator) matches(f *segmentFilter, contact *core.Record) bool {
+	var result bool
+
+	switch {
+	case f.isGroup():
+		if f.Match == "any" {
+			result = false
+			for i := range f.Conditions {
+				if e.matches(&f.Conditions[i], contact) {
+					result = true
+					break
+				}
+			}
+		} else {
+			result = true
+			for i := range f.Conditions {
+				if !e.matches(&f.Conditions[i], contact) {
+					result = false
+					break
+				}
+			}
+		}
+
+	case f.Activity != nil:
+		count := e.activityCounts[f][contact.Id]
+		switch f.Op {
+		case "exists":
+			result = count > 0
+		case "not_exists":
+			result = count == 0
+		default:
+			n, _ := segmentNumber(f.Value)
+			result = compareSegmentNumbers(float64(count), f.Op, n)
+		}
+
+	case f.GuestList != nil:
+		member := e.memberships[f][contact.Id]
+		result = member == (f.Op == "member")
+
+	default:
+		result = compareSegmentValue(e.fieldValue(contact, f.Field), f.Op, f.Value)
+	}
+
+	if f.Not {
+		return !result
+	}
+	return result
+}
+
+// fieldValue resolves a contact or organisation.<field> value, decrypting PII
+func (e *segmentEvaluator) fieldValue(contact *core.Record, field string) any {
+	if orgField, ok := strings.CutPrefix(field, "organisation."); ok {
+		orgID := contact.GetString("organisation")
+		if orgID == "" {
+			return nil
+		}
+		org, cached := e.orgs[orgID]
+		if !cached {
+			org, _ = e.app.FindRecordById(utils.CollectionOrganisations, orgID)
+			e.orgs[orgID] = org
+		}
+		if org == nil {
+			return nil
+		}
+		return segmentRecordValue(org, orgField)
+	}
+
+	if segmentPIIFields[field] {
+		return utils.DecryptField(contact.GetString(field))
+	}
+	return segmentRecordValue(contact, field)
+}
+
+func segmentRecordValue(r *core.Record, field string) any {
+	if key, ok := strings.CutPrefix(field, "custom_fields."); ok {
+		return normaliseSegmentValue(recordCustomFields(r)[key])
+	}
+	return normaliseSegmentValue(r.Get(field))
+}
+
+// normaliseSegmentValue reduces record values to string, float64, bool or []string
+func normaliseSegmentValue(v any) any {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return val
+	case []string:
+		return val
+	case bool:
+		return val
+	case int:
+		return float64(val)
+	case int64:
+		return float64(val)
+	case float64:
+		return val
+	case types.DateTime:
+		if val.IsZero() {
+			return ""
+		}
+		return val.String()
+	case types.JSONRaw:
+		var decoded any
+		if err := json.Unmarshal(val, &decoded); err != nil {
+			return string(val)
+		}
+		return normaliseSegmentValue(decoded)
+	case []any:
+		list := make([]string, 0, len(val))
+		for _, item := range val {
+			list = append(list, fmt.Sprint(item))
+		}
+		return list
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+func compareSegmentValue(actual any, op string, expected any) bool {
+	switch op {
+	case "empty":
+		return segmentIsEmpty(actual)
+	case "not_empty":
+		return !segmentIsEmpty(actual)
+	case "neq":
+		return !compareSegmentValue(actual, "eq", expected)
+	case "not_contains":
+		return !compareSegmentValue(actual, "contains", expected)
+	case "not_has":
+		return !compareSegmentValue(actual, "has", expected)
+	case "not_in":
+		return !compareSegmentValue(actual, "in", expected)
+	}
+
+	want := fmt.Sprint(expected)
+
+	switch val := actual.(type) {
+	case []string:
+		switch op {
+		case "eq", "has":
+			for _, item := range val {
+				if strings.EqualFold(item, want) {
+					return true
+				}
+			}
+		case "contains":
+			for _, item := range val {
+				if strings.Contains(strings.ToLower(item), strings.ToLower(want)) {
+					return true
+				}
+			}
+		case "in":
+			for _, item := range val {
+				if segmentInList(item, expected) {
+					return true
+				}
+			}
+		}
+		return false
+
+	case float64:
+		n, ok := segmentNumber(expected)
+		if op == "in" {
+			return segmentInList(strconv.FormatFloat(val, 'f', -1, 64), expected)
+		}
+		return ok && compareSegmentNumbers(val, op, n)
+
+	case bool:
+		return op == "eq" && strconv.FormatBool(val) == strings.ToLower(want)
+
+	case string:
+		switch op {
+		case "eq", "has":
+			return strings.EqualFold(val, want)
+		case "contains":
+			return strings.Contains(strings.ToLower(val), strings.ToLower(want))
+		case "in":
+			return segmentInList(val, expected)
+		case "gt", "gte", "lt", "lte":
+			if val == "" {
+				return false
+			}
+			// Dates are stored as ISO strings so they compare lexically
+			if a, err := strconv.ParseFloat(val, 64); err == nil {
+				if b, ok := segmentNumber(expected); ok {
+					return compareSegmentNumbers(a, op, b)
+				}
+			}
+			c := strings.Compare(val, want)
+			return (op == "gt" && c > 0) || (op == "gte" && c >= 0) || (op == "lt" && c < 0) || (op == "lte" && c <= 0)
+		}
+	}
+
+	return false
+}
+
+func compareSegmentNumbers(a float64, op string, b float64) bool {
+	switch op {
+	case "eq":
+		return a == b
+	case "neq":
+		return a != b
+	case "gt":
+		return a > b
+	case "gte":
+		return a >= b
+	case "lt":
+		return a < b
+	case "lte":
+		return a <= b
+	}
+	return false
+}
+
+func segmentNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func segmentInList(value string, list any) bool {
+	items, ok := list.([]any)
+	if !ok {
+		return strings.EqualFold(value, fmt.Sprint(list))
+	}
+	for _, item := range items {
+		if strings.EqualFold(value, fmt.Sprint(item)) {
+			return true
+		}
+	}
+	return false
+}
+
+func segmentIsEmpty(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case []string:
+		return len(val) == 0
+	case float64:
+		return val == 0
+	case bool:
+		return !val
+	}
+	return false
+}
+
+// resolveSegment loads a saved segment, evaluates it and caches the member count.
+// Used by the segment endpoints and by consumers (guest list bulk add, Mailchimp sync, exports).
+func resolveSegment(app *pocketbase.PocketBase, segmentID string) (*core.Record, []*core.Record, error) {
+	segment, err := app.FindRecordById(utils.CollectionSegments, segmentID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("segment not found")
+	}
+
+	f, err := parseSegmentFilter(app, segment.Get("filter"))
+	if err != nil {
+		return segment, nil, err
+	}
+
+	contacts, err := evaluateSegment(app, f)
+	if err != nil {
+		return segment, nil, err
+	}
+
+	segment.Set("last_count", len(contacts))
+	segment.Set("last_evaluated_at", time.Now().UTC().Format(time.RFC3339))
+	app.Save(segment)
+
+	return segment, contacts, nil
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+)
+
+// --- Sessions ---
+// A guest list can offer sessions (dinners, workshops, tours) alongside its event, each
+// with its own date, time, location and capacity. Guests who accept pick the sessions
+// they'll come to (rsvp_sessions on the item); their plus-one comes too. A session's
+// capacity is checked when a guest picks it, so a full session is refused rather than
+// waitlisted. Guests promoted from the event waitlist keep the sessions they picked that
+// still have room.
+
+const (
+	sessionsMax       = 20
+	sessionNameMax    = 200
+	sessionTextMax    = 2000
+	sessionDateLayout = "2006-01-02"
+	sessionTimeLayout = "15:04"
+)
+
+// eventSession is one session of a guest list. Date and times are local to the event's
+// timezone.
+type eventSession struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Date        string `json:"date"`       // YYYY-MM-DD
+	StartTime   string `json:"start_time"` // HH:MM
+	EndTime     string `json:"end_time"`   // HH:MM, optional
+	Location    string `json:"location"`
+	Capacity    int    `json:"capacity"` // seats counting plus-ones; 0 means no limit
+}
+
+// sessionFullError is returned when a guest picks a session with no seats left
+type sessionFullError struct {
+	Name string
+}
+
+func (e *sessionFullError) Error() string {
+	return fmt.Sprintf("%s is full, so please choose another session", e.Name)
+}
+
+// guestListSessions returns a guest list's sessions in display order, never nil
+func guestListSessions(gl *core.Record) []eventSession {
+	var sessions []eventSession
+	if err := decodeJSONField(gl, "sessions", &sessions); err != nil || sessions == nil {
+		return []eventSession{}
+	}
+	return sessions
+}
+
+// itemRSVPSessions returns the IDs of the sessions a guest picked, never nil
+func itemRSVPSessions(item *core.Record) []string {
+	var ids []string
+	if err := decodeJSONField(item, "rsvp_sessions", &ids); err != nil || ids == nil {
+		return []string{}
+	}
+	return ids
+}
+
+// pickedSessions returns the sessions with the given IDs, in the guest list's order
+func pickedSessions(sessions []eventSession, ids []string) []eventSession {
+	picked := []eventSession{}
+	for _, s := range sessions {
+		if slices.Contains(ids, s.ID) {
+			picked = append(picked, s)
+		}
+	}
+	return picked
+}
+
+// validateSessions checks the sessions and gives new ones an ID
+func validateSessions(sessions []eventSession) ([]eventSession, error) {
+	if len(sessions) > sessionsMax {
+		return nil, fmt.Errorf("a guest list can have at most %d sessions", sessionsMax)
+	}
+	seen := map[string]bool{}
+	for i := range sessions {
+		s := &sessions[i]
+		s.Name = strings.TrimSpace(s.Name)
+		s.Description = strings.TrimSpace(s.Description)
+		s.Location = strings.TrimSpace(s.Location)
+		s.StartTime = strings.TrimSpace(s.StartTime)
+		s.EndTime = strings.TrimSpace(s.EndTime)
+
+		if s.Name == "" {
+			return nil, fmt.Errorf("session %d needs a name", i+1)
+		}
+		if len(s.Name) > sessionNameMax {
+			return nil, fmt.Errorf("%s: name must be %d characters or less", s.Name, sessionNameMax)
+		}
+		if len(s.Description) > sessionTextMax || len(s.Location) > sessionTextMax {
+			return nil, fmt.Errorf("%s: description and location must be %d characters or less", s.Name, sessionTextMax)
+		}
+		if _, err := time.Parse(sessionDateLayout, s.Date); err != nil {
+			return nil, fmt.Errorf("%s: date must be YYYY-MM-DD", s.Name)
+		}
+		start, err := time.Parse(sessionTimeLayout, s.StartTime)
+		if err != nil {
+			return nil, fmt.Errorf("%s: start_time must be HH:MM", s.Name)
+		}
+		if s.EndTime != "" {
+			end, err := time.Parse(sessionTimeLayout, s.EndTime)
+			if err != nil {
+				return nil, fmt.Errorf("%s: end_time must be HH:MM", s.Name)
+			}
+			if !end.After(start) {
+				return nil, fmt.Errorf("%s: end_time must be after start_time", s.Name)
+			}
+		}
+		if s.Capacity < 0 {
+			return nil, fmt.Errorf("%s: capacity can't be negative", s.Name)
+		}
+
+		if s.ID == "" {
+			s.ID = security.RandomString(10)
+		}
+		if seen[s.ID] {
+			return nil, fmt.Errorf("duplicate session id %s", s.ID)
+		}
+		seen[s.ID] = true
+	}
+	return sessions, nil
+}
+
+// validateRSVPSessions checks the session IDs a guest picked and returns them without
+// duplicates. Only guests who accept pick sessions.
+func validateRSVPSessions(sessions []eventSession, ids []string, response string) ([]string, error) {
+	picked := []string{}
+	if response != "accepted" {
+		return picked, nil
+	}
+	for _, id := range ids {
+		if slices.Contains(picked, id) {
+			continue
+		}
+		if !slices.ContainsFunc(sessions, func(s eventSession) bool { return s.ID == id }) {
+			return nil, fmt.Errorf("unknown session %q", id)
+		}
+		picked = append(picked, id)
+	}
+	return picked, nil
+}
+
+// sessionSeatsTaken counts the seats taken in each of a guest list's sessions by accepted
+// guests and their plus-ones. A plus-one who has also accepted through their own item is
+// counted on that item in the sessions they picked; in the host's other sessions the host
+// still holds their seat.
+func sessionSeatsTaken(app core.App, listID string) map[string]int {
+	seats := map[string]int{}
+	accepted, err := app.FindRecordsByFilter(utils.CollectionGuestListItems,
+		"guest_list = {:id} && invite_status = 'accepted'", "", 0, 0, dbx.Params{"id": listID})
+	if err != nil {
+		return seats
+	}
+
+	// Sessions each host's plus-one has picked through their own item
+	plusOneSessions := map[string][]string{}
+	for _, item := range accepted {
+		if host := item.GetString("plus_one_of"); host != "" {
+			plusOneSessions[host] = itemRSVPSessions(item)
+		}
+	}
+	for _, item := range accepted {
+		plusOne := item.GetBool("rsvp_plus_one")
+		for _, id := range itemRSVPSessions(item) {
+			seats[id] += itemSeats(plusOne && !slices.Contains(plusOneSessions[item.Id], id))
+		}
+	}
+	return seats
+}
+
+// checkSessionCapacity makes sure there's room in each session an accepting guest picked.
+// Seats the guest already holds in a session count towards it, as do seats a host holds
+// for them as a plus-one.
+func checkSessionCapacity(app core.App, gl, item *core.Record, ids []string, plusOne bool) error {
+	sessions := guestListSessions(gl)
+	if len(sessions) == 0 || len(ids) == 0 {
+		return nil
+	}
+
+	original := item.Original()
+	wasAccepted := !item.IsNew() && original.GetString("invite_status") == "accepted"
+	held := itemRSVPSessions(original)
+
+	var hostHeld []string
+	if !wasAccepted && plusOneSeatHeld(app, item) {
+		if host, err := app.FindRecordById(utils.CollectionGuestListItems, item.GetString("plus_one_of")); err == nil {
+			hostHeld = itemRSVPSessions(host)
+		}
+	}
+
+	taken := sessionSeatsTaken(app, gl.Id)
+	for _, s := range pickedSessions(sessions, ids) {
+		if s.Capacity == 0 {
+			continue
+		}
+		extra := itemSeats(plusOne)
+		if wasAccepted && slices.Contains(held, s.ID) {
+			extra -= itemSeats(original.GetBool("rsvp_plus_one"))
+		}
+		if slices.Contains(hostHeld, s.ID) {
+			extra--
+		}
+		if extra > 0 && taken[s.ID]+extra > s.Capacity {
+			return &sessionFullError{Name: s.Name}
+		}
+	}
+	return nil
+}
+
+// seatInSessions returns the sessions a guest being promoted from the waitlist picked that
+// still have room, and counts their seats in taken
+func seatInSessions(gl, item *core.Record, taken map[string]int) []string {
+	need := itemSeats(item.GetBool("rsvp_plus_one"))
+	seated := []string{}
+	for _, s := range pickedSessions(guestListSessions(gl), itemRSVPSessions(item)) {
+		if s.Capacity > 0 && taken[s.ID]+need > s.Capacity {
+			continue
+		}
+		taken[s.ID] += need
+		seated = append(seated, s.ID)
+	}
+	return seated
+}
+
+// buildSessionResponse describes a session with its seats taken
+func buildSessionResponse(s eventSession, taken int) map[string]any {
+	resp := map[string]any{
+		"id":          s.ID,
+		"name":        s.Name,
+		"description": s.Description,
+		"date":        s.Date,
+		"start_time":  s.StartTime,
+		"end_time":    s.EndTime,
+		"location":    s.Location,
+		"capacity":    s.Capacity,
+		"seats_taken": taken,
+		"full":        s.Capacity > 0 && taken >= s.Capacity,
+	}
+	if s.Capacity > 0 {
+		resp["seats_left"] = max(s.Capacity-taken, 0)
+	}
+	return resp
+}
+
+// guestListSessionsResponse lists a guest list's sessions with their seats taken
+func guestListSessionsResponse(app core.App, gl *core.Record) []map[string]any {
+	sessions := guestListSessions(gl)
+	out := make([]map[string]any, len(sessions))
+	if len(sessions) == 0 {
+		return out
+	}
+	taken := sessionSeatsTaken(app, gl.Id)
+	for i, s := range sessions {
+		out[i] = buildSessionResponse(s, taken[s.ID])
+	}
+	return out
+}
+
+// sessionICSEvents builds a calendar entry for each session, in the event's timezone
+func sessionICSEvents(app core.App, gl *core.Record, sessions []eventSession) []ICSEvent {
+	loc := guestListLocation(app, gl)
+	events := make([]ICSEvent, 0, len(sessions))
+	for _, s := range sessions {
+		start, err := time.ParseInLocation(sessionDateLayout+" "+sessionTimeLayout, s.Date+" "+s.StartTime, loc)
+		if err != nil {
+			continue
+		}
+		end := start.Add(2 * time.Hour)
+		if s.EndTime != "" {
+			if t, err := time.ParseInLocation(sessionDateLayout+" "+sessionTimeLayout, s.Date+" "+s.EndTime, loc); err == nil {
+				end = t
+			}
+		}
+		events = append(events, ICSEvent{
+			UID:         fmt.Sprintf("%s-%s@crm.theoutlook.io", gl.Id, s.ID),
+			Summary:     s.Name,
+			Description: s.Description,
+			Location:    s.Location,
+			Start:       start,
+			End:         end,
+			Timezone:    loc.String(),
+		})
+	}
+	return events
+}
+
+// sessionDisplayTime formats a session's date and time for email, e.g.
+// "Thursday 12 March, 6:00pm – 9:00pm"
+func sessionDisplayTime(s eventSession) string {
+	date, err := time.Parse(sessionDateLayout, s.Date)
+	if err != nil {
+		return s.Date
+	}
+	out := date.Format("Monday 2 January")
+	if start, err := time.Parse(sessionTimeLayout, s.StartTime); err == nil {
+		out += ", " + start.Format("3:04pm")
+		if end, err := time.Parse(sessionTimeLayout, s.EndTime); err == nil {
+			out += " – " + end.Format("3:04pm")
+		}
+	}
+	return out
+}
+
+// buildSessionsHTML builds the block listing a guest's sessions in RSVP emails
+func buildSessionsHTML(theme EmailTheme, sessions []eventSession) string {
+	if len(sessions) == 0 {
+		return ""
+	}
+	detailsColor := textWithOpacity(theme.Text, "0.7")
+	html := fmt.Sprintf(`<div style="padding: 0 0 24px 0; margin: 0 0 24px 0; border-bottom: 1px solid %s;">`, theme.Border)
+	html += fmt.Sprintf(`<p style="color: %s; font-size: 12px; text-transform: uppercase; letter-spacing: 2px; margin: 0 0 12px 0;">Your sessions</p>`,
+		textWithOpacity(theme.Text, "0.5"))
+	for _, s := range sessions {
+		html += fmt.Sprintf(`<p style="color: %s; font-size: 15px; margin: 0 0 2px 0;"><strong>%s</strong></p>`,
+			theme.Text, template.HTMLEscapeString(s.Name))
+		details := sessionDisplayTime(s)
+		if s.Location != "" {
+			details += ", " + s.Location
+		}
+		html += fmt.Sprintf(`<p style="color: %s; font-size: 14px; margin: 0 0 12px 0;">%s</p>`,
+			detailsColor, template.HTMLEscapeString(details))
+	}
+	html += `</div>`
+	return html
+}
+
+// addSessionsData adds a guest's sessions to RSVP email template data
+func addSessionsData(data map[string]any, theme EmailTheme, sessions []eventSession) {
+	data["sessions"] = template.HTML(buildSessionsHTML(theme, sessions))
+}
+
+// sampleSessionsData adds sessions for previewing templates: the guest list's own, or a
+// made-up pair without one
+func sampleSessionsData(data map[string]any, theme EmailTheme, guestList *core.Record) {
+	var sessions []eventSession
+	if guestList != nil {
+		sessions = guestListSessions(guestList)
+	}
+	if len(sessions) == 0 {
+		sessions = []eventSession{
+			{Name: "Welcome drinks", Date: "2026-03-12", StartTime: "18:00", EndTime: "19:00", Location: "The Outlook, Sydney"},
+			{Name: "Chef's table dinner", Date: "2026-03-12", StartTime: "19:30", EndTime: "22:00", Location: "The Outlook, Sydney"},
+		}
+	}
+	addSessionsData(data, theme, sessions)
+}
+
+// publicSessionsResponse lists a guest list's sessions for the RSVP page: seats left and
+// whether each is full, without the attendee counts
+func publicSessionsResponse(app core.App, gl *core.Record) []map[string]any {
+	out := guestListSessionsResponse(app, gl)
+	for _, s := range out {
+		delete(s, "seats_taken")
+		delete(s, "capacity")
+	}
+	return out
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/grtshw/outlook-apps-crm/utils"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+)
+
+// --- Email Suppression ---
+// email_suppressions is the global do-not-send list, one row per address keyed by its
+// blind index. deliverEmail consults it before every send. Unsubscribes and manual entries
+// block bulk email (invites, forwards, follow-ups); hard bounces and complaints block
+// transactional email too, since the address can't or won't receive anything.
+
+const (
+	suppressionUnsubscribed = "unsubscribed"
+	suppressionHardBounce   = "hard_bounce"
+	suppressionComplaint    = "complaint"
+	suppressionManual       = "manual"
+
+	unsubscribeTokenPurpose = "unsubscribe"
+)
+
+var suppressionReasons = []string{suppressionUnsubscribed, suppressionHardBounce, suppressionComplaint, suppressionManual}
+
+// errEmailSuppressed is returned by deliverEmail when every recipient is suppressed
+var errEmailSuppressed = errors.New("recipient is on the suppression list")
+
+// suppressionBlocksTransactional reports whether a reason also stops transactional email
+func suppressionBlocksTransactional(reason string) bool {
+	return reason == suppressionHardBounce || reason == suppressionComplaint
+}
+
+func normaliseEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// findEmailSuppression returns the suppression record for an address, or nil
+func findEmailSuppression(app core.App, email string) *core.Record {
+	idx := utils.BlindIndex(normaliseEmail(email))
+	if idx == "" {
+		return nil
+	}
+	record, err := app.FindFirstRecordByFilter(utils.CollectionEmailSuppressions, "email_index = {:idx}", dbx.Params{"idx": idx})
+	if err != nil {
+		return nil
+	}
+	return record
+}
+
+// isEmailSuppressed reports whether an address may not be sent the given kind of email
+func isEmailSuppressed(app core.App, email string, transactional bool) bool {
+	record := findEmailSuppression(app, email)
+	if record == nil {
+		return false
+	}
+	return !transactional || suppressionBlocksTransactional(record.GetString("reason"))
+}
+
+// addEmailSuppression suppresses an address, or updates its existing entry. An existing
+// bounce or complaint isn't downgraded to a weaker reason.
+func addEmailSuppression(app core.App, email, reason, source, details, createdBy string) (*core.Record, error) {
+	email = normaliseEmail(email)
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, fmt.Errorf("invalid email address")
+	}
+	if !slices.Contains(suppressionReasons, reason) {
+		return nil, fmt.Errorf("unknown suppression reason %q", reason)
+	}
+	idx := utils.BlindIndex(email)
+	if idx == "" {
+		return nil, utils.ErrNoKey
+	}
+
+	record := findEmailSuppression(app, email)
+	if record == nil {
+		collection, err := app.FindCollectionByNameOrId(utils.CollectionEmailSuppressions)
+		if err != nil {
+			return nil, err
+		}
+		encrypted, err := utils.Encrypt(email)
+		if err != nil {
+			return nil, err
+		}
+		record = core.NewRecord(collection)
+		record.Set("email_index", idx)
+		record.Set("email", encrypted)
+		record.Set("created_by", createdBy)
+	} else if suppressionBlocksTransactional(record.GetString("reason")) && !suppressionBlocksTransactional(reason) {
+		return record, nil
+	}
+
+	record.Set("reason", reason)
+	record.Set("source", source)
+	record.Set("details", details)
+	if err := app.Save(record); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Suppression] %s suppressed (%s via %s)", email, reason, source)
+	return record, nil
+}
+
+// unsubscribeURL returns the signed one-click unsubscribe link for an address,
+// or "" when tokens can't be signed (no encryption key)
+func unsubscribeURL(email string) string {
+	token := utils.SignToken(unsubscribeTokenPurpose, normaliseEmail(email))
+	if token == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/api/public/unsubscribe/%s", getPublicBaseURL(), url.PathEscape(token))
+}
+
+// withdrawConsentForEmail withdraws every consent category for the contacts that own an address
+func withdrawConsentForEmail(app *pocketbase.PocketBase, email string, proof consentProof) {
+	idx := utils.BlindIndex(normaliseEmail(email))
+	if idx == "" {
+		return
+	}
+	contacts, err := app.FindRecordsByFilter(utils.CollectionContacts,
+		"email_index = {:idx} || personal_email_index = {:idx}", "", 0, 0, dbx.Params{"idx": idx})
+	if err != nil {
+		return
+	}
+
+	changes := map[string]bool{}
+	for _, category := range consentCategories {
+		changes[category] = false
+	}
+	for _, contact := range contacts {
+		if _, err := updateContactConsent(app, contact, changes, proof); err != nil {
+			log.Printf("[Suppression] Failed to withdraw consent for contact %s: %v", contact.Id, err)
+		}
+	}
+}
+
+// deliverEmail sends a message after dropping suppressed To recipients. Bulk (non-transactional)
+// messages also get RFC 8058 List-Unsubscribe headers for the first recipient.
+func deliverEmail(app core.App, msg *mailer.Message, transactional bool) error {
+	to := msg.To[:0]
+	for _, addr := range msg.To {
+		if isEmailSuppressed(app, addr.Address, transactional) {
+			log.Printf("[Email] Skipped suppressed recipient %s (%s)", addr.Address, msg.Subject)
+			continue
+		}
+		to = append(to, addr)
+	}
+	msg.To = to
+	if len(msg.To) == 0 {
+		return errEmailSuppressed
+	}
+
+	if !transactional {
+		if link := unsubscribeURL(msg.To[0].Address); link != "" {
+			if msg.Headers == nil {
+				msg.Headers = map[string]string{}
+			}
+			msg.Headers["List-Unsubscribe"] = "<" + link + ">"
+			msg.Headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+		}
+	}
+
+	// PocketBase's sendmail client sends HTML only; build the multipart message ourselves
+	if !app.Settings().SMTP.Enabled {
+		return sendViaSendmail(msg)
+	}
+	return app.NewMailClient().Send(msg)
+}
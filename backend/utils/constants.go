@@ -16,6 +16,19 @@ const (
 	CollectionHumanitixSyncLog   = "humanitix_sync_log"
 	CollectionAttendeeOTPCodes   = "attendee_otp_codes"
 	CollectionThemes             = "themes"
+	CollectionDuplicateCandidates = "duplicate_candidates"
+	CollectionContactMerges      = "contact_merges"
+	CollectionSegments           = "segments"
+	CollectionCustomFieldDefinitions = "custom_field_definitions"
+	CollectionContactImports     = "contact_imports"
+	CollectionRetentionPolicies  = "retention_policies"
+	CollectionConsentEvents      = "consent_events"
+	CollectionEmailSuppressions  = "email_suppressions"
+	CollectionEmailOutbox        = "email_outbox"
+	CollectionEmailTemplates     = "email_templates"
+	CollectionEmailTemplateVersions = "email_template_versions"
+	CollectionRSVPRounds         = "rsvp_rounds"
+	CollectionEmailEvents        = "email_events"
 )
 
 // Field names
@@ -37,7 +50,7 @@ var (
 
 // Source values (where the record originated from)
 var (
-	SourceValues = []string{"presentations", "awards", "events", "hubspot", "humanitix", "mailchimp", "manual"}
+	SourceValues = []string{"presentations", "awards", "events", "hubspot", "humanitix", "mailchimp", "manual", "csv_import"}
 )
 
 // Activity types
@@ -16,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 )
 
 // currentKeyVersion is the version tag written by Encrypt().
@@ -205,6 +206,162 @@ func BlindIndex(value string) string {
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
+// --- Partial-match blind indexes ---
+// Encrypted fields can only be matched exactly via BlindIndex. These derive extra HMAC
+// tokens from parts of a value (email domain, phone last 4 digits, location words) so
+// contacts can be found by partial input without storing plaintext. Each kind is hashed
+// under its own context so equal text in different indexes gives different tokens.
+// Tokens are space-separated in a single text field and matched with `field ~ token`.
+// Like BlindIndex, they must be recomputed after key rotation.
+
+// Blind index token kinds
+const (
+	TokenKindEmailDomain = "email_domain"
+	TokenKindPhoneLast4  = "phone_last4"
+	TokenKindLocation    = "location"
+)
+
+const (
+	minPrefixTokenLength = 3  // shortest prefix that can be searched
+	maxPrefixTokenLength = 32 // longer prefixes aren't indexed
+)
+
+// SearchToken returns the HMAC token for a search term of the given kind
+func SearchToken(kind, value string) string {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	if normalized == "" {
+		return ""
+	}
+
+	keyOnce.Do(initKey)
+	if !keyInitialized {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, currentKey)
+	mac.Write([]byte(kind + "\x00" + normalized))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// prefixTokens hashes every prefix of value from minPrefixTokenLength up
+func prefixTokens(kind, value string) []string {
+	runes := []rune(strings.ToLower(strings.TrimSpace(value)))
+	tokens := []string{}
+	for n := minPrefixTokenLength; n <= len(runes) && n <= maxPrefixTokenLength; n++ {
+		if t := SearchToken(kind, string(runes[:n])); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	// Values too short for prefixes are still matchable exactly
+	if len(runes) > 0 && len(runes) < minPrefixTokenLength {
+		if t := SearchToken(kind, string(runes)); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// EmailDomainIndex indexes every prefix of an email's domain ("acme.com" matches
+// searches for "@acm", "@acme." and "@acme.com")
+func EmailDomainIndex(email string) string {
+	_, domain, ok := strings.Cut(strings.TrimSpace(email), "@")
+	if !ok || domain == "" {
+		return ""
+	}
+	return strings.Join(prefixTokens(TokenKindEmailDomain, domain), " ")
+}
+
+// PhoneLast4Index indexes the last four digits of a phone number
+func PhoneLast4Index(phone string) string {
+	digits := PhoneDigits(phone)
+	if len(digits) < 4 {
+		return ""
+	}
+	return SearchToken(TokenKindPhoneLast4, digits[len(digits)-4:])
+}
+
+// PhoneDigits strips everything but digits from a phone number
+func PhoneDigits(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// LocationTokensIndex indexes every prefix of each word in a location
+func LocationTokensIndex(location string) string {
+	seen := map[string]bool{}
+	tokens := []string{}
+	for _, word := range LocationWords(location) {
+		for _, t := range prefixTokens(TokenKindLocation, word) {
+			if !seen[t] {
+				seen[t] = true
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+// LocationWords splits a location into lowercase words on anything but letters and digits
+func LocationWords(location string) []string {
+	return strings.FieldsFunc(strings.ToLower(location), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// --- Signed tokens ---
+// Stateless tokens for public links (e.g. unsubscribe) that must not be forgeable.
+// The payload is readable by anyone holding the token; only its integrity is protected.
+// Each purpose signs under its own context so a token can't be replayed elsewhere.
+
+// SignToken returns "<base64url payload>.<base64url HMAC>", or "" if no key is configured
+func SignToken(purpose, payload string) string {
+	keyOnce.Do(initKey)
+	if !keyInitialized {
+		return ""
+	}
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(tokenMAC(currentKey, purpose, encoded))
+}
+
+// VerifyToken checks a SignToken token and returns its payload.
+// Tokens signed with the previous key are still accepted after rotation.
+func VerifyToken(purpose, token string) (string, bool) {
+	keyOnce.Do(initKey)
+	if !keyInitialized {
+		return "", false
+	}
+
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", false
+	}
+	if !hmac.Equal(mac, tokenMAC(currentKey, purpose, encoded)) &&
+		(previousKey == nil || !hmac.Equal(mac, tokenMAC(previousKey, purpose, encoded))) {
+		return "", false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+	return string(payload), true
+}
+
+func tokenMAC(key []byte, purpose, encoded string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("token:" + purpose + "\x00" + encoded))
+	return mac.Sum(nil)
+}
+
 // DecryptField is a helper that decrypts and returns the value.
 // Returns empty string on failure to avoid leaking ciphertext.
 func DecryptField(value string) string {